@@ -4,6 +4,8 @@ import (
 	"github.com/gin-gonic/gin"
 	"chat-room-backend/internal/handler"
 	"chat-room-backend/internal/middleware"
+	"chat-room-backend/internal/models"
+	"chat-room-backend/internal/repository"
 	"chat-room-backend/internal/utils"
 )
 
@@ -11,11 +13,18 @@ import (
 func SetupRoutes(
 	router *gin.Engine,
 	authHandler *handler.AuthHandler,
+	oidcHandler *handler.OIDCHandler,
+	roleHandler *handler.RoleHandler,
 	channelHandler *handler.ChannelHandler,
 	adminHandler *handler.AdminHandler,
+	reportHandler *handler.ReportHandler,
+	botHandler *handler.BotHandler,
 	wsHandler *handler.WebSocketHandler,
-	jwtSecret string,
-	adminHelper *utils.AdminHelper,
+	jwtKeys *utils.KeySet,
+	permissions *middleware.PermissionChecker,
+	banChecker *middleware.BanChecker,
+	apiKeyRepo *repository.APIKeyRepository,
+	userRepo *repository.UserRepository,
 ) {
 	// ============================================================
 	// Health Check
@@ -30,15 +39,20 @@ func SetupRoutes(
 	})
 
 	// ============================================================
-	// WebSocket Endpoint (requires authentication via token query param)
+	// WebSocket Endpoint (requires a ticket query param or a bearer token)
 	// ============================================================
-	// Store JWT secret in context for WebSocket handler
+	// Store JWT keys in context for WebSocket handler
 	router.Use(func(c *gin.Context) {
-		c.Set("jwtSecret", jwtSecret)
+		c.Set("jwtKeys", jwtKeys)
 		c.Next()
 	})
 	router.GET("/ws", wsHandler.HandleWebSocket)
 
+	// ============================================================
+	// Public signing keys, so other services can verify our tokens
+	// ============================================================
+	router.GET("/.well-known/jwks.json", authHandler.JWKS)
+
 	// ============================================================
 	// API Routes
 	// ============================================================
@@ -51,14 +65,55 @@ func SetupRoutes(
 	{
 		auth.POST("/register", authHandler.Register)
 		auth.POST("/login", authHandler.Login)
+		auth.POST("/login/2fa", authHandler.LoginTwoFactor)
+		auth.POST("/refresh", authHandler.Refresh)
+		auth.POST("/logout", authHandler.Logout)
 		auth.GET("/verify", authHandler.Verify)
+		auth.GET("/permissions", middleware.AuthMiddleware(jwtKeys, banChecker), roleHandler.GetMyPermissions)
+		auth.POST("/ws-ticket", middleware.AuthMiddleware(jwtKeys, banChecker), wsHandler.IssueTicket)
+	}
+
+	// Two-factor enrollment (requires authentication)
+	twoFactor := auth.Group("/2fa")
+	twoFactor.Use(middleware.AuthMiddleware(jwtKeys, banChecker))
+	{
+		twoFactor.GET("", authHandler.GetTwoFactorStatus)
+		twoFactor.POST("/setup", authHandler.SetupTwoFactor)
+		twoFactor.POST("/enable", authHandler.EnableTwoFactor)
+		twoFactor.POST("/disable", authHandler.DisableTwoFactor)
+		twoFactor.POST("/recovery-codes", authHandler.RegenerateRecoveryCodes)
+	}
+
+	// Single sign-on. The provider redirects the browser to the frontend,
+	// which posts the code and state to the callback.
+	oidc := auth.Group("/oidc")
+	{
+		oidc.GET("", oidcHandler.Status)
+		oidc.GET("/login", oidcHandler.Login)
+		oidc.POST("/callback", oidcHandler.Callback)
+		oidc.POST("/link", middleware.AuthMiddleware(jwtKeys, banChecker), oidcHandler.StartLink)
+		oidc.POST("/link/callback", middleware.AuthMiddleware(jwtKeys, banChecker), oidcHandler.CompleteLink)
+		oidc.DELETE("/link", middleware.AuthMiddleware(jwtKeys, banChecker), oidcHandler.Unlink)
+	}
+
+	// Password management
+	auth.POST("/password", middleware.AuthMiddleware(jwtKeys, banChecker), authHandler.ChangePassword)
+	auth.POST("/password/reset", authHandler.ResetPassword)
+
+	// Session management (requires authentication)
+	sessions := auth.Group("/sessions")
+	sessions.Use(middleware.AuthMiddleware(jwtKeys, banChecker))
+	{
+		sessions.GET("", authHandler.GetSessions)
+		sessions.DELETE("", authHandler.RevokeOtherSessions)
+		sessions.DELETE("/:id", authHandler.RevokeSession)
 	}
 
 	// ============================================================
 	// Channel Routes (require authentication)
 	// ============================================================
 	channels := api.Group("/channels")
-	channels.Use(middleware.AuthMiddleware(jwtSecret))
+	channels.Use(middleware.AuthMiddleware(jwtKeys, banChecker))
 	{
 		// User channel operations
 		channels.GET("", channelHandler.GetUserChannels)
@@ -67,31 +122,122 @@ func SetupRoutes(
 		channels.POST("/:id/leave", channelHandler.LeaveChannel)
 		channels.GET("/:id/messages", channelHandler.GetChannelMessages)
 
-		// Admin-only: create channel
-		channels.POST("", middleware.AdminMiddleware(adminHelper), channelHandler.CreateChannel)
+		// Create channels
+		channels.POST("", middleware.RequirePermission(permissions, models.PermChannelsCreate), channelHandler.CreateChannel)
+
+		// Manage channel members and who may post
+		manage := middleware.RequirePermission(permissions, models.PermChannelsManage)
+		channels.POST("/:id/kick", manage, channelHandler.KickMember)
+		channels.PUT("/:id/moderators/:userId", manage, channelHandler.AddModerator)
+		channels.DELETE("/:id/moderators/:userId", manage, channelHandler.RemoveModerator)
+		channels.PUT("/:id/posting-policy", manage, channelHandler.SetPostingPolicy)
+		channels.PUT("/:id/posters/:userId", manage, channelHandler.AddPoster)
+		channels.DELETE("/:id/posters/:userId", manage, channelHandler.RemovePoster)
+
+		// Channel managers and channel moderators (checked by the service)
+		channels.PUT("/:id/slow-mode", channelHandler.SetSlowMode)
+
+		// Channel-scoped word filters
+		filters := middleware.RequirePermission(permissions, models.PermWordFiltersManage)
+		channels.GET("/:id/word-filters", filters, adminHandler.GetChannelWordFilters)
+		channels.POST("/:id/word-filters", filters, adminHandler.AddChannelWordFilter)
+		channels.DELETE("/:id/word-filters/:filterId", filters, adminHandler.RemoveChannelWordFilter)
+	}
+
+	// ============================================================
+	// Message Routes (require authentication)
+	// ============================================================
+	messages := api.Group("/messages")
+	messages.Use(middleware.AuthMiddleware(jwtKeys, banChecker))
+	{
+		messages.POST("/:id/report", reportHandler.ReportMessage)
+	}
+
+	// ============================================================
+	// Bot API Routes (require a bot API key + a scope per route)
+	// ============================================================
+	bot := api.Group("/bot")
+	bot.Use(middleware.BotAuthMiddleware(apiKeyRepo, userRepo, banChecker))
+	{
+		bot.GET("/me", botHandler.GetMe)
+		bot.GET("/channels", botHandler.GetChannels)
+		bot.POST("/channels/:id/messages", middleware.RequireScope(models.APIKeyScopeMessagesWrite), botHandler.SendMessage)
+		bot.GET("/channels/:id/messages", middleware.RequireScope(models.APIKeyScopeMessagesRead), botHandler.GetMessages)
 	}
 
 	// ============================================================
-	// Admin Routes (require authentication + admin role)
+	// Admin Routes (require authentication + a permission per route)
 	// ============================================================
 	admin := api.Group("/admin")
-	admin.Use(middleware.AuthMiddleware(jwtSecret))
-	admin.Use(middleware.AdminMiddleware(adminHelper))
+	admin.Use(middleware.AuthMiddleware(jwtKeys, banChecker))
 	{
 		// Word filter management
-		admin.GET("/word-filters", adminHandler.GetWordFilters)
-		admin.POST("/word-filters", adminHandler.AddWordFilter)
-		admin.DELETE("/word-filters/:id", adminHandler.RemoveWordFilter)
+		filters := admin.Group("", middleware.RequirePermission(permissions, models.PermWordFiltersManage))
+		filters.GET("/word-filters", adminHandler.GetWordFilters)
+		filters.POST("/word-filters", adminHandler.AddWordFilter)
+		filters.POST("/word-filters/test", adminHandler.TestWordFilter)
+		filters.POST("/word-filters/import", adminHandler.ImportWordFilters)
+		filters.GET("/word-filters/export", adminHandler.ExportWordFilters)
+		filters.DELETE("/word-filters/:id", adminHandler.RemoveWordFilter)
+
+		// Review queue
+		reports := admin.Group("", middleware.RequirePermission(permissions, models.PermReportsManage))
+		reports.GET("/reports", reportHandler.GetReports)
+		reports.POST("/reports/:id/action", reportHandler.ActOnReport)
 
 		// User management
-		admin.GET("/users", adminHandler.GetAllUsers)
-		admin.POST("/mute-user", adminHandler.MuteUser)
-		admin.POST("/unmute-user", adminHandler.UnmuteUser)
+		users := admin.Group("", middleware.RequirePermission(permissions, models.PermUsersView))
+		users.GET("/users", adminHandler.GetAllUsers)
+		users.GET("/users/:id/strikes", adminHandler.GetUserStrikes)
+
+		mute := admin.Group("", middleware.RequirePermission(permissions, models.PermUsersMute))
+		mute.POST("/users/:id/strikes/reset", adminHandler.ResetUserStrikes)
+		mute.POST("/mute-user", adminHandler.MuteUser)
+		mute.POST("/unmute-user", adminHandler.UnmuteUser)
+
+		ban := admin.Group("", middleware.RequirePermission(permissions, models.PermUsersBan))
+		ban.POST("/ban-user", adminHandler.BanUser)
+		ban.POST("/unban-user", adminHandler.UnbanUser)
+
+		admin.POST("/users/:id/password-reset", middleware.RequirePermission(permissions, models.PermUsersPasswordReset), authHandler.IssuePasswordReset)
+
+		lockouts := admin.Group("", middleware.RequirePermission(permissions, models.PermLoginLockouts))
+		lockouts.GET("/login-lockouts", authHandler.GetLoginLockouts)
+		lockouts.DELETE("/login-lockouts/:kind/:key", authHandler.ClearLoginLockout)
 
 		// Global mute
-		admin.POST("/global-mute", adminHandler.ToggleGlobalMute)
+		globalMute := admin.Group("", middleware.RequirePermission(permissions, models.PermGlobalMuteManage))
+		globalMute.POST("/global-mute", adminHandler.ToggleGlobalMute)
+		globalMute.GET("/global-mute/schedules", adminHandler.GetGlobalMuteSchedules)
+		globalMute.POST("/global-mute/schedules", adminHandler.CreateGlobalMuteSchedule)
+		globalMute.DELETE("/global-mute/schedules/:id", adminHandler.DeleteGlobalMuteSchedule)
+
+		// Audit log
+		audit := admin.Group("", middleware.RequirePermission(permissions, models.PermAuditView))
+		audit.GET("/audit-log", adminHandler.GetAuditLogs)
+		audit.GET("/audit-log/export", adminHandler.ExportAuditLogs)
+
+		// Roles and permissions
+		roles := admin.Group("", middleware.RequirePermission(permissions, models.PermRolesManage))
+		roles.GET("/permissions", roleHandler.GetPermissions)
+		roles.GET("/roles", roleHandler.GetRoles)
+		roles.POST("/roles", roleHandler.CreateRole)
+		roles.PUT("/roles/:name", roleHandler.UpdateRole)
+		roles.DELETE("/roles/:name", roleHandler.DeleteRole)
+		roles.PUT("/users/:id/role", roleHandler.AssignRole)
+
+		// Bot accounts, their API keys and channels
+		bots := admin.Group("", middleware.RequirePermission(permissions, models.PermBotsManage))
+		bots.GET("/bots", botHandler.GetBots)
+		bots.POST("/bots", botHandler.CreateBot)
+		bots.DELETE("/bots/:id", botHandler.DeleteBot)
+		bots.GET("/bots/:id/keys", botHandler.GetAPIKeys)
+		bots.POST("/bots/:id/keys", botHandler.CreateAPIKey)
+		bots.DELETE("/bots/:id/keys/:keyId", botHandler.RevokeAPIKey)
+		bots.PUT("/bots/:id/channels/:channelId", botHandler.AddToChannel)
+		bots.DELETE("/bots/:id/channels/:channelId", botHandler.RemoveFromChannel)
 	}
 
 	// Global mute status (requires auth but not admin)
-	api.GET("/admin/global-mute", middleware.AuthMiddleware(jwtSecret), adminHandler.GetGlobalMuteStatus)
+	api.GET("/admin/global-mute", middleware.AuthMiddleware(jwtKeys, banChecker), adminHandler.GetGlobalMuteStatus)
 }
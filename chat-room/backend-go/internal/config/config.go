@@ -3,19 +3,176 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
+// DefaultJWTSecret is the development fallback for JWT_SECRET. The server
+// refuses to sign tokens with it in release mode.
+const DefaultJWTSecret = "your-secret-key-change-in-production"
+
+// Supported JWT signing algorithms
+const (
+	JWTAlgHS256 = "HS256"
+	JWTAlgEdDSA = "EdDSA"
+	JWTAlgRS256 = "RS256"
+)
+
 // Config holds all application configuration
 type Config struct {
 	Port         string
 	GinMode      string
 	MongoURI     string
 	JWTSecret    string
+	JWTKeys      JWTKeyConfig
 	CORSOrigin   string
 	AIServiceURL string
 	LogLevel     string
+	Tokens       TokenConfig
+	Passwords    PasswordConfig
+	LoginGuard   LoginGuardConfig
+	OIDC         OIDCConfig
+	Roles        RolesConfig
+	Escalation   EscalationConfig
+	RateLimit    RateLimitConfig
+	BotRateLimit RateLimitConfig // Bot API: messages use the message bucket, history reads the event bucket
+}
+
+// AllowedOrigins returns the origins listed in CORS_ORIGIN, which may hold
+// several comma-separated origins. "*" allows every origin.
+func (c *Config) AllowedOrigins() []string {
+	return splitList(c.CORSOrigin)
+}
+
+// JWTKeyConfig selects how access tokens are signed. With EdDSA or RS256
+// tokens are signed with the private key in SigningKeyFile; retired keys
+// listed in VerifyKeyFiles are still accepted, so keys can be rotated
+// without logging everyone out.
+type JWTKeyConfig struct {
+	Algorithm      string
+	SigningKeyFile string   // PEM private key, required unless HS256
+	VerifyKeyFiles []string // PEM public or private keys
+}
+
+// TokenConfig sets the lifetime of access and refresh tokens. Access tokens
+// are short-lived; clients renew them with a refresh token. WebSocket
+// tickets are single-use and only need to outlive the handshake.
+type TokenConfig struct {
+	AccessTTLMinutes   int
+	RefreshTTLDays     int
+	WSTicketTTLSeconds int
+}
+
+// AccessTTL returns the access token lifetime
+func (tc TokenConfig) AccessTTL() time.Duration {
+	return time.Duration(tc.AccessTTLMinutes) * time.Minute
+}
+
+// RefreshTTL returns the refresh token lifetime
+func (tc TokenConfig) RefreshTTL() time.Duration {
+	return time.Duration(tc.RefreshTTLDays) * 24 * time.Hour
+}
+
+// WSTicketTTL returns the WebSocket ticket lifetime
+func (tc TokenConfig) WSTicketTTL() time.Duration {
+	return time.Duration(tc.WSTicketTTLSeconds) * time.Second
+}
+
+// OIDCConfig enables single sign-on with an OpenID Connect provider using
+// the authorization code flow with PKCE. SSO is off while Issuer is empty.
+type OIDCConfig struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string // Empty for public clients, which rely on PKCE alone
+	RedirectURL  string // Frontend page the provider sends the browser back to
+	Scopes       []string
+	GroupsClaim  string // ID token or userinfo claim listing the user's groups
+	AdminGroup   string // Members of this group get admin rights, empty disables the mapping
+}
+
+// Enabled reports whether single sign-on is configured
+func (oc OIDCConfig) Enabled() bool {
+	return oc.Issuer != ""
+}
+
+// RolesConfig seeds the admin role. AdminsFile is the legacy list of
+// admin usernames, imported once into the database; BootstrapAdminID makes
+// a user admin on every start, to recover from having no admin left.
+type RolesConfig struct {
+	AdminsFile       string
+	BootstrapAdminID string
+}
+
+// PasswordConfig is the password policy and how passwords are hashed
+type PasswordConfig struct {
+	MinLength       int
+	MaxLength       int
+	MinClasses      int  // Character classes required, out of lowercase, uppercase, digits and symbols
+	RejectUsername  bool // Reject passwords containing the username
+	ResetTTLMinutes int  // Lifetime of admin-issued reset tokens
+	Argon2          Argon2Config
+}
+
+// Argon2Config sets the argon2id parameters of new password hashes. Hashes
+// made with other parameters, and legacy bcrypt hashes, are replaced on
+// the next successful login.
+type Argon2Config struct {
+	MemoryKiB   int
+	Iterations  int
+	Parallelism int
+}
+
+// LoginGuardConfig throttles failed logins per username and per IP.
+// Failures older than the window are forgotten.
+type LoginGuardConfig struct {
+	WindowMinutes      int `json:"windowMinutes"`
+	FreeAttempts       int `json:"freeAttempts"`       // Failures allowed for a username before backoff starts
+	IPFreeAttempts     int `json:"ipFreeAttempts"`     // Failures allowed from an IP, higher as IPs can be shared
+	BackoffBaseSeconds int `json:"backoffBaseSeconds"` // Doubled with every further failure
+	BackoffMaxSeconds  int `json:"backoffMaxSeconds"`
+	UserLockoutAfter   int `json:"userLockoutAfter"` // Failures for a username that lock it, 0 disables
+	IPLockoutAfter     int `json:"ipLockoutAfter"`   // Failures from an IP that lock it, 0 disables
+	LockoutMinutes     int `json:"lockoutMinutes"`
+	SuspiciousUsers    int `json:"suspiciousUsers"` // Distinct usernames failed from one IP that get audited, 0 disables
+}
+
+// EscalationConfig is the ladder applied to repeated word filter hits.
+// Strikes older than the window no longer count, so counters decay over time.
+type EscalationConfig struct {
+	WindowMinutes    int `json:"windowMinutes"` // Strikes within this window count towards warn and mute
+	WarnThreshold    int `json:"warnThreshold"` // Strikes in the window that trigger a warning
+	MuteThreshold    int `json:"muteThreshold"` // Strikes in the window that trigger a mute
+	MuteMinutes      int `json:"muteMinutes"`
+	BanAfterMutes    int `json:"banAfterMutes"` // Escalation mutes within the ban window that trigger a ban
+	BanWindowMinutes int `json:"banWindowMinutes"`
+	BanMinutes       int `json:"banMinutes"` // 0 for permanent
+}
+
+// RateLimitConfig limits how fast a user may send WebSocket events.
+// Each event class is a token bucket holding Burst tokens, refilled at
+// PerMinute tokens a minute.
+type RateLimitConfig struct {
+	MessageBurst     int // send-message
+	MessagePerMinute int
+	TypingBurst      int // typing and stop-typing
+	TypingPerMinute  int
+	EventBurst       int // Every other event
+	EventPerMinute   int
+
+	// Violations within the window escalate from dropping the event to a
+	// temporary mute and then to closing the connection
+	ViolationWindowSeconds int
+	MuteAfterViolations    int // 0 disables the auto-mute
+	MuteMinutes            int
+	DisconnectAfter        int // 0 disables disconnecting
+
+	// Sending the same text more than DuplicateLimit times within the
+	// duplicate window counts as a violation
+	DuplicateLimit         int // 0 disables duplicate detection
+	DuplicateWindowSeconds int
 }
 
 // Load loads configuration from environment variables
@@ -25,15 +182,115 @@ func Load() *Config {
 		log.Println("No .env file found, using system environment variables")
 	}
 
-	return &Config{
-		Port:         getEnv("PORT", "3000"),
-		GinMode:      getEnv("GIN_MODE", "debug"),
-		MongoURI:     getEnv("MONGODB_URI", "mongodb://localhost:27017/chat-room"),
-		JWTSecret:    getEnv("JWT_SECRET", "your-secret-key-change-in-production"),
+	cfg := &Config{
+		Port:      getEnv("PORT", "3000"),
+		GinMode:   getEnv("GIN_MODE", "debug"),
+		MongoURI:  getEnv("MONGODB_URI", "mongodb://localhost:27017/chat-room"),
+		JWTSecret: getEnv("JWT_SECRET", DefaultJWTSecret),
+		JWTKeys: JWTKeyConfig{
+			Algorithm:      getEnv("JWT_ALGORITHM", JWTAlgHS256),
+			SigningKeyFile: getEnv("JWT_SIGNING_KEY_FILE", ""),
+			VerifyKeyFiles: getEnvList("JWT_VERIFY_KEY_FILES"),
+		},
 		CORSOrigin:   getEnv("CORS_ORIGIN", "*"),
 		AIServiceURL: getEnv("AI_SERVICE_URL", "http://localhost:5000"),
 		LogLevel:     getEnv("LOG_LEVEL", "info"),
+		Tokens: TokenConfig{
+			AccessTTLMinutes:   getEnvInt("ACCESS_TOKEN_TTL_MINUTES", 15),
+			RefreshTTLDays:     getEnvInt("REFRESH_TOKEN_TTL_DAYS", 30),
+			WSTicketTTLSeconds: getEnvInt("WS_TICKET_TTL_SECONDS", 30),
+		},
+		Passwords: PasswordConfig{
+			MinLength:       getEnvInt("PASSWORD_MIN_LENGTH", 6),
+			MaxLength:       getEnvInt("PASSWORD_MAX_LENGTH", 128),
+			MinClasses:      getEnvInt("PASSWORD_MIN_CLASSES", 1),
+			RejectUsername:  getEnvBool("PASSWORD_REJECT_USERNAME", true),
+			ResetTTLMinutes: getEnvInt("PASSWORD_RESET_TTL_MINUTES", 60),
+			Argon2: Argon2Config{
+				MemoryKiB:   getEnvInt("ARGON2_MEMORY_KIB", 19*1024),
+				Iterations:  getEnvInt("ARGON2_ITERATIONS", 2),
+				Parallelism: getEnvInt("ARGON2_PARALLELISM", 1),
+			},
+		},
+		LoginGuard: LoginGuardConfig{
+			WindowMinutes:      getEnvInt("LOGIN_FAILURE_WINDOW_MINUTES", 15),
+			FreeAttempts:       getEnvInt("LOGIN_FREE_ATTEMPTS", 3),
+			IPFreeAttempts:     getEnvInt("LOGIN_IP_FREE_ATTEMPTS", 10),
+			BackoffBaseSeconds: getEnvInt("LOGIN_BACKOFF_BASE_SECONDS", 1),
+			BackoffMaxSeconds:  getEnvInt("LOGIN_BACKOFF_MAX_SECONDS", 60),
+			UserLockoutAfter:   getEnvInt("LOGIN_USER_LOCKOUT_AFTER", 10),
+			IPLockoutAfter:     getEnvInt("LOGIN_IP_LOCKOUT_AFTER", 50),
+			LockoutMinutes:     getEnvInt("LOGIN_LOCKOUT_MINUTES", 15),
+			SuspiciousUsers:    getEnvInt("LOGIN_SUSPICIOUS_USERNAMES", 10),
+		},
+		OIDC: OIDCConfig{
+			Issuer:       strings.TrimSuffix(getEnv("OIDC_ISSUER", ""), "/"),
+			ClientID:     getEnv("OIDC_CLIENT_ID", ""),
+			ClientSecret: getEnv("OIDC_CLIENT_SECRET", ""),
+			RedirectURL:  getEnv("OIDC_REDIRECT_URL", ""),
+			Scopes:       getEnvList("OIDC_SCOPES"),
+			GroupsClaim:  getEnv("OIDC_GROUPS_CLAIM", "groups"),
+			AdminGroup:   getEnv("OIDC_ADMIN_GROUP", ""),
+		},
+		Roles: RolesConfig{
+			AdminsFile:       getEnv("ADMINS_FILE", "internal/config/admins.json"),
+			BootstrapAdminID: getEnv("BOOTSTRAP_ADMIN_ID", ""),
+		},
+		Escalation: EscalationConfig{
+			WindowMinutes:    getEnvInt("STRIKE_WINDOW_MINUTES", 10),
+			WarnThreshold:    getEnvInt("STRIKE_WARN_THRESHOLD", 3),
+			MuteThreshold:    getEnvInt("STRIKE_MUTE_THRESHOLD", 5),
+			MuteMinutes:      getEnvInt("STRIKE_MUTE_MINUTES", 30),
+			BanAfterMutes:    getEnvInt("STRIKE_BAN_AFTER_MUTES", 3),
+			BanWindowMinutes: getEnvInt("STRIKE_BAN_WINDOW_MINUTES", 24*60),
+			BanMinutes:       getEnvInt("STRIKE_BAN_MINUTES", 24*60),
+		},
+		RateLimit: RateLimitConfig{
+			MessageBurst:           getEnvInt("WS_MESSAGE_BURST", 5),
+			MessagePerMinute:       getEnvInt("WS_MESSAGE_PER_MINUTE", 30),
+			TypingBurst:            getEnvInt("WS_TYPING_BURST", 10),
+			TypingPerMinute:        getEnvInt("WS_TYPING_PER_MINUTE", 120),
+			EventBurst:             getEnvInt("WS_EVENT_BURST", 20),
+			EventPerMinute:         getEnvInt("WS_EVENT_PER_MINUTE", 120),
+			ViolationWindowSeconds: getEnvInt("WS_FLOOD_WINDOW_SECONDS", 60),
+			MuteAfterViolations:    getEnvInt("WS_FLOOD_MUTE_AFTER", 10),
+			MuteMinutes:            getEnvInt("WS_FLOOD_MUTE_MINUTES", 5),
+			DisconnectAfter:        getEnvInt("WS_FLOOD_DISCONNECT_AFTER", 30),
+			DuplicateLimit:         getEnvInt("WS_DUPLICATE_LIMIT", 3),
+			DuplicateWindowSeconds: getEnvInt("WS_DUPLICATE_WINDOW_SECONDS", 30),
+		},
+		// Bots are refused with Retry-After instead of being muted or
+		// disconnected, and often send the same notification twice
+		BotRateLimit: RateLimitConfig{
+			MessageBurst:     getEnvInt("BOT_MESSAGE_BURST", 10),
+			MessagePerMinute: getEnvInt("BOT_MESSAGE_PER_MINUTE", 60),
+			EventBurst:       getEnvInt("BOT_READ_BURST", 10),
+			EventPerMinute:   getEnvInt("BOT_READ_PER_MINUTE", 60),
+		},
+	}
+
+	// Anyone can forge tokens signed with the default secret
+	if cfg.GinMode == "release" && cfg.JWTKeys.Algorithm == JWTAlgHS256 && cfg.JWTSecret == DefaultJWTSecret {
+		log.Fatal("⛔ JWT_SECRET must be set in release mode")
 	}
+
+	// With "*" any website could open a WebSocket with a user's ticket
+	if cfg.GinMode == "release" {
+		for _, origin := range cfg.AllowedOrigins() {
+			if origin == "*" {
+				log.Fatal("⛔ CORS_ORIGIN must list the allowed origins in release mode")
+			}
+		}
+	}
+
+	if len(cfg.OIDC.Scopes) == 0 {
+		cfg.OIDC.Scopes = []string{"openid", "profile", "email"}
+	}
+	if cfg.OIDC.Enabled() && (cfg.OIDC.ClientID == "" || cfg.OIDC.RedirectURL == "") {
+		log.Fatal("⛔ OIDC_CLIENT_ID and OIDC_REDIRECT_URL must be set when OIDC_ISSUER is set")
+	}
+
+	return cfg
 }
 
 // getEnv gets environment variable with default value
@@ -43,3 +300,41 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// getEnvList gets a comma-separated environment variable, skipping empty items
+func getEnvList(key string) []string {
+	return splitList(os.Getenv(key))
+}
+
+// splitList splits a comma-separated list, skipping empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// getEnvBool gets a boolean environment variable with default value
+func getEnvBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+		log.Printf("⚠️  Warning: Invalid %s=%q, using %t", key, value, defaultValue)
+	}
+	return defaultValue
+}
+
+// getEnvInt gets an integer environment variable with default value
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if n, err := strconv.Atoi(value); err == nil {
+			return n
+		}
+		log.Printf("⚠️  Warning: Invalid %s=%q, using %d", key, value, defaultValue)
+	}
+	return defaultValue
+}
@@ -1,25 +1,59 @@
 package handler
 
 import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"chat-room-backend/internal/middleware"
 	"chat-room-backend/internal/service"
 	"chat-room-backend/internal/utils"
+	ws "chat-room-backend/internal/websocket"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // AdminHandler handles admin HTTP requests
 type AdminHandler struct {
 	adminService *service.AdminService
+	auditService *service.AuditService
+	escalation   *service.EscalationService
+	scheduler    *service.GlobalMuteScheduler
 	wordFilter   *middleware.WordFilterCache
+	muteChecker  *middleware.MuteChecker
+	banChecker   *middleware.BanChecker
+	hub          *ws.Hub
 }
 
 // NewAdminHandler creates a new AdminHandler
-func NewAdminHandler(adminService *service.AdminService, wordFilter *middleware.WordFilterCache) *AdminHandler {
+func NewAdminHandler(
+	adminService *service.AdminService,
+	auditService *service.AuditService,
+	escalation *service.EscalationService,
+	scheduler *service.GlobalMuteScheduler,
+	wordFilter *middleware.WordFilterCache,
+	muteChecker *middleware.MuteChecker,
+	banChecker *middleware.BanChecker,
+	hub *ws.Hub,
+) *AdminHandler {
+	// Tell everyone when a schedule or expiry flips the global mute
+	hub.NotifyGlobalMuteChanges(scheduler)
+
 	return &AdminHandler{
 		adminService: adminService,
+		auditService: auditService,
+		escalation:   escalation,
+		scheduler:    scheduler,
 		wordFilter:   wordFilter,
+		muteChecker:  muteChecker,
+		banChecker:   banChecker,
+		hub:          hub,
 	}
 }
 
@@ -61,8 +95,13 @@ func (h *AdminHandler) AddWordFilter(c *gin.Context) {
 		return
 	}
 
-	filter, err := h.adminService.AddWordFilter(c.Request.Context(), req.Word, userID)
+	filter, err := h.adminService.AddWordFilter(c.Request.Context(), &req, userID)
 	if err != nil {
+		switch err.Error() {
+		case "敏感词不能为空", "无效的匹配方式", "无效的正则表达式", "无效的处理方式", "无效的禁言时长":
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add word filter"})
 		return
 	}
@@ -89,7 +128,18 @@ func (h *AdminHandler) AddWordFilter(c *gin.Context) {
 func (h *AdminHandler) RemoveWordFilter(c *gin.Context) {
 	filterID := c.Param("id")
 
-	if err := h.adminService.RemoveWordFilter(c.Request.Context(), filterID); err != nil {
+	userIDStr, _ := middleware.GetUserID(c)
+	userID, err := utils.ParseUserID(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	if err := h.adminService.RemoveWordFilter(c.Request.Context(), filterID, userID); err != nil {
+		if err.Error() == "敏感词不存在" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove word filter"})
 		return
 	}
@@ -107,6 +157,236 @@ func (h *AdminHandler) RemoveWordFilter(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "敏感词删除成功"})
 }
 
+// TestWordFilter explains which rules match a text without sending it
+// POST /api/admin/word-filters/test
+func (h *AdminHandler) TestWordFilter(c *gin.Context) {
+	var req service.TestWordFilterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, h.wordFilter.Explain(req.ChannelID, req.Text))
+}
+
+// GetChannelWordFilters returns the word filters scoped to a channel
+// GET /api/channels/:id/word-filters
+func (h *AdminHandler) GetChannelWordFilters(c *gin.Context) {
+	filters, err := h.adminService.GetChannelWordFilters(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		if err.Error() == "频道不存在" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get word filters"})
+		return
+	}
+
+	response := make([]interface{}, len(filters))
+	for i, filter := range filters {
+		response[i] = filter.ToResponse()
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// AddChannelWordFilter adds a word filter that only applies in a channel
+// POST /api/channels/:id/word-filters
+func (h *AdminHandler) AddChannelWordFilter(c *gin.Context) {
+	var req service.AddWordFilterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userIDStr, _ := middleware.GetUserID(c)
+	userID, err := utils.ParseUserID(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	filter, err := h.adminService.AddChannelWordFilter(c.Request.Context(), c.Param("id"), &req, userID)
+	if err != nil {
+		switch err.Error() {
+		case "频道不存在":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		case "敏感词不能为空", "无效的匹配方式", "无效的正则表达式", "无效的处理方式", "无效的禁言时长":
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add word filter"})
+		return
+	}
+
+	// Reload word filter cache
+	if err := h.wordFilter.Reload(); err != nil {
+		c.JSON(http.StatusCreated, gin.H{
+			"message": "敏感词添加成功",
+			"filter":  filter.ToResponse(),
+			"warning": "Failed to reload cache",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "敏感词添加成功",
+		"filter":  filter.ToResponse(),
+	})
+}
+
+// RemoveChannelWordFilter removes a word filter scoped to a channel
+// DELETE /api/channels/:id/word-filters/:filterId
+func (h *AdminHandler) RemoveChannelWordFilter(c *gin.Context) {
+	userIDStr, _ := middleware.GetUserID(c)
+	userID, err := utils.ParseUserID(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	if err := h.adminService.RemoveChannelWordFilter(c.Request.Context(), c.Param("id"), c.Param("filterId"), userID); err != nil {
+		if err.Error() == "频道不存在" || err.Error() == "敏感词不存在" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove word filter"})
+		return
+	}
+
+	// Reload word filter cache
+	if err := h.wordFilter.Reload(); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"message": "敏感词删除成功",
+			"warning": "Failed to reload cache",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "敏感词删除成功"})
+}
+
+// maxWordFilterImportBytes caps the size of an uploaded word filter list
+const maxWordFilterImportBytes = 5 << 20
+
+// ImportWordFilters bulk-imports a word filter list.
+// The list is sent as the request body or as a multipart "file" field.
+// POST /api/admin/word-filters/import?format=text|csv|json&dryRun=true
+func (h *AdminHandler) ImportWordFilters(c *gin.Context) {
+	userIDStr, _ := middleware.GetUserID(c)
+	userID, err := utils.ParseUserID(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxWordFilterImportBytes)
+
+	format := c.Query("format")
+	var data []byte
+	if c.ContentType() == "multipart/form-data" {
+		file, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "缺少上传文件"})
+			return
+		}
+		if format == "" {
+			format = wordFilterFormatFromName(file.Filename)
+		}
+		f, err := file.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无法读取上传文件"})
+			return
+		}
+		defer f.Close()
+		data, err = io.ReadAll(f)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无法读取上传文件"})
+			return
+		}
+	} else {
+		data, err = io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "文件过大"})
+			return
+		}
+	}
+	if format == "" {
+		format = service.WordFilterFormatText
+	}
+
+	dryRun := c.Query("dryRun") == "true"
+	result, err := h.adminService.ImportWordFilters(c.Request.Context(), format, data, dryRun, userID)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "failed to") {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import word filters"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if dryRun || len(result.Added) == 0 {
+		c.JSON(http.StatusOK, result)
+		return
+	}
+
+	// Reload word filter cache
+	if err := h.wordFilter.Reload(); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"result":  result,
+			"warning": "Failed to reload cache",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// ExportWordFilters downloads the active word filter list
+// GET /api/admin/word-filters/export?format=text|csv|json
+func (h *AdminHandler) ExportWordFilters(c *gin.Context) {
+	format := c.DefaultQuery("format", service.WordFilterFormatCSV)
+
+	data, err := h.adminService.ExportWordFilters(c.Request.Context(), format)
+	if err != nil {
+		if err.Error() == "不支持的格式" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export word filters"})
+		return
+	}
+
+	contentTypes := map[string]string{
+		service.WordFilterFormatText: "text/plain; charset=utf-8",
+		service.WordFilterFormatCSV:  "text/csv; charset=utf-8",
+		service.WordFilterFormatJSON: "application/json; charset=utf-8",
+	}
+	extensions := map[string]string{
+		service.WordFilterFormatText: "txt",
+		service.WordFilterFormatCSV:  "csv",
+		service.WordFilterFormatJSON: "json",
+	}
+
+	filename := fmt.Sprintf("word-filters-%s.%s", time.Now().Format("20060102-150405"), extensions[format])
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Data(http.StatusOK, contentTypes[format], data)
+}
+
+// wordFilterFormatFromName infers the list format from a file name
+func wordFilterFormatFromName(name string) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".csv":
+		return service.WordFilterFormatCSV
+	case ".json":
+		return service.WordFilterFormatJSON
+	default:
+		return service.WordFilterFormatText
+	}
+}
+
 // ============================================================
 // User Management Handlers
 // ============================================================
@@ -130,6 +410,9 @@ func (h *AdminHandler) GetAllUsers(c *gin.Context) {
 			"isMuted":     user.IsMuted,
 			"mutedUntil":  user.MutedUntil,
 			"mutedReason": user.MutedReason,
+			"isBanned":    user.IsBanActive(),
+			"bannedUntil": user.BannedUntil,
+			"banReason":   user.BanReason,
 			"createdAt":   user.CreatedAt,
 			"lastLogin":   user.LastLogin,
 		}
@@ -155,7 +438,7 @@ func (h *AdminHandler) MuteUser(c *gin.Context) {
 	}
 
 	if err := h.adminService.MuteUser(c.Request.Context(), &req, mutedBy); err != nil {
-		if err.Error() == "不能禁言管理员" {
+		if err.Error() == "不能禁言管理人员" {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
@@ -163,9 +446,75 @@ func (h *AdminHandler) MuteUser(c *gin.Context) {
 		return
 	}
 
+	// Refresh mute cache and tell the user
+	targetID, _ := utils.ParseUserID(req.UserID)
+	if err := notifyMuted(c.Request.Context(), h.muteChecker, h.hub, targetID); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"message": "禁言成功",
+			"warning": "Failed to refresh mute cache",
+		})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{"message": "禁言成功"})
 }
 
+// notifyMuted refreshes a user's cached mute state and pushes it to
+// their live connections right away
+func notifyMuted(ctx context.Context, muteChecker *middleware.MuteChecker, hub *ws.Hub, userID primitive.ObjectID) error {
+	if err := muteChecker.RefreshUser(ctx, userID); err != nil {
+		return err
+	}
+
+	mute := muteChecker.GetUserMute(userID)
+	hub.SendToUser(userID, &ws.WSMessage{
+		Event: ws.EventYouWereMuted,
+		Data: ws.YouWereMutedData{
+			Reason:     mute.Reason,
+			MutedUntil: formatOptionalTime(mute.MutedUntil),
+		},
+	})
+	return nil
+}
+
+// GetUserStrikes returns a user's word filter strike history
+// GET /api/admin/users/:id/strikes
+func (h *AdminHandler) GetUserStrikes(c *gin.Context) {
+	history, err := h.escalation.GetStrikeHistory(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get strikes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, history)
+}
+
+// ResetUserStrikes clears a user's strikes
+// POST /api/admin/users/:id/strikes/reset
+func (h *AdminHandler) ResetUserStrikes(c *gin.Context) {
+	userIDStr, _ := middleware.GetUserID(c)
+	resetBy, err := utils.ParseUserID(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	cleared, err := h.escalation.ResetStrikes(c.Request.Context(), c.Param("id"), resetBy)
+	if err != nil {
+		if err.Error() == "用户不存在" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset strikes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "违规记录已清零",
+		"cleared": cleared,
+	})
+}
+
 // UnmuteUser unmutes a user
 // POST /api/admin/unmute-user
 func (h *AdminHandler) UnmuteUser(c *gin.Context) {
@@ -175,14 +524,134 @@ func (h *AdminHandler) UnmuteUser(c *gin.Context) {
 		return
 	}
 
-	if err := h.adminService.UnmuteUser(c.Request.Context(), &req); err != nil {
+	userIDStr, _ := middleware.GetUserID(c)
+	unmutedBy, err := utils.ParseUserID(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	if err := h.adminService.UnmuteUser(c.Request.Context(), &req, unmutedBy); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unmute user"})
 		return
 	}
 
+	// Refresh mute cache
+	targetID, _ := utils.ParseUserID(req.UserID)
+	if err := h.muteChecker.RefreshUser(c.Request.Context(), targetID); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"message": "解除禁言成功",
+			"warning": "Failed to refresh mute cache",
+		})
+		return
+	}
+
+	h.hub.SendToUser(targetID, &ws.WSMessage{
+		Event: ws.EventUnmuted,
+		Data: ws.UnmutedData{
+			Reason: "管理员已解除您的禁言",
+		},
+	})
+
 	c.JSON(http.StatusOK, gin.H{"message": "解除禁言成功"})
 }
 
+// BanUser bans a user and closes their live connections
+// POST /api/admin/ban-user
+func (h *AdminHandler) BanUser(c *gin.Context) {
+	var req service.BanUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userIDStr, _ := middleware.GetUserID(c)
+	bannedBy, err := utils.ParseUserID(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	targetID, err := utils.ParseUserID(req.UserID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	// Addresses of the user's live connections, for IP bans
+	liveIPs := make([]string, 0)
+	for _, client := range h.hub.GetUserClients(targetID) {
+		liveIPs = append(liveIPs, client.IP())
+	}
+
+	if err := h.adminService.BanUser(c.Request.Context(), &req, bannedBy, liveIPs); err != nil {
+		switch err.Error() {
+		case "不能封禁管理人员":
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case "用户不存在":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	// Refresh ban cache so existing tokens stop working immediately
+	warning := ""
+	if err := h.banChecker.RefreshUser(c.Request.Context(), targetID); err != nil {
+		warning = "Failed to refresh ban cache"
+	}
+
+	ban := h.banChecker.CheckUser(targetID)
+	closed := h.hub.DisconnectUser(targetID, &ws.WSMessage{
+		Event: ws.EventBanned,
+		Data: ws.BannedData{
+			Reason:      ban.Reason,
+			BannedUntil: formatOptionalTime(ban.BannedUntil),
+		},
+	})
+
+	response := gin.H{"message": "封禁成功", "disconnected": closed}
+	if warning != "" {
+		response["warning"] = warning
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// UnbanUser lifts a user's ban
+// POST /api/admin/unban-user
+func (h *AdminHandler) UnbanUser(c *gin.Context) {
+	var req service.UnbanUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userIDStr, _ := middleware.GetUserID(c)
+	unbannedBy, err := utils.ParseUserID(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	if err := h.adminService.UnbanUser(c.Request.Context(), &req, unbannedBy); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unban user"})
+		return
+	}
+
+	// Refresh ban cache
+	targetID, _ := utils.ParseUserID(req.UserID)
+	if err := h.banChecker.RefreshUser(c.Request.Context(), targetID); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"message": "解除封禁成功",
+			"warning": "Failed to refresh ban cache",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "解除封禁成功"})
+}
+
 // ============================================================
 // Global Mute Handlers
 // ============================================================
@@ -225,5 +694,165 @@ func (h *AdminHandler) ToggleGlobalMute(c *gin.Context) {
 		message = "全局禁言已启用"
 	}
 
+	// Pick up the new expiry, or a schedule that should take over
+	h.scheduler.Wake()
+
+	// Refresh mute cache
+	if err := h.muteChecker.RefreshGlobal(c.Request.Context()); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"message": message,
+			"warning": "Failed to refresh mute cache",
+		})
+		return
+	}
+
+	// Broadcast so every composer updates immediately
+	status, err := h.adminService.GetGlobalMuteStatus(c.Request.Context())
+	if err == nil {
+		h.hub.BroadcastGlobalMute(status)
+	}
+
 	c.JSON(http.StatusOK, gin.H{"message": message})
 }
+
+// GetGlobalMuteSchedules returns the scheduled global mute windows
+// GET /api/admin/global-mute/schedules
+func (h *AdminHandler) GetGlobalMuteSchedules(c *gin.Context) {
+	schedules, err := h.scheduler.GetSchedules(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get schedules"})
+		return
+	}
+
+	c.JSON(http.StatusOK, schedules)
+}
+
+// CreateGlobalMuteSchedule schedules a global mute window
+// POST /api/admin/global-mute/schedules
+func (h *AdminHandler) CreateGlobalMuteSchedule(c *gin.Context) {
+	var req service.CreateScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userIDStr, _ := middleware.GetUserID(c)
+	createdBy, err := utils.ParseUserID(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	schedule, err := h.scheduler.CreateSchedule(c.Request.Context(), &req, createdBy)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "failed to") {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create schedule"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":  "定时全局禁言已创建",
+		"schedule": schedule,
+	})
+}
+
+// DeleteGlobalMuteSchedule removes a scheduled global mute window
+// DELETE /api/admin/global-mute/schedules/:id
+func (h *AdminHandler) DeleteGlobalMuteSchedule(c *gin.Context) {
+	userIDStr, _ := middleware.GetUserID(c)
+	deletedBy, err := utils.ParseUserID(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	if err := h.scheduler.DeleteSchedule(c.Request.Context(), c.Param("id"), deletedBy); err != nil {
+		if err.Error() == "定时任务不存在" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete schedule"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "定时全局禁言已删除"})
+}
+
+// ============================================================
+// Audit Log Handlers
+// ============================================================
+
+// GetAuditLogs returns a filtered, paginated page of the audit log
+// GET /api/admin/audit-log
+func (h *AdminHandler) GetAuditLogs(c *gin.Context) {
+	var query service.AuditLogQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page, err := h.auditService.GetAuditLogs(c.Request.Context(), &query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// ExportAuditLogs exports the filtered audit log as CSV
+// GET /api/admin/audit-log/export
+func (h *AdminHandler) ExportAuditLogs(c *gin.Context) {
+	var query service.AuditLogQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	logs, err := h.auditService.ExportAuditLogs(c.Request.Context(), &query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	filename := fmt.Sprintf("audit-log-%s.csv", time.Now().Format("20060102-150405"))
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	writer := csv.NewWriter(c.Writer)
+	writer.Write([]string{"timestamp", "actorId", "actorUsername", "action", "targetType", "targetId", "targetName", "reason", "before", "after"})
+	for _, entry := range logs {
+		targetID := ""
+		if entry.TargetID != nil {
+			targetID = entry.TargetID.Hex()
+		}
+		writer.Write([]string{
+			entry.Timestamp.Format(time.RFC3339),
+			entry.ActorID.Hex(),
+			entry.ActorUsername,
+			entry.Action,
+			entry.TargetType,
+			targetID,
+			entry.TargetName,
+			entry.Reason,
+			toJSONString(entry.Before),
+			toJSONString(entry.After),
+		})
+	}
+	writer.Flush()
+}
+
+// toJSONString encodes a value as compact JSON for CSV cells
+func toJSONString(v map[string]interface{}) string {
+	if v == nil {
+		return ""
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
@@ -1,10 +1,16 @@
 package handler
 
 import (
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"chat-room-backend/internal/middleware"
 	"chat-room-backend/internal/service"
+	"chat-room-backend/internal/utils"
+	ws "chat-room-backend/internal/websocket"
 )
 
 // AuthHandler handles authentication HTTP requests
@@ -13,7 +19,10 @@ type AuthHandler struct {
 }
 
 // NewAuthHandler creates a new AuthHandler
-func NewAuthHandler(authService *service.AuthService) *AuthHandler {
+func NewAuthHandler(authService *service.AuthService, hub *ws.Hub) *AuthHandler {
+	// Close the connections of ended sessions
+	hub.NotifySessionRevocations(authService)
+
 	return &AuthHandler{
 		authService: authService,
 	}
@@ -34,15 +43,12 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
-	// Validate password length
-	if len(req.Password) < 6 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "密码长度至少为6个字符"})
-		return
-	}
-
 	// Register user
-	resp, err := h.authService.Register(c.Request.Context(), &req)
+	resp, err := h.authService.Register(c.Request.Context(), &req, clientInfo(c))
 	if err != nil {
+		if respondBanned(c, err) || respondPasswordPolicy(c, err) {
+			return
+		}
 		if err.Error() == "用户名已存在" {
 			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
 			return
@@ -64,8 +70,11 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	}
 
 	// Login user
-	resp, err := h.authService.Login(c.Request.Context(), &req)
+	resp, err := h.authService.Login(c.Request.Context(), &req, clientInfo(c))
 	if err != nil {
+		if respondBanned(c, err) || respondThrottled(c, err) {
+			return
+		}
 		if err.Error() == "用户名或密码错误" {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 			return
@@ -77,6 +86,358 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	c.JSON(http.StatusOK, resp)
 }
 
+// LoginTwoFactor completes a login with a TOTP or recovery code
+// POST /api/auth/login/2fa
+func (h *AuthHandler) LoginTwoFactor(c *gin.Context) {
+	var req service.LoginTwoFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp, err := h.authService.LoginTwoFactor(c.Request.Context(), &req, clientInfo(c))
+	if err != nil {
+		if respondBanned(c, err) || respondThrottled(c, err) {
+			return
+		}
+		switch err.Error() {
+		case "验证已过期，请重新登录", "验证码错误":
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器错误"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// GetTwoFactorStatus returns the current user's two-factor enrollment
+// GET /api/auth/2fa
+func (h *AuthHandler) GetTwoFactorStatus(c *gin.Context) {
+	userIDStr, _ := middleware.GetUserID(c)
+	userID, err := utils.ParseUserID(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	status, err := h.authService.GetTwoFactorStatus(c.Request.Context(), userID)
+	if err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// SetupTwoFactor generates a TOTP secret and its provisioning URI
+// POST /api/auth/2fa/setup
+func (h *AuthHandler) SetupTwoFactor(c *gin.Context) {
+	userIDStr, _ := middleware.GetUserID(c)
+	userID, err := utils.ParseUserID(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	setup, err := h.authService.SetupTwoFactor(c.Request.Context(), userID)
+	if err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, setup)
+}
+
+// EnableTwoFactor confirms the TOTP secret and returns the recovery codes
+// POST /api/auth/2fa/enable
+func (h *AuthHandler) EnableTwoFactor(c *gin.Context) {
+	var req service.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userIDStr, _ := middleware.GetUserID(c)
+	userID, err := utils.ParseUserID(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	resp, err := h.authService.EnableTwoFactor(c.Request.Context(), userID, currentSessionID(c), &req)
+	if err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// DisableTwoFactor turns two-factor authentication off
+// POST /api/auth/2fa/disable
+func (h *AuthHandler) DisableTwoFactor(c *gin.Context) {
+	var req service.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userIDStr, _ := middleware.GetUserID(c)
+	userID, err := utils.ParseUserID(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	if err := h.authService.DisableTwoFactor(c.Request.Context(), userID, &req); err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "两步验证已关闭"})
+}
+
+// RegenerateRecoveryCodes replaces the current user's recovery codes
+// POST /api/auth/2fa/recovery-codes
+func (h *AuthHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	var req service.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userIDStr, _ := middleware.GetUserID(c)
+	userID, err := utils.ParseUserID(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	codes, err := h.authService.RegenerateRecoveryCodes(c.Request.Context(), userID, &req)
+	if err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"recoveryCodes": codes})
+}
+
+// ChangePassword changes the current user's password and ends their other sessions
+// POST /api/auth/password
+func (h *AuthHandler) ChangePassword(c *gin.Context) {
+	var req service.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userIDStr, _ := middleware.GetUserID(c)
+	userID, err := utils.ParseUserID(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	revoked, err := h.authService.ChangePassword(c.Request.Context(), userID, currentSessionID(c), &req, clientInfo(c))
+	if err != nil {
+		if respondThrottled(c, err) || respondPasswordPolicy(c, err) {
+			return
+		}
+		switch err.Error() {
+		case "用户不存在":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case "当前密码错误":
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		case "账号未设置密码", "新密码不能与当前密码相同":
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器错误"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "密码已修改",
+		"revoked": revoked,
+	})
+}
+
+// ResetPassword sets a new password with an admin-issued reset token
+// POST /api/auth/password/reset
+func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	var req service.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.authService.ResetPassword(c.Request.Context(), &req); err != nil {
+		if respondPasswordPolicy(c, err) {
+			return
+		}
+		if err.Error() == "无效或已过期的重置令牌" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器错误"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "密码已重置，请重新登录"})
+}
+
+// IssuePasswordReset issues a one-time password reset token for a user
+// POST /api/admin/users/:id/password-reset
+func (h *AuthHandler) IssuePasswordReset(c *gin.Context) {
+	targetID, err := utils.ParseUserID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	adminIDStr, _ := middleware.GetUserID(c)
+	adminID, err := utils.ParseUserID(adminIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	reset, err := h.authService.IssuePasswordReset(c.Request.Context(), targetID, adminID)
+	if err != nil {
+		switch err.Error() {
+		case "用户不存在":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case "机器人账号不能设置密码":
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器错误"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, reset)
+}
+
+// Refresh exchanges a refresh token for new tokens
+// POST /api/auth/refresh
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req service.RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp, err := h.authService.Refresh(c.Request.Context(), &req, clientInfo(c))
+	if err != nil {
+		if respondBanned(c, err) {
+			return
+		}
+		switch err.Error() {
+		case "无效的刷新令牌", "刷新令牌已失效":
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器错误"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// Logout revokes the caller's refresh token family and access token.
+// An expired access token is ignored, so a client can always log out.
+// POST /api/auth/logout
+func (h *AuthHandler) Logout(c *gin.Context) {
+	var req service.LogoutRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	var claims *utils.JWTClaims
+	if authHeader := c.GetHeader("Authorization"); len(authHeader) > 7 && authHeader[:7] == "Bearer " {
+		claims, _ = utils.ValidateToken(authHeader[7:], middleware.GetJWTKeys(c))
+	}
+
+	if err := h.authService.Logout(c.Request.Context(), &req, claims); err != nil {
+		if err.Error() == "无效的刷新令牌" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器错误"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "已退出登录"})
+}
+
+// GetSessions lists the current user's active sessions
+// GET /api/auth/sessions
+func (h *AuthHandler) GetSessions(c *gin.Context) {
+	userIDStr, _ := middleware.GetUserID(c)
+	userID, err := utils.ParseUserID(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	sessions, err := h.authService.GetSessions(c.Request.Context(), userID, currentSessionID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器错误"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"sessions": sessions})
+}
+
+// RevokeSession ends one of the current user's sessions
+// DELETE /api/auth/sessions/:id
+func (h *AuthHandler) RevokeSession(c *gin.Context) {
+	userIDStr, _ := middleware.GetUserID(c)
+	userID, err := utils.ParseUserID(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	if err := h.authService.RevokeUserSession(c.Request.Context(), userID, c.Param("id")); err != nil {
+		if err.Error() == "会话不存在" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器错误"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "会话已注销"})
+}
+
+// RevokeOtherSessions ends every session of the current user except this one
+// DELETE /api/auth/sessions
+func (h *AuthHandler) RevokeOtherSessions(c *gin.Context) {
+	userIDStr, _ := middleware.GetUserID(c)
+	userID, err := utils.ParseUserID(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	revoked, err := h.authService.RevokeOtherSessions(c.Request.Context(), userID, currentSessionID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器错误"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "其他会话已注销",
+		"revoked": revoked,
+	})
+}
+
 // Verify handles JWT token verification
 // GET /api/auth/verify
 func (h *AuthHandler) Verify(c *gin.Context) {
@@ -99,9 +460,123 @@ func (h *AuthHandler) Verify(c *gin.Context) {
 	// Verify token
 	user, err := h.authService.VerifyToken(c.Request.Context(), token)
 	if err != nil {
+		if respondBanned(c, err) {
+			return
+		}
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的认证令牌"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"user": user})
 }
+
+// GetLoginLockouts lists usernames and IPs throttled after failed logins
+// GET /api/admin/login-lockouts
+func (h *AuthHandler) GetLoginLockouts(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"lockouts": h.authService.GetLoginLockouts()})
+}
+
+// ClearLoginLockout lets a username or IP log in again right away
+// DELETE /api/admin/login-lockouts/:kind/:key
+func (h *AuthHandler) ClearLoginLockout(c *gin.Context) {
+	userIDStr, _ := middleware.GetUserID(c)
+	clearedBy, err := utils.ParseUserID(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	if err := h.authService.ClearLoginLockout(c.Request.Context(), c.Param("kind"), c.Param("key"), clearedBy); err != nil {
+		switch err.Error() {
+		case "无效的锁定类型":
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case "锁定记录不存在":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器错误"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "登录锁定已解除"})
+}
+
+// JWKS publishes the public keys access tokens can be verified with
+// GET /.well-known/jwks.json
+func (h *AuthHandler) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.authService.JWKS())
+}
+
+// clientInfo describes the client making an authentication request
+func clientInfo(c *gin.Context) *service.ClientInfo {
+	return &service.ClientInfo{
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+}
+
+// currentSessionID returns the session of the access token used for the request
+func currentSessionID(c *gin.Context) string {
+	if claims, ok := middleware.GetTokenClaims(c); ok {
+		return claims.SessionID
+	}
+	return ""
+}
+
+// respondTwoFactorError maps two-factor enrollment errors to HTTP statuses
+func respondTwoFactorError(c *gin.Context, err error) {
+	switch err.Error() {
+	case "用户不存在":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case "两步验证已启用":
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case "两步验证未启用", "请先生成两步验证密钥", "验证码错误":
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器错误"})
+	}
+}
+
+// respondThrottled writes a 429 response with Retry-After if err is a login throttle
+func respondThrottled(c *gin.Context, err error) bool {
+	var throttleErr *service.LoginThrottledError
+	if !errors.As(err, &throttleErr) {
+		return false
+	}
+
+	retryAfter := int(math.Ceil(throttleErr.RetryAfter.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":      throttleErr.Error(),
+		"retryAfter": retryAfter,
+		"locked":     throttleErr.Locked,
+	})
+	return true
+}
+
+// respondPasswordPolicy writes a 400 response if err is a password policy violation
+func respondPasswordPolicy(c *gin.Context, err error) bool {
+	var policyErr *service.PasswordPolicyError
+	if !errors.As(err, &policyErr) {
+		return false
+	}
+
+	c.JSON(http.StatusBadRequest, gin.H{"error": policyErr.Error()})
+	return true
+}
+
+// respondBanned writes a 403 response if err is a ban error
+func respondBanned(c *gin.Context, err error) bool {
+	var banErr *service.BanError
+	if !errors.As(err, &banErr) {
+		return false
+	}
+
+	c.JSON(http.StatusForbidden, gin.H{
+		"error":       banErr.Error(),
+		"reason":      banErr.Reason,
+		"bannedUntil": banErr.BannedUntil,
+	})
+	return true
+}
@@ -0,0 +1,380 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"chat-room-backend/internal/middleware"
+	"chat-room-backend/internal/service"
+	"chat-room-backend/internal/utils"
+	ws "chat-room-backend/internal/websocket"
+)
+
+// BotHandler handles bot management and bot API HTTP requests
+type BotHandler struct {
+	botService *service.BotService
+	hub        *ws.Hub
+}
+
+// NewBotHandler creates a new BotHandler
+func NewBotHandler(botService *service.BotService, hub *ws.Hub) *BotHandler {
+	return &BotHandler{
+		botService: botService,
+		hub:        hub,
+	}
+}
+
+// GetBots returns all bot accounts
+// GET /api/admin/bots
+func (h *BotHandler) GetBots(c *gin.Context) {
+	bots, err := h.botService.GetBots(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器错误"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"bots": bots})
+}
+
+// CreateBot creates a bot account owned by the current admin
+// POST /api/admin/bots
+func (h *BotHandler) CreateBot(c *gin.Context) {
+	var req service.CreateBotRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	adminIDStr, _ := middleware.GetUserID(c)
+	adminID, err := utils.ParseUserID(adminIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	bot, err := h.botService.CreateBot(c.Request.Context(), &req, adminID)
+	if err != nil {
+		respondBotAdminError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "机器人已创建",
+		"bot":     bot,
+	})
+}
+
+// DeleteBot deletes a bot account and revokes its keys
+// DELETE /api/admin/bots/:id
+func (h *BotHandler) DeleteBot(c *gin.Context) {
+	botID, err := utils.ParseUserID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid bot ID"})
+		return
+	}
+
+	adminIDStr, _ := middleware.GetUserID(c)
+	adminID, err := utils.ParseUserID(adminIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	if err := h.botService.DeleteBot(c.Request.Context(), botID, adminID); err != nil {
+		respondBotAdminError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "机器人已删除"})
+}
+
+// GetAPIKeys returns a bot's API keys. Key values are never returned.
+// GET /api/admin/bots/:id/keys
+func (h *BotHandler) GetAPIKeys(c *gin.Context) {
+	botID, err := utils.ParseUserID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid bot ID"})
+		return
+	}
+
+	keys, err := h.botService.GetAPIKeys(c.Request.Context(), botID)
+	if err != nil {
+		respondBotAdminError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"keys": keys})
+}
+
+// CreateAPIKey creates an API key for a bot. The key is only shown in
+// this response.
+// POST /api/admin/bots/:id/keys
+func (h *BotHandler) CreateAPIKey(c *gin.Context) {
+	var req service.CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	botID, err := utils.ParseUserID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid bot ID"})
+		return
+	}
+
+	adminIDStr, _ := middleware.GetUserID(c)
+	adminID, err := utils.ParseUserID(adminIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	resp, err := h.botService.CreateAPIKey(c.Request.Context(), botID, &req, adminID)
+	if err != nil {
+		respondBotAdminError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, resp)
+}
+
+// RevokeAPIKey revokes one of a bot's API keys
+// DELETE /api/admin/bots/:id/keys/:keyId
+func (h *BotHandler) RevokeAPIKey(c *gin.Context) {
+	botID, err := utils.ParseUserID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid bot ID"})
+		return
+	}
+
+	adminIDStr, _ := middleware.GetUserID(c)
+	adminID, err := utils.ParseUserID(adminIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	if err := h.botService.RevokeAPIKey(c.Request.Context(), botID, c.Param("keyId"), adminID); err != nil {
+		respondBotAdminError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "API 密钥已吊销"})
+}
+
+// AddToChannel adds a bot to a channel
+// PUT /api/admin/bots/:id/channels/:channelId
+func (h *BotHandler) AddToChannel(c *gin.Context) {
+	botID, err := utils.ParseUserID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid bot ID"})
+		return
+	}
+
+	adminIDStr, _ := middleware.GetUserID(c)
+	adminID, err := utils.ParseUserID(adminIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	if err := h.botService.AddToChannel(c.Request.Context(), botID, c.Param("channelId"), adminID); err != nil {
+		respondBotAdminError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "已将机器人加入频道"})
+}
+
+// RemoveFromChannel removes a bot from a channel
+// DELETE /api/admin/bots/:id/channels/:channelId
+func (h *BotHandler) RemoveFromChannel(c *gin.Context) {
+	botID, err := utils.ParseUserID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid bot ID"})
+		return
+	}
+
+	adminIDStr, _ := middleware.GetUserID(c)
+	adminID, err := utils.ParseUserID(adminIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	if err := h.botService.RemoveFromChannel(c.Request.Context(), botID, c.Param("channelId"), adminID); err != nil {
+		respondBotAdminError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "已将机器人移出频道"})
+}
+
+// GetMe returns the bot and the scopes of the key it used
+// GET /api/bot/me
+func (h *BotHandler) GetMe(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	username, _ := middleware.GetUsername(c)
+	key, _ := middleware.GetAPIKey(c)
+
+	c.JSON(http.StatusOK, gin.H{
+		"id":       userID,
+		"username": username,
+		"keyName":  key.Name,
+		"scopes":   key.Scopes,
+	})
+}
+
+// GetChannels returns the channels the bot was added to
+// GET /api/bot/channels
+func (h *BotHandler) GetChannels(c *gin.Context) {
+	botIDStr, _ := middleware.GetUserID(c)
+	botID, err := utils.ParseUserID(botIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	channels, err := h.botService.GetChannels(c.Request.Context(), botID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器错误"})
+		return
+	}
+
+	response := make([]interface{}, len(channels))
+	for i, ch := range channels {
+		response[i] = ch.ToResponse()
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// SendMessage posts a message to a channel and delivers it to the
+// channel's WebSocket clients
+// POST /api/bot/channels/:id/messages
+func (h *BotHandler) SendMessage(c *gin.Context) {
+	var req service.BotMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	channelID := c.Param("id")
+	botIDStr, _ := middleware.GetUserID(c)
+	botID, err := utils.ParseUserID(botIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	username, _ := middleware.GetUsername(c)
+
+	savedMsg, report, err := h.botService.SendMessage(c.Request.Context(), botID, username, channelID, req.Message)
+	if err != nil {
+		respondBotError(c, err)
+		return
+	}
+
+	if report != nil {
+		h.hub.NotifyNewReport(report)
+	}
+
+	h.hub.BroadcastToChannel(channelID, &ws.WSMessage{
+		Event: ws.EventNewMessage,
+		Data: ws.MessageData{
+			ID:          savedMsg.ID.Hex(),
+			Username:    savedMsg.Username,
+			UserID:      botIDStr,
+			Message:     savedMsg.Message,
+			Timestamp:   savedMsg.Timestamp.Format(time.RFC3339),
+			MessageType: savedMsg.MessageType,
+			ChannelID:   savedMsg.ChannelID.Hex(),
+		},
+	}, nil)
+
+	log.Printf("🤖 [%s] %s: %s", channelID, username, savedMsg.Message[:min(50, len(savedMsg.Message))])
+
+	c.JSON(http.StatusCreated, savedMsg.ToResponse())
+}
+
+// GetMessages returns recent messages of a channel
+// GET /api/bot/channels/:id/messages
+func (h *BotHandler) GetMessages(c *gin.Context) {
+	botIDStr, _ := middleware.GetUserID(c)
+	botID, err := utils.ParseUserID(botIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	limit := 100
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 {
+			limit = min(parsedLimit, 100)
+		}
+	}
+
+	messages, err := h.botService.GetHistory(c.Request.Context(), botID, c.Param("id"), limit)
+	if err != nil {
+		respondBotError(c, err)
+		return
+	}
+
+	response := make([]interface{}, len(messages))
+	for i, msg := range messages {
+		response[i] = msg.ToResponse()
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// respondBotAdminError maps bot management errors to HTTP statuses
+func respondBotAdminError(c *gin.Context, err error) {
+	switch err.Error() {
+	case "机器人不存在", "API 密钥不存在", "频道不存在", "机器人不在该频道中":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case "用户名已存在", "机器人已在该频道中":
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		if !strings.HasPrefix(err.Error(), "failed to") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器错误"})
+	}
+}
+
+// respondBotError maps bot API errors to HTTP statuses. Requests that
+// can be retried later get 429 with a Retry-After header.
+func respondBotError(c *gin.Context, err error) {
+	var blocked *service.BotBlockedError
+	if errors.As(err, &blocked) {
+		if blocked.RetryAfter <= 0 {
+			c.JSON(http.StatusForbidden, gin.H{"error": blocked.Reason})
+			return
+		}
+		retryAfter := int(math.Ceil(blocked.RetryAfter.Seconds()))
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"error":      blocked.Reason,
+			"retryAfter": retryAfter,
+		})
+		return
+	}
+
+	switch err.Error() {
+	case "机器人不在该频道中":
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		if !strings.HasPrefix(err.Error(), "failed to") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器错误"})
+	}
+}
@@ -3,24 +3,46 @@ package handler
 import (
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"chat-room-backend/internal/middleware"
+	"chat-room-backend/internal/models"
 	"chat-room-backend/internal/service"
 	"chat-room-backend/internal/utils"
+	ws "chat-room-backend/internal/websocket"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // ChannelHandler handles channel HTTP requests
 type ChannelHandler struct {
 	channelService *service.ChannelService
 	chatService    *service.ChatService
+	memberships    *middleware.MembershipCache
+	slowMode       *middleware.SlowModeCache
+	posting        *middleware.PostingPermissions
+	permissions    *middleware.PermissionChecker
+	hub            *ws.Hub
 }
 
 // NewChannelHandler creates a new ChannelHandler
-func NewChannelHandler(channelService *service.ChannelService, chatService *service.ChatService) *ChannelHandler {
+func NewChannelHandler(
+	channelService *service.ChannelService,
+	chatService *service.ChatService,
+	memberships *middleware.MembershipCache,
+	slowMode *middleware.SlowModeCache,
+	posting *middleware.PostingPermissions,
+	permissions *middleware.PermissionChecker,
+	hub *ws.Hub,
+) *ChannelHandler {
 	return &ChannelHandler{
 		channelService: channelService,
 		chatService:    chatService,
+		memberships:    memberships,
+		slowMode:       slowMode,
+		posting:        posting,
+		permissions:    permissions,
+		hub:            hub,
 	}
 }
 
@@ -97,6 +119,9 @@ func (h *ChannelHandler) CreateChannel(c *gin.Context) {
 		return
 	}
 
+	// Creator is added as a member
+	h.memberships.Add(userID, channel.ID)
+
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "频道创建成功",
 		"channel": channel.ToResponse(),
@@ -128,6 +153,9 @@ func (h *ChannelHandler) JoinChannel(c *gin.Context) {
 		return
 	}
 
+	// Reload memberships so the next WebSocket event sees the new channel
+	h.memberships.Invalidate(userID)
+
 	c.JSON(http.StatusOK, gin.H{"message": "加入频道成功"})
 }
 
@@ -152,9 +180,242 @@ func (h *ChannelHandler) LeaveChannel(c *gin.Context) {
 		return
 	}
 
+	// Stop authorizing and delivering events for the channel
+	h.memberships.Invalidate(userID)
+	h.posting.RemoveUser(userID, channelID)
+	h.hub.RemoveUserFromChannel(userID, channelID)
+
 	c.JSON(http.StatusOK, gin.H{"message": "离开频道成功"})
 }
 
+// KickMember removes a user from a channel (admin only)
+// POST /api/channels/:id/kick
+func (h *ChannelHandler) KickMember(c *gin.Context) {
+	channelID := c.Param("id")
+
+	var req service.KickMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, err := utils.ParseUserID(req.UserID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	adminIDStr, _ := middleware.GetUserID(c)
+	adminID, err := utils.ParseUserID(adminIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	if err := h.channelService.KickMember(c.Request.Context(), userID, channelID, adminID); err != nil {
+		switch err.Error() {
+		case "频道不存在", "该用户不是频道成员":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case "不能将用户移出默认频道":
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	// Stop authorizing and delivering events for the channel
+	h.memberships.Invalidate(userID)
+	h.posting.RemoveUser(userID, channelID)
+	for _, client := range h.hub.RemoveUserFromChannel(userID, channelID) {
+		client.Send(&ws.WSMessage{
+			Event: ws.EventRemovedFromChannel,
+			Data: ws.RemovedFromChannelData{
+				ChannelID: channelID,
+				Reason:    "您已被管理员移出频道",
+			},
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "已将用户移出频道"})
+}
+
+// SetSlowMode turns slow mode on or off (channel managers and channel moderators)
+// PUT /api/channels/:id/slow-mode
+func (h *ChannelHandler) SetSlowMode(c *gin.Context) {
+	channelID := c.Param("id")
+
+	var req service.SlowModeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userIDStr, _ := middleware.GetUserID(c)
+	userID, err := utils.ParseUserID(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	canManage := middleware.HasVerifiedPermission(c, h.permissions, models.PermChannelsManage)
+
+	channel, err := h.channelService.SetSlowMode(c.Request.Context(), channelID, req.Seconds, userID, canManage)
+	if err != nil {
+		switch err.Error() {
+		case "频道不存在":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case "需要频道管理权限":
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		default:
+			if !strings.HasPrefix(err.Error(), "failed to") {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set slow mode"})
+		}
+		return
+	}
+
+	h.slowMode.Set(channelID, channel.SlowModeSeconds)
+	h.hub.BroadcastToChannel(channelID, &ws.WSMessage{
+		Event: ws.EventSlowModeChanged,
+		Data: ws.SlowModeChangedData{
+			ChannelID:       channelID,
+			SlowModeSeconds: channel.SlowModeSeconds,
+		},
+	}, nil)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "慢速模式已更新",
+		"channel": channel.ToResponse(),
+	})
+}
+
+// SetPostingPolicy makes a channel read-only for regular members or opens it again (admin only)
+// PUT /api/channels/:id/posting-policy
+func (h *ChannelHandler) SetPostingPolicy(c *gin.Context) {
+	channelID := c.Param("id")
+
+	var req service.PostingPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	adminIDStr, _ := middleware.GetUserID(c)
+	adminID, err := utils.ParseUserID(adminIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	channel, err := h.channelService.SetPostingPolicy(c.Request.Context(), channelID, req.Policy, adminID)
+	if err != nil {
+		if err.Error() == "频道不存在" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set posting policy"})
+		return
+	}
+
+	response := gin.H{
+		"message": "发言权限已更新",
+		"channel": channel.ToResponse(),
+	}
+	if err := h.posting.RefreshChannel(c.Request.Context(), channel.ID); err != nil {
+		response["warning"] = "Failed to refresh posting permissions"
+	}
+
+	// Every member's composer may change
+	for _, client := range h.hub.GetChannelClients(channelID) {
+		h.notifyPostingPolicy(client, channelID, channel.GetPostingPolicy())
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// AddModerator makes a member a channel moderator (admin only)
+// PUT /api/channels/:id/moderators/:userId
+func (h *ChannelHandler) AddModerator(c *gin.Context) {
+	h.setRole(c, models.ChannelRoleModerator, "已设为频道管理员")
+}
+
+// RemoveModerator revokes a member's moderator role (admin only)
+// DELETE /api/channels/:id/moderators/:userId
+func (h *ChannelHandler) RemoveModerator(c *gin.Context) {
+	h.setRole(c, models.ChannelRoleMember, "已取消频道管理员")
+}
+
+// AddPoster allows a member to post in a restricted channel (admin only)
+// PUT /api/channels/:id/posters/:userId
+func (h *ChannelHandler) AddPoster(c *gin.Context) {
+	h.setRole(c, models.ChannelRolePoster, "已授予发言权限")
+}
+
+// RemovePoster revokes a member's posting right (admin only)
+// DELETE /api/channels/:id/posters/:userId
+func (h *ChannelHandler) RemovePoster(c *gin.Context) {
+	h.setRole(c, models.ChannelRoleMember, "已取消发言权限")
+}
+
+// setRole sets a member's channel role and updates their composer
+func (h *ChannelHandler) setRole(c *gin.Context, role, message string) {
+	channelID := c.Param("id")
+
+	userID, err := utils.ParseUserID(c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	adminIDStr, _ := middleware.GetUserID(c)
+	adminID, err := utils.ParseUserID(adminIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	if err := h.channelService.SetMemberRole(c.Request.Context(), channelID, userID, role, adminID); err != nil {
+		switch err.Error() {
+		case "频道不存在", "该用户不是频道成员":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update member role"})
+		}
+		return
+	}
+
+	channelObjID, _ := primitive.ObjectIDFromHex(channelID)
+	if err := h.posting.RefreshChannel(c.Request.Context(), channelObjID); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"message": message,
+			"warning": "Failed to refresh posting permissions",
+		})
+		return
+	}
+
+	if channel, err := h.channelService.GetChannelByID(c.Request.Context(), channelID); err == nil && channel != nil {
+		for _, client := range h.hub.GetUserClients(userID) {
+			h.notifyPostingPolicy(client, channelID, channel.GetPostingPolicy())
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": message})
+}
+
+// notifyPostingPolicy tells a client whether it may post in a channel
+func (h *ChannelHandler) notifyPostingPolicy(client *ws.Client, channelID, policy string) {
+	client.Send(&ws.WSMessage{
+		Event: ws.EventPostingPolicyChanged,
+		Data: ws.PostingPolicyChangedData{
+			ChannelID:     channelID,
+			PostingPolicy: policy,
+			CanPost:       h.posting.CanPost(client.UserID(), channelID, client.Can(models.PermModerationExempt)),
+		},
+	})
+}
+
 // GetChannelMessages returns message history for a channel
 // GET /api/channels/:id/messages
 func (h *ChannelHandler) GetChannelMessages(c *gin.Context) {
@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"chat-room-backend/internal/middleware"
+	"chat-room-backend/internal/service"
+	"chat-room-backend/internal/utils"
+)
+
+// OIDCHandler handles single sign-on HTTP requests
+type OIDCHandler struct {
+	oidcService *service.OIDCService
+}
+
+// NewOIDCHandler creates a new OIDCHandler
+func NewOIDCHandler(oidcService *service.OIDCService) *OIDCHandler {
+	return &OIDCHandler{
+		oidcService: oidcService,
+	}
+}
+
+// Status reports whether single sign-on is available, so the frontend
+// knows whether to offer it
+// GET /api/auth/oidc
+func (h *OIDCHandler) Status(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"enabled": h.oidcService.Enabled()})
+}
+
+// Login returns the provider URL to send the browser to
+// GET /api/auth/oidc/login
+func (h *OIDCHandler) Login(c *gin.Context) {
+	authorization, err := h.oidcService.StartLogin(c.Request.Context())
+	if err != nil {
+		respondOIDCError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, authorization)
+}
+
+// Callback completes a login with the code and state the provider
+// redirected the browser back with
+// POST /api/auth/oidc/callback
+func (h *OIDCHandler) Callback(c *gin.Context) {
+	var req service.OIDCCallbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp, err := h.oidcService.CompleteLogin(c.Request.Context(), &req, clientInfo(c))
+	if err != nil {
+		respondOIDCError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// StartLink begins linking an SSO identity to the current account
+// POST /api/auth/oidc/link
+func (h *OIDCHandler) StartLink(c *gin.Context) {
+	var req service.OIDCLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userIDStr, _ := middleware.GetUserID(c)
+	userID, err := utils.ParseUserID(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	authorization, err := h.oidcService.StartLink(c.Request.Context(), userID, &req, clientInfo(c))
+	if err != nil {
+		respondOIDCError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, authorization)
+}
+
+// CompleteLink links the identity the provider returned to the current account
+// POST /api/auth/oidc/link/callback
+func (h *OIDCHandler) CompleteLink(c *gin.Context) {
+	var req service.OIDCCallbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userIDStr, _ := middleware.GetUserID(c)
+	userID, err := utils.ParseUserID(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	user, err := h.oidcService.CompleteLink(c.Request.Context(), userID, &req)
+	if err != nil {
+		respondOIDCError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "单点登录账号已绑定",
+		"user":    user,
+	})
+}
+
+// Unlink removes the SSO identity from the current account
+// DELETE /api/auth/oidc/link
+func (h *OIDCHandler) Unlink(c *gin.Context) {
+	userIDStr, _ := middleware.GetUserID(c)
+	userID, err := utils.ParseUserID(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	if err := h.oidcService.Unlink(c.Request.Context(), userID); err != nil {
+		respondOIDCError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "单点登录账号已解绑"})
+}
+
+// respondOIDCError maps single sign-on errors to HTTP statuses
+func respondOIDCError(c *gin.Context, err error) {
+	if respondBanned(c, err) || respondThrottled(c, err) {
+		return
+	}
+
+	switch err.Error() {
+	case "未启用单点登录", "用户不存在":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case "登录已过期，请重新登录", "未绑定单点登录账号", "账号没有密码，无法解绑单点登录":
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case "单点登录失败", "密码错误":
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case "已绑定单点登录账号", "该单点登录账号已绑定其他用户":
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case "单点登录服务不可用":
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+	case "登录请求过多，请稍后再试":
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器错误"})
+	}
+}
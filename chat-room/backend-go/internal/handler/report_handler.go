@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"chat-room-backend/internal/middleware"
+	"chat-room-backend/internal/models"
+	"chat-room-backend/internal/service"
+	"chat-room-backend/internal/utils"
+	ws "chat-room-backend/internal/websocket"
+)
+
+// ReportHandler handles message reports and the review queue
+type ReportHandler struct {
+	reportService *service.ReportService
+	muteChecker   *middleware.MuteChecker
+	hub           *ws.Hub
+}
+
+// NewReportHandler creates a new ReportHandler
+func NewReportHandler(
+	reportService *service.ReportService,
+	muteChecker *middleware.MuteChecker,
+	hub *ws.Hub,
+) *ReportHandler {
+	return &ReportHandler{
+		reportService: reportService,
+		muteChecker:   muteChecker,
+		hub:           hub,
+	}
+}
+
+// ReportMessage reports a message for moderator review
+// POST /api/messages/:id/report
+func (h *ReportHandler) ReportMessage(c *gin.Context) {
+	var body struct {
+		Reason string `json:"reason" binding:"required,max=500"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userIDStr, _ := middleware.GetUserID(c)
+	userID, err := utils.ParseUserID(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	username, _ := middleware.GetUsername(c)
+
+	report, err := h.reportService.ReportMessage(c.Request.Context(), userID, username, &service.ReportMessageRequest{
+		MessageID: c.Param("id"),
+		Reason:    body.Reason,
+	})
+	if err != nil {
+		switch err.Error() {
+		case "消息不存在":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case "您不是该频道成员":
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case "你已举报过该消息":
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case "请填写举报原因", "不能举报自己的消息":
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to report message"})
+		}
+		return
+	}
+
+	h.hub.NotifyNewReport(report)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":  "举报已提交",
+		"reportId": report.ID.Hex(),
+	})
+}
+
+// GetReports returns the moderator review queue
+// GET /api/admin/reports?status=open
+func (h *ReportHandler) GetReports(c *gin.Context) {
+	status := c.DefaultQuery("status", models.ReportStatusOpen)
+
+	reports, err := h.reportService.GetReports(c.Request.Context(), status)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get reports"})
+		return
+	}
+
+	if reports == nil {
+		reports = []*models.Report{}
+	}
+	c.JSON(http.StatusOK, reports)
+}
+
+// ActOnReport dismisses a report or acts on the reported message
+// POST /api/admin/reports/:id/action
+func (h *ReportHandler) ActOnReport(c *gin.Context) {
+	var req service.ReportActionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userIDStr, _ := middleware.GetUserID(c)
+	actorID, err := utils.ParseUserID(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	report, err := h.reportService.ActOnReport(c.Request.Context(), c.Param("id"), &req, actorID)
+	if err != nil {
+		switch err.Error() {
+		case "举报不存在":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case "举报已处理":
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case "该消息没有可禁言的作者", "不能禁言管理人员", "用户不存在":
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			if !strings.HasPrefix(err.Error(), "failed to") {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to act on report"})
+		}
+		return
+	}
+
+	// Apply the action to live clients
+	warning := ""
+	switch req.Action {
+	case models.ReportActionDeleteMessage:
+		h.hub.BroadcastToChannel(report.ChannelID.Hex(), &ws.WSMessage{
+			Event: ws.EventMessageDeleted,
+			Data: ws.MessageDeletedData{
+				MessageID: report.MessageID.Hex(),
+				ChannelID: report.ChannelID.Hex(),
+			},
+		}, nil)
+
+	case models.ReportActionMuteAuthor:
+		if err := notifyMuted(c.Request.Context(), h.muteChecker, h.hub, *report.AuthorID); err != nil {
+			warning = "Failed to refresh mute cache"
+		}
+	}
+
+	response := gin.H{
+		"message": "举报已处理",
+		"report":  report,
+	}
+	if warning != "" {
+		response["warning"] = warning
+	}
+	c.JSON(http.StatusOK, response)
+}
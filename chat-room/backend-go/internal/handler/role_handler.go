@@ -0,0 +1,182 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"chat-room-backend/internal/middleware"
+	"chat-room-backend/internal/service"
+	"chat-room-backend/internal/utils"
+)
+
+// RoleHandler handles role and permission HTTP requests
+type RoleHandler struct {
+	roleService *service.RoleService
+}
+
+// NewRoleHandler creates a new RoleHandler
+func NewRoleHandler(roleService *service.RoleService) *RoleHandler {
+	return &RoleHandler{
+		roleService: roleService,
+	}
+}
+
+// GetMyPermissions returns the current user's role and permissions, so the
+// frontend knows which admin tools to show
+// GET /api/auth/permissions
+func (h *RoleHandler) GetMyPermissions(c *gin.Context) {
+	claims, _ := middleware.GetTokenClaims(c)
+	userID, err := utils.ParseUserID(claims.UserID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	c.JSON(http.StatusOK, h.roleService.GetUserPermissions(userID, claims.TwoFactor))
+}
+
+// GetPermissions lists every permission a role can grant
+// GET /api/admin/permissions
+func (h *RoleHandler) GetPermissions(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"permissions": h.roleService.GetPermissions()})
+}
+
+// GetRoles returns every role
+// GET /api/admin/roles
+func (h *RoleHandler) GetRoles(c *gin.Context) {
+	roles, err := h.roleService.GetRoles(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器错误"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"roles": roles})
+}
+
+// CreateRole creates a role
+// POST /api/admin/roles
+func (h *RoleHandler) CreateRole(c *gin.Context) {
+	var req service.RoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	adminIDStr, _ := middleware.GetUserID(c)
+	adminID, err := utils.ParseUserID(adminIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	role, err := h.roleService.CreateRole(c.Request.Context(), &req, adminID)
+	if err != nil {
+		respondRoleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "角色已创建",
+		"role":    role,
+	})
+}
+
+// UpdateRole changes a role's description and permissions
+// PUT /api/admin/roles/:name
+func (h *RoleHandler) UpdateRole(c *gin.Context) {
+	var req service.UpdateRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	adminIDStr, _ := middleware.GetUserID(c)
+	adminID, err := utils.ParseUserID(adminIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	role, err := h.roleService.UpdateRole(c.Request.Context(), c.Param("name"), &req, adminID)
+	if err != nil {
+		respondRoleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "角色已更新",
+		"role":    role,
+	})
+}
+
+// DeleteRole deletes a role no user holds
+// DELETE /api/admin/roles/:name
+func (h *RoleHandler) DeleteRole(c *gin.Context) {
+	adminIDStr, _ := middleware.GetUserID(c)
+	adminID, err := utils.ParseUserID(adminIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	if err := h.roleService.DeleteRole(c.Request.Context(), c.Param("name"), adminID); err != nil {
+		respondRoleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "角色已删除"})
+}
+
+// AssignRole gives a user a role
+// PUT /api/admin/users/:id/role
+func (h *RoleHandler) AssignRole(c *gin.Context) {
+	var req service.AssignRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	targetID, err := utils.ParseUserID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	adminIDStr, _ := middleware.GetUserID(c)
+	adminID, err := utils.ParseUserID(adminIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	user, err := h.roleService.AssignRole(c.Request.Context(), targetID, &req, adminID)
+	if err != nil {
+		respondRoleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "角色已分配",
+		"userId":  user.ID.Hex(),
+		"role":    user.Role,
+	})
+}
+
+// respondRoleError maps role errors to HTTP statuses
+func respondRoleError(c *gin.Context, err error) {
+	switch err.Error() {
+	case "角色不存在", "用户不存在":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case "角色已存在":
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case "内置角色不能修改", "内置角色不能删除":
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		if !strings.HasPrefix(err.Error(), "failed to") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器错误"})
+	}
+}
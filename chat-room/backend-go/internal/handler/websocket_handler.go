@@ -2,81 +2,107 @@ package handler
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
 	"chat-room-backend/internal/middleware"
+	"chat-room-backend/internal/models"
 	"chat-room-backend/internal/service"
 	"chat-room-backend/internal/utils"
 	ws "chat-room-backend/internal/websocket"
 )
 
-var upgrader = websocket.Upgrader{
-	ReadBufferSize:  1024,
-	WriteBufferSize: 1024,
-	CheckOrigin: func(r *http.Request) bool {
-		// Allow all origins (should be restricted in production)
-		return true
-	},
-}
-
 // WebSocketHandler handles WebSocket connections
 type WebSocketHandler struct {
+	upgrader       websocket.Upgrader
 	hub            *ws.Hub
 	authService    *service.AuthService
+	tickets        *service.WSTicketService
 	chatService    *service.ChatService
 	channelService *service.ChannelService
-	adminHelper    *utils.AdminHelper
-	wordFilter     *middleware.WordFilterCache
+	moderation     *service.ModerationService
+	reports        *service.ReportService
 	muteChecker    *middleware.MuteChecker
+	banChecker     *middleware.BanChecker
+	floodGuard     *middleware.FloodGuard
+	posting        *middleware.PostingPermissions
+	permissions    *middleware.PermissionChecker
+	authorizer     *ws.Authorizer
 }
 
 // NewWebSocketHandler creates a new WebSocketHandler
 func NewWebSocketHandler(
 	hub *ws.Hub,
 	authService *service.AuthService,
+	tickets *service.WSTicketService,
 	chatService *service.ChatService,
 	channelService *service.ChannelService,
-	adminHelper *utils.AdminHelper,
-	wordFilter *middleware.WordFilterCache,
+	moderation *service.ModerationService,
+	reports *service.ReportService,
 	muteChecker *middleware.MuteChecker,
+	banChecker *middleware.BanChecker,
+	floodGuard *middleware.FloodGuard,
+	posting *middleware.PostingPermissions,
+	permissions *middleware.PermissionChecker,
+	authorizer *ws.Authorizer,
+	allowedOrigins []string,
 ) *WebSocketHandler {
+	// Tell users when their timed mute runs out
+	hub.NotifyMuteExpiry(muteChecker)
+
 	return &WebSocketHandler{
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  1024,
+			WriteBufferSize: 1024,
+			CheckOrigin:     checkOrigin(allowedOrigins),
+		},
 		hub:            hub,
 		authService:    authService,
+		tickets:        tickets,
 		chatService:    chatService,
 		channelService: channelService,
-		adminHelper:    adminHelper,
-		wordFilter:     wordFilter,
+		moderation:     moderation,
+		reports:        reports,
 		muteChecker:    muteChecker,
+		banChecker:     banChecker,
+		floodGuard:     floodGuard,
+		posting:        posting,
+		permissions:    permissions,
+		authorizer:     authorizer,
 	}
 }
 
-// HandleWebSocket handles WebSocket connection upgrade
-// This endpoint requires JWT token in query parameter or header
-func (h *WebSocketHandler) HandleWebSocket(c *gin.Context) {
-	// Get token from query parameter (for WebSocket handshake)
-	token := c.Query("token")
-	if token == "" {
-		// Fallback to Authorization header
-		authHeader := c.GetHeader("Authorization")
-		if len(authHeader) > 7 && authHeader[:7] == "Bearer " {
-			token = authHeader[7:]
-		}
-	}
+// IssueTicket exchanges the caller's access token for a single-use,
+// short-lived WebSocket ticket
+// POST /api/auth/ws-ticket
+func (h *WebSocketHandler) IssueTicket(c *gin.Context) {
+	claims, _ := middleware.GetTokenClaims(c)
 
-	if token == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "未提供认证令牌"})
+	ticket, err := h.tickets.Issue(claims, c.ClientIP())
+	if err != nil {
+		if err.Error() == "连接请求过多，请稍后再试" {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器错误"})
 		return
 	}
 
-	// Validate token
-	claims, err := utils.ValidateToken(token, c.GetString("jwtSecret"))
+	c.JSON(http.StatusOK, ticket)
+}
+
+// HandleWebSocket handles WebSocket connection upgrade.
+// Browsers authenticate with a ticket in the query string; other clients
+// may send the access token in the Authorization header instead.
+func (h *WebSocketHandler) HandleWebSocket(c *gin.Context) {
+	claims, err := h.handshakeClaims(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的认证令牌"})
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 		return
 	}
 
@@ -87,11 +113,26 @@ func (h *WebSocketHandler) HandleWebSocket(c *gin.Context) {
 		return
 	}
 
-	// Check if user is admin
-	isAdmin := h.adminHelper.IsAdmin(claims.Username)
+	// Reject banned users, banned IPs and revoked tokens
+	if ban := h.banChecker.CheckUser(userID); ban.IsBanned {
+		c.JSON(http.StatusForbidden, gin.H{"error": "账号已被封禁", "reason": ban.Reason})
+		return
+	}
+	if ban := h.banChecker.CheckIP(c.ClientIP()); ban.IsBanned {
+		c.JSON(http.StatusForbidden, gin.H{"error": "账号已被封禁", "reason": ban.Reason})
+		return
+	}
+	if claims.IssuedAt != nil && h.banChecker.IsTokenRevoked(userID, claims.IssuedAt.Time) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "认证令牌已失效"})
+		return
+	}
+	if h.banChecker.IsAccessTokenRevoked(claims.ID, claims.SessionID) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "认证令牌已失效"})
+		return
+	}
 
 	// Upgrade to WebSocket
-	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
 		log.Printf("Failed to upgrade to WebSocket: %v", err)
 		return
@@ -103,15 +144,20 @@ func (h *WebSocketHandler) HandleWebSocket(c *gin.Context) {
 		conn,
 		userID,
 		claims.Username,
-		isAdmin,
+		claims.TwoFactor,
+		c.ClientIP(),
+		claims.SessionID,
 		h.chatService,
 		h.channelService,
-		h.wordFilter,
-		h.muteChecker,
+		h.moderation,
+		h.reports,
+		h.floodGuard,
+		h.permissions,
+		h.authorizer,
 	)
 
 	// Register client to hub
-	h.hub.register <- client
+	h.hub.Register(client)
 
 	// Send initial data to client
 	go h.sendInitialData(client)
@@ -126,6 +172,42 @@ func (h *WebSocketHandler) HandleWebSocket(c *gin.Context) {
 	log.Printf("✅ WebSocket connection established: %s", claims.Username)
 }
 
+// handshakeClaims authenticates a handshake with a ticket from the query
+// string or an access token from the Authorization header
+func (h *WebSocketHandler) handshakeClaims(c *gin.Context) (*utils.JWTClaims, error) {
+	if ticket := c.Query("ticket"); ticket != "" {
+		return h.tickets.Redeem(ticket, c.ClientIP())
+	}
+
+	authHeader := c.GetHeader("Authorization")
+	if !strings.HasPrefix(authHeader, "Bearer ") {
+		return nil, fmt.Errorf("未提供认证令牌")
+	}
+
+	claims, err := utils.ValidateToken(strings.TrimPrefix(authHeader, "Bearer "), middleware.GetJWTKeys(c))
+	if err != nil {
+		return nil, fmt.Errorf("无效的认证令牌")
+	}
+	return claims, nil
+}
+
+// checkOrigin only lets browsers on an allowed origin open a connection.
+// Requests without an Origin header do not come from a browser and are
+// authenticated like any other API client.
+func checkOrigin(allowedOrigins []string) func(r *http.Request) bool {
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			return true
+		}
+		if utils.OriginAllowed(origin, allowedOrigins) {
+			return true
+		}
+		log.Printf("🚫 Rejected WebSocket handshake from origin %s", origin)
+		return false
+	}
+}
+
 // sendInitialData sends initial data to a newly connected client
 func (h *WebSocketHandler) sendInitialData(client *ws.Client) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -150,11 +232,14 @@ func (h *WebSocketHandler) sendInitialData(client *ws.Client) {
 	channelData := make([]ws.ChannelData, len(channels))
 	for i, ch := range channels {
 		channelData[i] = ws.ChannelData{
-			ID:          ch.ID.Hex(),
-			Name:        ch.Name,
-			Description: ch.Description,
-			IsDefault:   ch.IsDefault,
-			Icon:        ch.Icon,
+			ID:              ch.ID.Hex(),
+			Name:            ch.Name,
+			Description:     ch.Description,
+			IsDefault:       ch.IsDefault,
+			Icon:            ch.Icon,
+			SlowModeSeconds: ch.SlowModeSeconds,
+			PostingPolicy:   ch.GetPostingPolicy(),
+			CanPost:         h.posting.CanPost(client.UserID(), ch.ID.Hex(), client.Can(models.PermModerationExempt)),
 		}
 
 		// Join channel room
@@ -174,21 +259,39 @@ func (h *WebSocketHandler) sendInitialData(client *ws.Client) {
 	availableData := make([]ws.ChannelData, len(availableChannels))
 	for i, ch := range availableChannels {
 		availableData[i] = ws.ChannelData{
-			ID:          ch.ID.Hex(),
-			Name:        ch.Name,
-			Description: ch.Description,
-			IsDefault:   ch.IsDefault,
-			Icon:        ch.Icon,
+			ID:              ch.ID.Hex(),
+			Name:            ch.Name,
+			Description:     ch.Description,
+			IsDefault:       ch.IsDefault,
+			Icon:            ch.Icon,
+			SlowModeSeconds: ch.SlowModeSeconds,
+			PostingPolicy:   ch.GetPostingPolicy(),
+			CanPost:         h.posting.CanPost(client.UserID(), ch.ID.Hex(), client.Can(models.PermModerationExempt)),
+		}
+	}
+
+	// Current mute state so the composer starts in the right mode
+	muteStatus := ws.MuteStatusData{}
+	if mute, err := h.muteChecker.CheckMuteStatus(ctx, client.UserID(), client.Can(models.PermModerationExempt)); err == nil {
+		muteStatus = ws.MuteStatusData{
+			IsMuted:    mute.IsMuted,
+			IsGlobal:   mute.IsGlobal,
+			Reason:     mute.Reason,
+			MutedUntil: formatOptionalTime(mute.MutedUntil),
 		}
 	}
 
 	// Send initial data
+	permissions := client.Permissions()
 	initialData := ws.InitialData{
 		Channels:          channelData,
 		AvailableChannels: availableData,
-		IsAdmin:           client.IsAdmin(),
+		IsAdmin:           len(permissions) > 0,
+		Role:              client.Role(),
+		Permissions:       permissions,
 		Username:          client.Username(),
 		UserID:            client.UserID().Hex(),
+		MuteStatus:        muteStatus,
 	}
 
 	client.Send(&ws.WSMessage{
@@ -207,3 +310,11 @@ func (h *WebSocketHandler) broadcastUserList() {
 		Data:  users,
 	})
 }
+
+// formatOptionalTime formats an optional time as RFC3339, or "" when nil
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
@@ -9,7 +9,7 @@ import (
 )
 
 // AuthMiddleware validates JWT token and sets user info in context
-func AuthMiddleware(jwtSecret string) gin.HandlerFunc {
+func AuthMiddleware(jwtKeys *utils.KeySet, banChecker *BanChecker) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Get token from Authorization header
 		authHeader := c.GetHeader("Authorization")
@@ -30,16 +30,57 @@ func AuthMiddleware(jwtSecret string) gin.HandlerFunc {
 		token := parts[1]
 
 		// Validate token
-		claims, err := utils.ValidateToken(token, jwtSecret)
+		claims, err := utils.ValidateToken(token, jwtKeys)
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的认证令牌"})
 			c.Abort()
 			return
 		}
 
+		userID, err := utils.ParseUserID(claims.UserID)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的认证令牌"})
+			c.Abort()
+			return
+		}
+
+		// Reject banned users and tokens revoked by a ban
+		if ban := banChecker.CheckUser(userID); ban.IsBanned {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":       "账号已被封禁",
+				"reason":      ban.Reason,
+				"bannedUntil": ban.BannedUntil,
+			})
+			c.Abort()
+			return
+		}
+		// Tokens issued before an IP ban stop working from that address
+		if ban := banChecker.CheckIP(c.ClientIP()); ban.IsBanned {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":       "账号已被封禁",
+				"reason":      ban.Reason,
+				"bannedUntil": ban.BannedUntil,
+			})
+			c.Abort()
+			return
+		}
+		if claims.IssuedAt != nil && banChecker.IsTokenRevoked(userID, claims.IssuedAt.Time) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "认证令牌已失效"})
+			c.Abort()
+			return
+		}
+
+		// Reject tokens revoked by logout or refresh token reuse
+		if banChecker.IsAccessTokenRevoked(claims.ID, claims.SessionID) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "认证令牌已失效"})
+			c.Abort()
+			return
+		}
+
 		// Set user info in context
 		c.Set("userId", claims.UserID)
 		c.Set("username", claims.Username)
+		c.Set("tokenClaims", claims)
 
 		c.Next()
 	}
@@ -54,6 +95,20 @@ func GetUserID(c *gin.Context) (string, bool) {
 	return userID.(string), true
 }
 
+// GetJWTKeys retrieves the token verification keys stored by the router
+func GetJWTKeys(c *gin.Context) *utils.KeySet {
+	return c.MustGet("jwtKeys").(*utils.KeySet)
+}
+
+// GetTokenClaims retrieves the access token claims from context
+func GetTokenClaims(c *gin.Context) (*utils.JWTClaims, bool) {
+	claims, exists := c.Get("tokenClaims")
+	if !exists {
+		return nil, false
+	}
+	return claims.(*utils.JWTClaims), true
+}
+
 // GetUsername retrieves username from context
 func GetUsername(c *gin.Context) (string, bool) {
 	username, exists := c.Get("username")
@@ -0,0 +1,277 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"chat-room-backend/internal/models"
+	"chat-room-backend/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// BanCheckResult represents the result of a ban check
+type BanCheckResult struct {
+	IsBanned    bool
+	Reason      string
+	BannedUntil *time.Time // nil for permanent bans
+}
+
+// banState is the cached state of a single user or IP ban
+type banState struct {
+	reason      string
+	bannedUntil *time.Time
+}
+
+// active reports whether the ban has not expired yet
+func (b *banState) active() bool {
+	return b.bannedUntil == nil || b.bannedUntil.After(time.Now())
+}
+
+// BanChecker keeps banned users, banned IPs and token revocations in memory
+// so that every authenticated request can be checked without a database query
+type BanChecker struct {
+	userRepo    *repository.UserRepository
+	banRepo     *repository.BanRepository
+	revokedRepo *repository.RevokedTokenRepository
+
+	mu        sync.RWMutex
+	users     map[primitive.ObjectID]*banState
+	ips       map[string]*banState
+	revokedAt map[primitive.ObjectID]time.Time
+
+	// Access token revocation list, entry -> time the tokens it covers expire
+	revokedTokens   map[string]time.Time // jti
+	revokedSessions map[string]time.Time // sid
+}
+
+// NewBanChecker creates a new BanChecker
+func NewBanChecker(userRepo *repository.UserRepository, banRepo *repository.BanRepository, revokedRepo *repository.RevokedTokenRepository) *BanChecker {
+	bc := &BanChecker{
+		userRepo:        userRepo,
+		banRepo:         banRepo,
+		revokedRepo:     revokedRepo,
+		users:           make(map[primitive.ObjectID]*banState),
+		ips:             make(map[string]*banState),
+		revokedAt:       make(map[primitive.ObjectID]time.Time),
+		revokedTokens:   make(map[string]time.Time),
+		revokedSessions: make(map[string]time.Time),
+	}
+
+	// Load initial cache
+	if err := bc.Reload(); err != nil {
+		log.Printf("⚠️  Warning: Failed to load ban cache: %v", err)
+	}
+
+	// Forget revocations once the tokens they cover have expired
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for range ticker.C {
+			bc.pruneRevocations(time.Now())
+		}
+	}()
+
+	return bc
+}
+
+// Reload reloads all bans from database
+func (bc *BanChecker) Reload() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	users, err := bc.userRepo.FindBanned(ctx)
+	if err != nil {
+		return err
+	}
+
+	bc.mu.Lock()
+	bc.users = make(map[primitive.ObjectID]*banState)
+	bc.revokedAt = make(map[primitive.ObjectID]time.Time)
+	bc.mu.Unlock()
+
+	for _, user := range users {
+		bc.setUser(user)
+	}
+
+	if err := bc.refreshIPs(ctx); err != nil {
+		return err
+	}
+
+	entries, err := bc.revokedRepo.FindActive(ctx)
+	if err != nil {
+		return err
+	}
+
+	bc.mu.Lock()
+	bc.revokedTokens = make(map[string]time.Time)
+	bc.revokedSessions = make(map[string]time.Time)
+	bc.mu.Unlock()
+
+	for _, entry := range entries {
+		bc.AddRevocation(entry)
+	}
+
+	log.Printf("✅ Loaded ban cache (%d user(s), %d revoked token(s))", len(users), len(entries))
+	return nil
+}
+
+// RefreshUser reloads a single user's ban state and all IP bans from database
+func (bc *BanChecker) RefreshUser(ctx context.Context, userID primitive.ObjectID) error {
+	user, err := bc.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if user == nil {
+		bc.mu.Lock()
+		delete(bc.users, userID)
+		delete(bc.revokedAt, userID)
+		bc.mu.Unlock()
+	} else {
+		bc.setUser(user)
+	}
+
+	return bc.refreshIPs(ctx)
+}
+
+// setUser caches a user's ban state and token revocation time
+func (bc *BanChecker) setUser(user *models.User) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+
+	if user.IsBanActive() {
+		bc.users[user.ID] = &banState{
+			reason:      user.BanReason,
+			bannedUntil: user.BannedUntil,
+		}
+	} else {
+		delete(bc.users, user.ID)
+	}
+
+	if user.TokensRevokedAt != nil {
+		bc.revokedAt[user.ID] = *user.TokensRevokedAt
+	} else {
+		delete(bc.revokedAt, user.ID)
+	}
+}
+
+// refreshIPs reloads all active IP bans
+func (bc *BanChecker) refreshIPs(ctx context.Context) error {
+	bans, err := bc.banRepo.FindActive(ctx)
+	if err != nil {
+		return err
+	}
+
+	ips := make(map[string]*banState, len(bans))
+	for _, ban := range bans {
+		if ban.IsBanActive() {
+			ips[ban.IP] = &banState{
+				reason:      ban.Reason,
+				bannedUntil: ban.BannedUntil,
+			}
+		}
+	}
+
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	bc.ips = ips
+	return nil
+}
+
+// CheckUser checks if a user is banned
+func (bc *BanChecker) CheckUser(userID primitive.ObjectID) *BanCheckResult {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	return toBanCheckResult(bc.users[userID])
+}
+
+// CheckIP checks if an IP address is banned
+func (bc *BanChecker) CheckIP(ip string) *BanCheckResult {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	return toBanCheckResult(bc.ips[ip])
+}
+
+// IsTokenRevoked checks if a token issued at issuedAt has been revoked
+func (bc *BanChecker) IsTokenRevoked(userID primitive.ObjectID, issuedAt time.Time) bool {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	revokedAt, ok := bc.revokedAt[userID]
+	if !ok {
+		return false
+	}
+	// JWT timestamps have second precision
+	return issuedAt.Before(revokedAt.Truncate(time.Second))
+}
+
+// AddRevocation caches a revocation list entry after it was stored
+func (bc *BanChecker) AddRevocation(entry *models.RevokedToken) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+
+	switch entry.Kind {
+	case models.RevocationKindToken:
+		bc.revokedTokens[entry.Value] = entry.ExpiresAt
+	case models.RevocationKindSession:
+		if entry.ExpiresAt.After(bc.revokedSessions[entry.Value]) {
+			bc.revokedSessions[entry.Value] = entry.ExpiresAt
+		}
+	}
+}
+
+// IsAccessTokenRevoked checks an access token's jti and sid against the
+// revocation list
+func (bc *BanChecker) IsAccessTokenRevoked(tokenID, sessionID string) bool {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	if tokenID != "" {
+		if _, ok := bc.revokedTokens[tokenID]; ok {
+			return true
+		}
+	}
+	if sessionID != "" {
+		if _, ok := bc.revokedSessions[sessionID]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// pruneRevocations removes revocation list entries that expired
+func (bc *BanChecker) pruneRevocations(now time.Time) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+
+	for jti, expiresAt := range bc.revokedTokens {
+		if !expiresAt.After(now) {
+			delete(bc.revokedTokens, jti)
+		}
+	}
+	for sid, expiresAt := range bc.revokedSessions {
+		if !expiresAt.After(now) {
+			delete(bc.revokedSessions, sid)
+		}
+	}
+}
+
+// toBanCheckResult converts a cached ban to a BanCheckResult
+func toBanCheckResult(state *banState) *BanCheckResult {
+	if state == nil || !state.active() {
+		return &BanCheckResult{IsBanned: false}
+	}
+
+	reason := state.reason
+	if reason == "" {
+		reason = "您已被封禁"
+	}
+
+	return &BanCheckResult{
+		IsBanned:    true,
+		Reason:      reason,
+		BannedUntil: state.bannedUntil,
+	}
+}
@@ -0,0 +1,175 @@
+package middleware
+
+import (
+	"testing"
+	"time"
+
+	"chat-room-backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// newTestBanChecker returns a BanChecker without repositories
+func newTestBanChecker() *BanChecker {
+	return &BanChecker{
+		users:           make(map[primitive.ObjectID]*banState),
+		ips:             make(map[string]*banState),
+		revokedAt:       make(map[primitive.ObjectID]time.Time),
+		revokedTokens:   make(map[string]time.Time),
+		revokedSessions: make(map[string]time.Time),
+	}
+}
+
+func TestBanCheckerUsers(t *testing.T) {
+	future := time.Now().Add(time.Hour)
+	past := time.Now().Add(-time.Minute)
+
+	tests := []struct {
+		name       string
+		user       models.User
+		wantBanned bool
+		wantReason string
+	}{
+		{"not banned", models.User{}, false, ""},
+		{"permanent ban", models.User{IsBanned: true, BanReason: "spam"}, true, "spam"},
+		{"timed ban", models.User{IsBanned: true, BannedUntil: &future, BanReason: "spam"}, true, "spam"},
+		{"expired ban", models.User{IsBanned: true, BannedUntil: &past}, false, ""},
+		{"ban without reason", models.User{IsBanned: true}, true, "您已被封禁"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bc := newTestBanChecker()
+			user := tt.user
+			user.ID = primitive.NewObjectID()
+			bc.setUser(&user)
+
+			got := bc.CheckUser(user.ID)
+			if got.IsBanned != tt.wantBanned || got.Reason != tt.wantReason {
+				t.Errorf("CheckUser() = %+v, want banned %v reason %q", got, tt.wantBanned, tt.wantReason)
+			}
+			if tt.wantBanned && got.BannedUntil != user.BannedUntil {
+				t.Errorf("BannedUntil = %v, want %v", got.BannedUntil, user.BannedUntil)
+			}
+		})
+	}
+}
+
+func TestBanCheckerUnban(t *testing.T) {
+	bc := newTestBanChecker()
+	user := &models.User{ID: primitive.NewObjectID(), IsBanned: true}
+	bc.setUser(user)
+	if !bc.CheckUser(user.ID).IsBanned {
+		t.Fatal("banned user not reported as banned")
+	}
+
+	user.IsBanned = false
+	bc.setUser(user)
+	if bc.CheckUser(user.ID).IsBanned {
+		t.Error("unbanned user still reported as banned")
+	}
+	if bc.CheckUser(primitive.NewObjectID()).IsBanned {
+		t.Error("unknown user reported as banned")
+	}
+}
+
+func TestBanCheckerIPs(t *testing.T) {
+	bc := newTestBanChecker()
+	future := time.Now().Add(time.Hour)
+	past := time.Now().Add(-time.Minute)
+	bc.ips["203.0.113.1"] = &banState{reason: "ban evasion"}
+	bc.ips["203.0.113.2"] = &banState{bannedUntil: &future}
+	bc.ips["203.0.113.3"] = &banState{bannedUntil: &past}
+
+	tests := []struct {
+		ip         string
+		wantBanned bool
+		wantReason string
+	}{
+		{"203.0.113.1", true, "ban evasion"},
+		{"203.0.113.2", true, "您已被封禁"},
+		{"203.0.113.3", false, ""},
+		{"203.0.113.4", false, ""},
+	}
+
+	for _, tt := range tests {
+		got := bc.CheckIP(tt.ip)
+		if got.IsBanned != tt.wantBanned || got.Reason != tt.wantReason {
+			t.Errorf("CheckIP(%s) = %+v, want banned %v reason %q", tt.ip, got, tt.wantBanned, tt.wantReason)
+		}
+	}
+}
+
+func TestBanCheckerTokenRevoked(t *testing.T) {
+	bc := newTestBanChecker()
+	revokedAt := time.Date(2026, 3, 1, 12, 0, 0, 500_000_000, time.UTC)
+	user := &models.User{ID: primitive.NewObjectID(), IsBanned: true, TokensRevokedAt: &revokedAt}
+	bc.setUser(user)
+
+	tests := []struct {
+		name     string
+		userID   primitive.ObjectID
+		issuedAt time.Time
+		want     bool
+	}{
+		{"issued before the ban", user.ID, revokedAt.Add(-time.Hour), true},
+		// iat is truncated to the second, so a token issued right after
+		// the ban in the same second must still work
+		{"issued in the same second", user.ID, revokedAt.Truncate(time.Second), false},
+		{"issued after the ban", user.ID, revokedAt.Add(time.Hour), false},
+		{"other user", primitive.NewObjectID(), revokedAt.Add(-time.Hour), false},
+	}
+
+	for _, tt := range tests {
+		if got := bc.IsTokenRevoked(tt.userID, tt.issuedAt); got != tt.want {
+			t.Errorf("%s: IsTokenRevoked() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	// Unbanning keeps the revocation time
+	user.IsBanned = false
+	bc.setUser(user)
+	if !bc.IsTokenRevoked(user.ID, revokedAt.Add(-time.Hour)) {
+		t.Error("revocation forgotten after unban")
+	}
+}
+
+func TestBanCheckerRevocationList(t *testing.T) {
+	bc := newTestBanChecker()
+	now := time.Now()
+
+	bc.AddRevocation(&models.RevokedToken{Kind: models.RevocationKindToken, Value: "jti-1", ExpiresAt: now.Add(time.Minute)})
+	bc.AddRevocation(&models.RevokedToken{Kind: models.RevocationKindSession, Value: "sid-1", ExpiresAt: now.Add(time.Hour)})
+	// A later entry for the same session must not shorten it
+	bc.AddRevocation(&models.RevokedToken{Kind: models.RevocationKindSession, Value: "sid-1", ExpiresAt: now.Add(time.Minute)})
+
+	tests := []struct {
+		tokenID   string
+		sessionID string
+		want      bool
+	}{
+		{"jti-1", "", true},
+		{"jti-1", "sid-2", true},
+		{"jti-2", "sid-1", true},
+		{"", "sid-1", true},
+		{"jti-2", "sid-2", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		if got := bc.IsAccessTokenRevoked(tt.tokenID, tt.sessionID); got != tt.want {
+			t.Errorf("IsAccessTokenRevoked(%q, %q) = %v, want %v", tt.tokenID, tt.sessionID, got, tt.want)
+		}
+	}
+
+	// Entries are forgotten once the tokens they cover have expired
+	bc.pruneRevocations(now.Add(2 * time.Minute))
+	if bc.IsAccessTokenRevoked("jti-1", "") {
+		t.Error("expired token entry was not pruned")
+	}
+	if !bc.IsAccessTokenRevoked("", "sid-1") {
+		t.Error("session entry pruned before its tokens expired")
+	}
+	bc.pruneRevocations(now.Add(time.Hour))
+	if bc.IsAccessTokenRevoked("", "sid-1") {
+		t.Error("expired session entry was not pruned")
+	}
+}
@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"chat-room-backend/internal/models"
+	"chat-room-backend/internal/repository"
+	"chat-room-backend/internal/utils"
+)
+
+// BotAuthMiddleware validates a bot API key and sets the bot's info in context
+func BotAuthMiddleware(keyRepo *repository.APIKeyRepository, userRepo *repository.UserRepository, banChecker *BanChecker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Extract key from "Bearer <key>"
+		parts := strings.Split(c.GetHeader("Authorization"), " ")
+		if len(parts) != 2 || parts[0] != "Bearer" || parts[1] == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "未提供 API 密钥"})
+			c.Abort()
+			return
+		}
+
+		ctx := c.Request.Context()
+		key, err := keyRepo.FindByHash(ctx, utils.HashToken(parts[1]))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器错误"})
+			c.Abort()
+			return
+		}
+		if key == nil || !key.IsActive() {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的 API 密钥"})
+			c.Abort()
+			return
+		}
+
+		bot, err := userRepo.FindByID(ctx, key.BotID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器错误"})
+			c.Abort()
+			return
+		}
+		if bot == nil || !bot.IsBot {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的 API 密钥"})
+			c.Abort()
+			return
+		}
+
+		// Bots are banned like users
+		if ban := banChecker.CheckUser(bot.ID); ban.IsBanned {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":       "账号已被封禁",
+				"reason":      ban.Reason,
+				"bannedUntil": ban.BannedUntil,
+			})
+			c.Abort()
+			return
+		}
+
+		if err := keyRepo.TouchLastUsed(ctx, key.ID); err != nil {
+			log.Printf("⚠️  Warning: Failed to record use of API key %s: %v", key.ID.Hex(), err)
+		}
+
+		// Set bot info in context
+		c.Set("userId", bot.ID.Hex())
+		c.Set("username", bot.Username)
+		c.Set("apiKey", key)
+
+		c.Next()
+	}
+}
+
+// RequireScope rejects requests whose API key lacks a scope.
+// Must run after BotAuthMiddleware.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key, ok := GetAPIKey(c)
+		if !ok || !key.HasScope(scope) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "API 密钥缺少所需权限",
+				"scope": scope,
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// GetAPIKey retrieves the bot's API key from context
+func GetAPIKey(c *gin.Context) (*models.APIKey, bool) {
+	key, exists := c.Get("apiKey")
+	if !exists {
+		return nil, false
+	}
+	return key.(*models.APIKey), true
+}
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"chat-room-backend/internal/models"
+)
+
+func TestRequireScope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name string
+		key  *models.APIKey
+		want int
+	}{
+		{"scope granted", &models.APIKey{Scopes: []string{models.APIKeyScopeMessagesWrite}}, http.StatusOK},
+		{"other scope", &models.APIKey{Scopes: []string{models.APIKeyScopeMessagesRead}}, http.StatusForbidden},
+		{"no key", nil, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		router := gin.New()
+		router.POST("/messages", func(c *gin.Context) {
+			if tt.key != nil {
+				c.Set("apiKey", tt.key)
+			}
+			c.Next()
+		}, RequireScope(models.APIKeyScopeMessagesWrite), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/messages", nil))
+		if w.Code != tt.want {
+			t.Errorf("%s: status %d, want %d", tt.name, w.Code, tt.want)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package middleware
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"chat-room-backend/internal/models"
+	"chat-room-backend/internal/utils"
+)
+
+// ContentMatch describes a single rule hit in a piece of text
+type ContentMatch struct {
+	FilterID    string `json:"filterId"`
+	Word        string `json:"word"`        // The rule as configured
+	Pattern     string `json:"pattern"`     // The word or variant that matched
+	MatchType   string `json:"matchType"`   // substring | word | regex
+	MatchedText string `json:"matchedText"` // Excerpt of the original text
+	Start       int    `json:"start"`       // Byte offset in the original text
+	End         int    `json:"end"`         // Byte end offset in the original text
+	Action      string `json:"action"`      // mask | review | block | mute
+	MuteMinutes int    `json:"muteMinutes,omitempty"`
+}
+
+// ContentExplanation is the dry-run result for a piece of text
+type ContentExplanation struct {
+	Blocked    bool           `json:"blocked"`
+	Normalized string         `json:"normalized"`
+	Matches    []ContentMatch `json:"matches"`
+	Allowed    []ContentMatch `json:"allowed"`    // Allowlist entries that applied
+	Suppressed []ContentMatch `json:"suppressed"` // Matches cancelled by the allowlist
+}
+
+// literalPattern is a folded word or variant compiled into the automaton
+type literalPattern struct {
+	filter  *models.WordFilter
+	pattern string
+}
+
+// regexPattern is a compiled regex filter
+type regexPattern struct {
+	filter *models.WordFilter
+	re     *regexp.Regexp
+}
+
+// ContentMatcher matches text against compiled word filters.
+// Literal words are matched with a single Aho-Corasick pass over the
+// normalized text; regex filters run on the folded text.
+type ContentMatcher struct {
+	automaton *utils.AhoCorasick
+	literals  []literalPattern
+	regexes   []regexPattern
+}
+
+// NewContentMatcher compiles filters into a matcher.
+// Filters that fail to compile are skipped and reported in the returned errors.
+func NewContentMatcher(filters []*models.WordFilter) (*ContentMatcher, []error) {
+	m := &ContentMatcher{}
+	var errs []error
+
+	patterns := make([][]rune, 0, len(filters))
+	for _, filter := range filters {
+		if filter.GetMatchType() == models.MatchTypeRegex {
+			re, err := utils.CompileFilterRegex(filter.Word)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("word filter %s: %w", filter.ID.Hex(), err))
+				continue
+			}
+			m.regexes = append(m.regexes, regexPattern{filter: filter, re: re})
+			continue
+		}
+
+		words := append([]string{filter.Word}, filter.Variants...)
+		for _, word := range words {
+			pattern := utils.FoldPattern(word)
+			if len(pattern) == 0 {
+				continue
+			}
+			patterns = append(patterns, pattern)
+			m.literals = append(m.literals, literalPattern{filter: filter, pattern: word})
+		}
+	}
+
+	m.automaton = utils.NewAhoCorasick(patterns)
+	return m, errs
+}
+
+// Match returns the blocking matches in text after applying the allowlist
+func (m *ContentMatcher) Match(text string) []ContentMatch {
+	return m.Explain(text).Matches
+}
+
+// Explain matches text and reports every rule that applied
+func (m *ContentMatcher) Explain(text string) *ContentExplanation {
+	nt := utils.NormalizeForMatch(text)
+
+	var blocked, allowed []ContentMatch
+
+	add := func(filter *models.WordFilter, pattern string, start, end int) {
+		match := ContentMatch{
+			FilterID:    filter.ID.Hex(),
+			Word:        filter.Word,
+			Pattern:     pattern,
+			MatchType:   filter.GetMatchType(),
+			MatchedText: text[start:end],
+			Start:       start,
+			End:         end,
+		}
+		if filter.IsAllow {
+			allowed = append(allowed, match)
+			return
+		}
+
+		match.Action = filter.GetAction()
+		if match.Action == models.FilterActionMute {
+			match.MuteMinutes = filter.MuteDuration
+			if match.MuteMinutes <= 0 {
+				match.MuteMinutes = models.DefaultFilterMuteDuration
+			}
+		}
+		blocked = append(blocked, match)
+	}
+
+	for _, hit := range m.automaton.FindAll(nt.Runes) {
+		literal := m.literals[hit.Pattern]
+		if literal.filter.GetMatchType() == models.MatchTypeWord && !nt.IsWordAt(hit.Start, hit.End) {
+			continue
+		}
+		add(literal.filter, literal.pattern, nt.Starts[hit.Start], nt.Ends[hit.End-1])
+	}
+
+	for _, rp := range m.regexes {
+		for _, loc := range rp.re.FindAllStringIndex(nt.Folded, -1) {
+			if loc[0] == loc[1] {
+				continue
+			}
+			start, end := nt.OriginalRange(loc[0], loc[1])
+			add(rp.filter, rp.filter.Word, start, end)
+		}
+	}
+
+	// A blocked match fully inside an allowlisted phrase is not a violation
+	explanation := &ContentExplanation{
+		Normalized: string(nt.Runes),
+		Matches:    []ContentMatch{},
+		Allowed:    []ContentMatch{},
+		Suppressed: []ContentMatch{},
+	}
+	usedAllow := make(map[int]bool)
+	for _, match := range blocked {
+		suppressed := false
+		for i, allow := range allowed {
+			if allow.Start <= match.Start && match.End <= allow.End {
+				suppressed = true
+				usedAllow[i] = true
+			}
+		}
+		if suppressed {
+			explanation.Suppressed = append(explanation.Suppressed, match)
+		} else {
+			explanation.Matches = append(explanation.Matches, match)
+		}
+	}
+	for i, allow := range allowed {
+		if usedAllow[i] {
+			explanation.Allowed = append(explanation.Allowed, allow)
+		}
+	}
+	explanation.Blocked = len(explanation.Matches) > 0
+
+	return explanation
+}
+
+// StrictestAction returns the strictest action among matches ("" if none)
+func StrictestAction(matches []ContentMatch) string {
+	action := ""
+	for _, match := range matches {
+		if models.FilterActionRank[match.Action] > models.FilterActionRank[action] {
+			action = match.Action
+		}
+	}
+	return action
+}
+
+// MaskMatches replaces every match with asterisks, one per character
+func MaskMatches(text string, matches []ContentMatch) string {
+	if len(matches) == 0 {
+		return text
+	}
+
+	masked := make([]bool, len(text))
+	for _, match := range matches {
+		for i := match.Start; i < match.End && i < len(text); i++ {
+			masked[i] = true
+		}
+	}
+
+	var b strings.Builder
+	b.Grow(len(text))
+	for offset, r := range text {
+		if masked[offset] {
+			b.WriteRune('*')
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,133 @@
+package middleware
+
+import (
+	"testing"
+
+	"chat-room-backend/internal/models"
+)
+
+func newTestContentMatcher(t *testing.T) *ContentMatcher {
+	t.Helper()
+
+	m, errs := NewContentMatcher([]*models.WordFilter{
+		{Word: "bad", Action: models.FilterActionMask},
+		{Word: "ass", MatchType: models.MatchTypeWord, Action: models.FilterActionBlock},
+		{Word: "sp[a4]m+", MatchType: models.MatchTypeRegex, Action: models.FilterActionMute, MuteDuration: 10},
+		{Word: "敏感", Variants: []string{"min gan"}, Action: models.FilterActionReview},
+		{Word: "badminton", IsAllow: true},
+	})
+	if len(errs) > 0 {
+		t.Fatalf("NewContentMatcher: %v", errs)
+	}
+	return m
+}
+
+func TestContentMatcherMatch(t *testing.T) {
+	m := newTestContentMatcher(t)
+
+	tests := []struct {
+		name    string
+		text    string
+		words   []string // Rules expected to match, in order
+		matched string   // Original text of the first match
+	}{
+		{"plain", "so bad", []string{"bad"}, "bad"},
+		{"upper case", "BAD", []string{"bad"}, "BAD"},
+		{"full-width", "ｂａｄ", []string{"bad"}, "ｂａｄ"},
+		{"Cyrillic homoglyph", "bаd", []string{"bad"}, "bаd"},
+		{"zero-width space", "b​ad", []string{"bad"}, "b​ad"},
+		{"separators", "b.a.d", []string{"bad"}, "b.a.d"},
+		{"leetspeak", "b4d", []string{"bad"}, "b4d"},
+		{"overlapping rules", "bad ass", []string{"bad", "ass"}, "bad"},
+		{"whole word", "kick ass!", []string{"ass"}, "ass"},
+		{"inside a word", "classic", nil, ""},
+		{"regex", "SPAMMM", []string{"sp[a4]m+"}, "SPAMMM"},
+		{"CJK with spaces", "敏 感", []string{"敏感"}, "敏 感"},
+		{"variant", "min-gan", []string{"敏感"}, "min-gan"},
+		{"allowlisted", "badminton", nil, ""},
+		{"allowlist elsewhere", "badminton is bad", []string{"bad"}, "bad"},
+		{"clean", "hello", nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matches := m.Match(tt.text)
+			if len(matches) != len(tt.words) {
+				t.Fatalf("Match(%q) = %+v, want rules %v", tt.text, matches, tt.words)
+			}
+			for i, match := range matches {
+				if match.Word != tt.words[i] {
+					t.Errorf("match %d: rule %q, want %q", i, match.Word, tt.words[i])
+				}
+			}
+			if len(matches) > 0 && matches[0].MatchedText != tt.matched {
+				t.Errorf("matched text %q, want %q", matches[0].MatchedText, tt.matched)
+			}
+		})
+	}
+}
+
+func TestContentMatcherActions(t *testing.T) {
+	m := newTestContentMatcher(t)
+
+	tests := []struct {
+		text   string
+		action string
+		masked string
+	}{
+		{"so bad", models.FilterActionMask, "so ***"},
+		{"b​ad day", models.FilterActionMask, "**** day"},
+		{"bad spam", models.FilterActionMute, "*** ****"},
+		{"hello", "", "hello"},
+	}
+
+	for _, tt := range tests {
+		matches := m.Match(tt.text)
+		if got := StrictestAction(matches); got != tt.action {
+			t.Errorf("StrictestAction(%q) = %q, want %q", tt.text, got, tt.action)
+		}
+		if got := MaskMatches(tt.text, matches); got != tt.masked {
+			t.Errorf("MaskMatches(%q) = %q, want %q", tt.text, got, tt.masked)
+		}
+	}
+}
+
+func TestContentMatcherFilterActions(t *testing.T) {
+	m, errs := NewContentMatcher([]*models.WordFilter{
+		{Word: "legacy"},
+		{Word: "spam", Action: models.FilterActionMute, MuteDuration: 15},
+		{Word: "scam", Action: models.FilterActionReview},
+	})
+	if len(errs) > 0 {
+		t.Fatalf("NewContentMatcher: %v", errs)
+	}
+
+	tests := []struct {
+		text    string
+		action  string
+		minutes int
+	}{
+		// Filters created before actions existed block
+		{"legacy", models.FilterActionBlock, 0},
+		{"spam", models.FilterActionMute, 15},
+		{"scam", models.FilterActionReview, 0},
+	}
+
+	for _, tt := range tests {
+		matches := m.Match(tt.text)
+		if len(matches) != 1 {
+			t.Fatalf("Match(%q) = %+v, want one match", tt.text, matches)
+		}
+		if matches[0].Action != tt.action || matches[0].MuteMinutes != tt.minutes {
+			t.Errorf("Match(%q) action %q for %d minutes, want %q for %d", tt.text, matches[0].Action, matches[0].MuteMinutes, tt.action, tt.minutes)
+		}
+	}
+
+	// The strictest action wins whatever the order of the hits
+	if got := StrictestAction(m.Match("spam legacy scam")); got != models.FilterActionMute {
+		t.Errorf("StrictestAction = %q, want mute", got)
+	}
+	if got := StrictestAction(m.Match("scam legacy")); got != models.FilterActionBlock {
+		t.Errorf("StrictestAction = %q, want block", got)
+	}
+}
@@ -0,0 +1,239 @@
+package middleware
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"chat-room-backend/internal/config"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Event classes with separate rate limits
+const (
+	FloodClassMessage = "message"
+	FloodClassTyping  = "typing"
+	FloodClassEvent   = "event"
+)
+
+// Responses to a user exceeding a limit, in escalating order
+const (
+	FloodActionDrop       = "drop"
+	FloodActionMute       = "mute"
+	FloodActionDisconnect = "disconnect"
+)
+
+// floodIdleTimeout is how long an idle user's state is kept
+const floodIdleTimeout = 10 * time.Minute
+
+// maxRecentMessages caps the texts kept per user for duplicate detection
+const maxRecentMessages = 50
+
+// FloodVerdict is the outcome of checking an event against the limits
+type FloodVerdict struct {
+	Allowed     bool
+	Action      string // Set when not allowed
+	Reason      string
+	RetryAfter  time.Duration // Until the next token is available
+	Violations  int           // Violations within the window, including this one
+	MuteMinutes int           // Set when Action is mute
+}
+
+// tokenBucket allows burst events at once, refilled at rate tokens a second
+type tokenBucket struct {
+	tokens float64
+	burst  float64
+	rate   float64
+	last   time.Time
+}
+
+// take refills the bucket and takes a token. It returns how long until
+// a token is available when the bucket is empty.
+func (b *tokenBucket) take(now time.Time) (bool, time.Duration) {
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
+
+// recentMessage is a text a user sent recently
+type recentMessage struct {
+	key string
+	at  time.Time
+}
+
+// floodState is the rate limiting state of a single user, shared by all
+// of the user's connections
+type floodState struct {
+	buckets    map[string]*tokenBucket
+	violations []time.Time
+	recent     []recentMessage
+	lastSeen   time.Time
+}
+
+// FloodGuard rate limits inbound WebSocket events per user and event class,
+// and detects the same text being sent over and over
+type FloodGuard struct {
+	config config.RateLimitConfig
+	mu     sync.Mutex
+	users  map[primitive.ObjectID]*floodState
+}
+
+// NewFloodGuard creates a new FloodGuard
+func NewFloodGuard(cfg config.RateLimitConfig) *FloodGuard {
+	fg := &FloodGuard{
+		config: cfg,
+		users:  make(map[primitive.ObjectID]*floodState),
+	}
+
+	// Drop the state of users that went quiet
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for range ticker.C {
+			fg.prune(time.Now())
+		}
+	}()
+
+	return fg
+}
+
+// Allow takes a token for an event of a class. When the bucket is empty
+// the event counts as a violation.
+func (fg *FloodGuard) Allow(userID primitive.ObjectID, class string) *FloodVerdict {
+	burst, perMinute := fg.limit(class)
+	if burst <= 0 || perMinute <= 0 {
+		return &FloodVerdict{Allowed: true}
+	}
+
+	fg.mu.Lock()
+	defer fg.mu.Unlock()
+
+	now := time.Now()
+	state := fg.state(userID, now)
+	bucket, ok := state.buckets[class]
+	if !ok {
+		bucket = &tokenBucket{
+			tokens: float64(burst),
+			burst:  float64(burst),
+			rate:   float64(perMinute) / 60,
+			last:   now,
+		}
+		state.buckets[class] = bucket
+	}
+
+	allowed, retryAfter := bucket.take(now)
+	if allowed {
+		return &FloodVerdict{Allowed: true}
+	}
+
+	verdict := fg.violate(state, now)
+	verdict.Reason = "发送过于频繁，请稍后再试"
+	verdict.RetryAfter = retryAfter
+	return verdict
+}
+
+// CheckDuplicate records a message text and counts it as a violation when
+// the same text was already sent DuplicateLimit times within the window
+func (fg *FloodGuard) CheckDuplicate(userID primitive.ObjectID, text string) *FloodVerdict {
+	if fg.config.DuplicateLimit <= 0 {
+		return &FloodVerdict{Allowed: true}
+	}
+
+	// Case and whitespace changes do not make a message different
+	key := strings.Join(strings.Fields(strings.ToLower(text)), " ")
+
+	fg.mu.Lock()
+	defer fg.mu.Unlock()
+
+	now := time.Now()
+	state := fg.state(userID, now)
+	since := now.Add(-time.Duration(fg.config.DuplicateWindowSeconds) * time.Second)
+
+	recent := state.recent[:0]
+	count := 0
+	for _, msg := range state.recent {
+		if msg.at.Before(since) {
+			continue
+		}
+		recent = append(recent, msg)
+		if msg.key == key {
+			count++
+		}
+	}
+	if len(recent) >= maxRecentMessages {
+		recent = recent[1:]
+	}
+	state.recent = append(recent, recentMessage{key: key, at: now})
+
+	if count < fg.config.DuplicateLimit {
+		return &FloodVerdict{Allowed: true}
+	}
+	verdict := fg.violate(state, now)
+	verdict.Reason = "请勿重复发送相同内容"
+	return verdict
+}
+
+// violate records a violation and picks the response. The mute is applied
+// once, when the count reaches the threshold. Must be called with mu held.
+func (fg *FloodGuard) violate(state *floodState, now time.Time) *FloodVerdict {
+	since := now.Add(-time.Duration(fg.config.ViolationWindowSeconds) * time.Second)
+	violations := state.violations[:0]
+	for _, at := range state.violations {
+		if !at.Before(since) {
+			violations = append(violations, at)
+		}
+	}
+	state.violations = append(violations, now)
+
+	verdict := &FloodVerdict{
+		Action:     FloodActionDrop,
+		Violations: len(state.violations),
+	}
+	switch {
+	case fg.config.DisconnectAfter > 0 && verdict.Violations >= fg.config.DisconnectAfter:
+		verdict.Action = FloodActionDisconnect
+	case fg.config.MuteAfterViolations > 0 && verdict.Violations == fg.config.MuteAfterViolations:
+		verdict.Action = FloodActionMute
+		verdict.MuteMinutes = fg.config.MuteMinutes
+	}
+	return verdict
+}
+
+// state returns a user's state, creating it if needed. Must be called with mu held.
+func (fg *FloodGuard) state(userID primitive.ObjectID, now time.Time) *floodState {
+	state, ok := fg.users[userID]
+	if !ok {
+		state = &floodState{buckets: make(map[string]*tokenBucket)}
+		fg.users[userID] = state
+	}
+	state.lastSeen = now
+	return state
+}
+
+// limit returns the burst and refill rate of an event class
+func (fg *FloodGuard) limit(class string) (int, int) {
+	switch class {
+	case FloodClassMessage:
+		return fg.config.MessageBurst, fg.config.MessagePerMinute
+	case FloodClassTyping:
+		return fg.config.TypingBurst, fg.config.TypingPerMinute
+	default:
+		return fg.config.EventBurst, fg.config.EventPerMinute
+	}
+}
+
+// prune removes users idle for longer than floodIdleTimeout
+func (fg *FloodGuard) prune(now time.Time) {
+	fg.mu.Lock()
+	defer fg.mu.Unlock()
+
+	for userID, state := range fg.users {
+		if now.Sub(state.lastSeen) > floodIdleTimeout {
+			delete(fg.users, userID)
+		}
+	}
+}
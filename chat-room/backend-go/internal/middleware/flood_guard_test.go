@@ -0,0 +1,118 @@
+package middleware
+
+import (
+	"testing"
+	"time"
+
+	"chat-room-backend/internal/config"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestTokenBucketTake(t *testing.T) {
+	start := time.Now()
+	bucket := &tokenBucket{tokens: 3, burst: 3, rate: 1, last: start}
+
+	steps := []struct {
+		name       string
+		at         time.Duration // Since start
+		allowed    bool
+		retryAfter time.Duration
+	}{
+		{"burst 1", 0, true, 0},
+		{"burst 2", 0, true, 0},
+		{"burst 3", 0, true, 0},
+		{"burst exhausted", 0, false, time.Second},
+		{"half refilled", 500 * time.Millisecond, false, 500 * time.Millisecond},
+		{"refilled", time.Second, true, 0},
+		{"empty again", time.Second, false, time.Second},
+		{"refill capped at burst 1", 11 * time.Second, true, 0},
+		{"refill capped at burst 2", 11 * time.Second, true, 0},
+		{"refill capped at burst 3", 11 * time.Second, true, 0},
+		{"refill capped at burst", 11 * time.Second, false, time.Second},
+	}
+
+	for _, step := range steps {
+		allowed, retryAfter := bucket.take(start.Add(step.at))
+		if allowed != step.allowed || retryAfter != step.retryAfter {
+			t.Errorf("%s: take() = %v, %v, want %v, %v", step.name, allowed, retryAfter, step.allowed, step.retryAfter)
+		}
+	}
+}
+
+// newTestFloodGuard returns a FloodGuard without the pruning goroutine
+func newTestFloodGuard(cfg config.RateLimitConfig) *FloodGuard {
+	return &FloodGuard{config: cfg, users: make(map[primitive.ObjectID]*floodState)}
+}
+
+func TestFloodGuardAllow(t *testing.T) {
+	fg := newTestFloodGuard(config.RateLimitConfig{
+		MessageBurst:           2,
+		MessagePerMinute:       1,
+		TypingBurst:            1,
+		TypingPerMinute:        1,
+		ViolationWindowSeconds: 60,
+		MuteAfterViolations:    2,
+		MuteMinutes:            5,
+		DisconnectAfter:        4,
+	})
+	userID := primitive.NewObjectID()
+
+	steps := []struct {
+		class       string
+		allowed     bool
+		action      string
+		violations  int
+		muteMinutes int
+	}{
+		{FloodClassMessage, true, "", 0, 0},
+		{FloodClassMessage, true, "", 0, 0},
+		{FloodClassMessage, false, FloodActionDrop, 1, 0},
+		{FloodClassTyping, true, "", 0, 0}, // Classes have their own buckets
+		{FloodClassMessage, false, FloodActionMute, 2, 5},
+		{FloodClassTyping, false, FloodActionDrop, 3, 0}, // Violations are shared
+		{FloodClassMessage, false, FloodActionDisconnect, 4, 0},
+		{FloodClassEvent, true, "", 0, 0}, // No limit configured
+	}
+
+	for i, step := range steps {
+		verdict := fg.Allow(userID, step.class)
+		if verdict.Allowed != step.allowed || verdict.Action != step.action ||
+			verdict.Violations != step.violations || verdict.MuteMinutes != step.muteMinutes {
+			t.Errorf("step %d (%s): got %+v", i, step.class, verdict)
+		}
+		if !verdict.Allowed && verdict.RetryAfter <= 0 {
+			t.Errorf("step %d (%s): RetryAfter = %v", i, step.class, verdict.RetryAfter)
+		}
+	}
+
+	// Users do not share buckets
+	if verdict := fg.Allow(primitive.NewObjectID(), FloodClassMessage); !verdict.Allowed {
+		t.Errorf("another user was limited: %+v", verdict)
+	}
+}
+
+func TestFloodGuardCheckDuplicate(t *testing.T) {
+	fg := newTestFloodGuard(config.RateLimitConfig{
+		ViolationWindowSeconds: 60,
+		DuplicateLimit:         2,
+		DuplicateWindowSeconds: 30,
+	})
+	userID := primitive.NewObjectID()
+
+	steps := []struct {
+		text    string
+		allowed bool
+	}{
+		{"hello there", true},
+		{"HELLO  there", true},
+		{"something else", true},
+		{" hello there ", false},
+		{"hello there!", true},
+	}
+
+	for _, step := range steps {
+		if verdict := fg.CheckDuplicate(userID, step.text); verdict.Allowed != step.allowed {
+			t.Errorf("CheckDuplicate(%q): got %+v, want allowed=%v", step.text, verdict, step.allowed)
+		}
+	}
+}
@@ -0,0 +1,270 @@
+package middleware
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"chat-room-backend/internal/config"
+)
+
+// Login guard key kinds
+const (
+	LoginKeyUsername = "username"
+	LoginKeyIP       = "ip"
+)
+
+// maxTrackedUsernames caps the distinct usernames remembered per IP
+const maxTrackedUsernames = 100
+
+// LoginThrottle tells a client to wait before trying to log in again
+type LoginThrottle struct {
+	RetryAfter time.Duration
+	Locked     bool // Locked out rather than backing off
+}
+
+// LoginFailure describes what a failed login triggered. Attempts are
+// refused while locked, so a lock flag is set once per lockout and the
+// suspicious flag once per window.
+type LoginFailure struct {
+	UserFailures int
+	IPFailures   int
+	UserLocked   bool
+	IPLocked     bool
+	Suspicious   bool // The IP failed with SuspiciousUsers distinct usernames
+	Usernames    int  // Distinct usernames failed from the IP
+}
+
+// LoginLockout is a throttled username or IP returned to admins
+type LoginLockout struct {
+	Kind        string     `json:"kind"`
+	Key         string     `json:"key"`
+	Failures    int        `json:"failures"`
+	LastFailure time.Time  `json:"lastFailure"`
+	LockedUntil *time.Time `json:"lockedUntil,omitempty"`
+	RetryAfter  int        `json:"retryAfter"` // Seconds
+}
+
+// loginState is the failure history of a single username or IP
+type loginState struct {
+	failures    int
+	last        time.Time
+	lockedUntil time.Time
+	usernames   map[string]bool // IPs only
+}
+
+// LoginGuard tracks failed logins per username and per IP, makes clients
+// back off exponentially and locks out a username or IP that keeps failing.
+// It is checked before the password is compared, so throttled attempts
+// cost no bcrypt work.
+type LoginGuard struct {
+	config config.LoginGuardConfig
+
+	mu    sync.Mutex
+	users map[string]*loginState
+	ips   map[string]*loginState
+}
+
+// NewLoginGuard creates a new LoginGuard
+func NewLoginGuard(cfg config.LoginGuardConfig) *LoginGuard {
+	lg := &LoginGuard{
+		config: cfg,
+		users:  make(map[string]*loginState),
+		ips:    make(map[string]*loginState),
+	}
+
+	// Forget failures outside the window
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for range ticker.C {
+			lg.prune(time.Now())
+		}
+	}()
+
+	return lg
+}
+
+// LockoutMinutes returns how long a lockout lasts
+func (lg *LoginGuard) LockoutMinutes() int {
+	return lg.config.LockoutMinutes
+}
+
+// Check returns how long the client has to wait, or nil if it may try now
+func (lg *LoginGuard) Check(username, ip string) *LoginThrottle {
+	lg.mu.Lock()
+	defer lg.mu.Unlock()
+
+	now := time.Now()
+	throttle := lg.throttle(lg.users[normalizeLoginName(username)], lg.config.FreeAttempts, now)
+	if t := lg.throttle(lg.ips[ip], lg.config.IPFreeAttempts, now); t != nil &&
+		(throttle == nil || t.RetryAfter > throttle.RetryAfter) {
+		throttle = t
+	}
+	return throttle
+}
+
+// RecordFailure counts a failed login for the username and the IP
+func (lg *LoginGuard) RecordFailure(username, ip string) *LoginFailure {
+	lg.mu.Lock()
+	defer lg.mu.Unlock()
+
+	now := time.Now()
+	name := normalizeLoginName(username)
+	result := &LoginFailure{}
+
+	user := lg.fail(lg.users, name, now)
+	result.UserFailures = user.failures
+	if lg.config.UserLockoutAfter > 0 && user.failures >= lg.config.UserLockoutAfter {
+		user.lockedUntil = now.Add(time.Duration(lg.config.LockoutMinutes) * time.Minute)
+		result.UserLocked = true
+	}
+
+	if ip != "" {
+		addr := lg.fail(lg.ips, ip, now)
+		result.IPFailures = addr.failures
+		if lg.config.IPLockoutAfter > 0 && addr.failures >= lg.config.IPLockoutAfter {
+			addr.lockedUntil = now.Add(time.Duration(lg.config.LockoutMinutes) * time.Minute)
+			result.IPLocked = true
+		}
+
+		if addr.usernames == nil {
+			addr.usernames = make(map[string]bool)
+		}
+		if !addr.usernames[name] && len(addr.usernames) < maxTrackedUsernames {
+			addr.usernames[name] = true
+			result.Suspicious = lg.config.SuspiciousUsers > 0 && len(addr.usernames) == lg.config.SuspiciousUsers
+		}
+		result.Usernames = len(addr.usernames)
+	}
+
+	return result
+}
+
+// RecordSuccess clears a username's failures after a successful login.
+// The IP's failures are kept, so one valid account cannot reset them.
+func (lg *LoginGuard) RecordSuccess(username string) {
+	lg.mu.Lock()
+	defer lg.mu.Unlock()
+	delete(lg.users, normalizeLoginName(username))
+}
+
+// Clear removes a username's or IP's failures and lockout. It returns
+// false if nothing was tracked.
+func (lg *LoginGuard) Clear(kind, key string) bool {
+	lg.mu.Lock()
+	defer lg.mu.Unlock()
+
+	states := lg.ips
+	if kind == LoginKeyUsername {
+		states = lg.users
+		key = normalizeLoginName(key)
+	}
+	if _, ok := states[key]; !ok {
+		return false
+	}
+	delete(states, key)
+	return true
+}
+
+// Lockouts returns every username and IP that is currently backing off or locked out
+func (lg *LoginGuard) Lockouts() []*LoginLockout {
+	lg.mu.Lock()
+	defer lg.mu.Unlock()
+
+	now := time.Now()
+	lockouts := make([]*LoginLockout, 0)
+	for kind, states := range map[string]map[string]*loginState{LoginKeyUsername: lg.users, LoginKeyIP: lg.ips} {
+		free := lg.config.FreeAttempts
+		if kind == LoginKeyIP {
+			free = lg.config.IPFreeAttempts
+		}
+		for key, state := range states {
+			throttle := lg.throttle(state, free, now)
+			if throttle == nil {
+				continue
+			}
+			lockout := &LoginLockout{
+				Kind:        kind,
+				Key:         key,
+				Failures:    state.failures,
+				LastFailure: state.last,
+				RetryAfter:  int((throttle.RetryAfter + time.Second - 1) / time.Second),
+			}
+			if throttle.Locked {
+				lockedUntil := state.lockedUntil
+				lockout.LockedUntil = &lockedUntil
+			}
+			lockouts = append(lockouts, lockout)
+		}
+	}
+
+	sort.Slice(lockouts, func(i, j int) bool { return lockouts[i].LastFailure.After(lockouts[j].LastFailure) })
+	return lockouts
+}
+
+// fail counts a failure for a key, restarting the count once the
+// window has passed. Must be called with mu held.
+func (lg *LoginGuard) fail(states map[string]*loginState, key string, now time.Time) *loginState {
+	state, ok := states[key]
+	if !ok || lg.expired(state, now) {
+		state = &loginState{}
+		states[key] = state
+	}
+	state.failures++
+	state.last = now
+	return state
+}
+
+// throttle returns the wait imposed by a key's failures beyond the free
+// attempts. Must be called with mu held.
+func (lg *LoginGuard) throttle(state *loginState, free int, now time.Time) *LoginThrottle {
+	if state == nil || lg.expired(state, now) {
+		return nil
+	}
+	if now.Before(state.lockedUntil) {
+		return &LoginThrottle{RetryAfter: state.lockedUntil.Sub(now), Locked: true}
+	}
+
+	excess := state.failures - free
+	if excess <= 0 || lg.config.BackoffBaseSeconds <= 0 {
+		return nil
+	}
+	delay := time.Duration(lg.config.BackoffBaseSeconds) * time.Second
+	maxDelay := time.Duration(lg.config.BackoffMaxSeconds) * time.Second
+	for i := 1; i < excess && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	delay = min(delay, maxDelay)
+
+	if wait := state.last.Add(delay).Sub(now); wait > 0 {
+		return &LoginThrottle{RetryAfter: wait}
+	}
+	return nil
+}
+
+// expired reports whether a key's failures fell out of the window and its lockout ended
+func (lg *LoginGuard) expired(state *loginState, now time.Time) bool {
+	window := time.Duration(lg.config.WindowMinutes) * time.Minute
+	return now.Sub(state.last) > window && !now.Before(state.lockedUntil)
+}
+
+// prune removes keys whose failures expired
+func (lg *LoginGuard) prune(now time.Time) {
+	lg.mu.Lock()
+	defer lg.mu.Unlock()
+
+	for _, states := range []map[string]*loginState{lg.users, lg.ips} {
+		for key, state := range states {
+			if lg.expired(state, now) {
+				delete(states, key)
+			}
+		}
+	}
+}
+
+// normalizeLoginName makes differently cased spellings share one counter
+func normalizeLoginName(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
@@ -0,0 +1,176 @@
+package middleware
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"chat-room-backend/internal/config"
+)
+
+var testLoginGuardConfig = config.LoginGuardConfig{
+	WindowMinutes:      15,
+	FreeAttempts:       3,
+	IPFreeAttempts:     10,
+	BackoffBaseSeconds: 1,
+	BackoffMaxSeconds:  8,
+	UserLockoutAfter:   8,
+	IPLockoutAfter:     20,
+	LockoutMinutes:     30,
+	SuspiciousUsers:    3,
+}
+
+// newTestLoginGuard returns a LoginGuard without the pruning goroutine
+func newTestLoginGuard(cfg config.LoginGuardConfig) *LoginGuard {
+	return &LoginGuard{
+		config: cfg,
+		users:  make(map[string]*loginState),
+		ips:    make(map[string]*loginState),
+	}
+}
+
+func TestLoginGuardBackoff(t *testing.T) {
+	lg := newTestLoginGuard(testLoginGuardConfig)
+	now := time.Now()
+
+	// Failures beyond the free attempts double the wait up to the maximum
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{1, 0},
+		{3, 0},
+		{4, time.Second},
+		{5, 2 * time.Second},
+		{6, 4 * time.Second},
+		{7, 8 * time.Second},
+		{12, 8 * time.Second},
+	}
+
+	for _, tt := range tests {
+		state := &loginState{failures: tt.failures, last: now}
+		var got time.Duration
+		if throttle := lg.throttle(state, testLoginGuardConfig.FreeAttempts, now); throttle != nil {
+			if throttle.Locked {
+				t.Errorf("%d failures: locked", tt.failures)
+			}
+			got = throttle.RetryAfter
+		}
+		if got != tt.want {
+			t.Errorf("%d failures: wait %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+
+	// The wait runs from the last failure
+	state := &loginState{failures: 6, last: now.Add(-3 * time.Second)}
+	if throttle := lg.throttle(state, testLoginGuardConfig.FreeAttempts, now); throttle == nil || throttle.RetryAfter != time.Second {
+		t.Errorf("wait after 3s = %+v, want 1s", throttle)
+	}
+	state.last = now.Add(-5 * time.Second)
+	if throttle := lg.throttle(state, testLoginGuardConfig.FreeAttempts, now); throttle != nil {
+		t.Errorf("wait after the backoff = %+v, want none", throttle)
+	}
+}
+
+func TestLoginGuardUserLockout(t *testing.T) {
+	lg := newTestLoginGuard(testLoginGuardConfig)
+
+	for i := 1; i < testLoginGuardConfig.UserLockoutAfter; i++ {
+		if failure := lg.RecordFailure("alice", "203.0.113.1"); failure.UserLocked || failure.UserFailures != i {
+			t.Fatalf("failure %d: %+v", i, failure)
+		}
+	}
+	// Differently cased names share the counter
+	failure := lg.RecordFailure(" Alice ", "203.0.113.2")
+	if !failure.UserLocked || failure.UserFailures != testLoginGuardConfig.UserLockoutAfter {
+		t.Fatalf("lockout failure: %+v", failure)
+	}
+
+	// The lockout applies from any IP and outlasts the backoff
+	throttle := lg.Check("ALICE", "198.51.100.1")
+	if throttle == nil || !throttle.Locked || throttle.RetryAfter <= 29*time.Minute {
+		t.Fatalf("Check() = %+v, want a 30 minute lockout", throttle)
+	}
+	if throttle := lg.Check("bob", "198.51.100.1"); throttle != nil {
+		t.Errorf("other user throttled: %+v", throttle)
+	}
+
+	lockouts := lg.Lockouts()
+	if len(lockouts) != 1 || lockouts[0].Kind != LoginKeyUsername || lockouts[0].Key != "alice" || lockouts[0].LockedUntil == nil {
+		t.Errorf("Lockouts() = %+v", lockouts)
+	}
+
+	if !lg.Clear(LoginKeyUsername, "Alice") {
+		t.Fatal("Clear() found no lockout")
+	}
+	if throttle := lg.Check("alice", "198.51.100.1"); throttle != nil {
+		t.Errorf("Check() after Clear = %+v", throttle)
+	}
+	if lg.Clear(LoginKeyUsername, "alice") {
+		t.Error("Clear() of an untracked user returned true")
+	}
+}
+
+func TestLoginGuardIP(t *testing.T) {
+	lg := newTestLoginGuard(testLoginGuardConfig)
+	ip := "203.0.113.1"
+
+	var suspicious int
+	for i := 1; i <= testLoginGuardConfig.IPLockoutAfter; i++ {
+		failure := lg.RecordFailure(fmt.Sprintf("user%d", i%5), ip)
+		if failure.Suspicious {
+			suspicious++
+			if failure.Usernames != testLoginGuardConfig.SuspiciousUsers {
+				t.Errorf("suspicious after %d usernames", failure.Usernames)
+			}
+		}
+		if failure.IPLocked != (i == testLoginGuardConfig.IPLockoutAfter) {
+			t.Errorf("failure %d: IPLocked = %v", i, failure.IPLocked)
+		}
+	}
+	if suspicious != 1 {
+		t.Errorf("flagged suspicious %d times, want once", suspicious)
+	}
+
+	// A successful login clears the username but not the IP
+	lg.RecordSuccess("user1")
+	if throttle := lg.Check("user1", ip); throttle == nil || !throttle.Locked {
+		t.Errorf("Check() after success = %+v, want the IP still locked", throttle)
+	}
+	if throttle := lg.Check("user1", "198.51.100.1"); throttle != nil {
+		t.Errorf("Check() from another IP = %+v", throttle)
+	}
+}
+
+func TestLoginGuardWindow(t *testing.T) {
+	lg := newTestLoginGuard(testLoginGuardConfig)
+	now := time.Now()
+	window := time.Duration(testLoginGuardConfig.WindowMinutes) * time.Minute
+
+	lg.users["old"] = &loginState{failures: 5, last: now.Add(-window - time.Second)}
+	lg.users["recent"] = &loginState{failures: 5, last: now.Add(-time.Minute)}
+	lg.users["locked"] = &loginState{failures: 8, last: now.Add(-window - time.Second), lockedUntil: now.Add(time.Minute)}
+
+	// Old failures no longer count
+	state := lg.fail(lg.users, "old", now)
+	if state.failures != 1 {
+		t.Errorf("failures after the window = %d, want 1", state.failures)
+	}
+	lg.users["old"].last = now.Add(-window - time.Second)
+
+	lg.prune(now)
+	if _, ok := lg.users["old"]; ok {
+		t.Error("expired failures were not pruned")
+	}
+	if _, ok := lg.users["recent"]; !ok {
+		t.Error("recent failures were pruned")
+	}
+	// A lockout is kept until it ends, even past the window
+	if _, ok := lg.users["locked"]; !ok {
+		t.Error("active lockout was pruned")
+	}
+	lg.prune(now.Add(2 * time.Minute))
+	if _, ok := lg.users["locked"]; ok {
+		t.Error("ended lockout was not pruned")
+	}
+}
@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"context"
+	"sync"
+
+	"chat-room-backend/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MembershipCache keeps each user's joined channels in memory so that
+// per-event authorization does not need a database round trip
+type MembershipCache struct {
+	channels map[primitive.ObjectID]map[primitive.ObjectID]bool
+	versions map[primitive.ObjectID]uint64
+	mu       sync.RWMutex
+	repo     *repository.ChannelMemberRepository
+}
+
+// NewMembershipCache creates a new MembershipCache
+func NewMembershipCache(repo *repository.ChannelMemberRepository) *MembershipCache {
+	return &MembershipCache{
+		channels: make(map[primitive.ObjectID]map[primitive.ObjectID]bool),
+		versions: make(map[primitive.ObjectID]uint64),
+		repo:     repo,
+	}
+}
+
+// IsMember checks if a user is a member of a channel.
+// The user's memberships are loaded from the database on first use only.
+func (mc *MembershipCache) IsMember(ctx context.Context, userID, channelID primitive.ObjectID) (bool, error) {
+	mc.mu.RLock()
+	channels, loaded := mc.channels[userID]
+	isMember := channels[channelID]
+	version := mc.versions[userID]
+	mc.mu.RUnlock()
+
+	if loaded {
+		return isMember, nil
+	}
+
+	members, err := mc.repo.FindByUserID(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+
+	channels = make(map[primitive.ObjectID]bool, len(members))
+	for _, member := range members {
+		channels[member.ChannelID] = true
+	}
+
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	// Only keep the loaded set if no join/leave happened while querying,
+	// otherwise the next lookup reloads it
+	if mc.versions[userID] == version {
+		mc.channels[userID] = channels
+	}
+
+	return channels[channelID], nil
+}
+
+// Add records that a user joined a channel
+func (mc *MembershipCache) Add(userID, channelID primitive.ObjectID) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	mc.versions[userID]++
+	if channels, ok := mc.channels[userID]; ok {
+		channels[channelID] = true
+	}
+}
+
+// Remove records that a user left or was removed from a channel
+func (mc *MembershipCache) Remove(userID, channelID primitive.ObjectID) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	mc.versions[userID]++
+	if channels, ok := mc.channels[userID]; ok {
+		delete(channels, channelID)
+	}
+}
+
+// Invalidate drops a user's cached memberships so they are reloaded on next use
+func (mc *MembershipCache) Invalidate(userID primitive.ObjectID) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	mc.versions[userID]++
+	delete(mc.channels, userID)
+}
@@ -2,97 +2,298 @@ package middleware
 
 import (
 	"context"
+	"log"
+	"sync"
 	"time"
 
 	"chat-room-backend/internal/models"
 	"chat-room-backend/internal/repository"
-	"chat-room-backend/internal/utils"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // MuteCheckResult represents the result of a mute check
 type MuteCheckResult struct {
-	IsMuted  bool
-	Reason   string
-	IsGlobal bool
+	IsMuted    bool
+	Reason     string
+	IsGlobal   bool
+	MutedUntil *time.Time // nil for permanent mutes
 }
 
-// MuteChecker handles mute status checking
+// userMuteState is the cached mute state of a single muted user
+type userMuteState struct {
+	reason     string
+	mutedUntil *time.Time
+	timer      *time.Timer
+}
+
+// muteUserStore is the user data MuteChecker loads mute state from
+type muteUserStore interface {
+	FindMuted(ctx context.Context) ([]*models.User, error)
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.User, error)
+	UnmuteExpired(ctx context.Context, userID primitive.ObjectID) (bool, error)
+}
+
+// globalMuteStore holds the global mute status
+type globalMuteStore interface {
+	GetGlobalMuteStatus(ctx context.Context) (*models.GlobalMuteStatus, error)
+}
+
+// MuteChecker handles mute status checking.
+// Global and per-user mute state is kept in memory and refreshed whenever
+// an admin changes it, so checking a message never touches the database.
 type MuteChecker struct {
-	userRepo  *repository.UserRepository
-	adminRepo *repository.AdminRepository
-	adminHelper *utils.AdminHelper
+	userRepo  muteUserStore
+	adminRepo globalMuteStore
+
+	mu           sync.RWMutex
+	globalMuted  bool
+	globalReason string
+	globalUntil  *time.Time // Set when the global mute turns off by itself
+	users        map[primitive.ObjectID]*userMuteState
+
+	// onExpire is called after a timed mute has expired
+	onExpire func(userID primitive.ObjectID)
 }
 
 // NewMuteChecker creates a new MuteChecker
-func NewMuteChecker(userRepo *repository.UserRepository, adminRepo *repository.AdminRepository, adminHelper *utils.AdminHelper) *MuteChecker {
-	return &MuteChecker{
+func NewMuteChecker(userRepo *repository.UserRepository, adminRepo *repository.AdminRepository) *MuteChecker {
+	return newMuteChecker(userRepo, adminRepo)
+}
+
+// newMuteChecker creates a MuteChecker backed by any store
+func newMuteChecker(userRepo muteUserStore, adminRepo globalMuteStore) *MuteChecker {
+	mc := &MuteChecker{
 		userRepo:  userRepo,
 		adminRepo: adminRepo,
-		adminHelper: adminHelper,
+		users:     make(map[primitive.ObjectID]*userMuteState),
 	}
+
+	// Load initial cache
+	if err := mc.Reload(); err != nil {
+		log.Printf("⚠️  Warning: Failed to load mute cache: %v", err)
+	}
+
+	return mc
 }
 
-// CheckMuteStatus checks if a user is muted (global or individual)
-func (mc *MuteChecker) CheckMuteStatus(ctx context.Context, userID primitive.ObjectID, username string) (*MuteCheckResult, error) {
-	// Admins are never muted
-	if mc.adminHelper.IsAdmin(username) {
-		return &MuteCheckResult{IsMuted: false}, nil
+// Reload reloads global and per-user mute state from database
+func (mc *MuteChecker) Reload() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := mc.RefreshGlobal(ctx); err != nil {
+		return err
 	}
 
-	// Check global mute
-	globalMute, err := mc.adminRepo.GetGlobalMuteStatus(ctx)
+	users, err := mc.userRepo.FindMuted(ctx)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	if globalMute.IsEnabled {
-		return &MuteCheckResult{
-			IsMuted:  true,
-			Reason:   globalMute.Reason,
-			IsGlobal: true,
-		}, nil
+	mc.mu.Lock()
+	for userID, state := range mc.users {
+		if state.timer != nil {
+			state.timer.Stop()
+		}
+		delete(mc.users, userID)
 	}
+	mc.mu.Unlock()
 
-	// Check individual mute
+	for _, user := range users {
+		mc.setUser(ctx, user)
+	}
+
+	log.Printf("✅ Loaded mute cache (%d muted user(s))", len(users))
+	return nil
+}
+
+// RefreshGlobal reloads the global mute status from database
+func (mc *MuteChecker) RefreshGlobal(ctx context.Context) error {
+	status, err := mc.adminRepo.GetGlobalMuteStatus(ctx)
+	if err != nil {
+		return err
+	}
+
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	mc.globalMuted = status.IsEnabled
+	mc.globalReason = status.Reason
+	mc.globalUntil = status.ExpiresAt
+	return nil
+}
+
+// RefreshUser reloads a single user's mute state from database
+func (mc *MuteChecker) RefreshUser(ctx context.Context, userID primitive.ObjectID) error {
 	user, err := mc.userRepo.FindByID(ctx, userID)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	if user == nil {
-		return &MuteCheckResult{IsMuted: false}, nil
+		mc.clearUser(userID)
+		return nil
 	}
 
+	mc.setUser(ctx, user)
+	return nil
+}
+
+// OnExpire registers a callback invoked after a timed mute expires
+func (mc *MuteChecker) OnExpire(fn func(userID primitive.ObjectID)) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	mc.onExpire = fn
+}
+
+// setUser caches a user's mute state and schedules its expiry
+func (mc *MuteChecker) setUser(ctx context.Context, user *models.User) {
 	if !user.IsMuted {
-		return &MuteCheckResult{IsMuted: false}, nil
+		mc.clearUser(user.ID)
+		return
 	}
 
-	// Check if mute has expired
-	if user.MutedUntil != nil && user.MutedUntil.Before(time.Now()) {
-		// Mute expired, unmute the user
-		if err := mc.userRepo.Unmute(ctx, userID); err != nil {
-			return nil, err
+	// Mute already expired while nobody was watching
+	if user.MutedUntil != nil && !user.MutedUntil.After(time.Now()) {
+		mc.clearUser(user.ID)
+		if _, err := mc.userRepo.UnmuteExpired(ctx, user.ID); err != nil {
+			log.Printf("❌ Failed to unmute expired user %s: %v", user.ID.Hex(), err)
 		}
+		return
+	}
+
+	state := &userMuteState{
+		reason:     user.MutedReason,
+		mutedUntil: user.MutedUntil,
+	}
+
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	if old, ok := mc.users[user.ID]; ok && old.timer != nil {
+		old.timer.Stop()
+	}
+	mc.users[user.ID] = state
+
+	// Expiry is driven by a timer instead of waiting for the next message
+	if user.MutedUntil != nil {
+		userID := user.ID
+		state.timer = time.AfterFunc(time.Until(*user.MutedUntil), func() {
+			mc.expire(userID, state)
+		})
+	}
+}
+
+// clearUser removes a user from the mute cache
+func (mc *MuteChecker) clearUser(userID primitive.ObjectID) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	if state, ok := mc.users[userID]; ok {
+		if state.timer != nil {
+			state.timer.Stop()
+		}
+		delete(mc.users, userID)
+	}
+}
+
+// expire lifts a timed mute once its MutedUntil has passed
+func (mc *MuteChecker) expire(userID primitive.ObjectID, state *userMuteState) {
+	mc.mu.Lock()
+	// The mute may have been replaced or lifted in the meantime
+	if mc.users[userID] != state {
+		mc.mu.Unlock()
+		return
+	}
+	delete(mc.users, userID)
+	onExpire := mc.onExpire
+	mc.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// An admin may have renewed the mute after the timer fired; the
+	// database only changes if the stored mute has really ended
+	unmuted, err := mc.userRepo.UnmuteExpired(ctx, userID)
+	if err != nil {
+		log.Printf("❌ Failed to unmute expired user %s: %v", userID.Hex(), err)
+	} else if !unmuted {
+		if err := mc.RefreshUser(ctx, userID); err != nil {
+			log.Printf("❌ Failed to reload mute of user %s: %v", userID.Hex(), err)
+		}
+		return
+	}
+
+	log.Printf("🔊 Mute expired for user %s", userID.Hex())
+
+	if onExpire != nil {
+		onExpire(userID)
+	}
+}
+
+// CheckMuteStatus checks if a user is muted (global or individual).
+// exempt is whether the caller may use PermModerationExempt, which
+// depends on the session as well as the user.
+func (mc *MuteChecker) CheckMuteStatus(ctx context.Context, userID primitive.ObjectID, exempt bool) (*MuteCheckResult, error) {
+	// Users exempt from moderation are never muted
+	if exempt {
+		return &MuteCheckResult{IsMuted: false}, nil
+	}
+
+	mc.mu.RLock()
+	defer mc.mu.RUnlock()
+
+	// Check global mute. An expired one no longer applies even before
+	// the scheduler turns it off.
+	if mc.globalMuted && (mc.globalUntil == nil || time.Now().Before(*mc.globalUntil)) {
+		return &MuteCheckResult{
+			IsMuted:    true,
+			Reason:     mc.globalReason,
+			IsGlobal:   true,
+			MutedUntil: mc.globalUntil,
+		}, nil
+	}
+
+	// Check individual mute
+	state, ok := mc.users[userID]
+	if !ok {
 		return &MuteCheckResult{IsMuted: false}, nil
 	}
 
 	// User is muted
-	reason := user.MutedReason
+	reason := state.reason
 	if reason == "" {
 		reason = "您已被禁言"
 	}
 
 	return &MuteCheckResult{
-		IsMuted:  true,
-		Reason:   reason,
-		IsGlobal: false,
+		IsMuted:    true,
+		Reason:     reason,
+		IsGlobal:   false,
+		MutedUntil: state.mutedUntil,
 	}, nil
 }
 
+// GetUserMute returns a user's individual mute state, ignoring global mute
+func (mc *MuteChecker) GetUserMute(userID primitive.ObjectID) *MuteCheckResult {
+	mc.mu.RLock()
+	defer mc.mu.RUnlock()
+
+	state, ok := mc.users[userID]
+	if !ok {
+		return &MuteCheckResult{IsMuted: false}
+	}
+
+	return &MuteCheckResult{
+		IsMuted:    true,
+		Reason:     state.reason,
+		MutedUntil: state.mutedUntil,
+	}
+}
+
 // IsMuted is a convenience method that returns only the muted status
-func (mc *MuteChecker) IsMuted(ctx context.Context, user *models.User, username string) bool {
-	result, err := mc.CheckMuteStatus(ctx, user.ID, username)
+func (mc *MuteChecker) IsMuted(ctx context.Context, user *models.User, exempt bool) bool {
+	result, err := mc.CheckMuteStatus(ctx, user.ID, exempt)
 	if err != nil {
 		return false
 	}
@@ -0,0 +1,190 @@
+package middleware
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"chat-room-backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// countingMuteStore is an in-memory mute store that counts every call,
+// standing in for the database
+type countingMuteStore struct {
+	calls  atomic.Int64
+	muted  []*models.User
+	global *models.GlobalMuteStatus
+}
+
+func (s *countingMuteStore) FindMuted(ctx context.Context) ([]*models.User, error) {
+	s.calls.Add(1)
+	return s.muted, nil
+}
+
+func (s *countingMuteStore) FindByID(ctx context.Context, id primitive.ObjectID) (*models.User, error) {
+	s.calls.Add(1)
+	for _, user := range s.muted {
+		if user.ID == id {
+			return user, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *countingMuteStore) UnmuteExpired(ctx context.Context, userID primitive.ObjectID) (bool, error) {
+	s.calls.Add(1)
+	for _, user := range s.muted {
+		if user.ID == userID && user.IsMuted && user.MutedUntil != nil && !user.MutedUntil.After(time.Now()) {
+			user.IsMuted = false
+			user.MutedUntil = nil
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *countingMuteStore) GetGlobalMuteStatus(ctx context.Context) (*models.GlobalMuteStatus, error) {
+	s.calls.Add(1)
+	return s.global, nil
+}
+
+// newTestMuteChecker returns a warmed-up MuteChecker with one timed mute
+// and one permanent mute, and the store it loaded from
+func newTestMuteChecker(tb testing.TB) (*MuteChecker, *countingMuteStore, []primitive.ObjectID) {
+	tb.Helper()
+
+	until := time.Now().Add(time.Hour)
+	timed := &models.User{ID: primitive.NewObjectID(), IsMuted: true, MutedUntil: &until, MutedReason: "spam"}
+	permanent := &models.User{ID: primitive.NewObjectID(), IsMuted: true}
+
+	store := &countingMuteStore{
+		muted:  []*models.User{timed, permanent},
+		global: &models.GlobalMuteStatus{},
+	}
+
+	mc := newMuteChecker(store, store)
+	if store.calls.Load() == 0 {
+		tb.Fatal("expected the cache to load from the store")
+	}
+
+	users := []primitive.ObjectID{timed.ID, permanent.ID, primitive.NewObjectID()}
+	return mc, store, users
+}
+
+func TestCheckMuteStatus(t *testing.T) {
+	mc, store, users := newTestMuteChecker(t)
+	store.calls.Store(0)
+
+	tests := []struct {
+		name   string
+		userID primitive.ObjectID
+		exempt bool
+		muted  bool
+		reason string
+	}{
+		{"timed mute", users[0], false, true, "spam"},
+		{"permanent mute uses the default reason", users[1], false, true, "您已被禁言"},
+		{"moderation exempt", users[0], true, false, ""},
+		{"not muted", users[2], false, false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := mc.CheckMuteStatus(context.Background(), tt.userID, tt.exempt)
+			if err != nil {
+				t.Fatalf("CheckMuteStatus: %v", err)
+			}
+			if result.IsMuted != tt.muted || result.Reason != tt.reason {
+				t.Errorf("got muted=%v reason=%q, want muted=%v reason=%q", result.IsMuted, result.Reason, tt.muted, tt.reason)
+			}
+		})
+	}
+
+	if calls := store.calls.Load(); calls != 0 {
+		t.Errorf("CheckMuteStatus hit the store %d time(s) after warm-up", calls)
+	}
+}
+
+func TestCheckMuteStatusGlobal(t *testing.T) {
+	mc, store, users := newTestMuteChecker(t)
+	store.global = &models.GlobalMuteStatus{IsEnabled: true, Reason: "maintenance"}
+	if err := mc.RefreshGlobal(context.Background()); err != nil {
+		t.Fatalf("RefreshGlobal: %v", err)
+	}
+	store.calls.Store(0)
+
+	result, _ := mc.CheckMuteStatus(context.Background(), users[2], false)
+	if !result.IsMuted || !result.IsGlobal || result.Reason != "maintenance" {
+		t.Errorf("got %+v, want a global mute", result)
+	}
+	if result, _ := mc.CheckMuteStatus(context.Background(), users[2], true); result.IsMuted {
+		t.Error("moderation exempt user was held back by the global mute")
+	}
+	if calls := store.calls.Load(); calls != 0 {
+		t.Errorf("CheckMuteStatus hit the store %d time(s) after warm-up", calls)
+	}
+}
+
+// BenchmarkCheckMuteStatus measures the per-message mute check, which must
+// be answered from memory
+func BenchmarkCheckMuteStatus(b *testing.B) {
+	mc, store, users := newTestMuteChecker(b)
+	ctx := context.Background()
+	store.calls.Store(0)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := mc.CheckMuteStatus(ctx, users[i%len(users)], false); err != nil {
+			b.Fatalf("CheckMuteStatus: %v", err)
+		}
+	}
+	b.StopTimer()
+
+	if calls := store.calls.Load(); calls != 0 {
+		b.Fatalf("CheckMuteStatus hit the store %d time(s) after warm-up", calls)
+	}
+}
+
+func TestMuteExpiry(t *testing.T) {
+	tests := []struct {
+		name    string
+		renewed bool
+	}{
+		{"expired mute is lifted", false},
+		{"mute renewed after the timer fired is kept", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mc, store, users := newTestMuteChecker(t)
+			user := store.muted[0]
+			var expired []primitive.ObjectID
+			mc.OnExpire(func(userID primitive.ObjectID) {
+				expired = append(expired, userID)
+			})
+
+			// The timer fires for the cached mute while the stored one
+			// has either ended or been renewed by an admin
+			mc.mu.RLock()
+			state := mc.users[user.ID]
+			mc.mu.RUnlock()
+			until := time.Now().Add(-time.Second)
+			if tt.renewed {
+				until = time.Now().Add(time.Hour)
+			}
+			user.MutedUntil = &until
+
+			mc.expire(user.ID, state)
+
+			result, _ := mc.CheckMuteStatus(context.Background(), users[0], false)
+			if result.IsMuted != tt.renewed || user.IsMuted != tt.renewed {
+				t.Errorf("cached muted=%v, stored muted=%v, want %v", result.IsMuted, user.IsMuted, tt.renewed)
+			}
+			if notified := len(expired) == 1; notified == tt.renewed {
+				t.Errorf("expiry callbacks = %d", len(expired))
+			}
+		})
+	}
+}
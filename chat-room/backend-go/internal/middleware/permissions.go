@@ -0,0 +1,204 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"chat-room-backend/internal/models"
+	"chat-room-backend/internal/repository"
+	"chat-room-backend/internal/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PermissionChecker keeps the roles and the users holding a role other
+// than the default one in memory, so permission checks on every request
+// and message do not need a database round trip. Users are keyed by ID,
+// so renaming a user does not change what they may do.
+type PermissionChecker struct {
+	roleRepo *repository.RoleRepository
+	userRepo *repository.UserRepository
+
+	mu        sync.RWMutex
+	roles     map[string]map[string]bool    // Role name -> permissions
+	userRoles map[primitive.ObjectID]string // User ID -> role name
+}
+
+// NewPermissionChecker creates a new PermissionChecker
+func NewPermissionChecker(roleRepo *repository.RoleRepository, userRepo *repository.UserRepository) *PermissionChecker {
+	pc := &PermissionChecker{
+		roleRepo:  roleRepo,
+		userRepo:  userRepo,
+		roles:     make(map[string]map[string]bool),
+		userRoles: make(map[primitive.ObjectID]string),
+	}
+
+	// Load initial cache
+	if err := pc.Reload(); err != nil {
+		log.Printf("⚠️  Warning: Failed to load roles: %v", err)
+	}
+
+	return pc
+}
+
+// Reload loads every role and every user with a role from the database
+func (pc *PermissionChecker) Reload() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	roles, err := pc.roleRepo.FindAll(ctx)
+	if err != nil {
+		return err
+	}
+	users, err := pc.userRepo.FindWithRoles(ctx)
+	if err != nil {
+		return err
+	}
+
+	roleMap := make(map[string]map[string]bool, len(roles))
+	for _, role := range roles {
+		roleMap[role.Name] = permissionSet(role.Permissions)
+	}
+	userRoles := make(map[primitive.ObjectID]string, len(users))
+	for _, user := range users {
+		userRoles[user.ID] = user.Role
+	}
+
+	pc.mu.Lock()
+	pc.roles = roleMap
+	pc.userRoles = userRoles
+	pc.mu.Unlock()
+
+	log.Printf("🛡️  Loaded %d role(s), %d user(s) with a role", len(roleMap), len(userRoles))
+	return nil
+}
+
+// Has reports whether a user holds a permission
+func (pc *PermissionChecker) Has(userID primitive.ObjectID, permission string) bool {
+	pc.mu.RLock()
+	defer pc.mu.RUnlock()
+
+	role, ok := pc.userRoles[userID]
+	if !ok {
+		return false
+	}
+	return pc.roles[role][permission]
+}
+
+// RoleOf returns a user's role name
+func (pc *PermissionChecker) RoleOf(userID primitive.ObjectID) string {
+	pc.mu.RLock()
+	defer pc.mu.RUnlock()
+
+	if role, ok := pc.userRoles[userID]; ok {
+		return role
+	}
+	return models.RoleUser
+}
+
+// Permissions returns the permissions a user holds, in the order of
+// models.AllPermissions
+func (pc *PermissionChecker) Permissions(userID primitive.ObjectID) []string {
+	pc.mu.RLock()
+	defer pc.mu.RUnlock()
+
+	permissions := []string{}
+	role, ok := pc.userRoles[userID]
+	if !ok {
+		return permissions
+	}
+	for _, p := range models.AllPermissions {
+		if pc.roles[role][p.Name] {
+			permissions = append(permissions, p.Name)
+		}
+	}
+	return permissions
+}
+
+// SetUserRole updates the cache after a user's role changed
+func (pc *PermissionChecker) SetUserRole(userID primitive.ObjectID, role string) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	if role == "" || role == models.RoleUser {
+		delete(pc.userRoles, userID)
+	} else {
+		pc.userRoles[userID] = role
+	}
+}
+
+// SetRole updates the cache after a role was created or edited
+func (pc *PermissionChecker) SetRole(role *models.Role) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	pc.roles[role.Name] = permissionSet(role.Permissions)
+}
+
+// RemoveRole updates the cache after a role was deleted
+func (pc *PermissionChecker) RemoveRole(name string) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	delete(pc.roles, name)
+}
+
+// permissionSet turns a permission list into a set
+func permissionSet(permissions []string) map[string]bool {
+	set := make(map[string]bool, len(permissions))
+	for _, p := range permissions {
+		set[p] = true
+	}
+	return set
+}
+
+// RequirePermission checks that the user's role grants a permission.
+// Anyone holding a permission must log in with two-factor authentication,
+// so a session that was not verified with a second factor is rejected
+// until the user enrolls and logs in again.
+func RequirePermission(checker *PermissionChecker, permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Get claims from context (set by AuthMiddleware)
+		claims, exists := GetTokenClaims(c)
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "未认证"})
+			c.Abort()
+			return
+		}
+
+		userID, err := utils.ParseUserID(claims.UserID)
+		if err != nil || !checker.Has(userID, permission) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":      "权限不足",
+				"permission": permission,
+			})
+			c.Abort()
+			return
+		}
+
+		if !claims.TwoFactor {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":             "管理员必须启用两步验证并使用验证码登录",
+				"twoFactorRequired": true,
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// HasVerifiedPermission reports whether the request comes from a user
+// holding a permission whose session was verified with a second factor
+func HasVerifiedPermission(c *gin.Context, checker *PermissionChecker, permission string) bool {
+	claims, ok := GetTokenClaims(c)
+	if !ok || !claims.TwoFactor {
+		return false
+	}
+	userID, err := utils.ParseUserID(claims.UserID)
+	return err == nil && checker.Has(userID, permission)
+}
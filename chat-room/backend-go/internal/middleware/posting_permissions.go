@@ -0,0 +1,131 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"chat-room-backend/internal/models"
+	"chat-room-backend/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PostingPermissions keeps the restricted channels and the members allowed
+// to post in them in memory, so every message ingest path can check who may
+// post without a database round trip
+type PostingPermissions struct {
+	channelRepo *repository.ChannelRepository
+	memberRepo  *repository.ChannelMemberRepository
+
+	mu         sync.RWMutex
+	restricted map[string]map[primitive.ObjectID]bool // Channel ID -> users allowed to post
+}
+
+// NewPostingPermissions creates a new PostingPermissions
+func NewPostingPermissions(channelRepo *repository.ChannelRepository, memberRepo *repository.ChannelMemberRepository) *PostingPermissions {
+	pp := &PostingPermissions{
+		channelRepo: channelRepo,
+		memberRepo:  memberRepo,
+		restricted:  make(map[string]map[primitive.ObjectID]bool),
+	}
+
+	// Load initial cache
+	if err := pp.Reload(); err != nil {
+		log.Printf("⚠️  Warning: Failed to load posting permissions: %v", err)
+	}
+
+	return pp
+}
+
+// Reload loads every restricted channel and its posters
+func (pp *PostingPermissions) Reload() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	channels, err := pp.channelRepo.FindByPostingPolicy(ctx, models.PostingPolicyRestricted)
+	if err != nil {
+		return err
+	}
+
+	restricted := make(map[string]map[primitive.ObjectID]bool, len(channels))
+	for _, channel := range channels {
+		posters, err := pp.loadPosters(ctx, channel.ID)
+		if err != nil {
+			return err
+		}
+		restricted[channel.ID.Hex()] = posters
+	}
+
+	pp.mu.Lock()
+	pp.restricted = restricted
+	pp.mu.Unlock()
+
+	log.Printf("📢 Loaded %d restricted channel(s)", len(restricted))
+	return nil
+}
+
+// RefreshChannel reloads a channel's policy and posters after a change
+func (pp *PostingPermissions) RefreshChannel(ctx context.Context, channelID primitive.ObjectID) error {
+	channel, err := pp.channelRepo.FindByID(ctx, channelID)
+	if err != nil {
+		return err
+	}
+
+	if channel == nil || channel.GetPostingPolicy() != models.PostingPolicyRestricted {
+		pp.mu.Lock()
+		delete(pp.restricted, channelID.Hex())
+		pp.mu.Unlock()
+		return nil
+	}
+
+	posters, err := pp.loadPosters(ctx, channelID)
+	if err != nil {
+		return err
+	}
+
+	pp.mu.Lock()
+	pp.restricted[channelID.Hex()] = posters
+	pp.mu.Unlock()
+	return nil
+}
+
+// RemoveUser forgets a user's posting right after they left a channel
+func (pp *PostingPermissions) RemoveUser(userID primitive.ObjectID, channelID string) {
+	pp.mu.Lock()
+	defer pp.mu.Unlock()
+
+	if posters, ok := pp.restricted[channelID]; ok {
+		delete(posters, userID)
+	}
+}
+
+// CanPost reports whether a user may post in a channel. Admins always can.
+func (pp *PostingPermissions) CanPost(userID primitive.ObjectID, channelID string, isAdmin bool) bool {
+	if isAdmin {
+		return true
+	}
+
+	pp.mu.RLock()
+	defer pp.mu.RUnlock()
+
+	posters, restricted := pp.restricted[channelID]
+	return !restricted || posters[userID]
+}
+
+// loadPosters returns the members allowed to post in a restricted channel
+func (pp *PostingPermissions) loadPosters(ctx context.Context, channelID primitive.ObjectID) (map[primitive.ObjectID]bool, error) {
+	members, err := pp.memberRepo.FindByChannelAndRoles(ctx, channelID, []string{
+		models.ChannelRoleModerator,
+		models.ChannelRolePoster,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	posters := make(map[primitive.ObjectID]bool, len(members))
+	for _, member := range members {
+		posters[member.UserID] = true
+	}
+	return posters, nil
+}
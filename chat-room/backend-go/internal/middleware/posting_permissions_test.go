@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestPostingPermissions(t *testing.T) {
+	poster := primitive.NewObjectID()
+	member := primitive.NewObjectID()
+	pp := &PostingPermissions{
+		restricted: map[string]map[primitive.ObjectID]bool{
+			"announcements": {poster: true},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		userID    primitive.ObjectID
+		channelID string
+		isAdmin   bool
+		want      bool
+	}{
+		{"open channel", member, "general", false, true},
+		{"designated poster", poster, "announcements", false, true},
+		{"other member", member, "announcements", false, false},
+		{"admin", member, "announcements", true, true},
+		{"poster elsewhere", poster, "general", false, true},
+	}
+
+	for _, tt := range tests {
+		if got := pp.CanPost(tt.userID, tt.channelID, tt.isAdmin); got != tt.want {
+			t.Errorf("%s: CanPost() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	// A poster who leaves the channel loses the right at once
+	pp.RemoveUser(poster, "announcements")
+	if pp.CanPost(poster, "announcements", false) {
+		t.Error("poster can still post after leaving")
+	}
+	// Leaving an open channel restricts nothing
+	pp.RemoveUser(member, "general")
+	if !pp.CanPost(member, "general", false) {
+		t.Error("open channel became restricted")
+	}
+}
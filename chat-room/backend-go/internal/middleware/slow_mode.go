@@ -0,0 +1,141 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"chat-room-backend/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SlowModeCache enforces per-channel slow mode. Channel intervals and each
+// user's last message time are kept in memory, so sending a message never
+// touches the database for it.
+type SlowModeCache struct {
+	repo *repository.ChannelRepository
+
+	mu        sync.Mutex
+	intervals map[string]time.Duration                    // Channel ID -> interval
+	lastSent  map[string]map[primitive.ObjectID]time.Time // Channel ID -> user -> last message
+}
+
+// NewSlowModeCache creates a new SlowModeCache
+func NewSlowModeCache(repo *repository.ChannelRepository) *SlowModeCache {
+	sm := &SlowModeCache{
+		repo:      repo,
+		intervals: make(map[string]time.Duration),
+		lastSent:  make(map[string]map[primitive.ObjectID]time.Time),
+	}
+
+	// Load initial cache
+	if err := sm.Reload(); err != nil {
+		log.Printf("⚠️  Warning: Failed to load slow mode cache: %v", err)
+	}
+
+	// Forget message times older than the channel interval
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for range ticker.C {
+			sm.prune(time.Now())
+		}
+	}()
+
+	return sm
+}
+
+// Reload loads the slow mode interval of every channel
+func (sm *SlowModeCache) Reload() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	channels, err := sm.repo.FindAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	intervals := make(map[string]time.Duration)
+	for _, channel := range channels {
+		if channel.SlowModeSeconds > 0 {
+			intervals[channel.ID.Hex()] = time.Duration(channel.SlowModeSeconds) * time.Second
+		}
+	}
+
+	sm.mu.Lock()
+	sm.intervals = intervals
+	sm.mu.Unlock()
+
+	log.Printf("🐢 Loaded slow mode for %d channel(s)", len(intervals))
+	return nil
+}
+
+// Set updates a channel's interval after it was changed, 0 turns slow mode off
+func (sm *SlowModeCache) Set(channelID string, seconds int) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	if seconds <= 0 {
+		delete(sm.intervals, channelID)
+		delete(sm.lastSent, channelID)
+		return
+	}
+	sm.intervals[channelID] = time.Duration(seconds) * time.Second
+}
+
+// Reserve claims a message slot for a user in a channel. When the user
+// has to wait it returns the remaining time and claims nothing. Otherwise
+// it returns a function that gives the slot back, for messages that end
+// up not being posted.
+func (sm *SlowModeCache) Reserve(userID primitive.ObjectID, channelID string) (time.Duration, func()) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	interval, ok := sm.intervals[channelID]
+	if !ok {
+		return 0, func() {}
+	}
+
+	now := time.Now()
+	users := sm.lastSent[channelID]
+	if users == nil {
+		users = make(map[primitive.ObjectID]time.Time)
+		sm.lastSent[channelID] = users
+	}
+	if wait := users[userID].Add(interval).Sub(now); wait > 0 {
+		return wait, func() {}
+	}
+
+	users[userID] = now
+	return 0, func() { sm.release(userID, channelID, now) }
+}
+
+// release gives back a slot claimed at reservedAt, unless a later
+// message has claimed the channel since
+func (sm *SlowModeCache) release(userID primitive.ObjectID, channelID string, reservedAt time.Time) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	if users := sm.lastSent[channelID]; users != nil && users[userID].Equal(reservedAt) {
+		delete(users, userID)
+	}
+}
+
+// prune removes message times that no longer hold anyone back
+func (sm *SlowModeCache) prune(now time.Time) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	for channelID, users := range sm.lastSent {
+		interval := sm.intervals[channelID]
+		for userID, sent := range users {
+			if now.Sub(sent) >= interval {
+				delete(users, userID)
+			}
+		}
+		if len(users) == 0 {
+			delete(sm.lastSent, channelID)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// newTestSlowMode returns a SlowModeCache without a repository or the
+// pruning goroutine
+func newTestSlowMode() *SlowModeCache {
+	return &SlowModeCache{
+		intervals: make(map[string]time.Duration),
+		lastSent:  make(map[string]map[primitive.ObjectID]time.Time),
+	}
+}
+
+func TestSlowModeReserve(t *testing.T) {
+	sm := newTestSlowMode()
+	sm.Set("slow", 30)
+	userID := primitive.NewObjectID()
+
+	if wait, _ := sm.Reserve(userID, "fast"); wait != 0 {
+		t.Errorf("channel without slow mode: wait = %v", wait)
+	}
+	if wait, _ := sm.Reserve(userID, "fast"); wait != 0 {
+		t.Errorf("channel without slow mode, second message: wait = %v", wait)
+	}
+
+	if wait, _ := sm.Reserve(userID, "slow"); wait != 0 {
+		t.Fatalf("first message: wait = %v", wait)
+	}
+	wait, release := sm.Reserve(userID, "slow")
+	if wait <= 25*time.Second || wait > 30*time.Second {
+		t.Errorf("second message: wait = %v, want about 30s", wait)
+	}
+
+	// Releasing a refused reservation gives nothing back
+	release()
+	if wait, _ := sm.Reserve(userID, "slow"); wait == 0 {
+		t.Error("refused reservation released the slot")
+	}
+
+	// Other users and channels are not held back
+	if wait, _ := sm.Reserve(primitive.NewObjectID(), "slow"); wait != 0 {
+		t.Errorf("other user: wait = %v", wait)
+	}
+
+	// Turning slow mode off forgets the message times
+	sm.Set("slow", 0)
+	if wait, _ := sm.Reserve(userID, "slow"); wait != 0 {
+		t.Errorf("after turning slow mode off: wait = %v", wait)
+	}
+}
+
+func TestSlowModeRelease(t *testing.T) {
+	sm := newTestSlowMode()
+	sm.Set("slow", 30)
+	userID := primitive.NewObjectID()
+
+	// A message that was not posted gives its slot back
+	_, release := sm.Reserve(userID, "slow")
+	release()
+	wait, release := sm.Reserve(userID, "slow")
+	if wait != 0 {
+		t.Fatalf("after release: wait = %v", wait)
+	}
+
+	// A stale release must not free a slot claimed by a later message
+	stale := release
+	sm.mu.Lock()
+	sm.lastSent["slow"][userID] = time.Now().Add(time.Second)
+	sm.mu.Unlock()
+	stale()
+	if wait, _ := sm.Reserve(userID, "slow"); wait == 0 {
+		t.Error("stale release freed a later reservation")
+	}
+}
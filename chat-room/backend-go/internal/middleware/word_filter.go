@@ -3,25 +3,32 @@ package middleware
 import (
 	"context"
 	"log"
-	"strings"
 	"sync"
 	"time"
 
+	"chat-room-backend/internal/models"
 	"chat-room-backend/internal/repository"
 )
 
-// WordFilterCache maintains an in-memory cache of blocked words
+// WordFilterCache maintains in-memory compiled matchers of blocked words.
+// Global filters apply everywhere; a channel with its own filters gets a
+// matcher compiled from the global and channel filters together, so a
+// message is checked in a single pass.
 type WordFilterCache struct {
-	words map[string]bool
-	mu    sync.RWMutex
-	repo  *repository.AdminRepository
+	filters         []*models.WordFilter
+	matcher         *ContentMatcher            // Global filters only
+	channelMatchers map[string]*ContentMatcher // Global plus channel filters, by channel ID
+	mu              sync.RWMutex
+	repo            *repository.AdminRepository
 }
 
 // NewWordFilterCache creates a new WordFilterCache
 func NewWordFilterCache(repo *repository.AdminRepository) *WordFilterCache {
+	matcher, _ := NewContentMatcher(nil)
 	cache := &WordFilterCache{
-		words: make(map[string]bool),
-		repo:  repo,
+		matcher:         matcher,
+		channelMatchers: make(map[string]*ContentMatcher),
+		repo:            repo,
 	}
 
 	// Load initial cache
@@ -42,41 +49,88 @@ func (wfc *WordFilterCache) Reload() error {
 		return err
 	}
 
+	// Compile outside the lock so messages are not held up
+	matcher, channelMatchers, errs := compileWordFilters(filters)
+	for _, err := range errs {
+		log.Printf("⚠️  Warning: Skipping word filter: %v", err)
+	}
+
 	wfc.mu.Lock()
 	defer wfc.mu.Unlock()
 
-	// Clear and rebuild cache
-	wfc.words = make(map[string]bool)
+	wfc.filters = filters
+	wfc.matcher = matcher
+	wfc.channelMatchers = channelMatchers
+
+	log.Printf("✅ Loaded %d active word filter(s), %d channel(s) with their own rules", len(filters), len(channelMatchers))
+	return nil
+}
+
+// compileWordFilters compiles the global filters into one matcher, and the
+// global and channel filters of each channel with its own filters into
+// another
+func compileWordFilters(filters []*models.WordFilter) (*ContentMatcher, map[string]*ContentMatcher, []error) {
+	// Split global and channel-scoped filters
+	var global []*models.WordFilter
+	scoped := make(map[string][]*models.WordFilter)
 	for _, filter := range filters {
-		wfc.words[strings.ToLower(filter.Word)] = true
+		if filter.ChannelID == nil {
+			global = append(global, filter)
+		} else {
+			channelID := filter.ChannelID.Hex()
+			scoped[channelID] = append(scoped[channelID], filter)
+		}
 	}
 
-	log.Printf("✅ Loaded %d active word filter(s)", len(wfc.words))
-	return nil
+	matcher, errs := NewContentMatcher(global)
+
+	channelMatchers := make(map[string]*ContentMatcher, len(scoped))
+	for channelID, channelFilters := range scoped {
+		combined := append(append([]*models.WordFilter{}, global...), channelFilters...)
+		// Errors in global filters are already in errs
+		channelMatchers[channelID], _ = NewContentMatcher(combined)
+	}
+
+	return matcher, channelMatchers, errs
 }
 
-// ContainsBlockedWord checks if a message contains any blocked words
-func (wfc *WordFilterCache) ContainsBlockedWord(message string) bool {
+// matcherFor returns the matcher for a channel (the global one if empty)
+func (wfc *WordFilterCache) matcherFor(channelID string) *ContentMatcher {
 	wfc.mu.RLock()
 	defer wfc.mu.RUnlock()
 
-	lowerMsg := strings.ToLower(message)
-	for word := range wfc.words {
-		if strings.Contains(lowerMsg, word) {
-			return true
-		}
+	if matcher, ok := wfc.channelMatchers[channelID]; ok {
+		return matcher
 	}
-	return false
+	return wfc.matcher
 }
 
-// GetBlockedWords returns the list of blocked words (for debugging)
+// ContainsBlockedWord checks if a message sent to a channel contains any blocked words
+func (wfc *WordFilterCache) ContainsBlockedWord(channelID, message string) bool {
+	return len(wfc.Match(channelID, message)) > 0
+}
+
+// Match returns the blocking rule hits in a message sent to a channel.
+// An empty channel ID checks global filters only.
+func (wfc *WordFilterCache) Match(channelID, message string) []ContentMatch {
+	return wfc.matcherFor(channelID).Match(message)
+}
+
+// Explain reports which rules match a text, for the admin dry-run endpoint
+func (wfc *WordFilterCache) Explain(channelID, text string) *ContentExplanation {
+	return wfc.matcherFor(channelID).Explain(text)
+}
+
+// GetBlockedWords returns the list of global blocked words (for debugging)
 func (wfc *WordFilterCache) GetBlockedWords() []string {
 	wfc.mu.RLock()
 	defer wfc.mu.RUnlock()
 
-	words := make([]string, 0, len(wfc.words))
-	for word := range wfc.words {
-		words = append(words, word)
+	words := make([]string, 0, len(wfc.filters))
+	for _, filter := range wfc.filters {
+		if !filter.IsAllow && filter.ChannelID == nil {
+			words = append(words, filter.Word)
+		}
 	}
 	return words
 }
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"testing"
+
+	"chat-room-backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestWordFilterCacheChannels(t *testing.T) {
+	gaming := primitive.NewObjectID()
+	kids := primitive.NewObjectID()
+
+	matcher, channelMatchers, errs := compileWordFilters([]*models.WordFilter{
+		{Word: "spam"},
+		{Word: "noob", ChannelID: &gaming},
+		{Word: "darn", ChannelID: &kids},
+		{Word: "spammer", ChannelID: &kids, IsAllow: true},
+	})
+	if len(errs) > 0 {
+		t.Fatalf("compileWordFilters: %v", errs)
+	}
+	if len(channelMatchers) != 2 {
+		t.Fatalf("compiled %d channel matchers, want 2", len(channelMatchers))
+	}
+	wfc := &WordFilterCache{matcher: matcher, channelMatchers: channelMatchers}
+
+	tests := []struct {
+		channelID string
+		text      string
+		want      bool
+	}{
+		{"", "spam", true},
+		{"", "noob", false},
+		{gaming.Hex(), "spam", true}, // Global filters apply in every channel
+		{gaming.Hex(), "noob", true},
+		{gaming.Hex(), "darn", false}, // Channel filters stay in their channel
+		{kids.Hex(), "darn", true},
+		{kids.Hex(), "spammer", false}, // Channel allowlist overrides a global filter
+		{gaming.Hex(), "spammer", true},
+		{primitive.NewObjectID().Hex(), "spam", true},
+		{primitive.NewObjectID().Hex(), "noob", false},
+	}
+
+	for _, tt := range tests {
+		if got := wfc.ContainsBlockedWord(tt.channelID, tt.text); got != tt.want {
+			t.Errorf("ContainsBlockedWord(%q, %q) = %v, want %v", tt.channelID, tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestCompileWordFiltersReportsBadRegex(t *testing.T) {
+	channelID := primitive.NewObjectID()
+
+	_, channelMatchers, errs := compileWordFilters([]*models.WordFilter{
+		{Word: "(", MatchType: models.MatchTypeRegex},
+		{Word: "spam", ChannelID: &channelID},
+	})
+	// A broken global regex is reported once, not again for every channel
+	if len(errs) != 1 {
+		t.Errorf("got %d errors, want 1: %v", len(errs), errs)
+	}
+	if len(channelMatchers[channelID.Hex()].Match("spam")) != 1 {
+		t.Error("channel filter skipped because of a broken global filter")
+	}
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// API key scopes
+const (
+	APIKeyScopeMessagesWrite = "messages.write" // Post messages to the bot's channels
+	APIKeyScopeMessagesRead  = "messages.read"  // Read the history of the bot's channels
+)
+
+// AllAPIKeyScopes lists every scope an API key can have
+var AllAPIKeyScopes = []string{APIKeyScopeMessagesWrite, APIKeyScopeMessagesRead}
+
+// IsAPIKeyScope reports whether name is a known scope
+func IsAPIKeyScope(name string) bool {
+	for _, scope := range AllAPIKeyScopes {
+		if scope == name {
+			return true
+		}
+	}
+	return false
+}
+
+// APIKey authenticates a bot. The key is shown once when it is created;
+// only its hash and a short prefix, to tell keys apart, are stored.
+type APIKey struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	BotID      primitive.ObjectID `bson:"botId" json:"botId"`
+	Name       string             `bson:"name" json:"name"`
+	Prefix     string             `bson:"prefix" json:"prefix"`
+	KeyHash    string             `bson:"keyHash" json:"-"` // SHA-256 of the key
+	Scopes     []string           `bson:"scopes" json:"scopes"`
+	CreatedBy  primitive.ObjectID `bson:"createdBy" json:"createdBy"`
+	CreatedAt  time.Time          `bson:"createdAt" json:"createdAt"`
+	ExpiresAt  *time.Time         `bson:"expiresAt,omitempty" json:"expiresAt,omitempty"` // nil for keys that never expire
+	LastUsedAt *time.Time         `bson:"lastUsedAt,omitempty" json:"lastUsedAt,omitempty"`
+	RevokedAt  *time.Time         `bson:"revokedAt,omitempty" json:"revokedAt,omitempty"`
+}
+
+// IsActive reports whether the key is neither revoked nor expired
+func (k *APIKey) IsActive() bool {
+	if k.RevokedAt != nil {
+		return false
+	}
+	return k.ExpiresAt == nil || k.ExpiresAt.After(time.Now())
+}
+
+// HasScope reports whether the key grants a scope
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
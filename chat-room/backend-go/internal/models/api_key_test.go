@@ -0,0 +1,52 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAPIKeyIsActive(t *testing.T) {
+	past := time.Now().Add(-time.Minute)
+	future := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name string
+		key  APIKey
+		want bool
+	}{
+		{"never expires", APIKey{}, true},
+		{"not expired yet", APIKey{ExpiresAt: &future}, true},
+		{"expired", APIKey{ExpiresAt: &past}, false},
+		{"revoked", APIKey{RevokedAt: &past}, false},
+		{"revoked before expiry", APIKey{ExpiresAt: &future, RevokedAt: &past}, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.key.IsActive(); got != tt.want {
+			t.Errorf("%s: IsActive() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestAPIKeyScopes(t *testing.T) {
+	key := &APIKey{Scopes: []string{APIKeyScopeMessagesRead}}
+
+	if !key.HasScope(APIKeyScopeMessagesRead) {
+		t.Error("key lacks its own scope")
+	}
+	if key.HasScope(APIKeyScopeMessagesWrite) {
+		t.Error("read-only key may write")
+	}
+	if (&APIKey{}).HasScope(APIKeyScopeMessagesRead) {
+		t.Error("key without scopes has a scope")
+	}
+
+	for _, scope := range AllAPIKeyScopes {
+		if !IsAPIKeyScope(scope) {
+			t.Errorf("IsAPIKeyScope(%q) = false", scope)
+		}
+	}
+	if IsAPIKeyScope("messages.*") || IsAPIKeyScope("") {
+		t.Error("unknown scope accepted")
+	}
+}
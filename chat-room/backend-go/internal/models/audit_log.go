@@ -0,0 +1,74 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Audit log actions
+const (
+	AuditActionWordFilterAdd    = "word_filter.add"
+	AuditActionWordFilterRemove = "word_filter.remove"
+	AuditActionWordFilterImport = "word_filter.import"
+	AuditActionUserMute         = "user.mute"
+	AuditActionUserUnmute       = "user.unmute"
+	AuditActionUserBan          = "user.ban"
+	AuditActionUserUnban        = "user.unban"
+	AuditActionIPBan            = "ip.ban"
+	AuditActionUserStrikesReset = "user.strikes_reset"
+	AuditActionGlobalMute       = "global_mute.update"
+	AuditActionScheduleAdd      = "global_mute.schedule_add"
+	AuditActionScheduleRemove   = "global_mute.schedule_remove"
+	AuditActionChannelKick      = "channel.kick"
+	AuditActionChannelSlowMode  = "channel.slow_mode"
+	AuditActionChannelModerator = "channel.moderator"
+	AuditActionChannelPosting   = "channel.posting_policy"
+	AuditActionReportResolve    = "report.resolve"
+	AuditActionLoginLockout     = "auth.lockout"
+	AuditActionLoginSuspicious  = "auth.suspicious"
+	AuditActionLoginUnlock      = "auth.unlock"
+	AuditActionTwoFactorEnable  = "auth.2fa_enable"
+	AuditActionTwoFactorDisable = "auth.2fa_disable"
+	AuditActionSSOLink          = "auth.sso_link"
+	AuditActionSSOUnlink        = "auth.sso_unlink"
+	AuditActionUserRole         = "user.role"
+	AuditActionPasswordReset    = "user.password_reset"
+	AuditActionPasswordChange   = "auth.password_change"
+	AuditActionRoleCreate       = "role.create"
+	AuditActionRoleUpdate       = "role.update"
+	AuditActionRoleDelete       = "role.delete"
+	AuditActionBotCreate        = "bot.create"
+	AuditActionBotDelete        = "bot.delete"
+	AuditActionBotKeyCreate     = "bot.key_create"
+	AuditActionBotKeyRevoke     = "bot.key_revoke"
+	AuditActionBotChannelAdd    = "bot.channel_add"
+	AuditActionBotChannelRemove = "bot.channel_remove"
+)
+
+// Audit log target types
+const (
+	AuditTargetUser       = "user"
+	AuditTargetWordFilter = "word_filter"
+	AuditTargetGlobalMute = "global_mute"
+	AuditTargetChannel    = "channel"
+	AuditTargetReport     = "report"
+	AuditTargetIP         = "ip"
+	AuditTargetRole       = "role"
+	AuditTargetBot        = "bot"
+)
+
+// AuditLog is an append-only record of a moderation action
+type AuditLog struct {
+	ID            primitive.ObjectID     `bson:"_id,omitempty" json:"id"`
+	ActorID       primitive.ObjectID     `bson:"actorId" json:"actorId"`
+	ActorUsername string                 `bson:"actorUsername" json:"actorUsername"`
+	Action        string                 `bson:"action" json:"action"`
+	TargetType    string                 `bson:"targetType" json:"targetType"`
+	TargetID      *primitive.ObjectID    `bson:"targetId,omitempty" json:"targetId,omitempty"`
+	TargetName    string                 `bson:"targetName,omitempty" json:"targetName,omitempty"`
+	Reason        string                 `bson:"reason,omitempty" json:"reason,omitempty"`
+	Before        map[string]interface{} `bson:"before,omitempty" json:"before,omitempty"`
+	After         map[string]interface{} `bson:"after,omitempty" json:"after,omitempty"`
+	Timestamp     time.Time              `bson:"timestamp" json:"timestamp"`
+}
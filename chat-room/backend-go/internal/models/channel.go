@@ -6,6 +6,15 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// MaxSlowModeSeconds is the longest slow mode interval
+const MaxSlowModeSeconds = 6 * 60 * 60
+
+// Channel posting policies
+const (
+	PostingPolicyEveryone   = "everyone"   // Every member can post
+	PostingPolicyRestricted = "restricted" // Only admins, moderators and posters can post
+)
+
 // Channel represents a chat channel
 type Channel struct {
 	ID          primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
@@ -15,24 +24,41 @@ type Channel struct {
 	IsDefault   bool                `bson:"isDefault" json:"isDefault"`
 	CreatedAt   time.Time           `bson:"createdAt" json:"createdAt"`
 	Icon        string              `bson:"icon" json:"icon"`
+
+	// SlowModeSeconds is the minimum time between two messages of a user, 0 when off
+	SlowModeSeconds int `bson:"slowModeSeconds,omitempty" json:"slowModeSeconds"`
+
+	PostingPolicy string `bson:"postingPolicy,omitempty" json:"postingPolicy"`
+}
+
+// GetPostingPolicy returns the posting policy, defaulting to everyone
+func (c *Channel) GetPostingPolicy() string {
+	if c.PostingPolicy == "" {
+		return PostingPolicyEveryone
+	}
+	return c.PostingPolicy
 }
 
 // ChannelResponse is the channel data returned to clients
 type ChannelResponse struct {
-	ID          string `json:"id"`
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	IsDefault   bool   `json:"isDefault"`
-	Icon        string `json:"icon"`
+	ID              string `json:"id"`
+	Name            string `json:"name"`
+	Description     string `json:"description"`
+	IsDefault       bool   `json:"isDefault"`
+	Icon            string `json:"icon"`
+	SlowModeSeconds int    `json:"slowModeSeconds"`
+	PostingPolicy   string `json:"postingPolicy"`
 }
 
 // ToResponse converts Channel to ChannelResponse
 func (c *Channel) ToResponse() *ChannelResponse {
 	return &ChannelResponse{
-		ID:          c.ID.Hex(),
-		Name:        c.Name,
-		Description: c.Description,
-		IsDefault:   c.IsDefault,
-		Icon:        c.Icon,
+		ID:              c.ID.Hex(),
+		Name:            c.Name,
+		Description:     c.Description,
+		IsDefault:       c.IsDefault,
+		Icon:            c.Icon,
+		SlowModeSeconds: c.SlowModeSeconds,
+		PostingPolicy:   c.GetPostingPolicy(),
 	}
 }
@@ -6,6 +6,13 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// Channel member roles
+const (
+	ChannelRoleMember    = "member"
+	ChannelRoleModerator = "moderator"
+	ChannelRolePoster    = "poster" // May post in a restricted channel
+)
+
 // ChannelMember represents a user's membership in a channel
 type ChannelMember struct {
 	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
@@ -13,4 +20,10 @@ type ChannelMember struct {
 	ChannelID  primitive.ObjectID `bson:"channelId" json:"channelId"`
 	JoinedAt   time.Time          `bson:"joinedAt" json:"joinedAt"`
 	LastReadAt time.Time          `bson:"lastReadAt" json:"lastReadAt"`
+	Role       string             `bson:"role,omitempty" json:"role"`
+}
+
+// IsModerator reports whether the member moderates the channel
+func (m *ChannelMember) IsModerator() bool {
+	return m.Role == ChannelRoleModerator
 }
@@ -0,0 +1,93 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Global mute schedule types
+const (
+	ScheduleTypeOnce   = "once"   // A single window from StartAt to EndAt
+	ScheduleTypeDaily  = "daily"  // Every day from StartTime to EndTime
+	ScheduleTypeWeekly = "weekly" // On Weekdays from StartTime to EndTime
+)
+
+// GlobalMuteSchedule is a planned global mute window
+type GlobalMuteSchedule struct {
+	ID     primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Type   string             `bson:"type" json:"type"`
+	Reason string             `bson:"reason" json:"reason"`
+
+	// One-off window
+	StartAt *time.Time `bson:"startAt,omitempty" json:"startAt,omitempty"`
+	EndAt   *time.Time `bson:"endAt,omitempty" json:"endAt,omitempty"`
+
+	// Recurring window as HH:MM in Timezone. An end before the start
+	// means the window runs past midnight.
+	StartTime string `bson:"startTime,omitempty" json:"startTime,omitempty"`
+	EndTime   string `bson:"endTime,omitempty" json:"endTime,omitempty"`
+	Weekdays  []int  `bson:"weekdays,omitempty" json:"weekdays,omitempty"` // 0 = Sunday, the day the window starts
+	Timezone  string `bson:"timezone,omitempty" json:"timezone,omitempty"` // IANA name, server time if empty
+
+	CreatedBy primitive.ObjectID `bson:"createdBy" json:"createdBy"`
+	CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
+}
+
+// Location returns the schedule's time zone
+func (s *GlobalMuteSchedule) Location() *time.Location {
+	if s.Timezone == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
+// Window returns the window that contains now, or else the next one.
+// ok is false when the schedule has no window left.
+func (s *GlobalMuteSchedule) Window(now time.Time) (start, end time.Time, ok bool) {
+	if s.Type == ScheduleTypeOnce {
+		if s.StartAt == nil || s.EndAt == nil || !s.EndAt.After(now) {
+			return time.Time{}, time.Time{}, false
+		}
+		return *s.StartAt, *s.EndAt, true
+	}
+
+	startClock, err1 := time.Parse("15:04", s.StartTime)
+	endClock, err2 := time.Parse("15:04", s.EndTime)
+	if err1 != nil || err2 != nil {
+		return time.Time{}, time.Time{}, false
+	}
+
+	// Start from yesterday to catch a window running past midnight
+	local := now.In(s.Location())
+	for offset := -1; offset <= 7; offset++ {
+		day := time.Date(local.Year(), local.Month(), local.Day()+offset, 0, 0, 0, 0, local.Location())
+		if s.Type == ScheduleTypeWeekly && !s.runsOn(day.Weekday()) {
+			continue
+		}
+
+		start = time.Date(day.Year(), day.Month(), day.Day(), startClock.Hour(), startClock.Minute(), 0, 0, day.Location())
+		end = time.Date(day.Year(), day.Month(), day.Day(), endClock.Hour(), endClock.Minute(), 0, 0, day.Location())
+		if !end.After(start) {
+			end = end.AddDate(0, 0, 1)
+		}
+		if end.After(now) {
+			return start, end, true
+		}
+	}
+	return time.Time{}, time.Time{}, false
+}
+
+// runsOn reports whether a weekly window starts on a weekday
+func (s *GlobalMuteSchedule) runsOn(weekday time.Weekday) bool {
+	for _, d := range s.Weekdays {
+		if time.Weekday(d) == weekday {
+			return true
+		}
+	}
+	return false
+}
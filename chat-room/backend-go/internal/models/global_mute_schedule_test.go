@@ -0,0 +1,131 @@
+package models
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata" // Time zone tests must not depend on the host's zoneinfo
+)
+
+func TestGlobalMuteScheduleWindow(t *testing.T) {
+	utc := func(day, hour, minute int) time.Time {
+		return time.Date(2026, 3, day, hour, minute, 0, 0, time.UTC)
+	}
+	at := func(t time.Time) *time.Time { return &t }
+
+	// 4 March 2026 is a Wednesday
+	tests := []struct {
+		name      string
+		schedule  GlobalMuteSchedule
+		now       time.Time
+		wantStart time.Time
+		wantEnd   time.Time
+		wantOK    bool
+	}{
+		{
+			"daily, inside",
+			GlobalMuteSchedule{Type: ScheduleTypeDaily, StartTime: "09:00", EndTime: "17:00", Timezone: "UTC"},
+			utc(4, 12, 0), utc(4, 9, 0), utc(4, 17, 0), true,
+		},
+		{
+			"daily, before",
+			GlobalMuteSchedule{Type: ScheduleTypeDaily, StartTime: "09:00", EndTime: "17:00", Timezone: "UTC"},
+			utc(4, 8, 0), utc(4, 9, 0), utc(4, 17, 0), true,
+		},
+		{
+			"daily, end is exclusive",
+			GlobalMuteSchedule{Type: ScheduleTypeDaily, StartTime: "09:00", EndTime: "17:00", Timezone: "UTC"},
+			utc(4, 17, 0), utc(5, 9, 0), utc(5, 17, 0), true,
+		},
+		{
+			"across midnight, before midnight",
+			GlobalMuteSchedule{Type: ScheduleTypeDaily, StartTime: "22:00", EndTime: "06:00", Timezone: "UTC"},
+			utc(4, 23, 0), utc(4, 22, 0), utc(5, 6, 0), true,
+		},
+		{
+			"across midnight, after midnight",
+			GlobalMuteSchedule{Type: ScheduleTypeDaily, StartTime: "22:00", EndTime: "06:00", Timezone: "UTC"},
+			utc(5, 2, 0), utc(4, 22, 0), utc(5, 6, 0), true,
+		},
+		{
+			"across midnight, after the end",
+			GlobalMuteSchedule{Type: ScheduleTypeDaily, StartTime: "22:00", EndTime: "06:00", Timezone: "UTC"},
+			utc(5, 7, 0), utc(5, 22, 0), utc(6, 6, 0), true,
+		},
+		{
+			"same start and end lasts a day",
+			GlobalMuteSchedule{Type: ScheduleTypeDaily, StartTime: "08:00", EndTime: "08:00", Timezone: "UTC"},
+			utc(4, 12, 0), utc(4, 8, 0), utc(5, 8, 0), true,
+		},
+		{
+			"weekly, next weekday",
+			GlobalMuteSchedule{Type: ScheduleTypeWeekly, StartTime: "22:00", EndTime: "02:00", Weekdays: []int{5}, Timezone: "UTC"},
+			utc(4, 12, 0), utc(6, 22, 0), utc(7, 2, 0), true,
+		},
+		{
+			"weekly, past midnight into a day it does not run on",
+			GlobalMuteSchedule{Type: ScheduleTypeWeekly, StartTime: "22:00", EndTime: "02:00", Weekdays: []int{5}, Timezone: "UTC"},
+			utc(7, 1, 0), utc(6, 22, 0), utc(7, 2, 0), true,
+		},
+		{
+			"weekly, wraps to next week",
+			GlobalMuteSchedule{Type: ScheduleTypeWeekly, StartTime: "09:00", EndTime: "10:00", Weekdays: []int{0, 3}, Timezone: "UTC"},
+			utc(4, 11, 0), utc(8, 9, 0), utc(8, 10, 0), true,
+		},
+		{
+			"weekly without weekdays",
+			GlobalMuteSchedule{Type: ScheduleTypeWeekly, StartTime: "09:00", EndTime: "10:00", Timezone: "UTC"},
+			utc(4, 8, 0), time.Time{}, time.Time{}, false,
+		},
+		{
+			"time zone",
+			GlobalMuteSchedule{Type: ScheduleTypeDaily, StartTime: "22:00", EndTime: "23:00", Timezone: "Asia/Shanghai"},
+			utc(4, 14, 30), utc(4, 14, 0), utc(4, 15, 0), true,
+		},
+		{
+			"weekday in the schedule's time zone",
+			// Thursday 01:00 in Shanghai is still Wednesday in UTC
+			GlobalMuteSchedule{Type: ScheduleTypeWeekly, StartTime: "00:00", EndTime: "02:00", Weekdays: []int{4}, Timezone: "Asia/Shanghai"},
+			utc(4, 17, 0), utc(4, 16, 0), utc(4, 18, 0), true,
+		},
+		{
+			"daylight saving change inside the window",
+			// Clocks in New York go from 02:00 EST to 03:00 EDT on 8 March
+			GlobalMuteSchedule{Type: ScheduleTypeDaily, StartTime: "01:00", EndTime: "03:00", Timezone: "America/New_York"},
+			utc(8, 5, 0), utc(8, 6, 0), utc(8, 7, 0), true,
+		},
+		{
+			"bad clock",
+			GlobalMuteSchedule{Type: ScheduleTypeDaily, StartTime: "25:00", EndTime: "03:00", Timezone: "UTC"},
+			utc(4, 12, 0), time.Time{}, time.Time{}, false,
+		},
+		{
+			"once, upcoming",
+			GlobalMuteSchedule{Type: ScheduleTypeOnce, StartAt: at(utc(5, 9, 0)), EndAt: at(utc(5, 10, 0))},
+			utc(4, 12, 0), utc(5, 9, 0), utc(5, 10, 0), true,
+		},
+		{
+			"once, over",
+			GlobalMuteSchedule{Type: ScheduleTypeOnce, StartAt: at(utc(3, 9, 0)), EndAt: at(utc(3, 10, 0))},
+			utc(4, 12, 0), time.Time{}, time.Time{}, false,
+		},
+		{
+			"once without end",
+			GlobalMuteSchedule{Type: ScheduleTypeOnce, StartAt: at(utc(5, 9, 0))},
+			utc(4, 12, 0), time.Time{}, time.Time{}, false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end, ok := tt.schedule.Window(tt.now)
+			if ok != tt.wantOK {
+				t.Fatalf("Window() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && (!start.Equal(tt.wantStart) || !end.Equal(tt.wantEnd)) {
+				t.Errorf("Window() = %s – %s, want %s – %s",
+					start.UTC().Format(time.RFC3339), end.UTC().Format(time.RFC3339),
+					tt.wantStart.Format(time.RFC3339), tt.wantEnd.Format(time.RFC3339))
+			}
+		})
+	}
+}
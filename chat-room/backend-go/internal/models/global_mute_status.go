@@ -6,6 +6,12 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// Global mute sources
+const (
+	GlobalMuteSourceManual   = "manual"
+	GlobalMuteSourceSchedule = "schedule"
+)
+
 // GlobalMuteStatus represents the global mute status (singleton pattern)
 type GlobalMuteStatus struct {
 	ID        primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
@@ -13,18 +19,39 @@ type GlobalMuteStatus struct {
 	EnabledBy *primitive.ObjectID `bson:"enabledBy,omitempty" json:"enabledBy,omitempty"`
 	EnabledAt *time.Time          `bson:"enabledAt,omitempty" json:"enabledAt,omitempty"`
 	Reason    string              `bson:"reason" json:"reason"`
+
+	// Source is manual or schedule; ScheduleID is set for schedule
+	Source     string              `bson:"source,omitempty" json:"source,omitempty"`
+	ScheduleID *primitive.ObjectID `bson:"scheduleId,omitempty" json:"scheduleId,omitempty"`
+	ExpiresAt  *time.Time          `bson:"expiresAt,omitempty" json:"expiresAt,omitempty"` // nil until turned off
+
+	// SkipScheduleUntil is set when an admin turns off a scheduled mute,
+	// so the scheduler does not turn it back on before the window ends
+	SkipScheduleUntil *time.Time `bson:"skipScheduleUntil,omitempty" json:"skipScheduleUntil,omitempty"`
+}
+
+// IsScheduled reports whether the status was set by a schedule
+func (gms *GlobalMuteStatus) IsScheduled() bool {
+	return gms.Source == GlobalMuteSourceSchedule
 }
 
 // GlobalMuteResponse is the global mute status returned to clients
 type GlobalMuteResponse struct {
-	IsEnabled bool   `json:"isEnabled"`
-	Reason    string `json:"reason"`
+	IsEnabled bool       `json:"isEnabled"`
+	Reason    string     `json:"reason"`
+	Source    string     `json:"source,omitempty"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
 }
 
 // ToResponse converts GlobalMuteStatus to GlobalMuteResponse
 func (gms *GlobalMuteStatus) ToResponse() *GlobalMuteResponse {
-	return &GlobalMuteResponse{
+	response := &GlobalMuteResponse{
 		IsEnabled: gms.IsEnabled,
 		Reason:    gms.Reason,
 	}
+	if gms.IsEnabled {
+		response.Source = gms.Source
+		response.ExpiresAt = gms.ExpiresAt
+	}
+	return response
 }
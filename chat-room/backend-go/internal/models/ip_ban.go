@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// IPBan represents a banned IP address
+type IPBan struct {
+	ID          primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	IP          string              `bson:"ip" json:"ip"`
+	UserID      *primitive.ObjectID `bson:"userId,omitempty" json:"userId,omitempty"` // User whose ban created this entry
+	BannedBy    primitive.ObjectID  `bson:"bannedBy" json:"bannedBy"`
+	Reason      string              `bson:"reason" json:"reason"`
+	BannedUntil *time.Time          `bson:"bannedUntil,omitempty" json:"bannedUntil,omitempty"`
+	CreatedAt   time.Time           `bson:"createdAt" json:"createdAt"`
+	IsActive    bool                `bson:"isActive" json:"isActive"`
+}
+
+// IsBanActive reports whether the IP ban is currently in effect
+func (b *IPBan) IsBanActive() bool {
+	if !b.IsActive {
+		return false
+	}
+	return b.BannedUntil == nil || b.BannedUntil.After(time.Now())
+}
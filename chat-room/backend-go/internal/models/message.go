@@ -6,6 +6,14 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// Message types
+const (
+	MessageTypeUser   = "user"
+	MessageTypeSystem = "system"
+	MessageTypeAI     = "ai"
+	MessageTypeBot    = "bot" // Posted through the bot API
+)
+
 // Message represents a chat message
 type Message struct {
 	ID          primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
@@ -13,7 +21,7 @@ type Message struct {
 	UserID      *primitive.ObjectID `bson:"userId,omitempty" json:"userId,omitempty"`
 	Message     string              `bson:"message" json:"message"`
 	ChannelID   primitive.ObjectID  `bson:"channelId" json:"channelId"`
-	MessageType string              `bson:"messageType" json:"messageType"` // "user" | "system" | "ai"
+	MessageType string              `bson:"messageType" json:"messageType"` // One of the MessageType constants
 	IsDeleted   bool                `bson:"isDeleted" json:"isDeleted"`
 	Timestamp   time.Time           `bson:"timestamp" json:"timestamp"`
 }
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RefreshToken is a server-side refresh token. Tokens rotate on every use;
+// all tokens descending from one login share a FamilyID, so presenting an
+// already used token revokes the whole family.
+type RefreshToken struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID    primitive.ObjectID `bson:"userId" json:"userId"`
+	FamilyID  primitive.ObjectID `bson:"familyId" json:"familyId"`
+	TokenHash string             `bson:"tokenHash" json:"-"` // SHA-256 of the token, the token itself is never stored
+	ExpiresAt time.Time          `bson:"expiresAt" json:"expiresAt"`
+	CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
+	UsedAt    *time.Time         `bson:"usedAt,omitempty" json:"usedAt,omitempty"`
+	RevokedAt *time.Time         `bson:"revokedAt,omitempty" json:"revokedAt,omitempty"`
+}
+
+// Revocation kinds
+const (
+	RevocationKindToken   = "token"   // A single access token, by jti
+	RevocationKindSession = "session" // Every access token of a refresh token family, by sid
+)
+
+// RevokedToken is an entry of the access token revocation list. Entries
+// are kept until the access tokens they cover have expired.
+type RevokedToken struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Kind      string             `bson:"kind" json:"kind"`
+	Value     string             `bson:"value" json:"value"` // jti or sid
+	ExpiresAt time.Time          `bson:"expiresAt" json:"expiresAt"`
+	CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
+}
@@ -0,0 +1,58 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Report sources
+const (
+	ReportSourceFilter = "filter" // Flagged by a word filter with the review action
+	ReportSourceUser   = "user"   // Reported by a user
+)
+
+// Report statuses
+const (
+	ReportStatusOpen      = "open"
+	ReportStatusActioned  = "actioned"
+	ReportStatusDismissed = "dismissed"
+)
+
+// Moderator actions on a report
+const (
+	ReportActionDismiss       = "dismiss"
+	ReportActionDeleteMessage = "delete_message"
+	ReportActionMuteAuthor    = "mute_author"
+)
+
+// Reporter is a user who reported a message
+type Reporter struct {
+	UserID     primitive.ObjectID `bson:"userId" json:"userId"`
+	Username   string             `bson:"username" json:"username"`
+	Reason     string             `bson:"reason" json:"reason"`
+	ReportedAt time.Time          `bson:"reportedAt" json:"reportedAt"`
+}
+
+// Report is an entry in the moderator review queue.
+// There is at most one open report per message; further reports of the
+// same message are added to Reporters.
+type Report struct {
+	ID             primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	MessageID      primitive.ObjectID  `bson:"messageId" json:"messageId"`
+	ChannelID      primitive.ObjectID  `bson:"channelId" json:"channelId"`
+	AuthorID       *primitive.ObjectID `bson:"authorId,omitempty" json:"authorId,omitempty"`
+	AuthorUsername string              `bson:"authorUsername" json:"authorUsername"`
+	MessageText    string              `bson:"messageText" json:"messageText"`
+	Source         string              `bson:"source" json:"source"`
+	Reason         string              `bson:"reason" json:"reason"`
+	MatchedWords   []string            `bson:"matchedWords,omitempty" json:"matchedWords,omitempty"`
+	Reporters      []Reporter          `bson:"reporters,omitempty" json:"reporters,omitempty"`
+	ReportCount    int                 `bson:"reportCount" json:"reportCount"`
+	Status         string              `bson:"status" json:"status"`
+	Resolution     string              `bson:"resolution,omitempty" json:"resolution,omitempty"`
+	ResolutionNote string              `bson:"resolutionNote,omitempty" json:"resolutionNote,omitempty"`
+	ResolvedBy     *primitive.ObjectID `bson:"resolvedBy,omitempty" json:"resolvedBy,omitempty"`
+	ResolvedAt     *time.Time          `bson:"resolvedAt,omitempty" json:"resolvedAt,omitempty"`
+	CreatedAt      time.Time           `bson:"createdAt" json:"createdAt"`
+}
@@ -0,0 +1,94 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Permissions granted by roles
+const (
+	PermChannelsCreate     = "channels.create"
+	PermChannelsManage     = "channels.manage" // Kick members, moderators, posting policy, slow mode
+	PermWordFiltersManage  = "word_filters.manage"
+	PermReportsManage      = "reports.manage"
+	PermUsersView          = "users.view"
+	PermUsersMute          = "users.mute" // Mute, unmute and reset strikes
+	PermUsersBan           = "users.ban"
+	PermUsersPasswordReset = "users.password_reset"
+	PermGlobalMuteManage   = "global_mute.manage"
+	PermAuditView          = "audit.view"
+	PermLoginLockouts      = "auth.lockouts"
+	PermRolesManage        = "roles.manage"
+	PermBotsManage         = "bots.manage"
+	PermModerationExempt   = "moderation.exempt" // Not held back by mutes, filters, slow mode or read-only channels
+)
+
+// PermissionInfo describes a permission for the role editor
+type PermissionInfo struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// AllPermissions lists every permission
+var AllPermissions = []PermissionInfo{
+	{PermChannelsCreate, "创建频道"},
+	{PermChannelsManage, "管理频道成员、频道管理员、发言权限和慢速模式"},
+	{PermWordFiltersManage, "管理敏感词"},
+	{PermReportsManage, "处理举报和待审核消息"},
+	{PermUsersView, "查看用户列表和违规记录"},
+	{PermUsersMute, "禁言、解除禁言和清零违规记录"},
+	{PermUsersBan, "封禁和解封用户"},
+	{PermUsersPasswordReset, "签发密码重置令牌"},
+	{PermGlobalMuteManage, "管理全局禁言和定时计划"},
+	{PermAuditView, "查看和导出审计日志"},
+	{PermLoginLockouts, "查看和解除登录锁定"},
+	{PermRolesManage, "管理角色并为用户分配角色"},
+	{PermBotsManage, "管理机器人账号、API 密钥和机器人所在频道"},
+	{PermModerationExempt, "不受禁言、敏感词、慢速模式和只读频道限制"},
+}
+
+// IsPermission reports whether name is a known permission
+func IsPermission(name string) bool {
+	for _, p := range AllPermissions {
+		if p.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Built-in roles. Every user has exactly one role; RoleUser has no
+// permissions and RoleAdmin has all of them.
+const (
+	RoleUser      = "user"
+	RoleAdmin     = "admin"
+	RoleModerator = "moderator"
+)
+
+// Role sources, recording who manages a user's role
+const (
+	RoleSourceManual = ""    // Assigned by an admin
+	RoleSourceSSO    = "sso" // Granted by the SSO group mapping
+)
+
+// Role is a named set of permissions
+type Role struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name        string             `bson:"name" json:"name"`
+	Description string             `bson:"description,omitempty" json:"description"`
+	Permissions []string           `bson:"permissions" json:"permissions"`
+	System      bool               `bson:"system" json:"system"` // Built-in, cannot be edited or deleted
+	CreatedAt   time.Time          `bson:"createdAt" json:"createdAt"`
+	UpdatedAt   time.Time          `bson:"updatedAt" json:"updatedAt"`
+}
+
+// HasPermission reports whether the role grants a permission
+func (r *Role) HasPermission(permission string) bool {
+	for _, p := range r.Permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
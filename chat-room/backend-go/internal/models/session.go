@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Session is a single login of a user on a device. Its ID is the family ID
+// of the refresh tokens issued for it and the sid claim of its access tokens.
+type Session struct {
+	ID          primitive.ObjectID `bson:"_id" json:"id"`
+	UserID      primitive.ObjectID `bson:"userId" json:"userId"`
+	DeviceLabel string             `bson:"deviceLabel" json:"deviceLabel"`
+	UserAgent   string             `bson:"userAgent" json:"userAgent"`
+	IP          string             `bson:"ip" json:"ip"`
+	CreatedAt   time.Time          `bson:"createdAt" json:"createdAt"`
+	LastUsedAt  time.Time          `bson:"lastUsedAt" json:"lastUsedAt"` // Last login or token refresh
+	ExpiresAt   time.Time          `bson:"expiresAt" json:"expiresAt"`   // Expiry of the latest refresh token
+	TwoFactor   bool               `bson:"twoFactor" json:"twoFactor"`   // Verified with a second factor
+	RevokedAt   *time.Time         `bson:"revokedAt,omitempty" json:"revokedAt,omitempty"`
+}
+
+// SessionResponse is a session returned to its user
+type SessionResponse struct {
+	ID          string    `json:"id"`
+	DeviceLabel string    `json:"deviceLabel"`
+	UserAgent   string    `json:"userAgent"`
+	IP          string    `json:"ip"`
+	CreatedAt   time.Time `json:"createdAt"`
+	LastUsedAt  time.Time `json:"lastUsedAt"`
+	TwoFactor   bool      `json:"twoFactor"`
+	Current     bool      `json:"current"` // The session making the request
+}
+
+// ToResponse converts Session to SessionResponse
+func (s *Session) ToResponse(currentID string) *SessionResponse {
+	return &SessionResponse{
+		ID:          s.ID.Hex(),
+		DeviceLabel: s.DeviceLabel,
+		UserAgent:   s.UserAgent,
+		IP:          s.IP,
+		CreatedAt:   s.CreatedAt,
+		LastUsedAt:  s.LastUsedAt,
+		TwoFactor:   s.TwoFactor,
+		Current:     s.ID.Hex() == currentID,
+	}
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Escalation levels applied when a strike is recorded
+const (
+	EscalationNone    = ""
+	EscalationWarning = "warning"
+	EscalationMute    = "mute"
+	EscalationBan     = "ban"
+)
+
+// Strike records a blocked message counted against a user
+type Strike struct {
+	ID         primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	UserID     primitive.ObjectID  `bson:"userId" json:"userId"`
+	ChannelID  *primitive.ObjectID `bson:"channelId,omitempty" json:"channelId,omitempty"`
+	Reason     string              `bson:"reason" json:"reason"`
+	Words      []string            `bson:"words,omitempty" json:"words,omitempty"` // Filter words that matched
+	Escalation string              `bson:"escalation,omitempty" json:"escalation,omitempty"`
+	Cleared    bool                `bson:"cleared" json:"cleared"` // Reset by an admin
+	CreatedAt  time.Time           `bson:"createdAt" json:"createdAt"`
+}
@@ -13,23 +13,70 @@ type User struct {
 	Password    string              `bson:"password" json:"-"` // Never expose password in JSON
 	CreatedAt   time.Time           `bson:"createdAt" json:"createdAt"`
 	LastLogin   time.Time           `bson:"lastLogin" json:"lastLogin"`
-	Role        string              `bson:"role" json:"role"` // "user" | "admin"
+	Role        string              `bson:"role" json:"role"` // Name of a Role, "user" by default
+	RoleSource  string              `bson:"roleSource,omitempty" json:"roleSource,omitempty"`
 	IsMuted     bool                `bson:"isMuted" json:"isMuted"`
 	MutedUntil  *time.Time          `bson:"mutedUntil,omitempty" json:"mutedUntil,omitempty"`
 	MutedBy     *primitive.ObjectID `bson:"mutedBy,omitempty" json:"mutedBy,omitempty"`
 	MutedReason string              `bson:"mutedReason,omitempty" json:"mutedReason,omitempty"`
+	IsBanned    bool                `bson:"isBanned" json:"isBanned"`
+	BannedUntil *time.Time          `bson:"bannedUntil,omitempty" json:"bannedUntil,omitempty"`
+	BannedBy    *primitive.ObjectID `bson:"bannedBy,omitempty" json:"bannedBy,omitempty"`
+	BanReason   string              `bson:"banReason,omitempty" json:"banReason,omitempty"`
+	LastLoginIP string              `bson:"lastLoginIp,omitempty" json:"-"`
+
+	// Tokens issued before this time are rejected (set when a user is banned)
+	TokensRevokedAt *time.Time `bson:"tokensRevokedAt,omitempty" json:"-"`
+
+	PasswordChangedAt *time.Time `bson:"passwordChangedAt,omitempty" json:"passwordChangedAt,omitempty"`
+
+	// TOTP two-factor authentication. The pending secret is set during
+	// enrollment until the first code is verified.
+	TOTPEnabled       bool     `bson:"totpEnabled,omitempty" json:"totpEnabled"`
+	TOTPSecret        string   `bson:"totpSecret,omitempty" json:"-"`
+	TOTPPendingSecret string   `bson:"totpPendingSecret,omitempty" json:"-"`
+	TOTPLastStep      int64    `bson:"totpLastStep,omitempty" json:"-"`  // Last accepted time step, so a code works once
+	RecoveryCodes     []string `bson:"recoveryCodes,omitempty" json:"-"` // SHA-256 hashes of unused codes
+
+	// Single sign-on identity, nil for accounts that only log in locally.
+	// Accounts created by SSO have no password.
+	SSO *SSOIdentity `bson:"sso,omitempty" json:"-"`
+
+	// Bot accounts have no password and authenticate with API keys
+	IsBot      bool                `bson:"isBot,omitempty" json:"isBot"`
+	BotOwnerID *primitive.ObjectID `bson:"botOwnerId,omitempty" json:"botOwnerId,omitempty"` // Admin who created the bot
+}
+
+// SSOIdentity links a user to an account at an OpenID Connect provider
+type SSOIdentity struct {
+	Issuer   string    `bson:"issuer" json:"issuer"`
+	Subject  string    `bson:"subject" json:"subject"` // Stable user ID at the provider
+	Email    string    `bson:"email,omitempty" json:"email,omitempty"`
+	LinkedAt time.Time `bson:"linkedAt" json:"linkedAt"`
+}
+
+// IsBanActive reports whether the user is currently banned
+func (u *User) IsBanActive() bool {
+	if !u.IsBanned {
+		return false
+	}
+	return u.BannedUntil == nil || u.BannedUntil.After(time.Now())
 }
 
 // UserResponse is the user data returned to clients (without sensitive info)
 type UserResponse struct {
-	ID       string `json:"id"`
-	Username string `json:"username"`
+	ID               string `json:"id"`
+	Username         string `json:"username"`
+	TwoFactorEnabled bool   `json:"twoFactorEnabled"`
+	SSOLinked        bool   `json:"ssoLinked"`
 }
 
 // ToResponse converts User to UserResponse
 func (u *User) ToResponse() *UserResponse {
 	return &UserResponse{
-		ID:       u.ID.Hex(),
-		Username: u.Username,
+		ID:               u.ID.Hex(),
+		Username:         u.Username,
+		TwoFactorEnabled: u.TOTPEnabled,
+		SSOLinked:        u.SSO != nil,
 	}
 }
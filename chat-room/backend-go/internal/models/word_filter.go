@@ -6,29 +6,94 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// Word filter match types
+const (
+	MatchTypeSubstring = "substring" // Match anywhere, after normalization (default)
+	MatchTypeWord      = "word"      // Match whole words only
+	MatchTypeRegex     = "regex"     // Word is a regular expression
+)
+
+// Word filter actions, from mildest to strictest
+const (
+	FilterActionMask   = "mask"   // Replace the match with asterisks and deliver
+	FilterActionReview = "review" // Deliver, but queue the message for moderator review
+	FilterActionBlock  = "block"  // Do not deliver (default)
+	FilterActionMute   = "mute"   // Do not deliver and mute the sender for MuteDuration minutes
+)
+
+// DefaultFilterMuteDuration is used when a mute filter has no duration
+const DefaultFilterMuteDuration = 10
+
+// FilterActionRank orders actions by strictness; the strictest hit wins
+var FilterActionRank = map[string]int{
+	FilterActionMask:   1,
+	FilterActionReview: 2,
+	FilterActionBlock:  3,
+	FilterActionMute:   4,
+}
+
 // WordFilter represents a blocked word for content filtering
 type WordFilter struct {
-	ID       primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	Word     string             `bson:"word" json:"word"`
-	AddedBy  primitive.ObjectID `bson:"addedBy" json:"addedBy"`
-	AddedAt  time.Time          `bson:"addedAt" json:"addedAt"`
-	IsActive bool               `bson:"isActive" json:"isActive"`
+	ID           primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	Word         string              `bson:"word" json:"word"`
+	MatchType    string              `bson:"matchType,omitempty" json:"matchType"`
+	Variants     []string            `bson:"variants,omitempty" json:"variants,omitempty"` // Extra spellings such as pinyin
+	IsAllow      bool                `bson:"isAllow" json:"isAllow"`                       // Allowlist exception instead of a blocked word
+	Action       string              `bson:"action,omitempty" json:"action"`
+	MuteDuration int                 `bson:"muteDuration,omitempty" json:"muteDuration,omitempty"` // Minutes, for the mute action
+	ChannelID    *primitive.ObjectID `bson:"channelId,omitempty" json:"channelId,omitempty"`       // Nil for global filters
+	AddedBy      primitive.ObjectID  `bson:"addedBy" json:"addedBy"`
+	AddedAt      time.Time           `bson:"addedAt" json:"addedAt"`
+	IsActive     bool                `bson:"isActive" json:"isActive"`
+}
+
+// GetMatchType returns the match type, defaulting to substring for older filters
+func (wf *WordFilter) GetMatchType() string {
+	if wf.MatchType == "" {
+		return MatchTypeSubstring
+	}
+	return wf.MatchType
+}
+
+// GetAction returns the action, defaulting to block for older filters
+func (wf *WordFilter) GetAction() string {
+	if wf.Action == "" {
+		return FilterActionBlock
+	}
+	return wf.Action
 }
 
 // WordFilterResponse is the word filter data returned to clients
 type WordFilterResponse struct {
-	ID      string    `json:"id"`
-	Word    string    `json:"word"`
-	AddedBy string    `json:"addedBy"`
-	AddedAt time.Time `json:"addedAt"`
+	ID           string    `json:"id"`
+	Word         string    `json:"word"`
+	MatchType    string    `json:"matchType"`
+	Variants     []string  `json:"variants,omitempty"`
+	IsAllow      bool      `json:"isAllow"`
+	Action       string    `json:"action"`
+	MuteDuration int       `json:"muteDuration,omitempty"`
+	ChannelID    string    `json:"channelId,omitempty"`
+	AddedBy      string    `json:"addedBy"`
+	AddedAt      time.Time `json:"addedAt"`
 }
 
 // ToResponse converts WordFilter to WordFilterResponse
 func (wf *WordFilter) ToResponse() *WordFilterResponse {
+	channelID := ""
+	if wf.ChannelID != nil {
+		channelID = wf.ChannelID.Hex()
+	}
+
 	return &WordFilterResponse{
-		ID:      wf.ID.Hex(),
-		Word:    wf.Word,
-		AddedBy: wf.AddedBy.Hex(),
-		AddedAt: wf.AddedAt,
+		ID:           wf.ID.Hex(),
+		Word:         wf.Word,
+		MatchType:    wf.GetMatchType(),
+		Variants:     wf.Variants,
+		IsAllow:      wf.IsAllow,
+		Action:       wf.GetAction(),
+		MuteDuration: wf.MuteDuration,
+		ChannelID:    channelID,
+		AddedBy:      wf.AddedBy.Hex(),
+		AddedAt:      wf.AddedAt,
 	}
 }
@@ -15,8 +15,8 @@ import (
 
 // AdminRepository handles admin-related data access
 type AdminRepository struct {
-	wordFilterCollection    *mongo.Collection
-	globalMuteCollection    *mongo.Collection
+	wordFilterCollection *mongo.Collection
+	globalMuteCollection *mongo.Collection
 }
 
 // NewAdminRepository creates a new AdminRepository
@@ -49,9 +49,7 @@ func NewAdminRepository(db *mongo.Database) *AdminRepository {
 
 // CreateWordFilter creates a new word filter
 func (r *AdminRepository) CreateWordFilter(ctx context.Context, filter *models.WordFilter) error {
-	filter.AddedAt = time.Now()
-	filter.IsActive = true
-	filter.Word = strings.ToLower(strings.TrimSpace(filter.Word))
+	prepareWordFilter(filter, time.Now())
 
 	result, err := r.wordFilterCollection.InsertOne(ctx, filter)
 	if err != nil {
@@ -62,6 +60,42 @@ func (r *AdminRepository) CreateWordFilter(ctx context.Context, filter *models.W
 	return nil
 }
 
+// CreateWordFilters inserts many word filters at once (bulk import)
+func (r *AdminRepository) CreateWordFilters(ctx context.Context, filters []*models.WordFilter) error {
+	if len(filters) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	docs := make([]interface{}, len(filters))
+	for i, filter := range filters {
+		prepareWordFilter(filter, now)
+		docs[i] = filter
+	}
+
+	result, err := r.wordFilterCollection.InsertMany(ctx, docs)
+	if err != nil {
+		return fmt.Errorf("failed to create word filters: %w", err)
+	}
+
+	for i, id := range result.InsertedIDs {
+		filters[i].ID = id.(primitive.ObjectID)
+	}
+	return nil
+}
+
+// prepareWordFilter sets defaults and normalizes a word filter before insert
+func prepareWordFilter(filter *models.WordFilter, now time.Time) {
+	filter.AddedAt = now
+	filter.IsActive = true
+	filter.Word = strings.TrimSpace(filter.Word)
+
+	// Regular expressions are case-sensitive syntax, keep them as written
+	if filter.GetMatchType() != models.MatchTypeRegex {
+		filter.Word = strings.ToLower(filter.Word)
+	}
+}
+
 // GetActiveWordFilters returns all active word filters
 func (r *AdminRepository) GetActiveWordFilters(ctx context.Context) ([]*models.WordFilter, error) {
 	cursor, err := r.wordFilterCollection.Find(ctx, bson.M{"isActive": true})
@@ -78,11 +112,20 @@ func (r *AdminRepository) GetActiveWordFilters(ctx context.Context) ([]*models.W
 	return filters, nil
 }
 
-// GetAllWordFilters returns all word filters (for admin view)
+// GetAllWordFilters returns all active global word filters (for admin view)
 func (r *AdminRepository) GetAllWordFilters(ctx context.Context) ([]*models.WordFilter, error) {
+	return r.GetChannelWordFilters(ctx, nil)
+}
+
+// GetChannelWordFilters returns the active word filters scoped to a channel
+// (global filters if channelID is nil), newest first
+func (r *AdminRepository) GetChannelWordFilters(ctx context.Context, channelID *primitive.ObjectID) ([]*models.WordFilter, error) {
 	opts := options.Find().SetSort(bson.D{{Key: "addedAt", Value: -1}})
 
-	cursor, err := r.wordFilterCollection.Find(ctx, bson.M{"isActive": true}, opts)
+	// A nil channelId is encoded as null, which also matches filters without the field
+	filter := bson.M{"isActive": true, "channelId": channelID}
+
+	cursor, err := r.wordFilterCollection.Find(ctx, filter, opts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to find word filters: %w", err)
 	}
@@ -96,6 +139,19 @@ func (r *AdminRepository) GetAllWordFilters(ctx context.Context) ([]*models.Word
 	return filters, nil
 }
 
+// FindWordFilterByID finds a word filter by ID
+func (r *AdminRepository) FindWordFilterByID(ctx context.Context, filterID primitive.ObjectID) (*models.WordFilter, error) {
+	var filter models.WordFilter
+	err := r.wordFilterCollection.FindOne(ctx, bson.M{"_id": filterID}).Decode(&filter)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find word filter: %w", err)
+	}
+	return &filter, nil
+}
+
 // DeactivateWordFilter soft-deletes a word filter
 func (r *AdminRepository) DeactivateWordFilter(ctx context.Context, filterID primitive.ObjectID) error {
 	_, err := r.wordFilterCollection.UpdateOne(
@@ -137,18 +193,22 @@ func (r *AdminRepository) GetGlobalMuteStatus(ctx context.Context) (*models.Glob
 }
 
 // UpdateGlobalMuteStatus updates the global mute status
-func (r *AdminRepository) UpdateGlobalMuteStatus(ctx context.Context, enabled bool, enabledBy *primitive.ObjectID, reason string) error {
+func (r *AdminRepository) UpdateGlobalMuteStatus(ctx context.Context, status *models.GlobalMuteStatus) error {
 	var enabledAt *time.Time
-	if enabled {
+	if status.IsEnabled {
 		now := time.Now()
 		enabledAt = &now
 	}
 
 	update := bson.M{
-		"isEnabled": enabled,
-		"enabledBy": enabledBy,
-		"enabledAt": enabledAt,
-		"reason":    reason,
+		"isEnabled":         status.IsEnabled,
+		"enabledBy":         status.EnabledBy,
+		"enabledAt":         enabledAt,
+		"reason":            status.Reason,
+		"source":            status.Source,
+		"scheduleId":        status.ScheduleID,
+		"expiresAt":         status.ExpiresAt,
+		"skipScheduleUntil": status.SkipScheduleUntil,
 	}
 
 	// Use upsert to create if not exists
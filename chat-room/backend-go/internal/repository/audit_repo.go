@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"chat-room-backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AuditLogFilter narrows down audit log queries (zero values are ignored)
+type AuditLogFilter struct {
+	ActorID  *primitive.ObjectID
+	TargetID *primitive.ObjectID
+	Action   string
+	From     *time.Time
+	To       *time.Time
+}
+
+// AuditRepository handles audit log data access.
+// The collection is append-only: there are no update or delete operations.
+type AuditRepository struct {
+	collection *mongo.Collection
+}
+
+// NewAuditRepository creates a new AuditRepository
+func NewAuditRepository(db *mongo.Database) *AuditRepository {
+	collection := db.Collection("auditlogs")
+
+	// Create indexes
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// timestamp index (descending)
+	collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "timestamp", Value: -1}},
+	})
+
+	// actorId index
+	collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "actorId", Value: 1}},
+	})
+
+	// targetId index
+	collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "targetId", Value: 1}},
+	})
+
+	// action index
+	collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "action", Value: 1}},
+	})
+
+	return &AuditRepository{collection: collection}
+}
+
+// Create appends a new audit log entry
+func (r *AuditRepository) Create(ctx context.Context, entry *models.AuditLog) error {
+	entry.Timestamp = time.Now()
+
+	result, err := r.collection.InsertOne(ctx, entry)
+	if err != nil {
+		return fmt.Errorf("failed to create audit log: %w", err)
+	}
+
+	entry.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// Find returns audit log entries matching the filter, newest first.
+// skip and limit paginate the result; limit <= 0 returns everything.
+func (r *AuditRepository) Find(ctx context.Context, filter *AuditLogFilter, skip, limit int) ([]*models.AuditLog, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "timestamp", Value: -1}})
+	if skip > 0 {
+		opts.SetSkip(int64(skip))
+	}
+	if limit > 0 {
+		opts.SetLimit(int64(limit))
+	}
+
+	cursor, err := r.collection.Find(ctx, filter.toQuery(), opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find audit logs: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var entries []*models.AuditLog
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, fmt.Errorf("failed to decode audit logs: %w", err)
+	}
+
+	return entries, nil
+}
+
+// Count counts audit log entries matching the filter
+func (r *AuditRepository) Count(ctx context.Context, filter *AuditLogFilter) (int64, error) {
+	count, err := r.collection.CountDocuments(ctx, filter.toQuery())
+	if err != nil {
+		return 0, fmt.Errorf("failed to count audit logs: %w", err)
+	}
+	return count, nil
+}
+
+// toQuery converts the filter to a MongoDB query
+func (f *AuditLogFilter) toQuery() bson.M {
+	query := bson.M{}
+	if f == nil {
+		return query
+	}
+
+	if f.ActorID != nil {
+		query["actorId"] = *f.ActorID
+	}
+	if f.TargetID != nil {
+		query["targetId"] = *f.TargetID
+	}
+	if f.Action != "" {
+		query["action"] = f.Action
+	}
+
+	timestamp := bson.M{}
+	if f.From != nil {
+		timestamp["$gte"] = *f.From
+	}
+	if f.To != nil {
+		timestamp["$lte"] = *f.To
+	}
+	if len(timestamp) > 0 {
+		query["timestamp"] = timestamp
+	}
+
+	return query
+}
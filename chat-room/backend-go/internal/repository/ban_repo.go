@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"chat-room-backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// BanRepository handles IP ban data access
+type BanRepository struct {
+	collection *mongo.Collection
+}
+
+// NewBanRepository creates a new BanRepository
+func NewBanRepository(db *mongo.Database) *BanRepository {
+	collection := db.Collection("ipbans")
+
+	// Create indexes
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// ip + isActive index
+	collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "ip", Value: 1},
+			{Key: "isActive", Value: 1},
+		},
+	})
+
+	// userId index
+	collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "userId", Value: 1}},
+	})
+
+	return &BanRepository{collection: collection}
+}
+
+// Create creates a new IP ban
+func (r *BanRepository) Create(ctx context.Context, ban *models.IPBan) error {
+	ban.CreatedAt = time.Now()
+	ban.IsActive = true
+
+	result, err := r.collection.InsertOne(ctx, ban)
+	if err != nil {
+		return fmt.Errorf("failed to create IP ban: %w", err)
+	}
+
+	ban.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// FindActive returns all active IP bans
+func (r *BanRepository) FindActive(ctx context.Context) ([]*models.IPBan, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"isActive": true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find IP bans: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var bans []*models.IPBan
+	if err := cursor.All(ctx, &bans); err != nil {
+		return nil, fmt.Errorf("failed to decode IP bans: %w", err)
+	}
+
+	return bans, nil
+}
+
+// FindActiveByIP finds an active ban for an IP address
+func (r *BanRepository) FindActiveByIP(ctx context.Context, ip string) (*models.IPBan, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"ip": ip, "isActive": true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find IP ban: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var bans []*models.IPBan
+	if err := cursor.All(ctx, &bans); err != nil {
+		return nil, fmt.Errorf("failed to decode IP bans: %w", err)
+	}
+
+	for _, ban := range bans {
+		if ban.IsBanActive() {
+			return ban, nil
+		}
+	}
+	return nil, nil
+}
+
+// DeactivateByUserID lifts all IP bans created by a user's ban
+func (r *BanRepository) DeactivateByUserID(ctx context.Context, userID primitive.ObjectID) error {
+	_, err := r.collection.UpdateMany(
+		ctx,
+		bson.M{"userId": userID, "isActive": true},
+		bson.M{"$set": bson.M{"isActive": false}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to deactivate IP bans: %w", err)
+	}
+	return nil
+}
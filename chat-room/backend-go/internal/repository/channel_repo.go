@@ -81,7 +81,7 @@ func (r *ChannelRepository) FindDefault(ctx context.Context) (*models.Channel, e
 func (r *ChannelRepository) FindAll(ctx context.Context) ([]*models.Channel, error) {
 	opts := options.Find().SetSort(bson.D{
 		{Key: "isDefault", Value: -1}, // Default channels first
-		{Key: "name", Value: 1},        // Then by name
+		{Key: "name", Value: 1},       // Then by name
 	})
 
 	cursor, err := r.collection.Find(ctx, bson.M{}, opts)
@@ -98,6 +98,44 @@ func (r *ChannelRepository) FindAll(ctx context.Context) ([]*models.Channel, err
 	return channels, nil
 }
 
+// SetSlowMode sets a channel's slow mode interval, 0 turns it off
+func (r *ChannelRepository) SetSlowMode(ctx context.Context, id primitive.ObjectID, seconds int) error {
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+		"$set": bson.M{"slowModeSeconds": seconds},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set slow mode: %w", err)
+	}
+	return nil
+}
+
+// SetPostingPolicy sets who may post in a channel
+func (r *ChannelRepository) SetPostingPolicy(ctx context.Context, id primitive.ObjectID, policy string) error {
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+		"$set": bson.M{"postingPolicy": policy},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set posting policy: %w", err)
+	}
+	return nil
+}
+
+// FindByPostingPolicy finds channels with a posting policy
+func (r *ChannelRepository) FindByPostingPolicy(ctx context.Context, policy string) ([]*models.Channel, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"postingPolicy": policy})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find channels: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var channels []*models.Channel
+	if err := cursor.All(ctx, &channels); err != nil {
+		return nil, fmt.Errorf("failed to decode channels: %w", err)
+	}
+
+	return channels, nil
+}
+
 // FindByIDs finds channels by IDs
 func (r *ChannelRepository) FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]*models.Channel, error) {
 	cursor, err := r.collection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
@@ -207,6 +245,49 @@ func (r *ChannelMemberRepository) Delete(ctx context.Context, userID, channelID
 	return nil
 }
 
+// DeleteByUser removes all of a user's channel memberships
+func (r *ChannelMemberRepository) DeleteByUser(ctx context.Context, userID primitive.ObjectID) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"userId": userID})
+	if err != nil {
+		return fmt.Errorf("failed to delete channel members: %w", err)
+	}
+	return nil
+}
+
+// SetRole sets a member's role in a channel.
+// Returns false if the user is not a member.
+func (r *ChannelMemberRepository) SetRole(ctx context.Context, userID, channelID primitive.ObjectID, role string) (bool, error) {
+	result, err := r.collection.UpdateOne(ctx, bson.M{
+		"userId":    userID,
+		"channelId": channelID,
+	}, bson.M{
+		"$set": bson.M{"role": role},
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to set member role: %w", err)
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// FindByChannelAndRoles finds the members of a channel with one of the roles
+func (r *ChannelMemberRepository) FindByChannelAndRoles(ctx context.Context, channelID primitive.ObjectID, roles []string) ([]*models.ChannelMember, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{
+		"channelId": channelID,
+		"role":      bson.M{"$in": roles},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find channel members: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var members []*models.ChannelMember
+	if err := cursor.All(ctx, &members); err != nil {
+		return nil, fmt.Errorf("failed to decode channel members: %w", err)
+	}
+
+	return members, nil
+}
+
 // CountByChannelID counts members in a channel
 func (r *ChannelMemberRepository) CountByChannelID(ctx context.Context, channelID primitive.ObjectID) (int64, error) {
 	count, err := r.collection.CountDocuments(ctx, bson.M{"channelId": channelID})
@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"chat-room-backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// GlobalMuteScheduleRepository handles global mute schedule data access
+type GlobalMuteScheduleRepository struct {
+	collection *mongo.Collection
+}
+
+// NewGlobalMuteScheduleRepository creates a new GlobalMuteScheduleRepository
+func NewGlobalMuteScheduleRepository(db *mongo.Database) *GlobalMuteScheduleRepository {
+	return &GlobalMuteScheduleRepository{collection: db.Collection("globalmuteschedules")}
+}
+
+// Create creates a new schedule
+func (r *GlobalMuteScheduleRepository) Create(ctx context.Context, schedule *models.GlobalMuteSchedule) error {
+	schedule.CreatedAt = time.Now()
+
+	result, err := r.collection.InsertOne(ctx, schedule)
+	if err != nil {
+		return fmt.Errorf("failed to create schedule: %w", err)
+	}
+
+	schedule.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// FindByID finds a schedule by ID
+func (r *GlobalMuteScheduleRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.GlobalMuteSchedule, error) {
+	var schedule models.GlobalMuteSchedule
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&schedule)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find schedule: %w", err)
+	}
+	return &schedule, nil
+}
+
+// FindAll returns every schedule, oldest first
+func (r *GlobalMuteScheduleRepository) FindAll(ctx context.Context) ([]*models.GlobalMuteSchedule, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}})
+
+	cursor, err := r.collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find schedules: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var schedules []*models.GlobalMuteSchedule
+	if err := cursor.All(ctx, &schedules); err != nil {
+		return nil, fmt.Errorf("failed to decode schedules: %w", err)
+	}
+
+	return schedules, nil
+}
+
+// Delete removes a schedule
+func (r *GlobalMuteScheduleRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return fmt.Errorf("failed to delete schedule: %w", err)
+	}
+	return nil
+}
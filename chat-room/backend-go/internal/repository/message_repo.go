@@ -92,6 +92,19 @@ func (r *MessageRepository) FindByChannelID(ctx context.Context, channelID primi
 	return messages, nil
 }
 
+// FindByID finds a message by ID
+func (r *MessageRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Message, error) {
+	var message models.Message
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&message)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find message: %w", err)
+	}
+	return &message, nil
+}
+
 // SoftDelete marks a message as deleted (soft delete)
 func (r *MessageRepository) SoftDelete(ctx context.Context, messageID primitive.ObjectID) error {
 	_, err := r.collection.UpdateOne(
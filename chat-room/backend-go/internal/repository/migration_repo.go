@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// MigrationRepository records one-time data migrations that have run
+type MigrationRepository struct {
+	collection *mongo.Collection
+}
+
+// NewMigrationRepository creates a new MigrationRepository
+func NewMigrationRepository(db *mongo.Database) *MigrationRepository {
+	return &MigrationRepository{collection: db.Collection("migrations")}
+}
+
+// IsApplied reports whether a migration has run
+func (r *MigrationRepository) IsApplied(ctx context.Context, name string) (bool, error) {
+	count, err := r.collection.CountDocuments(ctx, bson.M{"_id": name})
+	if err != nil {
+		return false, fmt.Errorf("failed to find migration: %w", err)
+	}
+	return count > 0, nil
+}
+
+// MarkApplied records that a migration has run
+func (r *MigrationRepository) MarkApplied(ctx context.Context, name string) error {
+	_, err := r.collection.InsertOne(ctx, bson.M{"_id": name, "appliedAt": time.Now()})
+	if err != nil && !mongo.IsDuplicateKeyError(err) {
+		return fmt.Errorf("failed to record migration: %w", err)
+	}
+	return nil
+}
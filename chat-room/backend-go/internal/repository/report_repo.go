@@ -0,0 +1,165 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"chat-room-backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ReportRepository handles moderator review queue data access
+type ReportRepository struct {
+	collection *mongo.Collection
+}
+
+// NewReportRepository creates a new ReportRepository
+func NewReportRepository(db *mongo.Database) *ReportRepository {
+	collection := db.Collection("reports")
+
+	// Create indexes
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// status + createdAt index
+	collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "status", Value: 1},
+			{Key: "createdAt", Value: -1},
+		},
+	})
+
+	// At most one open report per message
+	collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "messageId", Value: 1}},
+		Options: options.Index().
+			SetUnique(true).
+			SetPartialFilterExpression(bson.M{"status": models.ReportStatusOpen}),
+	})
+
+	return &ReportRepository{collection: collection}
+}
+
+// Create creates a new report
+func (r *ReportRepository) Create(ctx context.Context, report *models.Report) error {
+	report.CreatedAt = time.Now()
+	if report.Status == "" {
+		report.Status = models.ReportStatusOpen
+	}
+
+	result, err := r.collection.InsertOne(ctx, report)
+	if err != nil {
+		return fmt.Errorf("failed to create report: %w", err)
+	}
+
+	report.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// FindByStatus returns reports with a status, newest first (all statuses if empty)
+func (r *ReportRepository) FindByStatus(ctx context.Context, status string, limit int) ([]*models.Report, error) {
+	if limit <= 0 {
+		limit = 100 // Default limit
+	}
+
+	filter := bson.M{}
+	if status != "" {
+		filter["status"] = status
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdAt", Value: -1}}).
+		SetLimit(int64(limit))
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find reports: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var reports []*models.Report
+	if err := cursor.All(ctx, &reports); err != nil {
+		return nil, fmt.Errorf("failed to decode reports: %w", err)
+	}
+
+	return reports, nil
+}
+
+// FindByID finds a report by ID
+func (r *ReportRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Report, error) {
+	var report models.Report
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&report)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find report: %w", err)
+	}
+	return &report, nil
+}
+
+// FindOpenByMessageID finds the open report for a message
+func (r *ReportRepository) FindOpenByMessageID(ctx context.Context, messageID primitive.ObjectID) (*models.Report, error) {
+	var report models.Report
+	err := r.collection.FindOne(ctx, bson.M{"messageId": messageID, "status": models.ReportStatusOpen}).Decode(&report)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find report: %w", err)
+	}
+	return &report, nil
+}
+
+// AddReporter adds a reporter to the open report for a message.
+// Returns the updated report, or nil if there is no open report or the
+// user has already reported the message.
+func (r *ReportRepository) AddReporter(ctx context.Context, messageID primitive.ObjectID, reporter models.Reporter) (*models.Report, error) {
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var report models.Report
+	err := r.collection.FindOneAndUpdate(
+		ctx,
+		bson.M{
+			"messageId":        messageID,
+			"status":           models.ReportStatusOpen,
+			"reporters.userId": bson.M{"$ne": reporter.UserID},
+		},
+		bson.M{
+			"$push": bson.M{"reporters": reporter},
+			"$inc":  bson.M{"reportCount": 1},
+		},
+		opts,
+	).Decode(&report)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to add reporter: %w", err)
+	}
+	return &report, nil
+}
+
+// Resolve closes an open report. Returns false if it was not open.
+func (r *ReportRepository) Resolve(ctx context.Context, id primitive.ObjectID, status, resolution, note string, resolvedBy primitive.ObjectID) (bool, error) {
+	now := time.Now()
+	result, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": id, "status": models.ReportStatusOpen},
+		bson.M{"$set": bson.M{
+			"status":         status,
+			"resolution":     resolution,
+			"resolutionNote": note,
+			"resolvedBy":     resolvedBy,
+			"resolvedAt":     now,
+		}},
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to resolve report: %w", err)
+	}
+	return result.ModifiedCount == 1, nil
+}
@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"chat-room-backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// RoleRepository handles role data access
+type RoleRepository struct {
+	collection *mongo.Collection
+}
+
+// NewRoleRepository creates a new RoleRepository
+func NewRoleRepository(db *mongo.Database) *RoleRepository {
+	collection := db.Collection("roles")
+
+	// Create indexes
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Role names are unique
+	collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "name", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+
+	return &RoleRepository{collection: collection}
+}
+
+// FindAll returns every role, oldest first
+func (r *RoleRepository) FindAll(ctx context.Context) ([]*models.Role, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}})
+
+	cursor, err := r.collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find roles: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var roles []*models.Role
+	if err := cursor.All(ctx, &roles); err != nil {
+		return nil, fmt.Errorf("failed to decode roles: %w", err)
+	}
+
+	return roles, nil
+}
+
+// FindByName finds a role by name
+func (r *RoleRepository) FindByName(ctx context.Context, name string) (*models.Role, error) {
+	var role models.Role
+	err := r.collection.FindOne(ctx, bson.M{"name": name}).Decode(&role)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find role: %w", err)
+	}
+	return &role, nil
+}
+
+// Create creates a new role. A taken name fails with a duplicate key error.
+func (r *RoleRepository) Create(ctx context.Context, role *models.Role) error {
+	role.CreatedAt = time.Now()
+	role.UpdatedAt = role.CreatedAt
+
+	result, err := r.collection.InsertOne(ctx, role)
+	if err != nil {
+		return fmt.Errorf("failed to create role: %w", err)
+	}
+
+	role.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// Update changes a role's description and permissions and returns the
+// updated role, or nil if there is no such role
+func (r *RoleRepository) Update(ctx context.Context, name, description string, permissions []string) (*models.Role, error) {
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var role models.Role
+	err := r.collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"name": name},
+		bson.M{"$set": bson.M{
+			"description": description,
+			"permissions": permissions,
+			"updatedAt":   time.Now(),
+		}},
+		opts,
+	).Decode(&role)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to update role: %w", err)
+	}
+	return &role, nil
+}
+
+// Delete removes a role. It returns false if there is no such role.
+func (r *RoleRepository) Delete(ctx context.Context, name string) (bool, error) {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"name": name})
+	if err != nil {
+		return false, fmt.Errorf("failed to delete role: %w", err)
+	}
+	return result.DeletedCount > 0, nil
+}
+
+// UpsertSystem creates or overwrites a built-in role, so its permissions
+// always match the running version
+func (r *RoleRepository) UpsertSystem(ctx context.Context, role *models.Role) error {
+	now := time.Now()
+	_, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"name": role.Name},
+		bson.M{
+			"$set": bson.M{
+				"description": role.Description,
+				"permissions": role.Permissions,
+				"system":      true,
+				"updatedAt":   now,
+			},
+			"$setOnInsert": bson.M{"createdAt": now},
+		},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to upsert role: %w", err)
+	}
+	return nil
+}
+
+// CreateIfMissing creates a role unless one with its name exists, so
+// edits made to a seeded role are kept
+func (r *RoleRepository) CreateIfMissing(ctx context.Context, role *models.Role) error {
+	now := time.Now()
+	_, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"name": role.Name},
+		bson.M{"$setOnInsert": bson.M{
+			"description": role.Description,
+			"permissions": role.Permissions,
+			"system":      false,
+			"createdAt":   now,
+			"updatedAt":   now,
+		}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create role: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"chat-room-backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// StrikeRepository handles escalation strike data access
+type StrikeRepository struct {
+	collection *mongo.Collection
+}
+
+// NewStrikeRepository creates a new StrikeRepository
+func NewStrikeRepository(db *mongo.Database) *StrikeRepository {
+	collection := db.Collection("strikes")
+
+	// Create indexes
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// userId + createdAt index
+	collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "userId", Value: 1},
+			{Key: "createdAt", Value: -1},
+		},
+	})
+
+	return &StrikeRepository{collection: collection}
+}
+
+// Create records a new strike
+func (r *StrikeRepository) Create(ctx context.Context, strike *models.Strike) error {
+	strike.CreatedAt = time.Now()
+
+	result, err := r.collection.InsertOne(ctx, strike)
+	if err != nil {
+		return fmt.Errorf("failed to create strike: %w", err)
+	}
+
+	strike.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// SetEscalation records the escalation a strike triggered
+func (r *StrikeRepository) SetEscalation(ctx context.Context, id primitive.ObjectID, escalation string) error {
+	_, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": id},
+		bson.M{"$set": bson.M{"escalation": escalation}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update strike: %w", err)
+	}
+	return nil
+}
+
+// CountSince counts a user's uncleared strikes since a time.
+// If escalation is set, only strikes that triggered it are counted.
+func (r *StrikeRepository) CountSince(ctx context.Context, userID primitive.ObjectID, since time.Time, escalation string) (int64, error) {
+	filter := bson.M{
+		"userId":    userID,
+		"cleared":   false,
+		"createdAt": bson.M{"$gte": since},
+	}
+	if escalation != "" {
+		filter["escalation"] = escalation
+	}
+
+	count, err := r.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count strikes: %w", err)
+	}
+	return count, nil
+}
+
+// FindByUserID returns a user's strike history, newest first
+func (r *StrikeRepository) FindByUserID(ctx context.Context, userID primitive.ObjectID, limit int) ([]*models.Strike, error) {
+	if limit <= 0 {
+		limit = 100 // Default limit
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdAt", Value: -1}}).
+		SetLimit(int64(limit))
+
+	cursor, err := r.collection.Find(ctx, bson.M{"userId": userID}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find strikes: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var strikes []*models.Strike
+	if err := cursor.All(ctx, &strikes); err != nil {
+		return nil, fmt.Errorf("failed to decode strikes: %w", err)
+	}
+
+	return strikes, nil
+}
+
+// ClearByUserID resets a user's strikes. History is kept, marked cleared.
+func (r *StrikeRepository) ClearByUserID(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	result, err := r.collection.UpdateMany(
+		ctx,
+		bson.M{"userId": userID, "cleared": false},
+		bson.M{"$set": bson.M{"cleared": true}},
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to clear strikes: %w", err)
+	}
+	return result.ModifiedCount, nil
+}
@@ -0,0 +1,250 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"chat-room-backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// RefreshTokenRepository handles refresh token data access
+type RefreshTokenRepository struct {
+	collection *mongo.Collection
+}
+
+// NewRefreshTokenRepository creates a new RefreshTokenRepository
+func NewRefreshTokenRepository(db *mongo.Database) *RefreshTokenRepository {
+	collection := db.Collection("refreshtokens")
+
+	// Create indexes
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Unique index on tokenHash
+	collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "tokenHash", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+
+	// familyId index
+	collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "familyId", Value: 1}},
+	})
+
+	// userId index
+	collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "userId", Value: 1}},
+	})
+
+	// Expired tokens are removed by MongoDB
+	collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+
+	return &RefreshTokenRepository{collection: collection}
+}
+
+// Create stores a new refresh token
+func (r *RefreshTokenRepository) Create(ctx context.Context, token *models.RefreshToken) error {
+	token.CreatedAt = time.Now()
+
+	result, err := r.collection.InsertOne(ctx, token)
+	if err != nil {
+		return fmt.Errorf("failed to create refresh token: %w", err)
+	}
+
+	token.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// FindByHash finds a refresh token by the hash of its value
+func (r *RefreshTokenRepository) FindByHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	var token models.RefreshToken
+	err := r.collection.FindOne(ctx, bson.M{"tokenHash": tokenHash}).Decode(&token)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find refresh token: %w", err)
+	}
+	return &token, nil
+}
+
+// MarkUsed marks an unused, unrevoked token as used. It returns false if
+// the token was already used or revoked, e.g. by a concurrent refresh.
+func (r *RefreshTokenRepository) MarkUsed(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	result, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{
+			"_id":       id,
+			"usedAt":    bson.M{"$exists": false},
+			"revokedAt": bson.M{"$exists": false},
+		},
+		bson.M{"$set": bson.M{"usedAt": time.Now()}},
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to update refresh token: %w", err)
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// RevokeFamily revokes every token of a family
+func (r *RefreshTokenRepository) RevokeFamily(ctx context.Context, familyID primitive.ObjectID) error {
+	_, err := r.collection.UpdateMany(
+		ctx,
+		bson.M{"familyId": familyID, "revokedAt": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revokedAt": time.Now()}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+	return nil
+}
+
+// RevokedTokenRepository handles access token revocation list data access
+type RevokedTokenRepository struct {
+	collection *mongo.Collection
+}
+
+// NewRevokedTokenRepository creates a new RevokedTokenRepository
+func NewRevokedTokenRepository(db *mongo.Database) *RevokedTokenRepository {
+	collection := db.Collection("revokedtokens")
+
+	// Create indexes
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Entries are removed by MongoDB once the tokens they cover expired
+	collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+
+	return &RevokedTokenRepository{collection: collection}
+}
+
+// Create adds an entry to the revocation list
+func (r *RevokedTokenRepository) Create(ctx context.Context, entry *models.RevokedToken) error {
+	entry.CreatedAt = time.Now()
+
+	result, err := r.collection.InsertOne(ctx, entry)
+	if err != nil {
+		return fmt.Errorf("failed to create revoked token: %w", err)
+	}
+
+	entry.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// FindActive returns every entry that has not expired yet
+func (r *RevokedTokenRepository) FindActive(ctx context.Context) ([]*models.RevokedToken, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"expiresAt": bson.M{"$gt": time.Now()}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find revoked tokens: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var entries []*models.RevokedToken
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, fmt.Errorf("failed to decode revoked tokens: %w", err)
+	}
+	return entries, nil
+}
+
+// PasswordResetRepository handles password reset token data access
+type PasswordResetRepository struct {
+	collection *mongo.Collection
+}
+
+// NewPasswordResetRepository creates a new PasswordResetRepository
+func NewPasswordResetRepository(db *mongo.Database) *PasswordResetRepository {
+	collection := db.Collection("passwordresets")
+
+	// Create indexes
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Unique index on tokenHash
+	collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "tokenHash", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+
+	// userId index
+	collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "userId", Value: 1}},
+	})
+
+	// Expired tokens are removed by MongoDB
+	collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+
+	return &PasswordResetRepository{collection: collection}
+}
+
+// Create stores a new reset token, replacing the user's unused ones
+func (r *PasswordResetRepository) Create(ctx context.Context, reset *models.PasswordReset) error {
+	if _, err := r.collection.DeleteMany(ctx, bson.M{
+		"userId": reset.UserID,
+		"usedAt": bson.M{"$exists": false},
+	}); err != nil {
+		return fmt.Errorf("failed to delete password resets: %w", err)
+	}
+
+	reset.CreatedAt = time.Now()
+
+	result, err := r.collection.InsertOne(ctx, reset)
+	if err != nil {
+		return fmt.Errorf("failed to create password reset: %w", err)
+	}
+
+	reset.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// FindActive finds an unused, unexpired reset token by the hash of its value
+func (r *PasswordResetRepository) FindActive(ctx context.Context, tokenHash string) (*models.PasswordReset, error) {
+	var reset models.PasswordReset
+	err := r.collection.FindOne(ctx, bson.M{
+		"tokenHash": tokenHash,
+		"usedAt":    bson.M{"$exists": false},
+		"expiresAt": bson.M{"$gt": time.Now()},
+	}).Decode(&reset)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find password reset: %w", err)
+	}
+	return &reset, nil
+}
+
+// Consume marks an unused, unexpired reset token as used and returns it.
+// It returns nil if there is no such token, so a token works once.
+func (r *PasswordResetRepository) Consume(ctx context.Context, tokenHash string) (*models.PasswordReset, error) {
+	var reset models.PasswordReset
+	err := r.collection.FindOneAndUpdate(
+		ctx,
+		bson.M{
+			"tokenHash": tokenHash,
+			"usedAt":    bson.M{"$exists": false},
+			"expiresAt": bson.M{"$gt": time.Now()},
+		},
+		bson.M{"$set": bson.M{"usedAt": time.Now()}},
+	).Decode(&reset)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to consume password reset: %w", err)
+	}
+	return &reset, nil
+}
@@ -9,6 +9,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 // UserRepository handles user data access
@@ -34,6 +35,14 @@ func NewUserRepository(db *mongo.Database) *UserRepository {
 		Keys: bson.D{{Key: "role", Value: 1}},
 	})
 
+	// An SSO identity belongs to one user
+	collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "sso.issuer", Value: 1}, {Key: "sso.subject", Value: 1}},
+		Options: options.Index().
+			SetUnique(true).
+			SetPartialFilterExpression(bson.M{"sso.subject": bson.M{"$exists": true}}),
+	})
+
 	return &UserRepository{collection: collection}
 }
 
@@ -41,7 +50,7 @@ func NewUserRepository(db *mongo.Database) *UserRepository {
 func (r *UserRepository) Create(ctx context.Context, user *models.User) error {
 	user.CreatedAt = time.Now()
 	user.LastLogin = time.Now()
-	user.Role = "user" // Default role
+	user.Role = models.RoleUser // Default role
 	user.IsMuted = false
 
 	result, err := r.collection.InsertOne(ctx, user)
@@ -79,12 +88,94 @@ func (r *UserRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*
 	return &user, nil
 }
 
-// UpdateLastLogin updates the user's last login time
-func (r *UserRepository) UpdateLastLogin(ctx context.Context, id primitive.ObjectID) error {
+// FindBySSO finds the user linked to an SSO identity
+func (r *UserRepository) FindBySSO(ctx context.Context, issuer, subject string) (*models.User, error) {
+	var user models.User
+	err := r.collection.FindOne(ctx, bson.M{"sso.issuer": issuer, "sso.subject": subject}).Decode(&user)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+	return &user, nil
+}
+
+// FindWithRoles returns the users whose role is not the default role
+func (r *UserRepository) FindWithRoles(ctx context.Context) ([]*models.User, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"role": bson.M{"$nin": bson.A{"", models.RoleUser}}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find users: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var users []*models.User
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, fmt.Errorf("failed to decode users: %w", err)
+	}
+
+	return users, nil
+}
+
+// CountByRole counts the users with a role
+func (r *UserRepository) CountByRole(ctx context.Context, role string) (int64, error) {
+	count, err := r.collection.CountDocuments(ctx, bson.M{"role": role})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count users: %w", err)
+	}
+	return count, nil
+}
+
+// LinkSSO links an SSO identity to a user. It returns false if the user
+// already has one. Linking an identity another user has fails with a
+// duplicate key error.
+func (r *UserRepository) LinkSSO(ctx context.Context, userID primitive.ObjectID, identity *models.SSOIdentity) (bool, error) {
+	result, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": userID, "sso": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"sso": identity}},
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to update user: %w", err)
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// UnlinkSSO removes a user's SSO identity
+func (r *UserRepository) UnlinkSSO(ctx context.Context, userID primitive.ObjectID) error {
+	_, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": userID},
+		bson.M{"$unset": bson.M{"sso": ""}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+	return nil
+}
+
+// SetRole changes a user's role and records who manages it
+func (r *UserRepository) SetRole(ctx context.Context, userID primitive.ObjectID, role, source string) error {
+	_, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": userID},
+		bson.M{"$set": bson.M{"role": role, "roleSource": source}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+	return nil
+}
+
+// UpdateLastLogin updates the user's last login time and IP address
+func (r *UserRepository) UpdateLastLogin(ctx context.Context, id primitive.ObjectID, ip string) error {
 	_, err := r.collection.UpdateOne(
 		ctx,
 		bson.M{"_id": id},
-		bson.M{"$set": bson.M{"lastLogin": time.Now()}},
+		bson.M{"$set": bson.M{
+			"lastLogin":   time.Now(),
+			"lastLoginIp": ip,
+		}},
 	)
 	if err != nil {
 		return fmt.Errorf("failed to update last login: %w", err)
@@ -92,6 +183,135 @@ func (r *UserRepository) UpdateLastLogin(ctx context.Context, id primitive.Objec
 	return nil
 }
 
+// UpdatePassword stores a new password hash
+func (r *UserRepository) UpdatePassword(ctx context.Context, userID primitive.ObjectID, passwordHash string) error {
+	_, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": userID},
+		bson.M{"$set": bson.M{"password": passwordHash, "passwordChangedAt": time.Now()}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+	return nil
+}
+
+// RehashPassword replaces a password hash with a stronger hash of the
+// same password. Nothing changes if the password was changed meanwhile.
+func (r *UserRepository) RehashPassword(ctx context.Context, userID primitive.ObjectID, oldHash, newHash string) error {
+	_, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": userID, "password": oldHash},
+		bson.M{"$set": bson.M{"password": newHash}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+	return nil
+}
+
+// SetPendingTOTP stores a TOTP secret awaiting its first verified code
+func (r *UserRepository) SetPendingTOTP(ctx context.Context, userID primitive.ObjectID, secret string) error {
+	_, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": userID},
+		bson.M{"$set": bson.M{"totpPendingSecret": secret}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+	return nil
+}
+
+// EnableTOTP activates the pending TOTP secret with a set of recovery code hashes
+func (r *UserRepository) EnableTOTP(ctx context.Context, userID primitive.ObjectID, secret string, step int64, recoveryCodes []string) error {
+	_, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": userID},
+		bson.M{
+			"$set": bson.M{
+				"totpEnabled":   true,
+				"totpSecret":    secret,
+				"totpLastStep":  step,
+				"recoveryCodes": recoveryCodes,
+			},
+			"$unset": bson.M{"totpPendingSecret": ""},
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+	return nil
+}
+
+// DisableTOTP removes a user's TOTP secret and recovery codes
+func (r *UserRepository) DisableTOTP(ctx context.Context, userID primitive.ObjectID) error {
+	_, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": userID},
+		bson.M{
+			"$set": bson.M{"totpEnabled": false},
+			"$unset": bson.M{
+				"totpSecret":        "",
+				"totpPendingSecret": "",
+				"totpLastStep":      "",
+				"recoveryCodes":     "",
+			},
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+	return nil
+}
+
+// SetRecoveryCodes replaces a user's recovery code hashes
+func (r *UserRepository) SetRecoveryCodes(ctx context.Context, userID primitive.ObjectID, recoveryCodes []string) error {
+	_, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": userID},
+		bson.M{"$set": bson.M{"recoveryCodes": recoveryCodes}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+	return nil
+}
+
+// UseTOTPStep records an accepted TOTP time step. It returns false if the
+// step or a later one was already used, so a code cannot be replayed.
+func (r *UserRepository) UseTOTPStep(ctx context.Context, userID primitive.ObjectID, step int64) (bool, error) {
+	result, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{
+			"_id": userID,
+			"$or": bson.A{
+				bson.M{"totpLastStep": bson.M{"$exists": false}},
+				bson.M{"totpLastStep": bson.M{"$lt": step}},
+			},
+		},
+		bson.M{"$set": bson.M{"totpLastStep": step}},
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to update user: %w", err)
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// UseRecoveryCode removes a recovery code hash. It returns false if the
+// user has no such unused code.
+func (r *UserRepository) UseRecoveryCode(ctx context.Context, userID primitive.ObjectID, codeHash string) (bool, error) {
+	result, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": userID, "recoveryCodes": codeHash},
+		bson.M{"$pull": bson.M{"recoveryCodes": codeHash}},
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to update user: %w", err)
+	}
+	return result.ModifiedCount > 0, nil
+}
+
 // FindAll returns all users (for admin use)
 func (r *UserRepository) FindAll(ctx context.Context) ([]*models.User, error) {
 	cursor, err := r.collection.Find(ctx, bson.M{})
@@ -108,6 +328,47 @@ func (r *UserRepository) FindAll(ctx context.Context) ([]*models.User, error) {
 	return users, nil
 }
 
+// FindBots returns all bot accounts
+func (r *UserRepository) FindBots(ctx context.Context) ([]*models.User, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"isBot": true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find bots: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	users := []*models.User{}
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, fmt.Errorf("failed to decode bots: %w", err)
+	}
+
+	return users, nil
+}
+
+// Delete removes a user
+func (r *UserRepository) Delete(ctx context.Context, userID primitive.ObjectID) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": userID})
+	if err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+	return nil
+}
+
+// FindMuted returns all currently muted users
+func (r *UserRepository) FindMuted(ctx context.Context) ([]*models.User, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"isMuted": true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find muted users: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var users []*models.User
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, fmt.Errorf("failed to decode muted users: %w", err)
+	}
+
+	return users, nil
+}
+
 // Mute mutes a user
 func (r *UserRepository) Mute(ctx context.Context, userID, mutedBy primitive.ObjectID, duration int, reason string) error {
 	var mutedUntil *time.Time
@@ -149,3 +410,90 @@ func (r *UserRepository) Unmute(ctx context.Context, userID primitive.ObjectID)
 	}
 	return nil
 }
+
+// UnmuteExpired lifts a timed mute whose end has passed. It reports false
+// if the mute was lifted or renewed in the meantime, leaving the user as is.
+func (r *UserRepository) UnmuteExpired(ctx context.Context, userID primitive.ObjectID) (bool, error) {
+	result, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{
+			"_id":        userID,
+			"isMuted":    true,
+			"mutedUntil": bson.M{"$ne": nil, "$lte": time.Now()},
+		},
+		bson.M{"$set": bson.M{
+			"isMuted":     false,
+			"mutedUntil":  nil,
+			"mutedBy":     nil,
+			"mutedReason": "",
+		}},
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to unmute user: %w", err)
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// FindBanned returns all banned users and users with revoked tokens
+func (r *UserRepository) FindBanned(ctx context.Context) ([]*models.User, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"$or": bson.A{
+		bson.M{"isBanned": true},
+		bson.M{"tokensRevokedAt": bson.M{"$ne": nil}},
+	}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find banned users: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var users []*models.User
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, fmt.Errorf("failed to decode banned users: %w", err)
+	}
+
+	return users, nil
+}
+
+// Ban bans a user and revokes all tokens issued so far
+func (r *UserRepository) Ban(ctx context.Context, userID, bannedBy primitive.ObjectID, duration int, reason string) error {
+	now := time.Now()
+
+	var bannedUntil *time.Time
+	if duration > 0 {
+		until := now.Add(time.Duration(duration) * time.Minute)
+		bannedUntil = &until
+	}
+
+	_, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": userID},
+		bson.M{"$set": bson.M{
+			"isBanned":        true,
+			"bannedUntil":     bannedUntil,
+			"bannedBy":        bannedBy,
+			"banReason":       reason,
+			"tokensRevokedAt": now,
+		}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to ban user: %w", err)
+	}
+	return nil
+}
+
+// Unban lifts a user's ban (previously issued tokens stay revoked)
+func (r *UserRepository) Unban(ctx context.Context, userID primitive.ObjectID) error {
+	_, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": userID},
+		bson.M{"$set": bson.M{
+			"isBanned":    false,
+			"bannedUntil": nil,
+			"bannedBy":    nil,
+			"banReason":   "",
+		}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to unban user: %w", err)
+	}
+	return nil
+}
@@ -3,23 +3,42 @@ package service
 import (
 	"context"
 	"fmt"
+	"strings"
+	"time"
 
+	"chat-room-backend/internal/middleware"
 	"chat-room-backend/internal/models"
 	"chat-room-backend/internal/repository"
+	"chat-room-backend/internal/utils"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // AdminService handles admin-related business logic
 type AdminService struct {
-	adminRepo *repository.AdminRepository
-	userRepo  *repository.UserRepository
+	adminRepo   *repository.AdminRepository
+	userRepo    *repository.UserRepository
+	banRepo     *repository.BanRepository
+	channelRepo *repository.ChannelRepository
+	permissions *middleware.PermissionChecker
+	audit       *AuditService
 }
 
 // NewAdminService creates a new AdminService
-func NewAdminService(adminRepo *repository.AdminRepository, userRepo *repository.UserRepository) *AdminService {
+func NewAdminService(
+	adminRepo *repository.AdminRepository,
+	userRepo *repository.UserRepository,
+	banRepo *repository.BanRepository,
+	channelRepo *repository.ChannelRepository,
+	permissions *middleware.PermissionChecker,
+	audit *AuditService,
+) *AdminService {
 	return &AdminService{
-		adminRepo: adminRepo,
-		userRepo:  userRepo,
+		adminRepo:   adminRepo,
+		userRepo:    userRepo,
+		banRepo:     banRepo,
+		channelRepo: channelRepo,
+		permissions: permissions,
+		audit:       audit,
 	}
 }
 
@@ -29,10 +48,21 @@ func NewAdminService(adminRepo *repository.AdminRepository, userRepo *repository
 
 // AddWordFilterRequest represents word filter creation data
 type AddWordFilterRequest struct {
-	Word string `json:"word" binding:"required,min=1"`
+	Word         string   `json:"word" binding:"required,min=1"`
+	MatchType    string   `json:"matchType" binding:"omitempty,oneof=substring word regex"`
+	Variants     []string `json:"variants"` // Extra spellings such as pinyin
+	IsAllow      bool     `json:"isAllow"`  // Add an allowlist exception instead of a blocked word
+	Action       string   `json:"action" binding:"omitempty,oneof=mask block review mute"`
+	MuteDuration int      `json:"muteDuration" binding:"min=0"` // Minutes, for the mute action
 }
 
-// GetWordFilters returns all active word filters
+// TestWordFilterRequest represents word filter dry-run data
+type TestWordFilterRequest struct {
+	Text      string `json:"text" binding:"required"`
+	ChannelID string `json:"channelId"` // Also apply this channel's rules
+}
+
+// GetWordFilters returns all active global word filters
 func (s *AdminService) GetWordFilters(ctx context.Context) ([]*models.WordFilter, error) {
 	filters, err := s.adminRepo.GetAllWordFilters(ctx)
 	if err != nil {
@@ -41,34 +71,184 @@ func (s *AdminService) GetWordFilters(ctx context.Context) ([]*models.WordFilter
 	return filters, nil
 }
 
-// AddWordFilter adds a new word filter
-func (s *AdminService) AddWordFilter(ctx context.Context, word string, addedBy primitive.ObjectID) (*models.WordFilter, error) {
-	filter := &models.WordFilter{
-		Word:    word,
-		AddedBy: addedBy,
+// AddWordFilter adds a new global word filter
+func (s *AdminService) AddWordFilter(ctx context.Context, req *AddWordFilterRequest, addedBy primitive.ObjectID) (*models.WordFilter, error) {
+	return s.addWordFilter(ctx, req, nil, addedBy)
+}
+
+// addWordFilter adds a word filter, scoped to a channel if channelID is set
+func (s *AdminService) addWordFilter(ctx context.Context, req *AddWordFilterRequest, channelID *primitive.ObjectID, addedBy primitive.ObjectID) (*models.WordFilter, error) {
+	filter, err := buildWordFilter(req, addedBy)
+	if err != nil {
+		return nil, err
 	}
+	filter.ChannelID = channelID
 
 	if err := s.adminRepo.CreateWordFilter(ctx, filter); err != nil {
 		return nil, fmt.Errorf("failed to add word filter: %w", err)
 	}
 
+	s.audit.Record(ctx, &models.AuditLog{
+		ActorID:    addedBy,
+		Action:     models.AuditActionWordFilterAdd,
+		TargetType: models.AuditTargetWordFilter,
+		TargetID:   &filter.ID,
+		TargetName: filter.Word,
+		After:      wordFilterSnapshot(filter),
+	})
+
 	return filter, nil
 }
 
+// buildWordFilter validates a request and applies defaults
+func buildWordFilter(req *AddWordFilterRequest, addedBy primitive.ObjectID) (*models.WordFilter, error) {
+	word := strings.TrimSpace(req.Word)
+	if word == "" {
+		return nil, fmt.Errorf("敏感词不能为空")
+	}
+
+	switch req.MatchType {
+	case "", models.MatchTypeSubstring, models.MatchTypeWord:
+	case models.MatchTypeRegex:
+		if _, err := utils.CompileFilterRegex(word); err != nil {
+			return nil, fmt.Errorf("无效的正则表达式")
+		}
+	default:
+		return nil, fmt.Errorf("无效的匹配方式")
+	}
+
+	action := req.Action
+	if action == "" {
+		action = models.FilterActionBlock
+	}
+	if _, ok := models.FilterActionRank[action]; !ok {
+		return nil, fmt.Errorf("无效的处理方式")
+	}
+	if req.MuteDuration < 0 {
+		return nil, fmt.Errorf("无效的禁言时长")
+	}
+	muteDuration := 0
+	if action == models.FilterActionMute {
+		muteDuration = req.MuteDuration
+		if muteDuration == 0 {
+			muteDuration = models.DefaultFilterMuteDuration
+		}
+	}
+
+	variants := make([]string, 0, len(req.Variants))
+	for _, variant := range req.Variants {
+		if variant = strings.ToLower(strings.TrimSpace(variant)); variant != "" {
+			variants = append(variants, variant)
+		}
+	}
+
+	return &models.WordFilter{
+		Word:         word,
+		MatchType:    req.MatchType,
+		Variants:     variants,
+		IsAllow:      req.IsAllow,
+		Action:       action,
+		MuteDuration: muteDuration,
+		AddedBy:      addedBy,
+	}, nil
+}
+
 // RemoveWordFilter removes (deactivates) a word filter
-func (s *AdminService) RemoveWordFilter(ctx context.Context, filterID string) error {
+func (s *AdminService) RemoveWordFilter(ctx context.Context, filterID string, removedBy primitive.ObjectID) error {
 	filterObjID, err := primitive.ObjectIDFromHex(filterID)
 	if err != nil {
 		return fmt.Errorf("invalid filter ID: %w", err)
 	}
 
+	before, err := s.adminRepo.FindWordFilterByID(ctx, filterObjID)
+	if err != nil {
+		return fmt.Errorf("failed to find word filter: %w", err)
+	}
+	if before == nil {
+		return fmt.Errorf("敏感词不存在")
+	}
+
 	if err := s.adminRepo.DeactivateWordFilter(ctx, filterObjID); err != nil {
 		return fmt.Errorf("failed to remove word filter: %w", err)
 	}
 
+	after := *before
+	after.IsActive = false
+	s.audit.Record(ctx, &models.AuditLog{
+		ActorID:    removedBy,
+		Action:     models.AuditActionWordFilterRemove,
+		TargetType: models.AuditTargetWordFilter,
+		TargetID:   &filterObjID,
+		TargetName: before.Word,
+		Before:     wordFilterSnapshot(before),
+		After:      wordFilterSnapshot(&after),
+	})
+
 	return nil
 }
 
+// GetChannelWordFilters returns the word filters scoped to a channel
+func (s *AdminService) GetChannelWordFilters(ctx context.Context, channelID string) ([]*models.WordFilter, error) {
+	channelObjID, err := s.findChannelID(ctx, channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	filters, err := s.adminRepo.GetChannelWordFilters(ctx, &channelObjID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get word filters: %w", err)
+	}
+	return filters, nil
+}
+
+// AddChannelWordFilter adds a word filter that only applies in one channel
+func (s *AdminService) AddChannelWordFilter(ctx context.Context, channelID string, req *AddWordFilterRequest, addedBy primitive.ObjectID) (*models.WordFilter, error) {
+	channelObjID, err := s.findChannelID(ctx, channelID)
+	if err != nil {
+		return nil, err
+	}
+	return s.addWordFilter(ctx, req, &channelObjID, addedBy)
+}
+
+// RemoveChannelWordFilter removes a word filter scoped to a channel
+func (s *AdminService) RemoveChannelWordFilter(ctx context.Context, channelID, filterID string, removedBy primitive.ObjectID) error {
+	channelObjID, err := s.findChannelID(ctx, channelID)
+	if err != nil {
+		return err
+	}
+
+	filterObjID, err := primitive.ObjectIDFromHex(filterID)
+	if err != nil {
+		return fmt.Errorf("invalid filter ID: %w", err)
+	}
+	filter, err := s.adminRepo.FindWordFilterByID(ctx, filterObjID)
+	if err != nil {
+		return fmt.Errorf("failed to find word filter: %w", err)
+	}
+	if filter == nil || filter.ChannelID == nil || *filter.ChannelID != channelObjID {
+		return fmt.Errorf("敏感词不存在")
+	}
+
+	return s.RemoveWordFilter(ctx, filterID, removedBy)
+}
+
+// findChannelID parses a channel ID and checks that the channel exists
+func (s *AdminService) findChannelID(ctx context.Context, channelID string) (primitive.ObjectID, error) {
+	channelObjID, err := primitive.ObjectIDFromHex(channelID)
+	if err != nil {
+		return primitive.NilObjectID, fmt.Errorf("invalid channel ID: %w", err)
+	}
+
+	channel, err := s.channelRepo.FindByID(ctx, channelObjID)
+	if err != nil {
+		return primitive.NilObjectID, fmt.Errorf("failed to find channel: %w", err)
+	}
+	if channel == nil {
+		return primitive.NilObjectID, fmt.Errorf("频道不存在")
+	}
+	return channelObjID, nil
+}
+
 // ============================================================
 // User Management Operations
 // ============================================================
@@ -85,7 +265,7 @@ func (s *AdminService) GetAllUsers(ctx context.Context) ([]*models.User, error)
 // MuteUserRequest represents user mute data
 type MuteUserRequest struct {
 	UserID   string `json:"userId" binding:"required"`
-	Duration int    `json:"duration"` // Duration in minutes, 0 for permanent
+	Duration int    `json:"duration" binding:"min=0"` // Duration in minutes, 0 for permanent
 	Reason   string `json:"reason"`
 }
 
@@ -105,9 +285,9 @@ func (s *AdminService) MuteUser(ctx context.Context, req *MuteUserRequest, muted
 		return fmt.Errorf("用户不存在")
 	}
 
-	// Don't allow muting admins (this should also be checked via adminHelper in middleware)
-	if user.Role == "admin" {
-		return fmt.Errorf("不能禁言管理员")
+	// A mute would never apply to admins or users exempt from moderation
+	if s.isStaff(user) {
+		return fmt.Errorf("不能禁言管理人员")
 	}
 
 	reason := req.Reason
@@ -119,6 +299,7 @@ func (s *AdminService) MuteUser(ctx context.Context, req *MuteUserRequest, muted
 		return fmt.Errorf("failed to mute user: %w", err)
 	}
 
+	s.recordUserChange(ctx, mutedBy, models.AuditActionUserMute, user, reason, muteSnapshot)
 	return nil
 }
 
@@ -128,16 +309,153 @@ type UnmuteUserRequest struct {
 }
 
 // UnmuteUser unmutes a user
-func (s *AdminService) UnmuteUser(ctx context.Context, req *UnmuteUserRequest) error {
+func (s *AdminService) UnmuteUser(ctx context.Context, req *UnmuteUserRequest, unmutedBy primitive.ObjectID) error {
 	userObjID, err := primitive.ObjectIDFromHex(req.UserID)
 	if err != nil {
 		return fmt.Errorf("invalid user ID: %w", err)
 	}
 
+	user, err := s.userRepo.FindByID(ctx, userObjID)
+	if err != nil {
+		return fmt.Errorf("failed to find user: %w", err)
+	}
+	if user == nil {
+		return fmt.Errorf("用户不存在")
+	}
+
 	if err := s.userRepo.Unmute(ctx, userObjID); err != nil {
 		return fmt.Errorf("failed to unmute user: %w", err)
 	}
 
+	s.recordUserChange(ctx, unmutedBy, models.AuditActionUserUnmute, user, "", muteSnapshot)
+	return nil
+}
+
+// BanUserRequest represents user ban data
+type BanUserRequest struct {
+	UserID   string `json:"userId" binding:"required"`
+	Duration int    `json:"duration" binding:"min=0"` // Duration in minutes, 0 for permanent
+	Reason   string `json:"reason"`
+	BanIP    bool   `json:"banIp"` // Also ban the user's known IP addresses
+}
+
+// BanUser bans a user and optionally the IP addresses they use.
+// liveIPs are the addresses of the user's current connections.
+func (s *AdminService) BanUser(ctx context.Context, req *BanUserRequest, bannedBy primitive.ObjectID, liveIPs []string) error {
+	userObjID, err := primitive.ObjectIDFromHex(req.UserID)
+	if err != nil {
+		return fmt.Errorf("invalid user ID: %w", err)
+	}
+
+	user, err := s.userRepo.FindByID(ctx, userObjID)
+	if err != nil {
+		return fmt.Errorf("failed to find user: %w", err)
+	}
+	if user == nil {
+		return fmt.Errorf("用户不存在")
+	}
+
+	// Checked before the user or their IP addresses are banned
+	if s.isStaff(user) {
+		return fmt.Errorf("不能封禁管理人员")
+	}
+
+	reason := req.Reason
+	if reason == "" {
+		reason = "违反聊天规则"
+	}
+
+	if err := s.userRepo.Ban(ctx, userObjID, bannedBy, req.Duration, reason); err != nil {
+		return fmt.Errorf("failed to ban user: %w", err)
+	}
+
+	s.recordUserChange(ctx, bannedBy, models.AuditActionUserBan, user, reason, banSnapshot)
+
+	if !req.BanIP {
+		return nil
+	}
+
+	var bannedUntil *time.Time
+	if req.Duration > 0 {
+		until := time.Now().Add(time.Duration(req.Duration) * time.Minute)
+		bannedUntil = &until
+	}
+
+	// Collect distinct addresses
+	ips := make(map[string]bool)
+	if user.LastLoginIP != "" {
+		ips[user.LastLoginIP] = true
+	}
+	for _, ip := range liveIPs {
+		if ip != "" {
+			ips[ip] = true
+		}
+	}
+
+	for ip := range ips {
+		ban := &models.IPBan{
+			IP:          ip,
+			UserID:      &userObjID,
+			BannedBy:    bannedBy,
+			Reason:      reason,
+			BannedUntil: bannedUntil,
+		}
+		if err := s.banRepo.Create(ctx, ban); err != nil {
+			return fmt.Errorf("failed to ban IP: %w", err)
+		}
+		s.audit.Record(ctx, &models.AuditLog{
+			ActorID:    bannedBy,
+			Action:     models.AuditActionIPBan,
+			TargetType: models.AuditTargetIP,
+			TargetName: ip,
+			Reason:     reason,
+			After: map[string]interface{}{
+				"userId":      userObjID,
+				"username":    user.Username,
+				"bannedUntil": bannedUntil,
+			},
+		})
+	}
+
+	return nil
+}
+
+// isStaff reports whether a user holds the admin role or is exempt from
+// moderation, as resolved by the permission checker that grants their rights
+func (s *AdminService) isStaff(user *models.User) bool {
+	return s.permissions.RoleOf(user.ID) == models.RoleAdmin ||
+		s.permissions.Has(user.ID, models.PermModerationExempt)
+}
+
+// UnbanUserRequest represents user unban data
+type UnbanUserRequest struct {
+	UserID string `json:"userId" binding:"required"`
+}
+
+// UnbanUser lifts a user's ban and the IP bans it created
+func (s *AdminService) UnbanUser(ctx context.Context, req *UnbanUserRequest, unbannedBy primitive.ObjectID) error {
+	userObjID, err := primitive.ObjectIDFromHex(req.UserID)
+	if err != nil {
+		return fmt.Errorf("invalid user ID: %w", err)
+	}
+
+	user, err := s.userRepo.FindByID(ctx, userObjID)
+	if err != nil {
+		return fmt.Errorf("failed to find user: %w", err)
+	}
+	if user == nil {
+		return fmt.Errorf("用户不存在")
+	}
+
+	if err := s.userRepo.Unban(ctx, userObjID); err != nil {
+		return fmt.Errorf("failed to unban user: %w", err)
+	}
+
+	if err := s.banRepo.DeactivateByUserID(ctx, userObjID); err != nil {
+		return fmt.Errorf("failed to lift IP bans: %w", err)
+	}
+
+	s.recordUserChange(ctx, unbannedBy, models.AuditActionUserUnban, user, "", banSnapshot)
 	return nil
 }
 
@@ -147,8 +465,9 @@ func (s *AdminService) UnmuteUser(ctx context.Context, req *UnmuteUserRequest) e
 
 // ToggleGlobalMuteRequest represents global mute toggle data
 type ToggleGlobalMuteRequest struct {
-	Enabled bool   `json:"enabled"`
-	Reason  string `json:"reason"`
+	Enabled  bool   `json:"enabled"`
+	Reason   string `json:"reason"`
+	Duration int    `json:"duration" binding:"min=0"` // Minutes until it turns off by itself, 0 for never
 }
 
 // GetGlobalMuteStatus returns the current global mute status
@@ -172,9 +491,117 @@ func (s *AdminService) ToggleGlobalMute(ctx context.Context, req *ToggleGlobalMu
 		enabledByPtr = &enabledBy
 	}
 
-	if err := s.adminRepo.UpdateGlobalMuteStatus(ctx, req.Enabled, enabledByPtr, reason); err != nil {
+	before, err := s.adminRepo.GetGlobalMuteStatus(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get global mute status: %w", err)
+	}
+
+	status := &models.GlobalMuteStatus{
+		IsEnabled: req.Enabled,
+		EnabledBy: enabledByPtr,
+		Reason:    reason,
+		Source:    models.GlobalMuteSourceManual,
+	}
+	if req.Enabled && req.Duration > 0 {
+		expiresAt := time.Now().Add(time.Duration(req.Duration) * time.Minute)
+		status.ExpiresAt = &expiresAt
+	}
+	// Turning off a scheduled mute skips the rest of its window
+	if !req.Enabled && before.IsEnabled && before.IsScheduled() {
+		status.SkipScheduleUntil = before.ExpiresAt
+	}
+
+	if err := s.adminRepo.UpdateGlobalMuteStatus(ctx, status); err != nil {
 		return fmt.Errorf("failed to update global mute status: %w", err)
 	}
 
+	entry := &models.AuditLog{
+		ActorID:    enabledBy,
+		Action:     models.AuditActionGlobalMute,
+		TargetType: models.AuditTargetGlobalMute,
+		Reason:     reason,
+		Before:     globalMuteSnapshot(before),
+	}
+	if after, err := s.adminRepo.GetGlobalMuteStatus(ctx); err == nil {
+		entry.After = globalMuteSnapshot(after)
+	}
+	s.audit.Record(ctx, entry)
+
 	return nil
 }
+
+// ============================================================
+// Audit Helpers
+// ============================================================
+
+// recordUserChange writes an audit entry for a change to a user,
+// re-reading the user so the entry holds the stored after-state
+func (s *AdminService) recordUserChange(
+	ctx context.Context,
+	actorID primitive.ObjectID,
+	action string,
+	before *models.User,
+	reason string,
+	snapshot func(*models.User) map[string]interface{},
+) {
+	entry := &models.AuditLog{
+		ActorID:    actorID,
+		Action:     action,
+		TargetType: models.AuditTargetUser,
+		TargetID:   &before.ID,
+		TargetName: before.Username,
+		Reason:     reason,
+		Before:     snapshot(before),
+	}
+	if after, err := s.userRepo.FindByID(ctx, before.ID); err == nil && after != nil {
+		entry.After = snapshot(after)
+	}
+	s.audit.Record(ctx, entry)
+}
+
+// muteSnapshot captures a user's mute state for the audit log
+func muteSnapshot(u *models.User) map[string]interface{} {
+	return map[string]interface{}{
+		"isMuted":     u.IsMuted,
+		"mutedUntil":  u.MutedUntil,
+		"mutedBy":     u.MutedBy,
+		"mutedReason": u.MutedReason,
+	}
+}
+
+// banSnapshot captures a user's ban state for the audit log
+func banSnapshot(u *models.User) map[string]interface{} {
+	return map[string]interface{}{
+		"isBanned":    u.IsBanned,
+		"bannedUntil": u.BannedUntil,
+		"bannedBy":    u.BannedBy,
+		"banReason":   u.BanReason,
+	}
+}
+
+// wordFilterSnapshot captures a word filter for the audit log
+func wordFilterSnapshot(wf *models.WordFilter) map[string]interface{} {
+	return map[string]interface{}{
+		"word":         wf.Word,
+		"matchType":    wf.GetMatchType(),
+		"variants":     wf.Variants,
+		"isAllow":      wf.IsAllow,
+		"action":       wf.GetAction(),
+		"muteDuration": wf.MuteDuration,
+		"addedBy":      wf.AddedBy,
+		"channelId":    wf.ChannelID,
+		"isActive":     wf.IsActive,
+	}
+}
+
+// globalMuteSnapshot captures the global mute status for the audit log
+func globalMuteSnapshot(gms *models.GlobalMuteStatus) map[string]interface{} {
+	return map[string]interface{}{
+		"isEnabled": gms.IsEnabled,
+		"enabledBy": gms.EnabledBy,
+		"enabledAt": gms.EnabledAt,
+		"reason":    gms.Reason,
+		"source":    gms.Source,
+		"expiresAt": gms.ExpiresAt,
+	}
+}
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"chat-room-backend/internal/models"
+	"chat-room-backend/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// maxAuditExportRows caps the number of rows in a single CSV export
+const maxAuditExportRows = 10000
+
+// AuditService records and queries the moderation audit log
+type AuditService struct {
+	auditRepo *repository.AuditRepository
+	userRepo  *repository.UserRepository
+}
+
+// NewAuditService creates a new AuditService
+func NewAuditService(auditRepo *repository.AuditRepository, userRepo *repository.UserRepository) *AuditService {
+	return &AuditService{
+		auditRepo: auditRepo,
+		userRepo:  userRepo,
+	}
+}
+
+// Record appends an entry to the audit log.
+// Failures are logged but never fail the audited operation.
+func (s *AuditService) Record(ctx context.Context, entry *models.AuditLog) {
+	if entry.ActorUsername == "" {
+		if actor, err := s.userRepo.FindByID(ctx, entry.ActorID); err == nil && actor != nil {
+			entry.ActorUsername = actor.Username
+		}
+	}
+
+	if err := s.auditRepo.Create(ctx, entry); err != nil {
+		log.Printf("❌ Failed to write audit log (%s by %s): %v", entry.Action, entry.ActorID.Hex(), err)
+	}
+}
+
+// AuditLogQuery represents audit log filter and pagination parameters
+type AuditLogQuery struct {
+	ActorID  string `form:"actorId"`
+	TargetID string `form:"targetId"`
+	Action   string `form:"action"`
+	From     string `form:"from"` // RFC3339
+	To       string `form:"to"`   // RFC3339
+	Page     int    `form:"page"`
+	Limit    int    `form:"limit"`
+}
+
+// AuditLogPage is a page of audit log entries
+type AuditLogPage struct {
+	Logs  []*models.AuditLog `json:"logs"`
+	Total int64              `json:"total"`
+	Page  int                `json:"page"`
+	Limit int                `json:"limit"`
+}
+
+// GetAuditLogs returns a filtered, paginated page of the audit log
+func (s *AuditService) GetAuditLogs(ctx context.Context, query *AuditLogQuery) (*AuditLogPage, error) {
+	filter, err := query.toFilter()
+	if err != nil {
+		return nil, err
+	}
+
+	page := query.Page
+	if page <= 0 {
+		page = 1
+	}
+	limit := query.Limit
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+
+	logs, err := s.auditRepo.Find(ctx, filter, (page-1)*limit, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audit logs: %w", err)
+	}
+
+	total, err := s.auditRepo.Count(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count audit logs: %w", err)
+	}
+
+	if logs == nil {
+		logs = []*models.AuditLog{}
+	}
+
+	return &AuditLogPage{
+		Logs:  logs,
+		Total: total,
+		Page:  page,
+		Limit: limit,
+	}, nil
+}
+
+// ExportAuditLogs returns all audit log entries matching the filter (pagination is ignored)
+func (s *AuditService) ExportAuditLogs(ctx context.Context, query *AuditLogQuery) ([]*models.AuditLog, error) {
+	filter, err := query.toFilter()
+	if err != nil {
+		return nil, err
+	}
+
+	logs, err := s.auditRepo.Find(ctx, filter, 0, maxAuditExportRows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export audit logs: %w", err)
+	}
+
+	return logs, nil
+}
+
+// toFilter validates the query and converts it to a repository filter
+func (q *AuditLogQuery) toFilter() (*repository.AuditLogFilter, error) {
+	filter := &repository.AuditLogFilter{Action: q.Action}
+
+	if q.ActorID != "" {
+		id, err := primitive.ObjectIDFromHex(q.ActorID)
+		if err != nil {
+			return nil, fmt.Errorf("无效的操作者ID")
+		}
+		filter.ActorID = &id
+	}
+	if q.TargetID != "" {
+		id, err := primitive.ObjectIDFromHex(q.TargetID)
+		if err != nil {
+			return nil, fmt.Errorf("无效的目标ID")
+		}
+		filter.TargetID = &id
+	}
+	if q.From != "" {
+		from, err := time.Parse(time.RFC3339, q.From)
+		if err != nil {
+			return nil, fmt.Errorf("无效的开始时间")
+		}
+		filter.From = &from
+	}
+	if q.To != "" {
+		to, err := time.Parse(time.RFC3339, q.To)
+		if err != nil {
+			return nil, fmt.Errorf("无效的结束时间")
+		}
+		filter.To = &to
+	}
+
+	return filter, nil
+}
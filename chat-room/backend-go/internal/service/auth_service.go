@@ -3,20 +3,48 @@ package service
 import (
 	"context"
 	"fmt"
+	"log"
+	"strings"
+	"sync"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
+	"chat-room-backend/internal/config"
+	"chat-room-backend/internal/middleware"
 	"chat-room-backend/internal/models"
 	"chat-room-backend/internal/repository"
 	"chat-room-backend/internal/utils"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// Two-factor authentication settings
+const (
+	totpIssuer        = "Chat Room"
+	challengeTTL      = 5 * time.Minute
+	recoveryCodeCount = 10
+)
+
 // AuthService handles authentication business logic
 type AuthService struct {
-	userRepo       *repository.UserRepository
-	channelRepo    *repository.ChannelRepository
+	userRepo          *repository.UserRepository
+	channelRepo       *repository.ChannelRepository
 	channelMemberRepo *repository.ChannelMemberRepository
-	jwtSecret      string
+	banRepo           *repository.BanRepository
+	refreshRepo       *repository.RefreshTokenRepository
+	revokedRepo       *repository.RevokedTokenRepository
+	sessionRepo       *repository.SessionRepository
+	resetRepo         *repository.PasswordResetRepository
+	banChecker        *middleware.BanChecker
+	loginGuard        *middleware.LoginGuard
+	audit             *AuditService
+	jwtKeys           *utils.KeySet
+	tokens            config.TokenConfig
+	passwords         config.PasswordConfig
+	hasher            *utils.PasswordHasher
+
+	mu               sync.Mutex
+	onSessionRevoked func(sessionID string)
 }
 
 // NewAuthService creates a new AuthService
@@ -24,37 +52,172 @@ func NewAuthService(
 	userRepo *repository.UserRepository,
 	channelRepo *repository.ChannelRepository,
 	channelMemberRepo *repository.ChannelMemberRepository,
-	jwtSecret string,
+	banRepo *repository.BanRepository,
+	refreshRepo *repository.RefreshTokenRepository,
+	revokedRepo *repository.RevokedTokenRepository,
+	sessionRepo *repository.SessionRepository,
+	resetRepo *repository.PasswordResetRepository,
+	banChecker *middleware.BanChecker,
+	loginGuard *middleware.LoginGuard,
+	audit *AuditService,
+	jwtKeys *utils.KeySet,
+	tokens config.TokenConfig,
+	passwords config.PasswordConfig,
 ) *AuthService {
 	return &AuthService{
-		userRepo:       userRepo,
-		channelRepo:    channelRepo,
+		userRepo:          userRepo,
+		channelRepo:       channelRepo,
 		channelMemberRepo: channelMemberRepo,
-		jwtSecret:      jwtSecret,
+		banRepo:           banRepo,
+		refreshRepo:       refreshRepo,
+		revokedRepo:       revokedRepo,
+		sessionRepo:       sessionRepo,
+		resetRepo:         resetRepo,
+		banChecker:        banChecker,
+		loginGuard:        loginGuard,
+		audit:             audit,
+		jwtKeys:           jwtKeys,
+		tokens:            tokens,
+		passwords:         passwords,
+		hasher:            utils.NewPasswordHasher(passwords.Argon2),
 	}
 }
 
+// BanError is returned when a banned user or IP address tries to authenticate
+type BanError struct {
+	Reason      string
+	BannedUntil *time.Time // nil for permanent bans
+}
+
+func (e *BanError) Error() string {
+	return "账号已被封禁"
+}
+
+// LoginThrottledError is returned when a username or IP address has to
+// wait before trying to log in again
+type LoginThrottledError struct {
+	RetryAfter time.Duration
+	Locked     bool
+}
+
+func (e *LoginThrottledError) Error() string {
+	if e.Locked {
+		return "登录失败次数过多，已被临时锁定"
+	}
+	return "登录尝试过于频繁，请稍后再试"
+}
+
+// PasswordPolicyError is returned when a new password does not meet the
+// password policy
+type PasswordPolicyError struct {
+	Message string
+}
+
+func (e *PasswordPolicyError) Error() string {
+	return e.Message
+}
+
 // RegisterRequest represents registration data
 type RegisterRequest struct {
-	Username string `json:"username" binding:"required,min=2,max=20"`
-	Password string `json:"password" binding:"required,min=6"`
+	Username    string `json:"username" binding:"required,min=2,max=20"`
+	Password    string `json:"password" binding:"required"`  // Checked against the password policy
+	DeviceLabel string `json:"deviceLabel" binding:"max=50"` // Derived from the User-Agent if empty
 }
 
 // LoginRequest represents login data
 type LoginRequest struct {
-	Username string `json:"username" binding:"required"`
-	Password string `json:"password" binding:"required"`
+	Username    string `json:"username" binding:"required"`
+	Password    string `json:"password" binding:"required"`
+	DeviceLabel string `json:"deviceLabel" binding:"max=50"` // Derived from the User-Agent if empty
+}
+
+// ClientInfo describes where an authentication request came from
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+}
+
+// LoginTwoFactorRequest represents the second step of a login
+type LoginTwoFactorRequest struct {
+	ChallengeToken string `json:"challengeToken" binding:"required"`
+	Code           string `json:"code" binding:"required"` // TOTP code or recovery code
+	DeviceLabel    string `json:"deviceLabel" binding:"max=50"`
+}
+
+// TwoFactorCodeRequest confirms a two-factor change with a current code
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"` // TOTP code, or recovery code where accepted
+}
+
+// TwoFactorSetupResponse is a new TOTP secret awaiting confirmation
+type TwoFactorSetupResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURL string `json:"otpauthUrl"` // Render as a QR code
+}
+
+// TwoFactorStatus describes a user's two-factor enrollment
+type TwoFactorStatus struct {
+	Enabled           bool `json:"enabled"`
+	RecoveryCodesLeft int  `json:"recoveryCodesLeft"`
+}
+
+// TwoFactorEnabledResponse is returned once after enrollment
+type TwoFactorEnabledResponse struct {
+	RecoveryCodes []string `json:"recoveryCodes"` // Shown once, only hashes are stored
+	Token         string   `json:"token"`         // Access token for the now verified session
+	ExpiresIn     int      `json:"expiresIn"`
+}
+
+// ChangePasswordRequest represents a password change by the user
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"currentPassword" binding:"required"`
+	NewPassword     string `json:"newPassword" binding:"required"`
+}
+
+// ResetPasswordRequest sets a new password with an admin-issued reset token
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"newPassword" binding:"required"`
+}
+
+// PasswordResetResponse is a reset token issued by an admin
+type PasswordResetResponse struct {
+	ResetToken string    `json:"resetToken"` // Shown once; hand it to the user over a trusted channel
+	ExpiresAt  time.Time `json:"expiresAt"`
+}
+
+// RefreshRequest represents token refresh data
+type RefreshRequest struct {
+	RefreshToken string `json:"refreshToken" binding:"required"`
+}
+
+// LogoutRequest represents logout data. The refresh token is optional when
+// the request carries an access token.
+type LogoutRequest struct {
+	RefreshToken string `json:"refreshToken"`
 }
 
 // AuthResponse represents authentication response
 type AuthResponse struct {
-	Message string               `json:"message"`
-	Token   string               `json:"token"`
-	User    *models.UserResponse `json:"user"`
+	Message      string               `json:"message"`
+	Token        string               `json:"token,omitempty"` // Access token
+	RefreshToken string               `json:"refreshToken,omitempty"`
+	ExpiresIn    int                  `json:"expiresIn,omitempty"` // Access token lifetime in seconds
+	User         *models.UserResponse `json:"user,omitempty"`
+
+	// Set instead of the tokens when the password step succeeded and a
+	// second factor is required; continue at POST /api/auth/login/2fa
+	TwoFactorRequired bool   `json:"twoFactorRequired,omitempty"`
+	ChallengeToken    string `json:"challengeToken,omitempty"`
 }
 
 // Register registers a new user
-func (s *AuthService) Register(ctx context.Context, req *RegisterRequest) (*AuthResponse, error) {
+func (s *AuthService) Register(ctx context.Context, req *RegisterRequest, client *ClientInfo) (*AuthResponse, error) {
+	// Banned IPs cannot create new accounts
+	if err := s.checkIPBan(ctx, client.IP); err != nil {
+		return nil, err
+	}
+
 	// Check if username already exists
 	existingUser, err := s.userRepo.FindByUsername(ctx, req.Username)
 	if err != nil {
@@ -64,8 +227,12 @@ func (s *AuthService) Register(ctx context.Context, req *RegisterRequest) (*Auth
 		return nil, fmt.Errorf("用户名已存在")
 	}
 
+	if err := s.checkPasswordPolicy(req.Username, req.Password); err != nil {
+		return nil, err
+	}
+
 	// Hash password
-	hashedPassword, err := utils.HashPassword(req.Password)
+	hashedPassword, err := s.hasher.Hash(req.Password)
 	if err != nil {
 		return nil, fmt.Errorf("failed to hash password: %w", err)
 	}
@@ -80,75 +247,578 @@ func (s *AuthService) Register(ctx context.Context, req *RegisterRequest) (*Auth
 		return nil, fmt.Errorf("failed to create user: %w", err)
 	}
 
-	// Add user to default channel
-	defaultChannel, err := s.channelRepo.FindDefault(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to find default channel: %w", err)
-	}
-
-	if defaultChannel != nil {
-		member := &models.ChannelMember{
-			UserID:    user.ID,
-			ChannelID: defaultChannel.ID,
-		}
-		if err := s.channelMemberRepo.Create(ctx, member); err != nil {
-			// Log error but don't fail registration
-			fmt.Printf("Warning: failed to add user to default channel: %v\n", err)
-		}
+	if err := s.joinDefaultChannel(ctx, user); err != nil {
+		return nil, err
 	}
 
-	// Generate JWT token
-	token, err := utils.GenerateToken(user.ID, user.Username, s.jwtSecret)
+	resp, err := s.startSession(ctx, user, req.DeviceLabel, client, false)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate token: %w", err)
+		return nil, err
 	}
 
-	return &AuthResponse{
-		Message: "注册成功",
-		Token:   token,
-		User:    user.ToResponse(),
-	}, nil
+	resp.Message = "注册成功"
+	return resp, nil
 }
 
 // Login authenticates a user
-func (s *AuthService) Login(ctx context.Context, req *LoginRequest) (*AuthResponse, error) {
+func (s *AuthService) Login(ctx context.Context, req *LoginRequest, client *ClientInfo) (*AuthResponse, error) {
+	if err := s.checkIPBan(ctx, client.IP); err != nil {
+		return nil, err
+	}
+
+	// Throttled attempts are refused before any hashing work
+	if throttle := s.loginGuard.Check(req.Username, client.IP); throttle != nil {
+		return nil, &LoginThrottledError{RetryAfter: throttle.RetryAfter, Locked: throttle.Locked}
+	}
+
 	// Find user
 	user, err := s.userRepo.FindByUsername(ctx, req.Username)
 	if err != nil {
 		return nil, fmt.Errorf("failed to find user: %w", err)
 	}
 	if user == nil {
+		s.loginFailed(ctx, req.Username, client.IP, nil)
 		return nil, fmt.Errorf("用户名或密码错误")
 	}
 
 	// Verify password
-	if !utils.ComparePassword(user.Password, req.Password) {
+	if !s.verifyPassword(ctx, user, req.Password) {
+		s.loginFailed(ctx, req.Username, client.IP, user)
 		return nil, fmt.Errorf("用户名或密码错误")
 	}
+	s.loginGuard.RecordSuccess(req.Username)
 
-	// Update last login
-	if err := s.userRepo.UpdateLastLogin(ctx, user.ID); err != nil {
-		// Log error but don't fail login
-		fmt.Printf("Warning: failed to update last login: %v\n", err)
+	// Check ban (only after the password matched, so bans are not leaked)
+	if user.IsBanActive() {
+		return nil, &BanError{Reason: user.BanReason, BannedUntil: user.BannedUntil}
 	}
 
-	// Generate JWT token
-	token, err := utils.GenerateToken(user.ID, user.Username, s.jwtSecret)
+	// The second factor is checked by LoginTwoFactor
+	if user.TOTPEnabled {
+		return s.twoFactorChallenge(user)
+	}
+
+	return s.completeLogin(ctx, user, req.DeviceLabel, client, false)
+}
+
+// LoginTwoFactor completes a login with a TOTP or recovery code
+func (s *AuthService) LoginTwoFactor(ctx context.Context, req *LoginTwoFactorRequest, client *ClientInfo) (*AuthResponse, error) {
+	claims, err := utils.ValidateChallengeToken(req.ChallengeToken, s.jwtKeys)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate token: %w", err)
+		return nil, fmt.Errorf("验证已过期，请重新登录")
 	}
 
-	return &AuthResponse{
-		Message: "登录成功",
-		Token:   token,
-		User:    user.ToResponse(),
+	// Codes are guessed far more easily than passwords
+	if throttle := s.loginGuard.Check(claims.Username, client.IP); throttle != nil {
+		return nil, &LoginThrottledError{RetryAfter: throttle.RetryAfter, Locked: throttle.Locked}
+	}
+
+	userID, err := utils.ParseUserID(claims.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("验证已过期，请重新登录")
+	}
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+	if user == nil || !user.TOTPEnabled {
+		return nil, fmt.Errorf("验证已过期，请重新登录")
+	}
+	if user.IsBanActive() {
+		return nil, &BanError{Reason: user.BanReason, BannedUntil: user.BannedUntil}
+	}
+
+	ok, err := s.verifySecondFactor(ctx, user, req.Code, true)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		s.loginFailed(ctx, user.Username, client.IP, user)
+		return nil, fmt.Errorf("验证码错误")
+	}
+	s.loginGuard.RecordSuccess(user.Username)
+
+	return s.completeLogin(ctx, user, req.DeviceLabel, client, true)
+}
+
+// GetTwoFactorStatus returns a user's two-factor enrollment
+func (s *AuthService) GetTwoFactorStatus(ctx context.Context, userID primitive.ObjectID) (*TwoFactorStatus, error) {
+	user, err := s.findUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &TwoFactorStatus{
+		Enabled:           user.TOTPEnabled,
+		RecoveryCodesLeft: len(user.RecoveryCodes),
+	}, nil
+}
+
+// SetupTwoFactor generates a TOTP secret. It takes effect once EnableTwoFactor
+// confirms a code from it.
+func (s *AuthService) SetupTwoFactor(ctx context.Context, userID primitive.ObjectID) (*TwoFactorSetupResponse, error) {
+	user, err := s.findUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled {
+		return nil, fmt.Errorf("两步验证已启用")
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate TOTP secret: %w", err)
+	}
+	if err := s.userRepo.SetPendingTOTP(ctx, user.ID, secret); err != nil {
+		return nil, err
+	}
+
+	return &TwoFactorSetupResponse{
+		Secret:     secret,
+		OTPAuthURL: utils.TOTPProvisioningURI(totpIssuer, user.Username, secret),
 	}, nil
 }
 
+// EnableTwoFactor confirms the pending secret with a code, turns two-factor
+// authentication on and marks the current session as verified
+func (s *AuthService) EnableTwoFactor(ctx context.Context, userID primitive.ObjectID, sessionID string, req *TwoFactorCodeRequest) (*TwoFactorEnabledResponse, error) {
+	user, err := s.findUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled {
+		return nil, fmt.Errorf("两步验证已启用")
+	}
+	if user.TOTPPendingSecret == "" {
+		return nil, fmt.Errorf("请先生成两步验证密钥")
+	}
+
+	step, ok := utils.ValidateTOTP(user.TOTPPendingSecret, strings.TrimSpace(req.Code), time.Now())
+	if !ok {
+		return nil, fmt.Errorf("验证码错误")
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.userRepo.EnableTOTP(ctx, user.ID, user.TOTPPendingSecret, step, hashes); err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(ctx, &models.AuditLog{
+		ActorID:    user.ID,
+		Action:     models.AuditActionTwoFactorEnable,
+		TargetType: models.AuditTargetUser,
+		TargetID:   &user.ID,
+		TargetName: user.Username,
+	})
+
+	resp := &TwoFactorEnabledResponse{RecoveryCodes: codes}
+
+	// The code just proved the second factor for this session
+	if sessionObjID, err := primitive.ObjectIDFromHex(sessionID); err == nil {
+		if err := s.sessionRepo.SetTwoFactor(ctx, sessionObjID); err != nil {
+			return nil, err
+		}
+		resp.Token, err = utils.GenerateToken(user.ID, user.Username, sessionID, true, s.jwtKeys, s.tokens.AccessTTL())
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate token: %w", err)
+		}
+		resp.ExpiresIn = int(s.tokens.AccessTTL().Seconds())
+	}
+
+	return resp, nil
+}
+
+// DisableTwoFactor turns two-factor authentication off after checking a
+// TOTP or recovery code
+func (s *AuthService) DisableTwoFactor(ctx context.Context, userID primitive.ObjectID, req *TwoFactorCodeRequest) error {
+	user, err := s.findUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !user.TOTPEnabled {
+		return fmt.Errorf("两步验证未启用")
+	}
+
+	ok, err := s.verifySecondFactor(ctx, user, req.Code, true)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("验证码错误")
+	}
+
+	if err := s.userRepo.DisableTOTP(ctx, user.ID); err != nil {
+		return err
+	}
+
+	s.audit.Record(ctx, &models.AuditLog{
+		ActorID:    user.ID,
+		Action:     models.AuditActionTwoFactorDisable,
+		TargetType: models.AuditTargetUser,
+		TargetID:   &user.ID,
+		TargetName: user.Username,
+	})
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces a user's recovery codes after checking a TOTP code
+func (s *AuthService) RegenerateRecoveryCodes(ctx context.Context, userID primitive.ObjectID, req *TwoFactorCodeRequest) ([]string, error) {
+	user, err := s.findUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.TOTPEnabled {
+		return nil, fmt.Errorf("两步验证未启用")
+	}
+
+	ok, err := s.verifySecondFactor(ctx, user, req.Code, false)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("验证码错误")
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.userRepo.SetRecoveryCodes(ctx, user.ID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// ChangePassword sets a new password after checking the current one and
+// ends every other session of the user. It returns how many sessions were
+// ended.
+func (s *AuthService) ChangePassword(ctx context.Context, userID primitive.ObjectID, sessionID string, req *ChangePasswordRequest, client *ClientInfo) (int, error) {
+	user, err := s.findUser(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	if user.Password == "" {
+		return 0, fmt.Errorf("账号未设置密码")
+	}
+
+	// Guessing the current password is throttled like logins
+	if throttle := s.loginGuard.Check(user.Username, client.IP); throttle != nil {
+		return 0, &LoginThrottledError{RetryAfter: throttle.RetryAfter, Locked: throttle.Locked}
+	}
+	if !s.verifyPassword(ctx, user, req.CurrentPassword) {
+		s.loginFailed(ctx, user.Username, client.IP, user)
+		return 0, fmt.Errorf("当前密码错误")
+	}
+	s.loginGuard.RecordSuccess(user.Username)
+
+	if req.NewPassword == req.CurrentPassword {
+		return 0, fmt.Errorf("新密码不能与当前密码相同")
+	}
+	if err := s.setPassword(ctx, user, req.NewPassword); err != nil {
+		return 0, err
+	}
+
+	s.audit.Record(ctx, &models.AuditLog{
+		ActorID:    user.ID,
+		Action:     models.AuditActionPasswordChange,
+		TargetType: models.AuditTargetUser,
+		TargetID:   &user.ID,
+		TargetName: user.Username,
+	})
+
+	return s.RevokeOtherSessions(ctx, user.ID, sessionID)
+}
+
+// IssuePasswordReset issues a one-time token the user can set a new
+// password with. Earlier unused tokens of the user stop working. The
+// current password keeps working until the token is used.
+func (s *AuthService) IssuePasswordReset(ctx context.Context, userID, issuedBy primitive.ObjectID) (*PasswordResetResponse, error) {
+	user, err := s.findUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	// Bots authenticate with API keys only
+	if user.IsBot {
+		return nil, fmt.Errorf("机器人账号不能设置密码")
+	}
+
+	token, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate reset token: %w", err)
+	}
+
+	reset := &models.PasswordReset{
+		UserID:    user.ID,
+		TokenHash: utils.HashToken(token),
+		IssuedBy:  issuedBy,
+		ExpiresAt: time.Now().Add(time.Duration(s.passwords.ResetTTLMinutes) * time.Minute),
+	}
+	if err := s.resetRepo.Create(ctx, reset); err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(ctx, &models.AuditLog{
+		ActorID:    issuedBy,
+		Action:     models.AuditActionPasswordReset,
+		TargetType: models.AuditTargetUser,
+		TargetID:   &user.ID,
+		TargetName: user.Username,
+		After:      map[string]interface{}{"expiresAt": reset.ExpiresAt},
+	})
+
+	return &PasswordResetResponse{ResetToken: token, ExpiresAt: reset.ExpiresAt}, nil
+}
+
+// ResetPassword sets a new password with a reset token, ends every
+// session of the user and lifts a login lockout of the username
+func (s *AuthService) ResetPassword(ctx context.Context, req *ResetPasswordRequest) error {
+	tokenHash := utils.HashToken(req.Token)
+
+	// The policy is checked before the token is used up
+	reset, err := s.resetRepo.FindActive(ctx, tokenHash)
+	if err != nil {
+		return err
+	}
+	if reset == nil {
+		return fmt.Errorf("无效或已过期的重置令牌")
+	}
+	user, err := s.userRepo.FindByID(ctx, reset.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to find user: %w", err)
+	}
+	if user == nil {
+		return fmt.Errorf("无效或已过期的重置令牌")
+	}
+	if err := s.checkPasswordPolicy(user.Username, req.NewPassword); err != nil {
+		return err
+	}
+
+	if reset, err = s.resetRepo.Consume(ctx, tokenHash); err != nil {
+		return err
+	}
+	if reset == nil {
+		return fmt.Errorf("无效或已过期的重置令牌")
+	}
+	if err := s.setPassword(ctx, user, req.NewPassword); err != nil {
+		return err
+	}
+
+	s.audit.Record(ctx, &models.AuditLog{
+		ActorID:    user.ID,
+		Action:     models.AuditActionPasswordChange,
+		TargetType: models.AuditTargetUser,
+		TargetID:   &user.ID,
+		TargetName: user.Username,
+		Reason:     "使用管理员签发的重置令牌",
+	})
+
+	s.loginGuard.Clear(middleware.LoginKeyUsername, user.Username)
+	_, err = s.RevokeOtherSessions(ctx, user.ID, "")
+	return err
+}
+
+// Refresh exchanges a refresh token for a new access token and a new
+// refresh token. Presenting a token that was already used means it was
+// stolen, so the whole family is revoked.
+func (s *AuthService) Refresh(ctx context.Context, req *RefreshRequest, client *ClientInfo) (*AuthResponse, error) {
+	token, err := s.refreshRepo.FindByHash(ctx, utils.HashToken(req.RefreshToken))
+	if err != nil {
+		return nil, err
+	}
+	if token == nil || !token.ExpiresAt.After(time.Now()) {
+		return nil, fmt.Errorf("无效的刷新令牌")
+	}
+
+	// Claiming fails for a used or revoked token, including when a
+	// concurrent refresh with the same token won the race
+	claimed := false
+	if token.UsedAt == nil && token.RevokedAt == nil {
+		if claimed, err = s.refreshRepo.MarkUsed(ctx, token.ID); err != nil {
+			return nil, err
+		}
+	}
+	if !claimed {
+		if token.RevokedAt == nil {
+			log.Printf("🚨 Refresh token reuse detected for user %s, revoking token family %s", token.UserID.Hex(), token.FamilyID.Hex())
+		}
+		if err := s.revokeSession(ctx, token.FamilyID); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("刷新令牌已失效")
+	}
+
+	user, err := s.userRepo.FindByID(ctx, token.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+	if user == nil {
+		return nil, fmt.Errorf("无效的刷新令牌")
+	}
+	if user.IsBanActive() {
+		return nil, &BanError{Reason: user.BanReason, BannedUntil: user.BannedUntil}
+	}
+	if user.TokensRevokedAt != nil && token.CreatedAt.Before(*user.TokensRevokedAt) {
+		return nil, fmt.Errorf("刷新令牌已失效")
+	}
+
+	session, err := s.sessionRepo.FindByID(ctx, token.FamilyID)
+	if err != nil {
+		return nil, err
+	}
+	if session == nil {
+		return nil, fmt.Errorf("无效的刷新令牌")
+	}
+
+	resp, err := s.issueTokens(ctx, user, token.FamilyID, session.TwoFactor)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.sessionRepo.Touch(ctx, token.FamilyID, client.IP, time.Now().Add(s.tokens.RefreshTTL())); err != nil {
+		log.Printf("⚠️  Warning: Failed to update session: %v", err)
+	}
+
+	resp.Message = "令牌已刷新"
+	return resp, nil
+}
+
+// Logout ends the session of the refresh token and of the access token,
+// and puts the access token on the revocation list
+func (s *AuthService) Logout(ctx context.Context, req *LogoutRequest, claims *utils.JWTClaims) error {
+	families := make(map[primitive.ObjectID]bool)
+
+	if req.RefreshToken != "" {
+		token, err := s.refreshRepo.FindByHash(ctx, utils.HashToken(req.RefreshToken))
+		if err != nil {
+			return err
+		}
+		if token != nil {
+			families[token.FamilyID] = true
+		}
+	}
+
+	if claims != nil {
+		if familyID, err := primitive.ObjectIDFromHex(claims.SessionID); err == nil {
+			families[familyID] = true
+		}
+		if claims.ID != "" && claims.ExpiresAt != nil {
+			if err := s.revoke(ctx, models.RevocationKindToken, claims.ID, claims.ExpiresAt.Time); err != nil {
+				return err
+			}
+		}
+	} else if len(families) == 0 {
+		return fmt.Errorf("无效的刷新令牌")
+	}
+
+	for familyID := range families {
+		if err := s.revokeSession(ctx, familyID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// JWKS returns the public keys access tokens can be verified with
+func (s *AuthService) JWKS() *utils.JWKS {
+	return s.jwtKeys.JWKS()
+}
+
+// GetLoginLockouts returns the usernames and IPs currently throttled
+func (s *AuthService) GetLoginLockouts() []*middleware.LoginLockout {
+	return s.loginGuard.Lockouts()
+}
+
+// ClearLoginLockout lets a username or IP address log in again right away
+func (s *AuthService) ClearLoginLockout(ctx context.Context, kind, key string, clearedBy primitive.ObjectID) error {
+	if kind != middleware.LoginKeyUsername && kind != middleware.LoginKeyIP {
+		return fmt.Errorf("无效的锁定类型")
+	}
+	if !s.loginGuard.Clear(kind, key) {
+		return fmt.Errorf("锁定记录不存在")
+	}
+
+	entry := &models.AuditLog{
+		ActorID:    clearedBy,
+		Action:     models.AuditActionLoginUnlock,
+		TargetType: models.AuditTargetIP,
+		TargetName: key,
+	}
+	if kind == middleware.LoginKeyUsername {
+		entry.TargetType = models.AuditTargetUser
+		if user, err := s.userRepo.FindByUsername(ctx, key); err == nil && user != nil {
+			entry.TargetID = &user.ID
+		}
+	}
+	s.audit.Record(ctx, entry)
+	return nil
+}
+
+// OnSessionRevoked registers a callback invoked after a session was revoked
+func (s *AuthService) OnSessionRevoked(fn func(sessionID string)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onSessionRevoked = fn
+}
+
+// GetSessions returns a user's active sessions. currentSessionID marks
+// the session making the request.
+func (s *AuthService) GetSessions(ctx context.Context, userID primitive.ObjectID, currentSessionID string) ([]*models.SessionResponse, error) {
+	sessions, err := s.sessionRepo.FindActiveByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]*models.SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		responses = append(responses, session.ToResponse(currentSessionID))
+	}
+	return responses, nil
+}
+
+// RevokeUserSession ends one of a user's sessions
+func (s *AuthService) RevokeUserSession(ctx context.Context, userID primitive.ObjectID, sessionID string) error {
+	sessionObjID, err := primitive.ObjectIDFromHex(sessionID)
+	if err != nil {
+		return fmt.Errorf("会话不存在")
+	}
+
+	session, err := s.sessionRepo.FindByID(ctx, sessionObjID)
+	if err != nil {
+		return err
+	}
+	if session == nil || session.UserID != userID || session.RevokedAt != nil {
+		return fmt.Errorf("会话不存在")
+	}
+
+	return s.revokeSession(ctx, session.ID)
+}
+
+// RevokeOtherSessions ends every session of a user except the current one
+// and returns how many were ended
+func (s *AuthService) RevokeOtherSessions(ctx context.Context, userID primitive.ObjectID, currentSessionID string) (int, error) {
+	sessions, err := s.sessionRepo.FindActiveByUser(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	revoked := 0
+	for _, session := range sessions {
+		if session.ID.Hex() == currentSessionID {
+			continue
+		}
+		if err := s.revokeSession(ctx, session.ID); err != nil {
+			return revoked, err
+		}
+		revoked++
+	}
+	return revoked, nil
+}
+
 // VerifyToken verifies a JWT token and returns user info
 func (s *AuthService) VerifyToken(ctx context.Context, tokenString string) (*models.UserResponse, error) {
 	// Validate token
-	claims, err := utils.ValidateToken(tokenString, s.jwtSecret)
+	claims, err := utils.ValidateToken(tokenString, s.jwtKeys)
 	if err != nil {
 		return nil, fmt.Errorf("invalid token: %w", err)
 	}
@@ -168,5 +838,342 @@ func (s *AuthService) VerifyToken(ctx context.Context, tokenString string) (*mod
 		return nil, fmt.Errorf("user not found")
 	}
 
+	if user.IsBanActive() {
+		return nil, &BanError{Reason: user.BanReason, BannedUntil: user.BannedUntil}
+	}
+	if user.TokensRevokedAt != nil && claims.IssuedAt != nil &&
+		claims.IssuedAt.Time.Before(user.TokensRevokedAt.Truncate(time.Second)) {
+		return nil, fmt.Errorf("token revoked")
+	}
+	if s.banChecker.IsAccessTokenRevoked(claims.ID, claims.SessionID) {
+		return nil, fmt.Errorf("token revoked")
+	}
+
 	return user.ToResponse(), nil
 }
+
+// loginFailed counts a failed login and audits the lockouts and the
+// suspicious patterns it triggered. user is nil for unknown usernames.
+func (s *AuthService) loginFailed(ctx context.Context, username, ip string, user *models.User) {
+	failure := s.loginGuard.RecordFailure(username, ip)
+	lockoutMinutes := s.loginGuard.LockoutMinutes()
+
+	if failure.UserLocked {
+		log.Printf("🔒 Login locked for username %q after %d failed attempt(s)", username, failure.UserFailures)
+		entry := &models.AuditLog{
+			ActorID:       primitive.NilObjectID,
+			ActorUsername: SystemActorName,
+			Action:        models.AuditActionLoginLockout,
+			TargetType:    models.AuditTargetUser,
+			TargetName:    username,
+			Reason:        fmt.Sprintf("连续登录失败 %d 次，锁定 %d 分钟", failure.UserFailures, lockoutMinutes),
+			After:         map[string]interface{}{"ip": ip, "failures": failure.UserFailures},
+		}
+		if user != nil {
+			entry.TargetID = &user.ID
+		}
+		s.audit.Record(ctx, entry)
+	}
+
+	if failure.IPLocked {
+		log.Printf("🔒 Login locked for IP %s after %d failed attempt(s)", ip, failure.IPFailures)
+		s.audit.Record(ctx, &models.AuditLog{
+			ActorID:       primitive.NilObjectID,
+			ActorUsername: SystemActorName,
+			Action:        models.AuditActionLoginLockout,
+			TargetType:    models.AuditTargetIP,
+			TargetName:    ip,
+			Reason:        fmt.Sprintf("该 IP 登录失败 %d 次，锁定 %d 分钟", failure.IPFailures, lockoutMinutes),
+			After:         map[string]interface{}{"failures": failure.IPFailures, "usernames": failure.Usernames},
+		})
+	}
+
+	if failure.Suspicious {
+		log.Printf("🚨 IP %s failed to log in as %d different usernames", ip, failure.Usernames)
+		s.audit.Record(ctx, &models.AuditLog{
+			ActorID:       primitive.NilObjectID,
+			ActorUsername: SystemActorName,
+			Action:        models.AuditActionLoginSuspicious,
+			TargetType:    models.AuditTargetIP,
+			TargetName:    ip,
+			Reason:        fmt.Sprintf("同一 IP 尝试登录 %d 个不同的用户名", failure.Usernames),
+			After:         map[string]interface{}{"failures": failure.IPFailures, "usernames": failure.Usernames},
+		})
+	}
+}
+
+// completeLogin records a successful login and starts its session
+func (s *AuthService) completeLogin(ctx context.Context, user *models.User, deviceLabel string, client *ClientInfo, twoFactor bool) (*AuthResponse, error) {
+	// Update last login
+	if err := s.userRepo.UpdateLastLogin(ctx, user.ID, client.IP); err != nil {
+		// Log error but don't fail login
+		fmt.Printf("Warning: failed to update last login: %v\n", err)
+	}
+
+	resp, err := s.startSession(ctx, user, deviceLabel, client, twoFactor)
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Message = "登录成功"
+	return resp, nil
+}
+
+// verifyPassword checks a user's password. A legacy bcrypt hash, or an
+// argon2id hash with outdated parameters, is replaced by a current hash.
+func (s *AuthService) verifyPassword(ctx context.Context, user *models.User, password string) bool {
+	ok, needsRehash := s.hasher.Verify(user.Password, password)
+	if !ok || !needsRehash {
+		return ok
+	}
+
+	hash, err := s.hasher.Hash(password)
+	if err == nil {
+		err = s.userRepo.RehashPassword(ctx, user.ID, user.Password, hash)
+	}
+	if err != nil {
+		log.Printf("⚠️  Warning: Failed to upgrade password hash of %s: %v", user.Username, err)
+		return true
+	}
+	user.Password = hash
+	return true
+}
+
+// setPassword checks a new password against the policy and stores its hash
+func (s *AuthService) setPassword(ctx context.Context, user *models.User, password string) error {
+	if err := s.checkPasswordPolicy(user.Username, password); err != nil {
+		return err
+	}
+
+	hash, err := s.hasher.Hash(password)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+	if err := s.userRepo.UpdatePassword(ctx, user.ID, hash); err != nil {
+		return err
+	}
+	user.Password = hash
+	return nil
+}
+
+// checkPasswordPolicy returns a PasswordPolicyError if a new password is
+// too short or too long, lacks character classes or contains the username
+func (s *AuthService) checkPasswordPolicy(username, password string) error {
+	length := utf8.RuneCountInString(password)
+	if length < s.passwords.MinLength {
+		return &PasswordPolicyError{Message: fmt.Sprintf("密码长度至少为%d个字符", s.passwords.MinLength)}
+	}
+	if s.passwords.MaxLength > 0 && length > s.passwords.MaxLength {
+		return &PasswordPolicyError{Message: fmt.Sprintf("密码长度不能超过%d个字符", s.passwords.MaxLength)}
+	}
+
+	var lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+	classes := 0
+	for _, present := range []bool{lower, upper, digit, symbol} {
+		if present {
+			classes++
+		}
+	}
+	if classes < s.passwords.MinClasses {
+		return &PasswordPolicyError{Message: fmt.Sprintf("密码需要包含小写字母、大写字母、数字和符号中的至少%d种", s.passwords.MinClasses)}
+	}
+
+	// Very short usernames would rule out too many passwords
+	if s.passwords.RejectUsername && utf8.RuneCountInString(username) >= 3 &&
+		strings.Contains(strings.ToLower(password), strings.ToLower(username)) {
+		return &PasswordPolicyError{Message: "密码不能包含用户名"}
+	}
+	return nil
+}
+
+// twoFactorChallenge returns the response asking for a second factor,
+// with a challenge token to continue the login at LoginTwoFactor
+func (s *AuthService) twoFactorChallenge(user *models.User) (*AuthResponse, error) {
+	challenge, err := utils.GenerateChallengeToken(user.ID, user.Username, s.jwtKeys, challengeTTL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+	return &AuthResponse{
+		Message:           "请输入两步验证码",
+		TwoFactorRequired: true,
+		ChallengeToken:    challenge,
+	}, nil
+}
+
+// joinDefaultChannel adds a new user to the default channel
+func (s *AuthService) joinDefaultChannel(ctx context.Context, user *models.User) error {
+	defaultChannel, err := s.channelRepo.FindDefault(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to find default channel: %w", err)
+	}
+
+	if defaultChannel != nil {
+		member := &models.ChannelMember{
+			UserID:    user.ID,
+			ChannelID: defaultChannel.ID,
+		}
+		if err := s.channelMemberRepo.Create(ctx, member); err != nil {
+			// Log error but don't fail registration
+			fmt.Printf("Warning: failed to add user to default channel: %v\n", err)
+		}
+	}
+	return nil
+}
+
+// verifySecondFactor checks a TOTP code, or a recovery code if allowed.
+// Each code is accepted once.
+func (s *AuthService) verifySecondFactor(ctx context.Context, user *models.User, code string, allowRecovery bool) (bool, error) {
+	code = strings.TrimSpace(code)
+
+	if step, ok := utils.ValidateTOTP(user.TOTPSecret, code, time.Now()); ok {
+		return s.userRepo.UseTOTPStep(ctx, user.ID, step)
+	}
+	if !allowRecovery {
+		return false, nil
+	}
+
+	used, err := s.userRepo.UseRecoveryCode(ctx, user.ID, utils.HashToken(utils.NormalizeRecoveryCode(code)))
+	if used {
+		log.Printf("🔑 User %s used a recovery code (%d left)", user.Username, len(user.RecoveryCodes)-1)
+	}
+	return used, err
+}
+
+// findUser finds a user that must exist
+func (s *AuthService) findUser(ctx context.Context, userID primitive.ObjectID) (*models.User, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+	if user == nil {
+		return nil, fmt.Errorf("用户不存在")
+	}
+	return user, nil
+}
+
+// newRecoveryCodes generates recovery codes and the hashes stored for them
+func newRecoveryCodes() ([]string, []string, error) {
+	codes, err := utils.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate recovery codes: %w", err)
+	}
+
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = utils.HashToken(utils.NormalizeRecoveryCode(code))
+	}
+	return codes, hashes, nil
+}
+
+// startSession creates a session for a login and issues its first tokens
+func (s *AuthService) startSession(ctx context.Context, user *models.User, deviceLabel string, client *ClientInfo, twoFactor bool) (*AuthResponse, error) {
+	deviceLabel = strings.TrimSpace(deviceLabel)
+	if deviceLabel == "" {
+		deviceLabel = utils.DeviceLabel(client.UserAgent)
+	}
+
+	session := &models.Session{
+		ID:          primitive.NewObjectID(),
+		UserID:      user.ID,
+		DeviceLabel: deviceLabel,
+		UserAgent:   client.UserAgent,
+		IP:          client.IP,
+		ExpiresAt:   time.Now().Add(s.tokens.RefreshTTL()),
+		TwoFactor:   twoFactor,
+	}
+	if err := s.sessionRepo.Create(ctx, session); err != nil {
+		return nil, err
+	}
+
+	return s.issueTokens(ctx, user, session.ID, twoFactor)
+}
+
+// issueTokens issues an access token and a refresh token in a token family
+func (s *AuthService) issueTokens(ctx context.Context, user *models.User, familyID primitive.ObjectID, twoFactor bool) (*AuthResponse, error) {
+	accessToken, err := utils.GenerateToken(user.ID, user.Username, familyID.Hex(), twoFactor, s.jwtKeys, s.tokens.AccessTTL())
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	refreshToken, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+
+	if err := s.refreshRepo.Create(ctx, &models.RefreshToken{
+		UserID:    user.ID,
+		FamilyID:  familyID,
+		TokenHash: utils.HashToken(refreshToken),
+		ExpiresAt: time.Now().Add(s.tokens.RefreshTTL()),
+	}); err != nil {
+		return nil, err
+	}
+
+	return &AuthResponse{
+		Token:        accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(s.tokens.AccessTTL().Seconds()),
+		User:         user.ToResponse(),
+	}, nil
+}
+
+// revokeSession ends a session: its refresh token family and every access
+// token issued for it are revoked, and its live connections are closed
+func (s *AuthService) revokeSession(ctx context.Context, sessionID primitive.ObjectID) error {
+	if err := s.refreshRepo.RevokeFamily(ctx, sessionID); err != nil {
+		return err
+	}
+	// Access tokens of the session expire within one access token lifetime
+	if err := s.revoke(ctx, models.RevocationKindSession, sessionID.Hex(), time.Now().Add(s.tokens.AccessTTL())); err != nil {
+		return err
+	}
+	if err := s.sessionRepo.Revoke(ctx, sessionID); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	onSessionRevoked := s.onSessionRevoked
+	s.mu.Unlock()
+	if onSessionRevoked != nil {
+		onSessionRevoked(sessionID.Hex())
+	}
+	return nil
+}
+
+// revoke adds an entry to the access token revocation list
+func (s *AuthService) revoke(ctx context.Context, kind, value string, expiresAt time.Time) error {
+	entry := &models.RevokedToken{Kind: kind, Value: value, ExpiresAt: expiresAt}
+	if err := s.revokedRepo.Create(ctx, entry); err != nil {
+		return err
+	}
+	s.banChecker.AddRevocation(entry)
+	return nil
+}
+
+// checkIPBan returns a BanError if the IP address is banned
+func (s *AuthService) checkIPBan(ctx context.Context, clientIP string) error {
+	if clientIP == "" {
+		return nil
+	}
+
+	ban, err := s.banRepo.FindActiveByIP(ctx, clientIP)
+	if err != nil {
+		return fmt.Errorf("failed to check IP ban: %w", err)
+	}
+	if ban != nil {
+		return &BanError{Reason: ban.Reason, BannedUntil: ban.BannedUntil}
+	}
+	return nil
+}
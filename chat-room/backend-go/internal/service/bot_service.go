@@ -0,0 +1,429 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"chat-room-backend/internal/config"
+	"chat-room-backend/internal/middleware"
+	"chat-room-backend/internal/models"
+	"chat-room-backend/internal/repository"
+	"chat-room-backend/internal/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// apiKeyPrefix marks bot API keys, so leaked keys are easy to recognise
+const apiKeyPrefix = "bot_"
+
+// apiKeyPrefixLength is how much of a key is kept to tell keys apart
+const apiKeyPrefixLength = len(apiKeyPrefix) + 6
+
+// BotService manages bot accounts and their API keys, and handles
+// messages sent through the bot API. Bot messages pass through the same
+// posting permissions, mutes, word filters and slow mode as user messages.
+type BotService struct {
+	userRepo          *repository.UserRepository
+	keyRepo           *repository.APIKeyRepository
+	channelMemberRepo *repository.ChannelMemberRepository
+	channelService    *ChannelService
+	chatService       *ChatService
+	moderation        *ModerationService
+	memberships       *middleware.MembershipCache
+	posting           *middleware.PostingPermissions
+	floodGuard        *middleware.FloodGuard
+	audit             *AuditService
+}
+
+// NewBotService creates a new BotService. Bots get their own rate limits,
+// separate from those of WebSocket users.
+func NewBotService(
+	userRepo *repository.UserRepository,
+	keyRepo *repository.APIKeyRepository,
+	channelMemberRepo *repository.ChannelMemberRepository,
+	channelService *ChannelService,
+	chatService *ChatService,
+	moderation *ModerationService,
+	memberships *middleware.MembershipCache,
+	posting *middleware.PostingPermissions,
+	audit *AuditService,
+	rateLimit config.RateLimitConfig,
+) *BotService {
+	return &BotService{
+		userRepo:          userRepo,
+		keyRepo:           keyRepo,
+		channelMemberRepo: channelMemberRepo,
+		channelService:    channelService,
+		chatService:       chatService,
+		moderation:        moderation,
+		memberships:       memberships,
+		posting:           posting,
+		floodGuard:        middleware.NewFloodGuard(rateLimit),
+		audit:             audit,
+	}
+}
+
+// BotBlockedError is returned when a bot request is refused by the rate
+// limits or by moderation. RetryAfter is set when waiting helps.
+type BotBlockedError struct {
+	Reason     string
+	RetryAfter time.Duration
+}
+
+func (e *BotBlockedError) Error() string {
+	return e.Reason
+}
+
+// CreateBotRequest represents bot creation data
+type CreateBotRequest struct {
+	Username string `json:"username" binding:"required,min=2,max=20"`
+}
+
+// CreateAPIKeyRequest represents API key creation data
+type CreateAPIKeyRequest struct {
+	Name          string   `json:"name" binding:"required,max=50"`
+	Scopes        []string `json:"scopes" binding:"required,min=1"`
+	ExpiresInDays int      `json:"expiresInDays" binding:"min=0"` // 0 for keys that never expire
+}
+
+// APIKeyResponse is a newly created API key. The key is only shown here.
+type APIKeyResponse struct {
+	Key    string         `json:"key"`
+	APIKey *models.APIKey `json:"apiKey"`
+}
+
+// BotMessageRequest represents a message sent by a bot
+type BotMessageRequest struct {
+	Message string `json:"message" binding:"required,max=4000"`
+}
+
+// GetBots returns all bot accounts
+func (s *BotService) GetBots(ctx context.Context) ([]*models.User, error) {
+	return s.userRepo.FindBots(ctx)
+}
+
+// GetBot returns a bot account
+func (s *BotService) GetBot(ctx context.Context, botID primitive.ObjectID) (*models.User, error) {
+	return s.findBot(ctx, botID)
+}
+
+// CreateBot creates a bot account owned by an admin. Bots have no
+// password and cannot log in; they use API keys.
+func (s *BotService) CreateBot(ctx context.Context, req *CreateBotRequest, ownerID primitive.ObjectID) (*models.User, error) {
+	username := strings.TrimSpace(req.Username)
+
+	existing, err := s.userRepo.FindByUsername(ctx, username)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check username: %w", err)
+	}
+	if existing != nil {
+		return nil, fmt.Errorf("用户名已存在")
+	}
+
+	bot := &models.User{
+		Username:   username,
+		IsBot:      true,
+		BotOwnerID: &ownerID,
+	}
+	if err := s.userRepo.Create(ctx, bot); err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(ctx, &models.AuditLog{
+		ActorID:    ownerID,
+		Action:     models.AuditActionBotCreate,
+		TargetType: models.AuditTargetBot,
+		TargetID:   &bot.ID,
+		TargetName: bot.Username,
+		After: map[string]interface{}{
+			"username": bot.Username,
+		},
+	})
+
+	return bot, nil
+}
+
+// DeleteBot revokes a bot's keys, removes it from its channels and
+// deletes the account. Its messages are kept.
+func (s *BotService) DeleteBot(ctx context.Context, botID, actorID primitive.ObjectID) error {
+	bot, err := s.findBot(ctx, botID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.keyRepo.RevokeAllForBot(ctx, bot.ID); err != nil {
+		return err
+	}
+	if err := s.channelMemberRepo.DeleteByUser(ctx, bot.ID); err != nil {
+		return err
+	}
+	if err := s.userRepo.Delete(ctx, bot.ID); err != nil {
+		return err
+	}
+	s.memberships.Invalidate(bot.ID)
+
+	s.audit.Record(ctx, &models.AuditLog{
+		ActorID:    actorID,
+		Action:     models.AuditActionBotDelete,
+		TargetType: models.AuditTargetBot,
+		TargetID:   &bot.ID,
+		TargetName: bot.Username,
+		Before: map[string]interface{}{
+			"username": bot.Username,
+		},
+	})
+
+	return nil
+}
+
+// GetAPIKeys returns a bot's API keys, including revoked ones
+func (s *BotService) GetAPIKeys(ctx context.Context, botID primitive.ObjectID) ([]*models.APIKey, error) {
+	bot, err := s.findBot(ctx, botID)
+	if err != nil {
+		return nil, err
+	}
+	return s.keyRepo.FindByBot(ctx, bot.ID)
+}
+
+// CreateAPIKey creates an API key for a bot
+func (s *BotService) CreateAPIKey(ctx context.Context, botID primitive.ObjectID, req *CreateAPIKeyRequest, actorID primitive.ObjectID) (*APIKeyResponse, error) {
+	for _, scope := range req.Scopes {
+		if !models.IsAPIKeyScope(scope) {
+			return nil, fmt.Errorf("未知的权限范围: %s", scope)
+		}
+	}
+
+	bot, err := s.findBot(ctx, botID)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := generateAPIKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate API key: %w", err)
+	}
+
+	apiKey := &models.APIKey{
+		BotID:     bot.ID,
+		Name:      strings.TrimSpace(req.Name),
+		Prefix:    key[:apiKeyPrefixLength],
+		KeyHash:   utils.HashToken(key),
+		Scopes:    req.Scopes,
+		CreatedBy: actorID,
+	}
+	if req.ExpiresInDays > 0 {
+		expiresAt := time.Now().AddDate(0, 0, req.ExpiresInDays)
+		apiKey.ExpiresAt = &expiresAt
+	}
+	if err := s.keyRepo.Create(ctx, apiKey); err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(ctx, &models.AuditLog{
+		ActorID:    actorID,
+		Action:     models.AuditActionBotKeyCreate,
+		TargetType: models.AuditTargetBot,
+		TargetID:   &bot.ID,
+		TargetName: bot.Username,
+		After: map[string]interface{}{
+			"keyId":     apiKey.ID,
+			"name":      apiKey.Name,
+			"prefix":    apiKey.Prefix,
+			"scopes":    apiKey.Scopes,
+			"expiresAt": apiKey.ExpiresAt,
+		},
+	})
+
+	return &APIKeyResponse{Key: key, APIKey: apiKey}, nil
+}
+
+// generateAPIKey returns a new random API key
+func generateAPIKey() (string, error) {
+	token, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+	return apiKeyPrefix + token, nil
+}
+
+// RevokeAPIKey revokes one of a bot's API keys
+func (s *BotService) RevokeAPIKey(ctx context.Context, botID primitive.ObjectID, keyID string, actorID primitive.ObjectID) error {
+	keyObjID, err := primitive.ObjectIDFromHex(keyID)
+	if err != nil {
+		return fmt.Errorf("API 密钥不存在")
+	}
+
+	bot, err := s.findBot(ctx, botID)
+	if err != nil {
+		return err
+	}
+
+	apiKey, err := s.keyRepo.Revoke(ctx, bot.ID, keyObjID)
+	if err != nil {
+		return err
+	}
+	if apiKey == nil {
+		return fmt.Errorf("API 密钥不存在")
+	}
+
+	s.audit.Record(ctx, &models.AuditLog{
+		ActorID:    actorID,
+		Action:     models.AuditActionBotKeyRevoke,
+		TargetType: models.AuditTargetBot,
+		TargetID:   &bot.ID,
+		TargetName: bot.Username,
+		Before: map[string]interface{}{
+			"keyId":  apiKey.ID,
+			"name":   apiKey.Name,
+			"prefix": apiKey.Prefix,
+		},
+	})
+
+	return nil
+}
+
+// AddToChannel makes a bot a member of a channel
+func (s *BotService) AddToChannel(ctx context.Context, botID primitive.ObjectID, channelID string, actorID primitive.ObjectID) error {
+	bot, err := s.findBot(ctx, botID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.channelService.JoinChannel(ctx, bot.ID, channelID); err != nil {
+		if err.Error() == "您已经是该频道成员" {
+			return fmt.Errorf("机器人已在该频道中")
+		}
+		return err
+	}
+	s.memberships.Invalidate(bot.ID)
+
+	s.recordChannelChange(ctx, bot, channelID, actorID, models.AuditActionBotChannelAdd)
+	return nil
+}
+
+// RemoveFromChannel removes a bot from a channel. Unlike users, bots can
+// be removed from the default channel.
+func (s *BotService) RemoveFromChannel(ctx context.Context, botID primitive.ObjectID, channelID string, actorID primitive.ObjectID) error {
+	channelObjID, err := primitive.ObjectIDFromHex(channelID)
+	if err != nil {
+		return fmt.Errorf("频道不存在")
+	}
+
+	bot, err := s.findBot(ctx, botID)
+	if err != nil {
+		return err
+	}
+
+	existing, err := s.channelMemberRepo.FindByUserAndChannel(ctx, bot.ID, channelObjID)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return fmt.Errorf("机器人不在该频道中")
+	}
+
+	if err := s.channelMemberRepo.Delete(ctx, bot.ID, channelObjID); err != nil {
+		return err
+	}
+	s.memberships.Invalidate(bot.ID)
+	s.posting.RemoveUser(bot.ID, channelID)
+
+	s.recordChannelChange(ctx, bot, channelID, actorID, models.AuditActionBotChannelRemove)
+	return nil
+}
+
+// GetChannels returns the channels a bot was added to
+func (s *BotService) GetChannels(ctx context.Context, botID primitive.ObjectID) ([]*models.Channel, error) {
+	return s.channelService.GetUserChannels(ctx, botID)
+}
+
+// SendMessage posts a bot message to a channel the bot was added to. It
+// returns the saved message and, if the message was queued for review,
+// the new report.
+func (s *BotService) SendMessage(ctx context.Context, botID primitive.ObjectID, username, channelID, text string) (*models.Message, *models.Report, error) {
+	message := strings.TrimSpace(text)
+	if message == "" {
+		return nil, nil, fmt.Errorf("消息不能为空")
+	}
+
+	if verdict := s.floodGuard.Allow(botID, middleware.FloodClassMessage); !verdict.Allowed {
+		return nil, nil, &BotBlockedError{Reason: verdict.Reason, RetryAfter: verdict.RetryAfter}
+	}
+
+	if err := s.checkMember(ctx, botID, channelID); err != nil {
+		return nil, nil, err
+	}
+
+	// Bots are never exempt from moderation
+	checked, err := s.moderation.CheckMessage(ctx, botID, false, channelID, message)
+	if err != nil {
+		var blocked *MessageBlockedError
+		if errors.As(err, &blocked) {
+			return nil, nil, &BotBlockedError{Reason: blocked.Reason, RetryAfter: blocked.RetryAfter}
+		}
+		return nil, nil, err
+	}
+
+	return s.moderation.PostMessage(ctx, checked, func(text string) (*models.Message, error) {
+		return s.chatService.SendBotMessage(ctx, botID, username, text, channelID)
+	})
+}
+
+// GetHistory returns recent messages of a channel the bot was added to
+func (s *BotService) GetHistory(ctx context.Context, botID primitive.ObjectID, channelID string, limit int) ([]*models.Message, error) {
+	if verdict := s.floodGuard.Allow(botID, middleware.FloodClassEvent); !verdict.Allowed {
+		return nil, &BotBlockedError{Reason: verdict.Reason, RetryAfter: verdict.RetryAfter}
+	}
+
+	if err := s.checkMember(ctx, botID, channelID); err != nil {
+		return nil, err
+	}
+
+	return s.chatService.GetChannelHistory(ctx, channelID, limit)
+}
+
+// checkMember fails unless the bot was added to the channel
+func (s *BotService) checkMember(ctx context.Context, botID primitive.ObjectID, channelID string) error {
+	channelObjID, err := primitive.ObjectIDFromHex(channelID)
+	if err != nil {
+		return fmt.Errorf("无效的频道ID")
+	}
+
+	isMember, err := s.memberships.IsMember(ctx, botID, channelObjID)
+	if err != nil {
+		return fmt.Errorf("failed to verify channel membership: %w", err)
+	}
+	if !isMember {
+		return fmt.Errorf("机器人不在该频道中")
+	}
+	return nil
+}
+
+// findBot finds a bot account
+func (s *BotService) findBot(ctx context.Context, botID primitive.ObjectID) (*models.User, error) {
+	bot, err := s.userRepo.FindByID(ctx, botID)
+	if err != nil {
+		return nil, err
+	}
+	if bot == nil || !bot.IsBot {
+		return nil, fmt.Errorf("机器人不存在")
+	}
+	return bot, nil
+}
+
+// recordChannelChange audits a bot being added to or removed from a channel
+func (s *BotService) recordChannelChange(ctx context.Context, bot *models.User, channelID string, actorID primitive.ObjectID, action string) {
+	s.audit.Record(ctx, &models.AuditLog{
+		ActorID:    actorID,
+		Action:     action,
+		TargetType: models.AuditTargetBot,
+		TargetID:   &bot.ID,
+		TargetName: bot.Username,
+		After: map[string]interface{}{
+			"channelId": channelID,
+			"isMember":  action == models.AuditActionBotChannelAdd,
+		},
+	})
+}
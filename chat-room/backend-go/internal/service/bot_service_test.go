@@ -0,0 +1,46 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"chat-room-backend/internal/models"
+	"chat-room-backend/internal/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestGenerateAPIKey(t *testing.T) {
+	first, err := generateAPIKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := generateAPIKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []string{first, second} {
+		if !strings.HasPrefix(key, apiKeyPrefix) || len(key) <= apiKeyPrefixLength {
+			t.Errorf("key %q lacks the %q prefix", key, apiKeyPrefix)
+		}
+		// Keys are looked up by the hash of the key presented
+		if hash := utils.HashToken(key); len(hash) != 64 || hash != utils.HashToken(strings.Clone(key)) {
+			t.Errorf("HashToken(%q) = %q", key, hash)
+		}
+	}
+	if first == second || utils.HashToken(first) == utils.HashToken(second) {
+		t.Error("two keys are the same")
+	}
+}
+
+func TestCreateAPIKeyRejectsUnknownScopes(t *testing.T) {
+	// No repositories: unknown scopes are refused before anything is stored
+	s := &BotService{}
+	req := &CreateAPIKeyRequest{Name: "deploy", Scopes: []string{models.APIKeyScopeMessagesRead, "users.admin"}}
+
+	_, err := s.CreateAPIKey(context.Background(), primitive.NewObjectID(), req, primitive.NewObjectID())
+	if err == nil || err.Error() != "未知的权限范围: users.admin" {
+		t.Errorf("err = %v", err)
+	}
+}
@@ -13,16 +13,19 @@ import (
 type ChannelService struct {
 	channelRepo       *repository.ChannelRepository
 	channelMemberRepo *repository.ChannelMemberRepository
+	audit             *AuditService
 }
 
 // NewChannelService creates a new ChannelService
 func NewChannelService(
 	channelRepo *repository.ChannelRepository,
 	channelMemberRepo *repository.ChannelMemberRepository,
+	audit *AuditService,
 ) *ChannelService {
 	return &ChannelService{
 		channelRepo:       channelRepo,
 		channelMemberRepo: channelMemberRepo,
+		audit:             audit,
 	}
 }
 
@@ -33,6 +36,21 @@ type CreateChannelRequest struct {
 	Icon        string `json:"icon"`
 }
 
+// KickMemberRequest represents channel kick data
+type KickMemberRequest struct {
+	UserID string `json:"userId" binding:"required"`
+}
+
+// PostingPolicyRequest represents channel posting settings
+type PostingPolicyRequest struct {
+	Policy string `json:"policy" binding:"required,oneof=everyone restricted"`
+}
+
+// SlowModeRequest represents slow mode settings
+type SlowModeRequest struct {
+	Seconds int `json:"seconds" binding:"min=0"` // 0 turns slow mode off
+}
+
 // GetUserChannels returns all channels a user has joined
 func (s *ChannelService) GetUserChannels(ctx context.Context, userID primitive.ObjectID) ([]*models.Channel, error) {
 	// Get user's channel memberships
@@ -189,6 +207,174 @@ func (s *ChannelService) LeaveChannel(ctx context.Context, userID primitive.Obje
 	return nil
 }
 
+// KickMember removes a user from a channel on behalf of an admin
+func (s *ChannelService) KickMember(ctx context.Context, userID primitive.ObjectID, channelID string, kickedBy primitive.ObjectID) error {
+	channelObjID, err := primitive.ObjectIDFromHex(channelID)
+	if err != nil {
+		return fmt.Errorf("invalid channel ID: %w", err)
+	}
+
+	channel, err := s.channelRepo.FindByID(ctx, channelObjID)
+	if err != nil {
+		return fmt.Errorf("failed to find channel: %w", err)
+	}
+	if channel == nil {
+		return fmt.Errorf("频道不存在")
+	}
+	if channel.IsDefault {
+		return fmt.Errorf("不能将用户移出默认频道")
+	}
+
+	existing, err := s.channelMemberRepo.FindByUserAndChannel(ctx, userID, channelObjID)
+	if err != nil {
+		return fmt.Errorf("failed to check membership: %w", err)
+	}
+	if existing == nil {
+		return fmt.Errorf("该用户不是频道成员")
+	}
+
+	if err := s.channelMemberRepo.Delete(ctx, userID, channelObjID); err != nil {
+		return fmt.Errorf("failed to kick member: %w", err)
+	}
+
+	s.audit.Record(ctx, &models.AuditLog{
+		ActorID:    kickedBy,
+		Action:     models.AuditActionChannelKick,
+		TargetType: models.AuditTargetUser,
+		TargetID:   &userID,
+		TargetName: channel.Name,
+		Before: map[string]interface{}{
+			"channelId": channelObjID,
+			"isMember":  true,
+			"joinedAt":  existing.JoinedAt,
+		},
+		After: map[string]interface{}{
+			"channelId": channelObjID,
+			"isMember":  false,
+		},
+	})
+
+	return nil
+}
+
+// SetSlowMode sets a channel's slow mode interval. Admins and the
+// channel's moderators may change it.
+func (s *ChannelService) SetSlowMode(ctx context.Context, channelID string, seconds int, actorID primitive.ObjectID, isAdmin bool) (*models.Channel, error) {
+	channelObjID, err := primitive.ObjectIDFromHex(channelID)
+	if err != nil {
+		return nil, fmt.Errorf("频道不存在")
+	}
+	if seconds < 0 || seconds > models.MaxSlowModeSeconds {
+		return nil, fmt.Errorf("慢速模式间隔须在 0 到 %d 秒之间", models.MaxSlowModeSeconds)
+	}
+
+	channel, err := s.channelRepo.FindByID(ctx, channelObjID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find channel: %w", err)
+	}
+	if channel == nil {
+		return nil, fmt.Errorf("频道不存在")
+	}
+
+	if !isAdmin {
+		member, err := s.channelMemberRepo.FindByUserAndChannel(ctx, actorID, channelObjID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check membership: %w", err)
+		}
+		if member == nil || !member.IsModerator() {
+			return nil, fmt.Errorf("需要频道管理权限")
+		}
+	}
+
+	if err := s.channelRepo.SetSlowMode(ctx, channelObjID, seconds); err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(ctx, &models.AuditLog{
+		ActorID:    actorID,
+		Action:     models.AuditActionChannelSlowMode,
+		TargetType: models.AuditTargetChannel,
+		TargetID:   &channelObjID,
+		TargetName: channel.Name,
+		Before:     map[string]interface{}{"slowModeSeconds": channel.SlowModeSeconds},
+		After:      map[string]interface{}{"slowModeSeconds": seconds},
+	})
+
+	channel.SlowModeSeconds = seconds
+	return channel, nil
+}
+
+// SetPostingPolicy sets who may post in a channel
+func (s *ChannelService) SetPostingPolicy(ctx context.Context, channelID, policy string, actorID primitive.ObjectID) (*models.Channel, error) {
+	channelObjID, err := primitive.ObjectIDFromHex(channelID)
+	if err != nil {
+		return nil, fmt.Errorf("频道不存在")
+	}
+
+	channel, err := s.channelRepo.FindByID(ctx, channelObjID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find channel: %w", err)
+	}
+	if channel == nil {
+		return nil, fmt.Errorf("频道不存在")
+	}
+
+	if err := s.channelRepo.SetPostingPolicy(ctx, channelObjID, policy); err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(ctx, &models.AuditLog{
+		ActorID:    actorID,
+		Action:     models.AuditActionChannelPosting,
+		TargetType: models.AuditTargetChannel,
+		TargetID:   &channelObjID,
+		TargetName: channel.Name,
+		Before:     map[string]interface{}{"postingPolicy": channel.GetPostingPolicy()},
+		After:      map[string]interface{}{"postingPolicy": policy},
+	})
+
+	channel.PostingPolicy = policy
+	return channel, nil
+}
+
+// SetMemberRole sets a member's role in a channel (member, moderator or poster)
+func (s *ChannelService) SetMemberRole(ctx context.Context, channelID string, userID primitive.ObjectID, role string, setBy primitive.ObjectID) error {
+	channelObjID, err := primitive.ObjectIDFromHex(channelID)
+	if err != nil {
+		return fmt.Errorf("频道不存在")
+	}
+
+	channel, err := s.channelRepo.FindByID(ctx, channelObjID)
+	if err != nil {
+		return fmt.Errorf("failed to find channel: %w", err)
+	}
+	if channel == nil {
+		return fmt.Errorf("频道不存在")
+	}
+
+	found, err := s.channelMemberRepo.SetRole(ctx, userID, channelObjID, role)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("该用户不是频道成员")
+	}
+
+	s.audit.Record(ctx, &models.AuditLog{
+		ActorID:    setBy,
+		Action:     models.AuditActionChannelModerator,
+		TargetType: models.AuditTargetUser,
+		TargetID:   &userID,
+		TargetName: channel.Name,
+		After: map[string]interface{}{
+			"channelId": channelObjID,
+			"role":      role,
+		},
+	})
+
+	return nil
+}
+
 // GetChannelByID returns a channel by ID
 func (s *ChannelService) GetChannelByID(ctx context.Context, channelID string) (*models.Channel, error) {
 	channelObjID, err := primitive.ObjectIDFromHex(channelID)
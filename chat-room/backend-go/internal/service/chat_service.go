@@ -16,8 +16,8 @@ import (
 
 // ChatService handles chat-related business logic
 type ChatService struct {
-	messageRepo   *repository.MessageRepository
-	aiServiceURL  string
+	messageRepo  *repository.MessageRepository
+	aiServiceURL string
 }
 
 // NewChatService creates a new ChatService
@@ -36,6 +36,16 @@ type SendMessageRequest struct {
 
 // SendMessage saves a message to the database
 func (s *ChatService) SendMessage(ctx context.Context, userID primitive.ObjectID, username, message, channelID string) (*models.Message, error) {
+	return s.saveMessage(ctx, userID, username, message, channelID, models.MessageTypeUser)
+}
+
+// SendBotMessage saves a message posted through the bot API
+func (s *ChatService) SendBotMessage(ctx context.Context, botID primitive.ObjectID, username, message, channelID string) (*models.Message, error) {
+	return s.saveMessage(ctx, botID, username, message, channelID, models.MessageTypeBot)
+}
+
+// saveMessage saves a message of a type
+func (s *ChatService) saveMessage(ctx context.Context, userID primitive.ObjectID, username, message, channelID, messageType string) (*models.Message, error) {
 	channelObjID, err := primitive.ObjectIDFromHex(channelID)
 	if err != nil {
 		return nil, fmt.Errorf("invalid channel ID: %w", err)
@@ -46,7 +56,7 @@ func (s *ChatService) SendMessage(ctx context.Context, userID primitive.ObjectID
 		UserID:      &userID,
 		Message:     strings.TrimSpace(message),
 		ChannelID:   channelObjID,
-		MessageType: "user",
+		MessageType: messageType,
 	}
 
 	if err := s.messageRepo.Create(ctx, msg); err != nil {
@@ -140,7 +150,7 @@ func (s *ChatService) CallAIService(ctx context.Context, userMessage, channelID,
 		UserID:      nil, // AI has no user ID
 		Message:     aiResp.Response,
 		ChannelID:   channelObjID,
-		MessageType: "ai",
+		MessageType: models.MessageTypeAI,
 	}
 
 	if err := s.messageRepo.Create(ctx, aiMessage); err != nil {
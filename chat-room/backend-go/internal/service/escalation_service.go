@@ -0,0 +1,288 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"chat-room-backend/internal/config"
+	"chat-room-backend/internal/middleware"
+	"chat-room-backend/internal/models"
+	"chat-room-backend/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SystemActorName is the audit log actor for automatic moderation
+const SystemActorName = "system"
+
+// strikeStore holds the strikes EscalationService counts
+type strikeStore interface {
+	Create(ctx context.Context, strike *models.Strike) error
+	SetEscalation(ctx context.Context, id primitive.ObjectID, escalation string) error
+	CountSince(ctx context.Context, userID primitive.ObjectID, since time.Time, escalation string) (int64, error)
+	FindByUserID(ctx context.Context, userID primitive.ObjectID, limit int) ([]*models.Strike, error)
+	ClearByUserID(ctx context.Context, userID primitive.ObjectID) (int64, error)
+}
+
+// EscalationService counts word filter strikes per user and applies the
+// configured ladder: warning, temporary mute, then ban for repeat offenders
+type EscalationService struct {
+	strikeRepo  strikeStore
+	userRepo    *repository.UserRepository
+	muteChecker *middleware.MuteChecker
+	banChecker  *middleware.BanChecker
+	audit       *AuditService
+	policy      config.EscalationConfig
+}
+
+// NewEscalationService creates a new EscalationService
+func NewEscalationService(
+	strikeRepo *repository.StrikeRepository,
+	userRepo *repository.UserRepository,
+	muteChecker *middleware.MuteChecker,
+	banChecker *middleware.BanChecker,
+	audit *AuditService,
+	policy config.EscalationConfig,
+) *EscalationService {
+	return &EscalationService{
+		strikeRepo:  strikeRepo,
+		userRepo:    userRepo,
+		muteChecker: muteChecker,
+		banChecker:  banChecker,
+		audit:       audit,
+		policy:      policy,
+	}
+}
+
+// EscalationOutcome is the consequence of a recorded strike
+type EscalationOutcome struct {
+	Level       string     `json:"level"` // warning | mute | ban, empty for none
+	Reason      string     `json:"reason"`
+	StrikeCount int64      `json:"strikeCount"` // Active strikes in the window
+	Until       *time.Time `json:"until,omitempty"`
+}
+
+// StrikeHistory is a user's strike record for admins
+type StrikeHistory struct {
+	ActiveStrikes int64                   `json:"activeStrikes"`
+	Strikes       []*models.Strike        `json:"strikes"`
+	Policy        config.EscalationConfig `json:"policy"`
+}
+
+// RecordStrike counts a blocked message against a user and escalates.
+// alreadyMuted is set when the matching filter muted the user itself.
+func (s *EscalationService) RecordStrike(ctx context.Context, userID primitive.ObjectID, channelID, reason string, words []string, alreadyMuted bool) (*EscalationOutcome, error) {
+	strike := &models.Strike{
+		UserID: userID,
+		Reason: reason,
+		Words:  words,
+	}
+	if channelObjID, err := primitive.ObjectIDFromHex(channelID); err == nil {
+		strike.ChannelID = &channelObjID
+	}
+	if err := s.strikeRepo.Create(ctx, strike); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	count, err := s.activeStrikes(ctx, userID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	outcome := &EscalationOutcome{StrikeCount: count}
+	if outcome.Level, err = s.level(ctx, userID, count, now); err != nil {
+		return nil, err
+	}
+
+	if outcome.Level == models.EscalationNone {
+		return outcome, nil
+	}
+	if err := s.strikeRepo.SetEscalation(ctx, strike.ID, outcome.Level); err != nil {
+		return nil, err
+	}
+
+	switch outcome.Level {
+	case models.EscalationWarning:
+		outcome.Reason = fmt.Sprintf("%d 分钟内已有 %d 条消息被拦截，继续违规将被禁言", s.policy.WindowMinutes, count)
+
+	case models.EscalationMute:
+		outcome.Reason = fmt.Sprintf("%d 分钟内 %d 条消息被拦截，自动禁言 %d 分钟", s.policy.WindowMinutes, count, s.policy.MuteMinutes)
+		if !alreadyMuted {
+			if err := s.MuteUser(ctx, userID, s.policy.MuteMinutes, outcome.Reason); err != nil {
+				return nil, err
+			}
+		}
+		if mute := s.muteChecker.GetUserMute(userID); mute.IsMuted {
+			outcome.Until = mute.MutedUntil
+		}
+
+	case models.EscalationBan:
+		outcome.Reason = "多次因违规被禁言，账号已被自动封禁"
+		if err := s.BanUser(ctx, userID, s.policy.BanMinutes, outcome.Reason); err != nil {
+			return nil, err
+		}
+		if ban := s.banChecker.CheckUser(userID); ban.IsBanned {
+			outcome.Until = ban.BannedUntil
+		}
+	}
+
+	log.Printf("⚖️  Escalation %s for user %s (%d strike(s))", outcome.Level, userID.Hex(), count)
+	return outcome, nil
+}
+
+// activeStrikes counts a user's strikes that still count towards the
+// ladder at now
+func (s *EscalationService) activeStrikes(ctx context.Context, userID primitive.ObjectID, now time.Time) (int64, error) {
+	return s.strikeRepo.CountSince(ctx, userID, now.Add(-time.Duration(s.policy.WindowMinutes)*time.Minute), "")
+}
+
+// level returns the ladder step a user with count active strikes reached
+func (s *EscalationService) level(ctx context.Context, userID primitive.ObjectID, count int64, now time.Time) (string, error) {
+	switch {
+	case s.policy.MuteThreshold > 0 && count >= int64(s.policy.MuteThreshold):
+		// Repeat offenders are banned instead of muted again
+		if s.policy.BanAfterMutes > 0 {
+			mutes, err := s.strikeRepo.CountSince(ctx, userID, now.Add(-time.Duration(s.policy.BanWindowMinutes)*time.Minute), models.EscalationMute)
+			if err != nil {
+				return "", err
+			}
+			if mutes+1 >= int64(s.policy.BanAfterMutes) {
+				return models.EscalationBan, nil
+			}
+		}
+		return models.EscalationMute, nil
+
+	case s.policy.WarnThreshold > 0 && count >= int64(s.policy.WarnThreshold):
+		return models.EscalationWarning, nil
+	}
+	return models.EscalationNone, nil
+}
+
+// MuteUser mutes a user on behalf of the system and records it in the audit log
+func (s *EscalationService) MuteUser(ctx context.Context, userID primitive.ObjectID, minutes int, reason string) error {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to find user: %w", err)
+	}
+	if user == nil {
+		return fmt.Errorf("用户不存在")
+	}
+
+	if err := s.userRepo.Mute(ctx, userID, primitive.NilObjectID, minutes, reason); err != nil {
+		return fmt.Errorf("failed to mute user: %w", err)
+	}
+	if err := s.muteChecker.RefreshUser(ctx, userID); err != nil {
+		log.Printf("⚠️  Warning: Failed to refresh mute cache: %v", err)
+	}
+
+	s.recordSystemChange(ctx, models.AuditActionUserMute, user, reason, muteSnapshot)
+	log.Printf("🔇 Auto-muted %s for %d minute(s)", user.Username, minutes)
+	return nil
+}
+
+// BanUser bans a user on behalf of the system and records it in the audit log
+func (s *EscalationService) BanUser(ctx context.Context, userID primitive.ObjectID, minutes int, reason string) error {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to find user: %w", err)
+	}
+	if user == nil {
+		return fmt.Errorf("用户不存在")
+	}
+
+	if err := s.userRepo.Ban(ctx, userID, primitive.NilObjectID, minutes, reason); err != nil {
+		return fmt.Errorf("failed to ban user: %w", err)
+	}
+	if err := s.banChecker.RefreshUser(ctx, userID); err != nil {
+		log.Printf("⚠️  Warning: Failed to refresh ban cache: %v", err)
+	}
+
+	s.recordSystemChange(ctx, models.AuditActionUserBan, user, reason, banSnapshot)
+	log.Printf("⛔ Auto-banned %s", user.Username)
+	return nil
+}
+
+// GetStrikeHistory returns a user's strikes and how many are still active
+func (s *EscalationService) GetStrikeHistory(ctx context.Context, userID string) (*StrikeHistory, error) {
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user ID: %w", err)
+	}
+
+	strikes, err := s.strikeRepo.FindByUserID(ctx, userObjID, 100)
+	if err != nil {
+		return nil, err
+	}
+	if strikes == nil {
+		strikes = []*models.Strike{}
+	}
+
+	active, err := s.activeStrikes(ctx, userObjID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return &StrikeHistory{
+		ActiveStrikes: active,
+		Strikes:       strikes,
+		Policy:        s.policy,
+	}, nil
+}
+
+// ResetStrikes clears a user's strikes so the ladder starts over
+func (s *EscalationService) ResetStrikes(ctx context.Context, userID string, resetBy primitive.ObjectID) (int64, error) {
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return 0, fmt.Errorf("invalid user ID: %w", err)
+	}
+
+	user, err := s.userRepo.FindByID(ctx, userObjID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find user: %w", err)
+	}
+	if user == nil {
+		return 0, fmt.Errorf("用户不存在")
+	}
+
+	cleared, err := s.strikeRepo.ClearByUserID(ctx, userObjID)
+	if err != nil {
+		return 0, err
+	}
+
+	s.audit.Record(ctx, &models.AuditLog{
+		ActorID:    resetBy,
+		Action:     models.AuditActionUserStrikesReset,
+		TargetType: models.AuditTargetUser,
+		TargetID:   &user.ID,
+		TargetName: user.Username,
+		After:      map[string]interface{}{"cleared": cleared},
+	})
+
+	return cleared, nil
+}
+
+// recordSystemChange writes an audit entry for an automatic action on a user
+func (s *EscalationService) recordSystemChange(
+	ctx context.Context,
+	action string,
+	before *models.User,
+	reason string,
+	snapshot func(*models.User) map[string]interface{},
+) {
+	entry := &models.AuditLog{
+		ActorID:       primitive.NilObjectID,
+		ActorUsername: SystemActorName,
+		Action:        action,
+		TargetType:    models.AuditTargetUser,
+		TargetID:      &before.ID,
+		TargetName:    before.Username,
+		Reason:        reason,
+		Before:        snapshot(before),
+	}
+	if after, err := s.userRepo.FindByID(ctx, before.ID); err == nil && after != nil {
+		entry.After = snapshot(after)
+	}
+	s.audit.Record(ctx, entry)
+}
@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"chat-room-backend/internal/config"
+	"chat-room-backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// memoryStrikeStore is an in-memory strike store standing in for the database
+type memoryStrikeStore struct {
+	strikes []*models.Strike
+}
+
+func (s *memoryStrikeStore) Create(ctx context.Context, strike *models.Strike) error {
+	strike.ID = primitive.NewObjectID()
+	strike.CreatedAt = time.Now()
+	s.strikes = append(s.strikes, strike)
+	return nil
+}
+
+func (s *memoryStrikeStore) SetEscalation(ctx context.Context, id primitive.ObjectID, escalation string) error {
+	for _, strike := range s.strikes {
+		if strike.ID == id {
+			strike.Escalation = escalation
+		}
+	}
+	return nil
+}
+
+func (s *memoryStrikeStore) CountSince(ctx context.Context, userID primitive.ObjectID, since time.Time, escalation string) (int64, error) {
+	var count int64
+	for _, strike := range s.strikes {
+		if strike.UserID == userID && !strike.Cleared && !strike.CreatedAt.Before(since) &&
+			(escalation == "" || strike.Escalation == escalation) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (s *memoryStrikeStore) FindByUserID(ctx context.Context, userID primitive.ObjectID, limit int) ([]*models.Strike, error) {
+	var strikes []*models.Strike
+	for _, strike := range s.strikes {
+		if strike.UserID == userID {
+			strikes = append(strikes, strike)
+		}
+	}
+	return strikes, nil
+}
+
+func (s *memoryStrikeStore) ClearByUserID(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	var cleared int64
+	for _, strike := range s.strikes {
+		if strike.UserID == userID && !strike.Cleared {
+			strike.Cleared = true
+			cleared++
+		}
+	}
+	return cleared, nil
+}
+
+var testEscalationPolicy = config.EscalationConfig{
+	WindowMinutes:    10,
+	WarnThreshold:    3,
+	MuteThreshold:    5,
+	MuteMinutes:      30,
+	BanAfterMutes:    3,
+	BanWindowMinutes: 24 * 60,
+}
+
+func TestEscalationLadder(t *testing.T) {
+	// strikes lists the age in minutes of each strike; mutes those of the
+	// strikes that muted the user
+	tests := []struct {
+		name    string
+		policy  config.EscalationConfig
+		strikes []int
+		mutes   []int
+		want    string
+	}{
+		{"below warning", testEscalationPolicy, []int{0, 1}, nil, models.EscalationNone},
+		{"warning", testEscalationPolicy, []int{0, 1, 2}, nil, models.EscalationWarning},
+		{"mute", testEscalationPolicy, []int{0, 1, 2, 3, 4}, nil, models.EscalationMute},
+		{"second mute", testEscalationPolicy, []int{0, 1, 2, 3, 4}, []int{60}, models.EscalationMute},
+		{"ban after repeated mutes", testEscalationPolicy, []int{0, 1, 2, 3, 4}, []int{60, 120}, models.EscalationBan},
+		{"old strikes decay", testEscalationPolicy, []int{0, 1, 2, 11, 20, 30}, nil, models.EscalationWarning},
+		{"all strikes decayed", testEscalationPolicy, []int{11, 12, 13, 14, 15}, nil, models.EscalationNone},
+		{"old mutes decay", testEscalationPolicy, []int{0, 1, 2, 3, 4}, []int{60, 25 * 60}, models.EscalationMute},
+		{
+			"warnings disabled",
+			config.EscalationConfig{WindowMinutes: 10, MuteThreshold: 5},
+			[]int{0, 1, 2, 3},
+			nil,
+			models.EscalationNone,
+		},
+		{
+			"bans disabled",
+			config.EscalationConfig{WindowMinutes: 10, WarnThreshold: 3, MuteThreshold: 5, BanWindowMinutes: 24 * 60},
+			[]int{0, 1, 2, 3, 4},
+			[]int{60, 120, 180},
+			models.EscalationMute,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Now()
+			userID := primitive.NewObjectID()
+			store := &memoryStrikeStore{}
+			add := func(age int, escalation string) {
+				store.strikes = append(store.strikes, &models.Strike{
+					UserID:     userID,
+					Escalation: escalation,
+					CreatedAt:  now.Add(-time.Duration(age) * time.Minute),
+				})
+			}
+			for _, age := range tt.strikes {
+				add(age, "")
+			}
+			for _, age := range tt.mutes {
+				add(age, models.EscalationMute)
+			}
+			// Another user's strikes never count
+			store.strikes = append(store.strikes, &models.Strike{UserID: primitive.NewObjectID(), CreatedAt: now})
+
+			s := &EscalationService{strikeRepo: store, policy: tt.policy}
+			count, err := s.activeStrikes(context.Background(), userID, now)
+			if err != nil {
+				t.Fatalf("activeStrikes: %v", err)
+			}
+			got, err := s.level(context.Background(), userID, count, now)
+			if err != nil {
+				t.Fatalf("level: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("level = %q with %d active strike(s), want %q", got, count, tt.want)
+			}
+		})
+	}
+}
+
+func TestEscalationWarningStrike(t *testing.T) {
+	store := &memoryStrikeStore{}
+	s := &EscalationService{strikeRepo: store, policy: testEscalationPolicy}
+	userID := primitive.NewObjectID()
+	channelID := primitive.NewObjectID()
+
+	levels := []string{models.EscalationNone, models.EscalationNone, models.EscalationWarning, models.EscalationWarning}
+	for i, want := range levels {
+		outcome, err := s.RecordStrike(context.Background(), userID, channelID.Hex(), "消息包含禁用词汇", []string{"spam"}, false)
+		if err != nil {
+			t.Fatalf("strike %d: %v", i+1, err)
+		}
+		if outcome.Level != want || outcome.StrikeCount != int64(i+1) {
+			t.Errorf("strike %d: level %q with %d strike(s), want %q", i+1, outcome.Level, outcome.StrikeCount, want)
+		}
+	}
+
+	// Only the strikes that escalated are marked
+	if store.strikes[1].Escalation != models.EscalationNone || store.strikes[2].Escalation != models.EscalationWarning {
+		t.Errorf("escalations = %q, %q", store.strikes[1].Escalation, store.strikes[2].Escalation)
+	}
+	if id := store.strikes[0].ChannelID; id == nil || *id != channelID {
+		t.Errorf("strike channel = %v, want %s", id, channelID.Hex())
+	}
+
+	// Strikes cleared by an admin no longer count
+	store.ClearByUserID(context.Background(), userID)
+	outcome, err := s.RecordStrike(context.Background(), userID, "", "消息包含禁用词汇", nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if outcome.Level != models.EscalationNone || outcome.StrikeCount != 1 {
+		t.Errorf("after reset: level %q with %d strike(s)", outcome.Level, outcome.StrikeCount)
+	}
+}
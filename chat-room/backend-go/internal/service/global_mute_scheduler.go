@@ -0,0 +1,371 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"chat-room-backend/internal/middleware"
+	"chat-room-backend/internal/models"
+	"chat-room-backend/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// maxSchedulerSleep bounds how long the scheduler waits between checks
+const maxSchedulerSleep = time.Minute
+
+// GlobalMuteScheduler turns the global mute on and off for scheduled
+// windows and for manual mutes that expire. A manual mute always wins
+// over a schedule.
+type GlobalMuteScheduler struct {
+	adminRepo    *repository.AdminRepository
+	scheduleRepo *repository.GlobalMuteScheduleRepository
+	muteChecker  *middleware.MuteChecker
+	audit        *AuditService
+
+	wake chan struct{}
+
+	mu       sync.Mutex
+	onChange func(status *models.GlobalMuteStatus)
+}
+
+// NewGlobalMuteScheduler creates a new GlobalMuteScheduler and starts it
+func NewGlobalMuteScheduler(
+	adminRepo *repository.AdminRepository,
+	scheduleRepo *repository.GlobalMuteScheduleRepository,
+	muteChecker *middleware.MuteChecker,
+	audit *AuditService,
+) *GlobalMuteScheduler {
+	s := &GlobalMuteScheduler{
+		adminRepo:    adminRepo,
+		scheduleRepo: scheduleRepo,
+		muteChecker:  muteChecker,
+		audit:        audit,
+		wake:         make(chan struct{}, 1),
+	}
+
+	go s.run()
+
+	return s
+}
+
+// CreateScheduleRequest represents global mute schedule data
+type CreateScheduleRequest struct {
+	Type      string     `json:"type" binding:"required,oneof=once daily weekly"`
+	Reason    string     `json:"reason" binding:"max=200"`
+	StartAt   *time.Time `json:"startAt"`   // once
+	EndAt     *time.Time `json:"endAt"`     // once
+	StartTime string     `json:"startTime"` // daily and weekly, HH:MM
+	EndTime   string     `json:"endTime"`   // daily and weekly, HH:MM
+	Weekdays  []int      `json:"weekdays"`  // weekly, 0 = Sunday
+	Timezone  string     `json:"timezone"`  // IANA name, server time if empty
+}
+
+// OnChange registers a callback invoked after the scheduler changes the global mute
+func (s *GlobalMuteScheduler) OnChange(fn func(status *models.GlobalMuteStatus)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onChange = fn
+}
+
+// Wake makes the scheduler re-check now, e.g. after a manual toggle
+func (s *GlobalMuteScheduler) Wake() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// GetSchedules returns every schedule
+func (s *GlobalMuteScheduler) GetSchedules(ctx context.Context) ([]*models.GlobalMuteSchedule, error) {
+	schedules, err := s.scheduleRepo.FindAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get schedules: %w", err)
+	}
+	if schedules == nil {
+		schedules = []*models.GlobalMuteSchedule{}
+	}
+	return schedules, nil
+}
+
+// CreateSchedule adds a global mute window
+func (s *GlobalMuteScheduler) CreateSchedule(ctx context.Context, req *CreateScheduleRequest, createdBy primitive.ObjectID) (*models.GlobalMuteSchedule, error) {
+	schedule := &models.GlobalMuteSchedule{
+		Type:      req.Type,
+		Reason:    strings.TrimSpace(req.Reason),
+		Timezone:  req.Timezone,
+		CreatedBy: createdBy,
+	}
+	if schedule.Reason == "" {
+		schedule.Reason = "定时全局禁言"
+	}
+	if req.Timezone != "" {
+		if _, err := time.LoadLocation(req.Timezone); err != nil {
+			return nil, fmt.Errorf("无效的时区")
+		}
+	}
+
+	switch req.Type {
+	case models.ScheduleTypeOnce:
+		if req.StartAt == nil || req.EndAt == nil || !req.EndAt.After(*req.StartAt) {
+			return nil, fmt.Errorf("结束时间必须晚于开始时间")
+		}
+		if !req.EndAt.After(time.Now()) {
+			return nil, fmt.Errorf("结束时间已过")
+		}
+		schedule.StartAt = req.StartAt
+		schedule.EndAt = req.EndAt
+
+	default:
+		if _, err := time.Parse("15:04", req.StartTime); err != nil {
+			return nil, fmt.Errorf("无效的开始时间")
+		}
+		if _, err := time.Parse("15:04", req.EndTime); err != nil {
+			return nil, fmt.Errorf("无效的结束时间")
+		}
+		if req.StartTime == req.EndTime {
+			return nil, fmt.Errorf("结束时间必须晚于开始时间")
+		}
+		schedule.StartTime = req.StartTime
+		schedule.EndTime = req.EndTime
+
+		if req.Type == models.ScheduleTypeWeekly {
+			if len(req.Weekdays) == 0 {
+				return nil, fmt.Errorf("请选择星期")
+			}
+			for _, d := range req.Weekdays {
+				if d < 0 || d > 6 {
+					return nil, fmt.Errorf("无效的星期")
+				}
+			}
+			schedule.Weekdays = req.Weekdays
+		}
+	}
+
+	if err := s.scheduleRepo.Create(ctx, schedule); err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(ctx, &models.AuditLog{
+		ActorID:    createdBy,
+		Action:     models.AuditActionScheduleAdd,
+		TargetType: models.AuditTargetGlobalMute,
+		TargetID:   &schedule.ID,
+		TargetName: schedule.Type,
+		Reason:     schedule.Reason,
+		After:      scheduleSnapshot(schedule),
+	})
+
+	s.Wake()
+	return schedule, nil
+}
+
+// DeleteSchedule removes a global mute window. A mute it started is turned off.
+func (s *GlobalMuteScheduler) DeleteSchedule(ctx context.Context, scheduleID string, deletedBy primitive.ObjectID) error {
+	scheduleObjID, err := primitive.ObjectIDFromHex(scheduleID)
+	if err != nil {
+		return fmt.Errorf("定时任务不存在")
+	}
+
+	schedule, err := s.scheduleRepo.FindByID(ctx, scheduleObjID)
+	if err != nil {
+		return err
+	}
+	if schedule == nil {
+		return fmt.Errorf("定时任务不存在")
+	}
+
+	if err := s.scheduleRepo.Delete(ctx, scheduleObjID); err != nil {
+		return err
+	}
+
+	s.audit.Record(ctx, &models.AuditLog{
+		ActorID:    deletedBy,
+		Action:     models.AuditActionScheduleRemove,
+		TargetType: models.AuditTargetGlobalMute,
+		TargetID:   &schedule.ID,
+		TargetName: schedule.Type,
+		Before:     scheduleSnapshot(schedule),
+	})
+
+	s.Wake()
+	return nil
+}
+
+// run re-evaluates the global mute at every transition
+func (s *GlobalMuteScheduler) run() {
+	for {
+		next := s.tick()
+
+		timer := time.NewTimer(next)
+		select {
+		case <-timer.C:
+		case <-s.wake:
+			timer.Stop()
+		}
+	}
+}
+
+// tick evaluates once and returns how long to sleep
+func (s *GlobalMuteScheduler) tick() time.Duration {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	next, err := s.evaluate(ctx, time.Now())
+	if err != nil {
+		log.Printf("❌ Global mute scheduler: %v", err)
+		return maxSchedulerSleep
+	}
+	return next
+}
+
+// evaluate applies the global mute state due at now and returns the time
+// until the next transition
+func (s *GlobalMuteScheduler) evaluate(ctx context.Context, now time.Time) (time.Duration, error) {
+	status, err := s.adminRepo.GetGlobalMuteStatus(ctx)
+	if err != nil {
+		return 0, err
+	}
+	schedules, err := s.scheduleRepo.FindAll(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	next := now.Add(maxSchedulerSleep)
+	wakeAt := func(t *time.Time) {
+		if t != nil && t.After(now) && t.Before(next) {
+			next = *t
+		}
+	}
+
+	// Find the schedule whose window is open, and the next transition
+	var active *models.GlobalMuteSchedule
+	var activeEnd time.Time
+	for _, schedule := range schedules {
+		start, end, ok := schedule.Window(now)
+		if !ok {
+			continue
+		}
+		if start.After(now) {
+			wakeAt(&start)
+			continue
+		}
+		wakeAt(&end)
+		if active == nil || end.After(activeEnd) {
+			active, activeEnd = schedule, end
+		}
+	}
+
+	manualOn := status.IsEnabled && !status.IsScheduled() &&
+		(status.ExpiresAt == nil || now.Before(*status.ExpiresAt))
+	if manualOn {
+		wakeAt(status.ExpiresAt)
+		return next.Sub(now), nil
+	}
+
+	skipping := status.SkipScheduleUntil != nil && now.Before(*status.SkipScheduleUntil)
+	wakeAt(status.SkipScheduleUntil)
+
+	desired := &models.GlobalMuteStatus{Source: models.GlobalMuteSourceSchedule}
+	if active != nil && !skipping {
+		desired.IsEnabled = true
+		desired.Reason = active.Reason
+		desired.ScheduleID = &active.ID
+		desired.ExpiresAt = &activeEnd
+	} else if status.IsEnabled {
+		// An expired manual mute or a finished window
+		desired.Source = status.Source
+		desired.SkipScheduleUntil = status.SkipScheduleUntil
+	} else {
+		return next.Sub(now), nil
+	}
+
+	if !globalMuteChanged(status, desired) {
+		return next.Sub(now), nil
+	}
+
+	if err := s.adminRepo.UpdateGlobalMuteStatus(ctx, desired); err != nil {
+		return 0, err
+	}
+	if err := s.muteChecker.RefreshGlobal(ctx); err != nil {
+		log.Printf("⚠️  Warning: Failed to refresh mute cache: %v", err)
+	}
+
+	after, err := s.adminRepo.GetGlobalMuteStatus(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	reason := "定时全局禁言结束"
+	if after.IsEnabled {
+		reason = "定时全局禁言开始"
+	} else if !status.IsScheduled() {
+		reason = "全局禁言已到期"
+	}
+	s.audit.Record(ctx, &models.AuditLog{
+		ActorID:       primitive.NilObjectID,
+		ActorUsername: SystemActorName,
+		Action:        models.AuditActionGlobalMute,
+		TargetType:    models.AuditTargetGlobalMute,
+		Reason:        reason,
+		Before:        globalMuteSnapshot(status),
+		After:         globalMuteSnapshot(after),
+	})
+	log.Printf("🕒 %s", reason)
+
+	s.mu.Lock()
+	onChange := s.onChange
+	s.mu.Unlock()
+	if onChange != nil {
+		onChange(after)
+	}
+
+	return next.Sub(now), nil
+}
+
+// globalMuteChanged reports whether applying desired changes the status
+func globalMuteChanged(current, desired *models.GlobalMuteStatus) bool {
+	if current.IsEnabled != desired.IsEnabled {
+		return true
+	}
+	if !desired.IsEnabled {
+		return false
+	}
+	return current.Source != desired.Source ||
+		current.Reason != desired.Reason ||
+		!sameObjectID(current.ScheduleID, desired.ScheduleID) ||
+		!sameTime(current.ExpiresAt, desired.ExpiresAt)
+}
+
+// sameObjectID compares two optional IDs
+func sameObjectID(a, b *primitive.ObjectID) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// sameTime compares two optional times
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+// scheduleSnapshot returns the audited fields of a schedule
+func scheduleSnapshot(schedule *models.GlobalMuteSchedule) map[string]interface{} {
+	return map[string]interface{}{
+		"type":      schedule.Type,
+		"startAt":   schedule.StartAt,
+		"endAt":     schedule.EndAt,
+		"startTime": schedule.StartTime,
+		"endTime":   schedule.EndTime,
+		"weekdays":  schedule.Weekdays,
+		"timezone":  schedule.Timezone,
+		"reason":    schedule.Reason,
+	}
+}
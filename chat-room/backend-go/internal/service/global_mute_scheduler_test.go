@@ -0,0 +1,57 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"chat-room-backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestGlobalMuteChanged(t *testing.T) {
+	scheduleID := primitive.NewObjectID()
+	otherID := primitive.NewObjectID()
+	end := time.Date(2026, 3, 4, 6, 0, 0, 0, time.UTC)
+	sameEnd := end.In(time.FixedZone("CST", 8*3600))
+	laterEnd := end.Add(time.Hour)
+
+	scheduled := func(id *primitive.ObjectID, expiresAt *time.Time) *models.GlobalMuteStatus {
+		return &models.GlobalMuteStatus{
+			IsEnabled:  true,
+			Reason:     "夜间禁言",
+			Source:     models.GlobalMuteSourceSchedule,
+			ScheduleID: id,
+			ExpiresAt:  expiresAt,
+		}
+	}
+
+	tests := []struct {
+		name    string
+		current *models.GlobalMuteStatus
+		desired *models.GlobalMuteStatus
+		want    bool
+	}{
+		{"both off", &models.GlobalMuteStatus{}, &models.GlobalMuteStatus{}, false},
+		{"off, details differ", &models.GlobalMuteStatus{Reason: "old"}, &models.GlobalMuteStatus{Source: models.GlobalMuteSourceSchedule}, false},
+		{"turns on", &models.GlobalMuteStatus{}, scheduled(&scheduleID, &end), true},
+		{"turns off", scheduled(&scheduleID, &end), &models.GlobalMuteStatus{}, true},
+		{"unchanged", scheduled(&scheduleID, &end), scheduled(&scheduleID, &end), false},
+		{"same end in another zone", scheduled(&scheduleID, &end), scheduled(&scheduleID, &sameEnd), false},
+		{"end moves", scheduled(&scheduleID, &end), scheduled(&scheduleID, &laterEnd), true},
+		{"end removed", scheduled(&scheduleID, &end), scheduled(&scheduleID, nil), true},
+		{"other schedule", scheduled(&scheduleID, &end), scheduled(&otherID, &end), true},
+		{"manual to scheduled", &models.GlobalMuteStatus{IsEnabled: true, Reason: "夜间禁言", Source: models.GlobalMuteSourceManual}, scheduled(nil, nil), true},
+		{
+			"reason changes",
+			scheduled(&scheduleID, &end),
+			&models.GlobalMuteStatus{IsEnabled: true, Reason: "维护", Source: models.GlobalMuteSourceSchedule, ScheduleID: &scheduleID, ExpiresAt: &end},
+			true,
+		},
+	}
+
+	for _, tt := range tests {
+		if got := globalMuteChanged(tt.current, tt.desired); got != tt.want {
+			t.Errorf("%s: globalMuteChanged() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,267 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"chat-room-backend/internal/middleware"
+	"chat-room-backend/internal/models"
+	"chat-room-backend/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ModerationService applies word filter actions to incoming messages.
+// Every message ingest path posts through it, so read-only channels,
+// mutes, word filters and slow mode apply to all of them alike.
+type ModerationService struct {
+	wordFilter  *middleware.WordFilterCache
+	muteChecker *middleware.MuteChecker
+	posting     *middleware.PostingPermissions
+	slowMode    *middleware.SlowModeCache
+	reportRepo  *repository.ReportRepository
+	escalation  *EscalationService
+}
+
+// NewModerationService creates a new ModerationService
+func NewModerationService(
+	wordFilter *middleware.WordFilterCache,
+	muteChecker *middleware.MuteChecker,
+	posting *middleware.PostingPermissions,
+	slowMode *middleware.SlowModeCache,
+	reportRepo *repository.ReportRepository,
+	escalation *EscalationService,
+) *ModerationService {
+	return &ModerationService{
+		wordFilter:  wordFilter,
+		muteChecker: muteChecker,
+		posting:     posting,
+		slowMode:    slowMode,
+		reportRepo:  reportRepo,
+		escalation:  escalation,
+	}
+}
+
+// MessageBlockedError is returned when a message may not be posted
+type MessageBlockedError struct {
+	Reason     string
+	IsGlobal   bool              // Held back by the global mute
+	RetryAfter time.Duration     // Set by slow mode
+	Screened   *ModerationResult // Set when the word filters blocked the message
+}
+
+func (e *MessageBlockedError) Error() string {
+	return e.Reason
+}
+
+// CheckedMessage is a message that passed CheckMessage and may be posted
+type CheckedMessage struct {
+	Text     string // With masked words replaced
+	Screened *ModerationResult
+	release  func()
+}
+
+// Release gives back the slow mode slot of a message that was not posted
+func (m *CheckedMessage) Release() {
+	m.release()
+}
+
+// CheckMessage runs the checks a new message passes before it is saved:
+// read-only channels, mutes, word filters and slow mode. exempt is whether
+// the sender may use PermModerationExempt in this session.
+func (s *ModerationService) CheckMessage(ctx context.Context, userID primitive.ObjectID, exempt bool, channelID, text string) (*CheckedMessage, error) {
+	if !s.posting.CanPost(userID, channelID, exempt) {
+		return nil, &MessageBlockedError{Reason: "该频道为只读频道，仅管理员和指定成员可以发言"}
+	}
+
+	mute, err := s.muteChecker.CheckMuteStatus(ctx, userID, exempt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check mute status: %w", err)
+	}
+	if mute.IsMuted {
+		return nil, &MessageBlockedError{Reason: mute.Reason, IsGlobal: mute.IsGlobal}
+	}
+
+	screened := s.ScreenMessage(ctx, userID, exempt, channelID, text)
+	if screened.Blocked {
+		return nil, &MessageBlockedError{Reason: screened.Reason, Screened: screened}
+	}
+
+	checked := &CheckedMessage{Text: screened.Message, Screened: screened, release: func() {}}
+	if !exempt {
+		wait, release := s.slowMode.Reserve(userID, channelID)
+		if wait > 0 {
+			return nil, &MessageBlockedError{
+				Reason:     fmt.Sprintf("慢速模式已开启，请在 %d 秒后再发送", int(math.Ceil(wait.Seconds()))),
+				RetryAfter: wait,
+			}
+		}
+		checked.release = release
+	}
+	return checked, nil
+}
+
+// PostMessage saves a checked message with save and queues it for review
+// when it hit a review filter. It returns the saved message and the new
+// report, if any. The slow mode slot is given back if saving fails.
+func (s *ModerationService) PostMessage(ctx context.Context, checked *CheckedMessage, save func(text string) (*models.Message, error)) (*models.Message, *models.Report, error) {
+	msg, err := save(checked.Text)
+	if err != nil {
+		checked.Release()
+		return nil, nil, err
+	}
+	return msg, s.FlagForReview(ctx, msg, checked.Screened), nil
+}
+
+// ModerationResult is the outcome of screening a message
+type ModerationResult struct {
+	Action     string // Strictest action hit, empty if the message is clean
+	Message    string // Text to deliver, with masked words replaced
+	Blocked    bool
+	Reason     string
+	Matches    []middleware.ContentMatch
+	MutedUntil *time.Time // Set when the sender was auto-muted
+	Muted      bool
+	Banned     bool
+	Escalation *EscalationOutcome // Set when a blocked message counted as a strike
+}
+
+// ScreenMessage checks a message against the global and channel word
+// filters and applies the strictest action. Messages of users exempt from
+// moderation are delivered unchanged.
+func (s *ModerationService) ScreenMessage(ctx context.Context, userID primitive.ObjectID, exempt bool, channelID, text string) *ModerationResult {
+	if exempt {
+		return &ModerationResult{Message: text}
+	}
+
+	matches := s.wordFilter.Match(channelID, text)
+	result := &ModerationResult{
+		Action:  middleware.StrictestAction(matches),
+		Message: text,
+		Matches: matches,
+	}
+
+	switch result.Action {
+	case models.FilterActionMask, models.FilterActionReview:
+		result.Message = middleware.MaskMatches(text, matchesWithAction(matches, models.FilterActionMask))
+
+	case models.FilterActionBlock:
+		result.Blocked = true
+		result.Reason = "消息包含禁用词汇"
+
+	case models.FilterActionMute:
+		result.Blocked = true
+		result.Reason = "消息包含禁用词汇"
+
+		minutes := 0
+		for _, match := range matchesWithAction(matches, models.FilterActionMute) {
+			minutes = max(minutes, match.MuteMinutes)
+		}
+		if err := s.escalation.MuteUser(ctx, userID, minutes, "自动禁言：发送违禁内容"); err != nil {
+			log.Printf("❌ Failed to auto-mute user %s: %v", userID.Hex(), err)
+			break
+		}
+		result.Muted = true
+		result.Reason = fmt.Sprintf("发送违禁内容，已被自动禁言 %d 分钟", minutes)
+		if mute := s.muteChecker.GetUserMute(userID); mute.IsMuted {
+			result.MutedUntil = mute.MutedUntil
+		}
+	}
+
+	if result.Blocked {
+		s.recordStrike(ctx, userID, channelID, result)
+	}
+
+	return result
+}
+
+// MuteForFlooding mutes a user who kept exceeding the WebSocket rate limits.
+// Users exempt from moderation are never auto-muted.
+func (s *ModerationService) MuteForFlooding(ctx context.Context, userID primitive.ObjectID, exempt bool, minutes int) *ModerationResult {
+	result := &ModerationResult{Blocked: true, Reason: "发送过于频繁"}
+	if exempt {
+		return result
+	}
+
+	if err := s.escalation.MuteUser(ctx, userID, minutes, "自动禁言：刷屏"); err != nil {
+		log.Printf("❌ Failed to auto-mute flooding user %s: %v", userID.Hex(), err)
+		return result
+	}
+	result.Muted = true
+	result.Reason = fmt.Sprintf("发送过于频繁，已被自动禁言 %d 分钟", minutes)
+	if mute := s.muteChecker.GetUserMute(userID); mute.IsMuted {
+		result.MutedUntil = mute.MutedUntil
+	}
+	return result
+}
+
+// recordStrike counts a blocked message towards the escalation ladder
+func (s *ModerationService) recordStrike(ctx context.Context, userID primitive.ObjectID, channelID string, result *ModerationResult) {
+	var words []string
+	for _, match := range result.Matches {
+		if match.Action == models.FilterActionBlock || match.Action == models.FilterActionMute {
+			words = append(words, match.Word)
+		}
+	}
+
+	outcome, err := s.escalation.RecordStrike(ctx, userID, channelID, "消息包含禁用词汇", words, result.Muted)
+	if err != nil {
+		log.Printf("❌ Failed to record strike for user %s: %v", userID.Hex(), err)
+		return
+	}
+	result.Escalation = outcome
+
+	switch outcome.Level {
+	case models.EscalationMute:
+		if !result.Muted {
+			result.Muted = true
+			result.Reason = outcome.Reason
+			result.MutedUntil = outcome.Until
+		}
+	case models.EscalationBan:
+		result.Banned = true
+		result.Reason = outcome.Reason
+	}
+}
+
+// FlagForReview queues a delivered message for moderator review when it
+// hit a review filter. Returns the new report, or nil.
+func (s *ModerationService) FlagForReview(ctx context.Context, msg *models.Message, result *ModerationResult) *models.Report {
+	if result.Action != models.FilterActionReview {
+		return nil
+	}
+
+	var words []string
+	for _, match := range matchesWithAction(result.Matches, models.FilterActionReview) {
+		words = append(words, match.Word)
+	}
+
+	report := &models.Report{
+		MessageID:      msg.ID,
+		ChannelID:      msg.ChannelID,
+		AuthorID:       msg.UserID,
+		AuthorUsername: msg.Username,
+		MessageText:    msg.Message,
+		Source:         models.ReportSourceFilter,
+		Reason:         "命中需审核的敏感词",
+		MatchedWords:   words,
+	}
+	if err := s.reportRepo.Create(ctx, report); err != nil {
+		log.Printf("❌ Failed to flag message %s for review: %v", msg.ID.Hex(), err)
+		return nil
+	}
+	return report
+}
+
+// matchesWithAction returns the matches that carry an action
+func matchesWithAction(matches []middleware.ContentMatch, action string) []middleware.ContentMatch {
+	var filtered []middleware.ContentMatch
+	for _, match := range matches {
+		if match.Action == action {
+			filtered = append(filtered, match)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"chat-room-backend/internal/middleware"
+	"chat-room-backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestMatchesWithAction(t *testing.T) {
+	matches := []middleware.ContentMatch{
+		{Word: "a", Action: models.FilterActionMask},
+		{Word: "b", Action: models.FilterActionReview},
+		{Word: "c", Action: models.FilterActionMask},
+	}
+
+	masked := matchesWithAction(matches, models.FilterActionMask)
+	if len(masked) != 2 || masked[0].Word != "a" || masked[1].Word != "c" {
+		t.Errorf("mask matches = %+v, want a and c", masked)
+	}
+	if got := matchesWithAction(matches, models.FilterActionMute); len(got) != 0 {
+		t.Errorf("mute matches = %+v, want none", got)
+	}
+}
+
+// TestReviewMasksOnlyMaskHits checks that a message held for review is
+// delivered with mask hits hidden and review hits left readable for the
+// moderator
+func TestReviewMasksOnlyMaskHits(t *testing.T) {
+	m, _ := middleware.NewContentMatcher([]*models.WordFilter{
+		{Word: "bad", Action: models.FilterActionMask},
+		{Word: "scam", Action: models.FilterActionReview},
+	})
+
+	text := "bad scam"
+	matches := m.Match(text)
+	if action := middleware.StrictestAction(matches); action != models.FilterActionReview {
+		t.Fatalf("action = %q, want review", action)
+	}
+	if got := middleware.MaskMatches(text, matchesWithAction(matches, models.FilterActionMask)); got != "*** scam" {
+		t.Errorf("delivered text = %q, want %q", got, "*** scam")
+	}
+}
+
+func TestFlagForReviewOnlyReviewHits(t *testing.T) {
+	// No report repository: only review hits may reach it
+	s := &ModerationService{}
+	msg := &models.Message{ID: primitive.NewObjectID()}
+
+	for _, action := range []string{"", models.FilterActionMask} {
+		if report := s.FlagForReview(context.Background(), msg, &ModerationResult{Action: action}); report != nil {
+			t.Errorf("action %q: report created", action)
+		}
+	}
+}
+
+func TestScreenMessageExempt(t *testing.T) {
+	s := &ModerationService{}
+	result := s.ScreenMessage(context.Background(), primitive.NewObjectID(), true, "", "anything goes")
+	if result.Blocked || result.Action != "" || result.Message != "anything goes" {
+		t.Errorf("exempt user's message screened: %+v", result)
+	}
+}
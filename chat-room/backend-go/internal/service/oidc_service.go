@@ -0,0 +1,503 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"chat-room-backend/internal/config"
+	"chat-room-backend/internal/models"
+	"chat-room-backend/internal/repository"
+	"chat-room-backend/internal/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Single sign-on settings
+const (
+	oidcStateTTL       = 10 * time.Minute
+	oidcMaxPending     = 10000 // Logins started but not finished, bounded as starting one needs no account
+	ssoUsernameMaxLen  = 20
+	ssoUsernameRetries = 10
+)
+
+// oidcPending is a login started at the provider, keyed by its state
+type oidcPending struct {
+	verifier  string              // PKCE code verifier
+	nonce     string              // Must come back in the ID token
+	linkUser  *primitive.ObjectID // Set when linking the identity to a signed-in user
+	expiresAt time.Time
+}
+
+// OIDCService handles single sign-on with an OpenID Connect provider:
+// the authorization code flow with PKCE, just-in-time provisioning of
+// users, linking identities to local accounts and mapping a provider
+// group to admin rights
+type OIDCService struct {
+	auth     *AuthService
+	userRepo *repository.UserRepository
+	roles    *RoleService
+	audit    *AuditService
+	provider *utils.OIDCProvider
+	cfg      config.OIDCConfig
+
+	mu      sync.Mutex
+	pending map[string]*oidcPending
+}
+
+// NewOIDCService creates a new OIDCService
+func NewOIDCService(
+	auth *AuthService,
+	userRepo *repository.UserRepository,
+	roles *RoleService,
+	audit *AuditService,
+	cfg config.OIDCConfig,
+) *OIDCService {
+	return &OIDCService{
+		auth:     auth,
+		userRepo: userRepo,
+		roles:    roles,
+		audit:    audit,
+		provider: utils.NewOIDCProvider(cfg),
+		cfg:      cfg,
+		pending:  make(map[string]*oidcPending),
+	}
+}
+
+// OIDCAuthorization is where to send the browser to log in at the provider
+type OIDCAuthorization struct {
+	AuthorizationURL string `json:"authorizationUrl"`
+	State            string `json:"state"` // Compare with the state the provider sends back
+}
+
+// OIDCCallbackRequest carries the provider's redirect back to the frontend
+type OIDCCallbackRequest struct {
+	Code        string `json:"code" binding:"required"`
+	State       string `json:"state" binding:"required"`
+	DeviceLabel string `json:"deviceLabel" binding:"max=50"`
+}
+
+// OIDCLinkRequest confirms linking an SSO identity with the account password
+type OIDCLinkRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
+// Enabled reports whether single sign-on is configured
+func (s *OIDCService) Enabled() bool {
+	return s.cfg.Enabled()
+}
+
+// StartLogin begins a login at the provider
+func (s *OIDCService) StartLogin(ctx context.Context) (*OIDCAuthorization, error) {
+	if !s.cfg.Enabled() {
+		return nil, fmt.Errorf("未启用单点登录")
+	}
+	return s.authorize(ctx, nil)
+}
+
+// CompleteLogin finishes a login with the code the provider returned. A
+// user is created on the first login of an identity. Users with local
+// two-factor authentication still get a challenge for their code.
+func (s *OIDCService) CompleteLogin(ctx context.Context, req *OIDCCallbackRequest, client *ClientInfo) (*AuthResponse, error) {
+	if !s.cfg.Enabled() {
+		return nil, fmt.Errorf("未启用单点登录")
+	}
+
+	pending, err := s.takePending(req.State, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.auth.checkIPBan(ctx, client.IP); err != nil {
+		return nil, err
+	}
+
+	identity, err := s.authenticate(ctx, pending, req.Code)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.FindBySSO(ctx, identity.Issuer, identity.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		if user, err = s.provision(ctx, identity); err != nil {
+			return nil, err
+		}
+	}
+
+	if user.IsBanActive() {
+		return nil, &BanError{Reason: user.BanReason, BannedUntil: user.BannedUntil}
+	}
+	if err := s.applyAdminGroup(ctx, user, identity); err != nil {
+		return nil, err
+	}
+
+	if user.TOTPEnabled {
+		return s.auth.twoFactorChallenge(user)
+	}
+
+	// A multi-factor login at the provider counts as a second factor
+	return s.auth.completeLogin(ctx, user, req.DeviceLabel, client, identity.MFA)
+}
+
+// StartLink begins linking an SSO identity to a local account, after
+// checking the account password
+func (s *OIDCService) StartLink(ctx context.Context, userID primitive.ObjectID, req *OIDCLinkRequest, client *ClientInfo) (*OIDCAuthorization, error) {
+	if !s.cfg.Enabled() {
+		return nil, fmt.Errorf("未启用单点登录")
+	}
+
+	user, err := s.auth.findUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.SSO != nil {
+		return nil, fmt.Errorf("已绑定单点登录账号")
+	}
+
+	if throttle := s.auth.loginGuard.Check(user.Username, client.IP); throttle != nil {
+		return nil, &LoginThrottledError{RetryAfter: throttle.RetryAfter, Locked: throttle.Locked}
+	}
+	if !s.auth.verifyPassword(ctx, user, req.Password) {
+		s.auth.loginFailed(ctx, user.Username, client.IP, user)
+		return nil, fmt.Errorf("密码错误")
+	}
+	s.auth.loginGuard.RecordSuccess(user.Username)
+
+	return s.authorize(ctx, &user.ID)
+}
+
+// CompleteLink links the identity the provider returned to the user who
+// started the link
+func (s *OIDCService) CompleteLink(ctx context.Context, userID primitive.ObjectID, req *OIDCCallbackRequest) (*models.UserResponse, error) {
+	if !s.cfg.Enabled() {
+		return nil, fmt.Errorf("未启用单点登录")
+	}
+
+	pending, err := s.takePending(req.State, &userID)
+	if err != nil {
+		return nil, err
+	}
+
+	identity, err := s.authenticate(ctx, pending, req.Code)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.auth.findUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.userRepo.FindBySSO(ctx, identity.Issuer, identity.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil && existing.ID != user.ID {
+		return nil, fmt.Errorf("该单点登录账号已绑定其他用户")
+	}
+
+	user.SSO = newSSOIdentity(identity)
+	linked, err := s.userRepo.LinkSSO(ctx, user.ID, user.SSO)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, fmt.Errorf("该单点登录账号已绑定其他用户")
+		}
+		return nil, err
+	}
+	if !linked {
+		return nil, fmt.Errorf("已绑定单点登录账号")
+	}
+
+	s.audit.Record(ctx, &models.AuditLog{
+		ActorID:    user.ID,
+		Action:     models.AuditActionSSOLink,
+		TargetType: models.AuditTargetUser,
+		TargetID:   &user.ID,
+		TargetName: user.Username,
+		After:      map[string]interface{}{"issuer": identity.Issuer, "subject": identity.Subject},
+	})
+
+	if err := s.applyAdminGroup(ctx, user, identity); err != nil {
+		return nil, err
+	}
+	return user.ToResponse(), nil
+}
+
+// Unlink removes a user's SSO identity. Accounts without a password
+// cannot be unlinked, as they would have no way to log in.
+func (s *OIDCService) Unlink(ctx context.Context, userID primitive.ObjectID) error {
+	user, err := s.auth.findUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.SSO == nil {
+		return fmt.Errorf("未绑定单点登录账号")
+	}
+	if user.Password == "" {
+		return fmt.Errorf("账号没有密码，无法解绑单点登录")
+	}
+
+	if err := s.userRepo.UnlinkSSO(ctx, user.ID); err != nil {
+		return err
+	}
+
+	s.audit.Record(ctx, &models.AuditLog{
+		ActorID:    user.ID,
+		Action:     models.AuditActionSSOUnlink,
+		TargetType: models.AuditTargetUser,
+		TargetID:   &user.ID,
+		TargetName: user.Username,
+		Before:     map[string]interface{}{"issuer": user.SSO.Issuer, "subject": user.SSO.Subject},
+	})
+
+	// Admin rights from the group mapping go with the identity
+	if user.Role == models.RoleAdmin && user.RoleSource == models.RoleSourceSSO {
+		return s.roles.setRole(ctx, user, models.RoleUser, models.RoleSourceSSO, primitive.NilObjectID, "解绑单点登录账号")
+	}
+	return nil
+}
+
+// authorize creates the state, nonce and PKCE verifier of a new login and
+// returns the provider URL for it
+func (s *OIDCService) authorize(ctx context.Context, linkUser *primitive.ObjectID) (*OIDCAuthorization, error) {
+	state, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate state: %w", err)
+	}
+	nonce, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	verifier, challenge, err := utils.GeneratePKCE()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate PKCE verifier: %w", err)
+	}
+
+	authURL, err := s.provider.AuthCodeURL(ctx, state, nonce, challenge)
+	if err != nil {
+		log.Printf("❌ SSO provider unavailable: %v", err)
+		return nil, fmt.Errorf("单点登录服务不可用")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.pending) >= oidcMaxPending {
+		now := time.Now()
+		for key, p := range s.pending {
+			if now.After(p.expiresAt) {
+				delete(s.pending, key)
+			}
+		}
+		if len(s.pending) >= oidcMaxPending {
+			return nil, fmt.Errorf("登录请求过多，请稍后再试")
+		}
+	}
+	s.pending[state] = &oidcPending{
+		verifier:  verifier,
+		nonce:     nonce,
+		linkUser:  linkUser,
+		expiresAt: time.Now().Add(oidcStateTTL),
+	}
+
+	return &OIDCAuthorization{AuthorizationURL: authURL, State: state}, nil
+}
+
+// takePending removes and returns the login started with state. A login
+// started for linking is only accepted from the user who started it.
+func (s *OIDCService) takePending(state string, linkUser *primitive.ObjectID) (*oidcPending, error) {
+	s.mu.Lock()
+	pending, ok := s.pending[state]
+	delete(s.pending, state)
+	s.mu.Unlock()
+
+	if !ok || time.Now().After(pending.expiresAt) {
+		return nil, fmt.Errorf("登录已过期，请重新登录")
+	}
+	if (pending.linkUser == nil) != (linkUser == nil) ||
+		(linkUser != nil && *pending.linkUser != *linkUser) {
+		return nil, fmt.Errorf("登录已过期，请重新登录")
+	}
+	return pending, nil
+}
+
+// authenticate exchanges the code and verifies the ID token. Provider
+// errors are logged and reported as a generic failure.
+func (s *OIDCService) authenticate(ctx context.Context, pending *oidcPending, code string) (*utils.OIDCIdentity, error) {
+	tokens, err := s.provider.Exchange(ctx, code, pending.verifier)
+	if err != nil {
+		log.Printf("⚠️  SSO code exchange failed: %v", err)
+		return nil, fmt.Errorf("单点登录失败")
+	}
+
+	identity, err := s.provider.VerifyIDToken(ctx, tokens.IDToken, pending.nonce)
+	if err != nil {
+		log.Printf("🚨 SSO ID token rejected: %v", err)
+		return nil, fmt.Errorf("单点登录失败")
+	}
+
+	// Some providers only put groups in the userinfo response
+	if s.cfg.AdminGroup != "" && !identity.GroupsPresent {
+		if err := s.provider.FetchGroups(ctx, tokens.AccessToken, identity); err != nil {
+			log.Printf("⚠️  SSO userinfo request failed: %v", err)
+			return nil, fmt.Errorf("单点登录失败")
+		}
+	}
+
+	return identity, nil
+}
+
+// provision creates the user for an identity's first login
+func (s *OIDCService) provision(ctx context.Context, identity *utils.OIDCIdentity) (*models.User, error) {
+	username, err := s.availableUsername(ctx, identity)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &models.User{
+		Username: username,
+		SSO:      newSSOIdentity(identity),
+	}
+	if err := s.userRepo.Create(ctx, user); err != nil {
+		// A concurrent first login of the same identity won the race
+		if mongo.IsDuplicateKeyError(err) {
+			if existing, findErr := s.userRepo.FindBySSO(ctx, identity.Issuer, identity.Subject); findErr == nil && existing != nil {
+				return existing, nil
+			}
+		}
+		return nil, err
+	}
+
+	if err := s.auth.joinDefaultChannel(ctx, user); err != nil {
+		return nil, err
+	}
+
+	log.Printf("👤 Created user %s for SSO subject %s", user.Username, identity.Subject)
+	return user, nil
+}
+
+// availableUsername picks a free username from the identity's claims,
+// adding a number or a random suffix if it is taken
+func (s *OIDCService) availableUsername(ctx context.Context, identity *utils.OIDCIdentity) (string, error) {
+	base := ssoUsernameBase(identity)
+
+	for i := 1; i <= ssoUsernameRetries*2; i++ {
+		candidate := base
+		switch {
+		case i > ssoUsernameRetries:
+			suffix, err := utils.GenerateOpaqueToken()
+			if err != nil {
+				return "", fmt.Errorf("failed to generate username: %w", err)
+			}
+			candidate = truncateUsername(base, ssoUsernameMaxLen-5) + "_" + strings.ToLower(suffix[:4])
+		case i > 1:
+			suffix := strconv.Itoa(i)
+			candidate = truncateUsername(base, ssoUsernameMaxLen-len(suffix)) + suffix
+		}
+
+		existing, err := s.userRepo.FindByUsername(ctx, candidate)
+		if err != nil {
+			return "", fmt.Errorf("failed to check username: %w", err)
+		}
+		if existing == nil {
+			return candidate, nil
+		}
+	}
+
+	return "", fmt.Errorf("failed to find a free username for %q", base)
+}
+
+// applyAdminGroup grants or withdraws the admin role according to the
+// identity's membership of the admin group. Only an admin role granted by
+// the mapping is withdrawn; roles assigned by an admin are left alone.
+func (s *OIDCService) applyAdminGroup(ctx context.Context, user *models.User, identity *utils.OIDCIdentity) error {
+	if s.cfg.AdminGroup == "" {
+		return nil
+	}
+
+	member := false
+	for _, group := range identity.Groups {
+		if group == s.cfg.AdminGroup {
+			member = true
+			break
+		}
+	}
+
+	switch {
+	case member && user.Role != models.RoleAdmin:
+		reason := fmt.Sprintf("单点登录组 %s 成员", s.cfg.AdminGroup)
+		return s.roles.setRole(ctx, user, models.RoleAdmin, models.RoleSourceSSO, primitive.NilObjectID, reason)
+	case !member && user.Role == models.RoleAdmin && user.RoleSource == models.RoleSourceSSO:
+		reason := fmt.Sprintf("不再是单点登录组 %s 成员", s.cfg.AdminGroup)
+		return s.roles.setRole(ctx, user, models.RoleUser, models.RoleSourceSSO, primitive.NilObjectID, reason)
+	}
+	return nil
+}
+
+// newSSOIdentity builds the stored identity. The email is kept only when
+// the provider verified it.
+func newSSOIdentity(identity *utils.OIDCIdentity) *models.SSOIdentity {
+	sso := &models.SSOIdentity{
+		Issuer:   identity.Issuer,
+		Subject:  identity.Subject,
+		LinkedAt: time.Now(),
+	}
+	if identity.EmailVerified {
+		sso.Email = identity.Email
+	}
+	return sso
+}
+
+// ssoUsernameBase derives a username from the preferred username, the
+// email address or the name, in that order
+func ssoUsernameBase(identity *utils.OIDCIdentity) string {
+	email := identity.Email
+	if at := strings.Index(email, "@"); at >= 0 {
+		email = email[:at]
+	}
+
+	for _, candidate := range []string{identity.PreferredUsername, email, identity.Name} {
+		if name := truncateUsername(sanitizeUsername(candidate), ssoUsernameMaxLen); len(name) >= 2 {
+			return name
+		}
+	}
+	return "user"
+}
+
+// sanitizeUsername keeps letters, digits and a few separators, turning
+// spaces into underscores
+func sanitizeUsername(name string) string {
+	var b strings.Builder
+	for _, r := range strings.TrimSpace(name) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-' || r == '.':
+			b.WriteRune(r)
+		case unicode.IsSpace(r):
+			b.WriteRune('_')
+		}
+	}
+	return b.String()
+}
+
+// truncateUsername cuts a username to at most maxBytes without splitting
+// a character. Usernames are limited in bytes, like at registration.
+func truncateUsername(name string, maxBytes int) string {
+	if len(name) <= maxBytes {
+		return name
+	}
+	cut := 0
+	for i := range name {
+		if i > maxBytes {
+			break
+		}
+		cut = i
+	}
+	return name[:cut]
+}
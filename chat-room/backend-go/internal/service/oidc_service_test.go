@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"chat-room-backend/internal/config"
+	"chat-room-backend/internal/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestOIDCAuthorize(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(utils.OIDCDiscovery{
+			Issuer:                server.URL,
+			AuthorizationEndpoint: server.URL + "/authorize",
+			TokenEndpoint:         server.URL + "/token",
+			JWKSURI:               server.URL + "/jwks",
+		})
+	}))
+	defer server.Close()
+
+	s := &OIDCService{
+		provider: utils.NewOIDCProvider(config.OIDCConfig{Issuer: server.URL, ClientID: "chat-room"}),
+		pending:  make(map[string]*oidcPending),
+	}
+	userID := primitive.NewObjectID()
+
+	first, err := s.authorize(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	second, err := s.authorize(context.Background(), &userID)
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	if first.State == second.State {
+		t.Fatal("two logins got the same state")
+	}
+
+	for _, auth := range []*OIDCAuthorization{first, second} {
+		pending := s.pending[auth.State]
+		if pending == nil {
+			t.Fatalf("no pending login for state %q", auth.State)
+		}
+		query, _ := url.Parse(auth.AuthorizationURL)
+		if got := query.Query().Get("state"); got != auth.State {
+			t.Errorf("URL state = %q, want %q", got, auth.State)
+		}
+		// The nonce checked against the ID token is the one sent to the provider
+		if got := query.Query().Get("nonce"); got != pending.nonce || got == auth.State {
+			t.Errorf("URL nonce = %q, pending nonce %q", got, pending.nonce)
+		}
+		if query.Query().Get("code_challenge") == "" || pending.verifier == "" {
+			t.Error("login started without PKCE")
+		}
+	}
+	if s.pending[first.State].linkUser != nil || *s.pending[second.State].linkUser != userID {
+		t.Error("link user not recorded with the pending login")
+	}
+}
+
+func TestOIDCTakePending(t *testing.T) {
+	alice := primitive.NewObjectID()
+	bob := primitive.NewObjectID()
+	future := time.Now().Add(oidcStateTTL)
+
+	tests := []struct {
+		name     string
+		pending  *oidcPending
+		linkUser *primitive.ObjectID
+		wantOK   bool
+	}{
+		{"login", &oidcPending{nonce: "n", expiresAt: future}, nil, true},
+		{"expired login", &oidcPending{nonce: "n", expiresAt: time.Now().Add(-time.Second)}, nil, false},
+		{"link by the same user", &oidcPending{nonce: "n", linkUser: &alice, expiresAt: future}, &alice, true},
+		{"link finished by another user", &oidcPending{nonce: "n", linkUser: &alice, expiresAt: future}, &bob, false},
+		{"link finished as a login", &oidcPending{nonce: "n", linkUser: &alice, expiresAt: future}, nil, false},
+		{"login finished as a link", &oidcPending{nonce: "n", expiresAt: future}, &alice, false},
+	}
+
+	for _, tt := range tests {
+		s := &OIDCService{pending: map[string]*oidcPending{"state": tt.pending}}
+
+		pending, err := s.takePending("state", tt.linkUser)
+		if (err == nil) != tt.wantOK {
+			t.Errorf("%s: err = %v, want ok %v", tt.name, err, tt.wantOK)
+		}
+		if tt.wantOK && pending != tt.pending {
+			t.Errorf("%s: got another pending login", tt.name)
+		}
+
+		// A state is single use, even when the attempt failed
+		if _, err := s.takePending("state", tt.linkUser); err == nil {
+			t.Errorf("%s: state accepted twice", tt.name)
+		}
+	}
+
+	s := &OIDCService{pending: map[string]*oidcPending{"state": {expiresAt: future}}}
+	if _, err := s.takePending("other-state", nil); err == nil {
+		t.Error("unknown state accepted")
+	}
+}
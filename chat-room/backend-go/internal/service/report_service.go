@@ -0,0 +1,224 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"chat-room-backend/internal/models"
+	"chat-room-backend/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ReportService handles message reports and the moderator review queue
+type ReportService struct {
+	reportRepo   *repository.ReportRepository
+	messageRepo  *repository.MessageRepository
+	memberRepo   *repository.ChannelMemberRepository
+	adminService *AdminService
+	audit        *AuditService
+}
+
+// NewReportService creates a new ReportService
+func NewReportService(
+	reportRepo *repository.ReportRepository,
+	messageRepo *repository.MessageRepository,
+	memberRepo *repository.ChannelMemberRepository,
+	adminService *AdminService,
+	audit *AuditService,
+) *ReportService {
+	return &ReportService{
+		reportRepo:   reportRepo,
+		messageRepo:  messageRepo,
+		memberRepo:   memberRepo,
+		adminService: adminService,
+		audit:        audit,
+	}
+}
+
+// ReportMessageRequest represents message report data
+type ReportMessageRequest struct {
+	MessageID string `json:"messageId" binding:"required"`
+	Reason    string `json:"reason" binding:"required,max=500"`
+}
+
+// ReportActionRequest represents a moderator action on a report
+type ReportActionRequest struct {
+	Action   string `json:"action" binding:"required,oneof=dismiss delete_message mute_author"`
+	Duration int    `json:"duration" binding:"min=0"` // Mute duration in minutes for mute_author, 0 for permanent
+	Note     string `json:"note" binding:"max=500"`
+}
+
+// ReportMessage reports a message for moderator review. Reports of a
+// message that already has an open report are merged into it.
+func (s *ReportService) ReportMessage(ctx context.Context, reporterID primitive.ObjectID, reporterName string, req *ReportMessageRequest) (*models.Report, error) {
+	messageID, err := primitive.ObjectIDFromHex(req.MessageID)
+	if err != nil {
+		return nil, fmt.Errorf("消息不存在")
+	}
+
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		return nil, fmt.Errorf("请填写举报原因")
+	}
+
+	message, err := s.messageRepo.FindByID(ctx, messageID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find message: %w", err)
+	}
+	if message == nil || message.IsDeleted {
+		return nil, fmt.Errorf("消息不存在")
+	}
+	if message.UserID != nil && *message.UserID == reporterID {
+		return nil, fmt.Errorf("不能举报自己的消息")
+	}
+
+	// Only members of the channel can see, and so report, its messages
+	member, err := s.memberRepo.FindByUserAndChannel(ctx, reporterID, message.ChannelID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check membership: %w", err)
+	}
+	if member == nil {
+		return nil, fmt.Errorf("您不是该频道成员")
+	}
+
+	reporter := models.Reporter{
+		UserID:     reporterID,
+		Username:   reporterName,
+		Reason:     reason,
+		ReportedAt: time.Now(),
+	}
+
+	// Merge into the open report if there is one
+	if report, err := s.addReporter(ctx, messageID, reporter); report != nil || err != nil {
+		return report, err
+	}
+
+	report := &models.Report{
+		MessageID:      message.ID,
+		ChannelID:      message.ChannelID,
+		AuthorID:       message.UserID,
+		AuthorUsername: message.Username,
+		MessageText:    message.Message,
+		Source:         models.ReportSourceUser,
+		Reason:         reason,
+		Reporters:      []models.Reporter{reporter},
+		ReportCount:    1,
+	}
+	if err := s.reportRepo.Create(ctx, report); err != nil {
+		// Another report of the same message was opened concurrently
+		if mongo.IsDuplicateKeyError(err) {
+			if report, err := s.addReporter(ctx, messageID, reporter); report != nil || err != nil {
+				return report, err
+			}
+		}
+		return nil, fmt.Errorf("failed to create report: %w", err)
+	}
+
+	return report, nil
+}
+
+// addReporter merges a reporter into the open report of a message.
+// Returns nil without error if there is no open report.
+func (s *ReportService) addReporter(ctx context.Context, messageID primitive.ObjectID, reporter models.Reporter) (*models.Report, error) {
+	report, err := s.reportRepo.AddReporter(ctx, messageID, reporter)
+	if err != nil || report != nil {
+		return report, err
+	}
+
+	// Either there is no open report or this user is already on it
+	existing, err := s.reportRepo.FindOpenByMessageID(ctx, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, fmt.Errorf("你已举报过该消息")
+	}
+	return nil, nil
+}
+
+// GetReports returns review queue entries with a status (all if empty)
+func (s *ReportService) GetReports(ctx context.Context, status string) ([]*models.Report, error) {
+	reports, err := s.reportRepo.FindByStatus(ctx, status, 100)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reports: %w", err)
+	}
+	return reports, nil
+}
+
+// ActOnReport applies a moderator action to an open report and closes it
+func (s *ReportService) ActOnReport(ctx context.Context, reportID string, req *ReportActionRequest, actorID primitive.ObjectID) (*models.Report, error) {
+	reportObjID, err := primitive.ObjectIDFromHex(reportID)
+	if err != nil {
+		return nil, fmt.Errorf("举报不存在")
+	}
+
+	report, err := s.reportRepo.FindByID(ctx, reportObjID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find report: %w", err)
+	}
+	if report == nil {
+		return nil, fmt.Errorf("举报不存在")
+	}
+	if report.Status != models.ReportStatusOpen {
+		return nil, fmt.Errorf("举报已处理")
+	}
+
+	status := models.ReportStatusActioned
+	switch req.Action {
+	case models.ReportActionDismiss:
+		status = models.ReportStatusDismissed
+
+	case models.ReportActionDeleteMessage:
+		if err := s.messageRepo.SoftDelete(ctx, report.MessageID); err != nil {
+			return nil, err
+		}
+
+	case models.ReportActionMuteAuthor:
+		if report.AuthorID == nil {
+			return nil, fmt.Errorf("该消息没有可禁言的作者")
+		}
+		reason := req.Note
+		if reason == "" {
+			reason = "被举报：" + report.Reason
+		}
+		muteReq := &MuteUserRequest{
+			UserID:   report.AuthorID.Hex(),
+			Duration: req.Duration,
+			Reason:   reason,
+		}
+		if err := s.adminService.MuteUser(ctx, muteReq, actorID); err != nil {
+			return nil, err
+		}
+	}
+
+	resolved, err := s.reportRepo.Resolve(ctx, reportObjID, status, req.Action, req.Note, actorID)
+	if err != nil {
+		return nil, err
+	}
+	if !resolved {
+		return nil, fmt.Errorf("举报已处理")
+	}
+
+	s.audit.Record(ctx, &models.AuditLog{
+		ActorID:    actorID,
+		Action:     models.AuditActionReportResolve,
+		TargetType: models.AuditTargetReport,
+		TargetID:   &reportObjID,
+		TargetName: report.AuthorUsername,
+		Reason:     req.Note,
+		Before: map[string]interface{}{
+			"status":    report.Status,
+			"messageId": report.MessageID,
+			"message":   report.MessageText,
+		},
+		After: map[string]interface{}{
+			"status":     status,
+			"resolution": req.Action,
+		},
+	})
+
+	return s.reportRepo.FindByID(ctx, reportObjID)
+}
@@ -0,0 +1,448 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"time"
+
+	"chat-room-backend/internal/config"
+	"chat-room-backend/internal/middleware"
+	"chat-room-backend/internal/models"
+	"chat-room-backend/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// migrationImportAdmins imports the legacy admins.json into the admin role
+const migrationImportAdmins = "import-admins-json"
+
+// roleNamePattern restricts role names to short identifiers
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]{1,31}$`)
+
+// RoleService manages roles and assigns them to users
+type RoleService struct {
+	roleRepo      *repository.RoleRepository
+	userRepo      *repository.UserRepository
+	migrationRepo *repository.MigrationRepository
+	permissions   *middleware.PermissionChecker
+	audit         *AuditService
+}
+
+// NewRoleService creates a new RoleService. It creates the built-in roles,
+// imports the legacy admins file once and applies the bootstrap admin.
+func NewRoleService(
+	roleRepo *repository.RoleRepository,
+	userRepo *repository.UserRepository,
+	migrationRepo *repository.MigrationRepository,
+	permissions *middleware.PermissionChecker,
+	audit *AuditService,
+	cfg config.RolesConfig,
+) *RoleService {
+	s := &RoleService{
+		roleRepo:      roleRepo,
+		userRepo:      userRepo,
+		migrationRepo: migrationRepo,
+		permissions:   permissions,
+		audit:         audit,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := s.seedRoles(ctx); err != nil {
+		log.Printf("⚠️  Warning: Failed to create built-in roles: %v", err)
+	}
+	if err := s.importAdminsFile(ctx, cfg.AdminsFile); err != nil {
+		log.Printf("⚠️  Warning: Failed to import %s: %v", cfg.AdminsFile, err)
+	}
+	if cfg.BootstrapAdminID != "" {
+		if err := s.bootstrapAdmin(ctx, cfg.BootstrapAdminID); err != nil {
+			log.Printf("⚠️  Warning: Failed to apply BOOTSTRAP_ADMIN_ID: %v", err)
+		}
+	}
+
+	if err := permissions.Reload(); err != nil {
+		log.Printf("⚠️  Warning: Failed to load roles: %v", err)
+	}
+
+	return s
+}
+
+// RoleRequest represents the data of a new role
+type RoleRequest struct {
+	Name        string   `json:"name" binding:"required"`
+	Description string   `json:"description" binding:"max=100"`
+	Permissions []string `json:"permissions"`
+}
+
+// UpdateRoleRequest represents changes to a role
+type UpdateRoleRequest struct {
+	Description string   `json:"description" binding:"max=100"`
+	Permissions []string `json:"permissions"`
+}
+
+// AssignRoleRequest represents a role assignment
+type AssignRoleRequest struct {
+	Role   string `json:"role" binding:"required"`
+	Reason string `json:"reason"`
+}
+
+// UserPermissions is the role and permissions of the current user
+type UserPermissions struct {
+	Role        string   `json:"role"`
+	Permissions []string `json:"permissions"`
+}
+
+// GetPermissions lists every permission a role can grant
+func (s *RoleService) GetPermissions() []models.PermissionInfo {
+	return models.AllPermissions
+}
+
+// GetUserPermissions returns a user's role and the permissions they can
+// use. Permissions only apply to sessions verified with a second factor.
+func (s *RoleService) GetUserPermissions(userID primitive.ObjectID, twoFactor bool) *UserPermissions {
+	permissions := []string{}
+	if twoFactor {
+		permissions = s.permissions.Permissions(userID)
+	}
+	return &UserPermissions{
+		Role:        s.permissions.RoleOf(userID),
+		Permissions: permissions,
+	}
+}
+
+// GetRoles returns every role
+func (s *RoleService) GetRoles(ctx context.Context) ([]*models.Role, error) {
+	roles, err := s.roleRepo.FindAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get roles: %w", err)
+	}
+	return roles, nil
+}
+
+// CreateRole creates a role
+func (s *RoleService) CreateRole(ctx context.Context, req *RoleRequest, actorID primitive.ObjectID) (*models.Role, error) {
+	if !roleNamePattern.MatchString(req.Name) {
+		return nil, fmt.Errorf("角色名称只能包含小写字母、数字、下划线和连字符，以字母开头，长度 2-32")
+	}
+	permissions, err := normalizePermissions(req.Permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	role := &models.Role{
+		Name:        req.Name,
+		Description: req.Description,
+		Permissions: permissions,
+	}
+	if err := s.roleRepo.Create(ctx, role); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, fmt.Errorf("角色已存在")
+		}
+		return nil, err
+	}
+	s.permissions.SetRole(role)
+
+	s.audit.Record(ctx, &models.AuditLog{
+		ActorID:    actorID,
+		Action:     models.AuditActionRoleCreate,
+		TargetType: models.AuditTargetRole,
+		TargetID:   &role.ID,
+		TargetName: role.Name,
+		After:      roleSnapshot(role),
+	})
+	return role, nil
+}
+
+// UpdateRole changes a role's description and permissions. Built-in roles
+// cannot be changed.
+func (s *RoleService) UpdateRole(ctx context.Context, name string, req *UpdateRoleRequest, actorID primitive.ObjectID) (*models.Role, error) {
+	before, err := s.findEditableRole(ctx, name, "内置角色不能修改")
+	if err != nil {
+		return nil, err
+	}
+	permissions, err := normalizePermissions(req.Permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	role, err := s.roleRepo.Update(ctx, name, req.Description, permissions)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, fmt.Errorf("角色不存在")
+	}
+	s.permissions.SetRole(role)
+
+	s.audit.Record(ctx, &models.AuditLog{
+		ActorID:    actorID,
+		Action:     models.AuditActionRoleUpdate,
+		TargetType: models.AuditTargetRole,
+		TargetID:   &role.ID,
+		TargetName: role.Name,
+		Before:     roleSnapshot(before),
+		After:      roleSnapshot(role),
+	})
+	return role, nil
+}
+
+// DeleteRole deletes a role no user holds. Built-in roles cannot be deleted.
+func (s *RoleService) DeleteRole(ctx context.Context, name string, actorID primitive.ObjectID) error {
+	role, err := s.findEditableRole(ctx, name, "内置角色不能删除")
+	if err != nil {
+		return err
+	}
+
+	count, err := s.userRepo.CountByRole(ctx, name)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("仍有 %d 名用户使用该角色", count)
+	}
+
+	deleted, err := s.roleRepo.Delete(ctx, name)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return fmt.Errorf("角色不存在")
+	}
+	s.permissions.RemoveRole(name)
+
+	s.audit.Record(ctx, &models.AuditLog{
+		ActorID:    actorID,
+		Action:     models.AuditActionRoleDelete,
+		TargetType: models.AuditTargetRole,
+		TargetID:   &role.ID,
+		TargetName: role.Name,
+		Before:     roleSnapshot(role),
+	})
+	return nil
+}
+
+// AssignRole gives a user a role. The last admin cannot be demoted.
+func (s *RoleService) AssignRole(ctx context.Context, userID primitive.ObjectID, req *AssignRoleRequest, actorID primitive.ObjectID) (*models.User, error) {
+	role, err := s.roleRepo.FindByName(ctx, req.Role)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, fmt.Errorf("角色不存在")
+	}
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, fmt.Errorf("用户不存在")
+	}
+	if user.IsBot {
+		return nil, fmt.Errorf("不能为机器人分配角色")
+	}
+
+	if user.Role == models.RoleAdmin && role.Name != models.RoleAdmin {
+		admins, err := s.userRepo.CountByRole(ctx, models.RoleAdmin)
+		if err != nil {
+			return nil, err
+		}
+		if admins <= 1 {
+			return nil, fmt.Errorf("至少需要保留一名管理员")
+		}
+	}
+
+	// An admin's choice overrides the SSO group mapping from now on
+	if err := s.setRole(ctx, user, role.Name, models.RoleSourceManual, actorID, req.Reason); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// setRole changes a user's role, updates the permission cache and audits
+// the change. A nil actor ID records the system as the actor.
+func (s *RoleService) setRole(ctx context.Context, user *models.User, role, source string, actorID primitive.ObjectID, reason string) error {
+	before := user.Role
+	if before == "" {
+		before = models.RoleUser
+	}
+
+	if err := s.userRepo.SetRole(ctx, user.ID, role, source); err != nil {
+		return err
+	}
+	user.Role = role
+	user.RoleSource = source
+	s.permissions.SetUserRole(user.ID, role)
+
+	log.Printf("🛡️  Role of %s changed from %s to %s", user.Username, before, role)
+
+	entry := &models.AuditLog{
+		ActorID:    actorID,
+		Action:     models.AuditActionUserRole,
+		TargetType: models.AuditTargetUser,
+		TargetID:   &user.ID,
+		TargetName: user.Username,
+		Reason:     reason,
+		Before:     map[string]interface{}{"role": before},
+		After:      map[string]interface{}{"role": role, "source": source},
+	}
+	if actorID.IsZero() {
+		entry.ActorUsername = SystemActorName
+	}
+	s.audit.Record(ctx, entry)
+	return nil
+}
+
+// findEditableRole loads a role that is not built-in
+func (s *RoleService) findEditableRole(ctx context.Context, name, systemError string) (*models.Role, error) {
+	role, err := s.roleRepo.FindByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, fmt.Errorf("角色不存在")
+	}
+	if role.System {
+		return nil, errors.New(systemError)
+	}
+	return role, nil
+}
+
+// seedRoles creates the built-in roles and the default moderator role
+func (s *RoleService) seedRoles(ctx context.Context) error {
+	all := make([]string, len(models.AllPermissions))
+	for i, p := range models.AllPermissions {
+		all[i] = p.Name
+	}
+
+	if err := s.roleRepo.UpsertSystem(ctx, &models.Role{
+		Name:        models.RoleAdmin,
+		Description: "管理员，拥有全部权限",
+		Permissions: all,
+	}); err != nil {
+		return err
+	}
+	if err := s.roleRepo.UpsertSystem(ctx, &models.Role{
+		Name:        models.RoleUser,
+		Description: "普通用户",
+		Permissions: []string{},
+	}); err != nil {
+		return err
+	}
+
+	// Moderators are seeded once and can be edited afterwards
+	return s.roleRepo.CreateIfMissing(ctx, &models.Role{
+		Name:        models.RoleModerator,
+		Description: "版主，处理举报并禁言违规用户",
+		Permissions: []string{
+			models.PermReportsManage,
+			models.PermUsersView,
+			models.PermUsersMute,
+			models.PermWordFiltersManage,
+			models.PermModerationExempt,
+		},
+	})
+}
+
+// legacyAdminsFile is the structure of admins.json
+type legacyAdminsFile struct {
+	Admins []string `json:"admins"`
+}
+
+// importAdminsFile gives the users listed in the legacy admins.json the
+// admin role. It runs once; usernames that are not registered are skipped.
+func (s *RoleService) importAdminsFile(ctx context.Context, path string) error {
+	applied, err := s.migrationRepo.IsApplied(ctx, migrationImportAdmins)
+	if err != nil || applied {
+		return err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			log.Printf("ℹ️  No admins file at %s, nothing to import", path)
+			return nil
+		}
+		return err
+	}
+
+	var file legacyAdminsFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("failed to parse admins file: %w", err)
+	}
+
+	imported := 0
+	for _, username := range file.Admins {
+		user, err := s.userRepo.FindByUsername(ctx, username)
+		if err != nil {
+			return err
+		}
+		if user == nil {
+			log.Printf("⚠️  Admin %q from %s is not registered, skipped", username, path)
+			continue
+		}
+		if user.Role == models.RoleAdmin {
+			continue
+		}
+		if err := s.setRole(ctx, user, models.RoleAdmin, models.RoleSourceManual, primitive.NilObjectID, "从 admins.json 导入"); err != nil {
+			return err
+		}
+		imported++
+	}
+
+	log.Printf("✅ Imported %d admin(s) from %s", imported, path)
+	return s.migrationRepo.MarkApplied(ctx, migrationImportAdmins)
+}
+
+// bootstrapAdmin gives a user the admin role, so a deployment without
+// any admin left can be recovered
+func (s *RoleService) bootstrapAdmin(ctx context.Context, userIDStr string) error {
+	userID, err := primitive.ObjectIDFromHex(userIDStr)
+	if err != nil {
+		return fmt.Errorf("invalid user ID %q", userIDStr)
+	}
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return fmt.Errorf("user %s not found", userIDStr)
+	}
+	if user.Role == models.RoleAdmin {
+		return nil
+	}
+
+	return s.setRole(ctx, user, models.RoleAdmin, models.RoleSourceManual, primitive.NilObjectID, "BOOTSTRAP_ADMIN_ID")
+}
+
+// normalizePermissions checks that every permission exists and removes
+// duplicates
+func normalizePermissions(permissions []string) ([]string, error) {
+	seen := make(map[string]bool, len(permissions))
+	result := make([]string, 0, len(permissions))
+	for _, p := range permissions {
+		if !models.IsPermission(p) {
+			return nil, fmt.Errorf("未知权限: %s", p)
+		}
+		if !seen[p] {
+			seen[p] = true
+			result = append(result, p)
+		}
+	}
+	return result, nil
+}
+
+// roleSnapshot returns the audited fields of a role
+func roleSnapshot(role *models.Role) map[string]interface{} {
+	return map[string]interface{}{
+		"description": role.Description,
+		"permissions": role.Permissions,
+	}
+}
@@ -0,0 +1,326 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"chat-room-backend/internal/models"
+	"chat-room-backend/internal/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Word filter list formats for bulk import and export
+const (
+	WordFilterFormatText = "text" // One word per line, # starts a comment
+	WordFilterFormatCSV  = "csv"  // Header row with the columns below
+	WordFilterFormatJSON = "json" // Array of AddWordFilterRequest objects
+)
+
+// maxWordFilterImport caps the number of entries in a single import
+const maxWordFilterImport = 10000
+
+// wordFilterCSVColumns are the CSV columns, in export order
+var wordFilterCSVColumns = []string{"word", "matchType", "action", "muteDuration", "variants", "isAllow"}
+
+// WordFilterImportError describes an entry that could not be imported
+type WordFilterImportError struct {
+	Line  int    `json:"line"`
+	Word  string `json:"word"`
+	Error string `json:"error"`
+}
+
+// WordFilterConflict is an imported entry whose word is already active
+// with different settings. Conflicts are reported, never overwritten.
+type WordFilterConflict struct {
+	Line     int                        `json:"line"`
+	Existing *models.WordFilterResponse `json:"existing"`
+	Imported *models.WordFilterResponse `json:"imported"`
+}
+
+// WordFilterImportResult is the diff between an import and the active list
+type WordFilterImportResult struct {
+	DryRun    bool                         `json:"dryRun"`
+	Added     []*models.WordFilterResponse `json:"added"`
+	Unchanged []string                     `json:"unchanged"` // Already active with the same settings
+	Conflicts []WordFilterConflict         `json:"conflicts"`
+	Invalid   []WordFilterImportError      `json:"invalid"`
+}
+
+// wordFilterEntry is a parsed import entry with its source line
+type wordFilterEntry struct {
+	line int
+	req  *AddWordFilterRequest
+}
+
+// ImportWordFilters adds the filters in a list, skipping entries already
+// active. With dryRun set nothing is written and the result is a preview.
+func (s *AdminService) ImportWordFilters(ctx context.Context, format string, data []byte, dryRun bool, importedBy primitive.ObjectID) (*WordFilterImportResult, error) {
+	entries, invalid, err := parseWordFilterList(format, data)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) > maxWordFilterImport {
+		return nil, fmt.Errorf("一次最多导入 %d 个敏感词", maxWordFilterImport)
+	}
+
+	// Imports are global, so only global filters count as duplicates
+	active, err := s.adminRepo.GetAllWordFilters(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get word filters: %w", err)
+	}
+	existing := make(map[string]*models.WordFilter, len(active))
+	for _, filter := range active {
+		existing[wordFilterKey(filter)] = filter
+	}
+
+	result := &WordFilterImportResult{
+		DryRun:    dryRun,
+		Added:     []*models.WordFilterResponse{},
+		Unchanged: []string{},
+		Conflicts: []WordFilterConflict{},
+		Invalid:   invalid,
+	}
+
+	var toAdd []*models.WordFilter
+	for _, entry := range entries {
+		filter, err := buildWordFilter(entry.req, importedBy)
+		if err != nil {
+			result.Invalid = append(result.Invalid, WordFilterImportError{Line: entry.line, Word: entry.req.Word, Error: err.Error()})
+			continue
+		}
+
+		key := wordFilterKey(filter)
+		if current, ok := existing[key]; ok {
+			if sameWordFilterSettings(current, filter) {
+				result.Unchanged = append(result.Unchanged, filter.Word)
+			} else {
+				result.Conflicts = append(result.Conflicts, WordFilterConflict{
+					Line:     entry.line,
+					Existing: current.ToResponse(),
+					Imported: filter.ToResponse(),
+				})
+			}
+			continue
+		}
+
+		// Later duplicates within the same file compare against the first
+		existing[key] = filter
+		toAdd = append(toAdd, filter)
+	}
+
+	if !dryRun && len(toAdd) > 0 {
+		if err := s.adminRepo.CreateWordFilters(ctx, toAdd); err != nil {
+			return nil, fmt.Errorf("failed to import word filters: %w", err)
+		}
+
+		words := make([]string, 0, min(len(toAdd), 100))
+		for _, filter := range toAdd[:min(len(toAdd), 100)] {
+			words = append(words, filter.Word)
+		}
+		s.audit.Record(ctx, &models.AuditLog{
+			ActorID:    importedBy,
+			Action:     models.AuditActionWordFilterImport,
+			TargetType: models.AuditTargetWordFilter,
+			TargetName: fmt.Sprintf("%d 个敏感词", len(toAdd)),
+			After: map[string]interface{}{
+				"format": format,
+				"count":  len(toAdd),
+				"words":  words, // First 100 only
+			},
+		})
+	}
+
+	for _, filter := range toAdd {
+		result.Added = append(result.Added, filter.ToResponse())
+	}
+	if result.Invalid == nil {
+		result.Invalid = []WordFilterImportError{}
+	}
+
+	return result, nil
+}
+
+// ExportWordFilters encodes the active global word filters in a list format
+func (s *AdminService) ExportWordFilters(ctx context.Context, format string) ([]byte, error) {
+	filters, err := s.adminRepo.GetAllWordFilters(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get word filters: %w", err)
+	}
+
+	var buf bytes.Buffer
+	switch format {
+	case WordFilterFormatText:
+		buf.WriteString("# word filters, one per line\n")
+		for _, filter := range filters {
+			if !filter.IsAllow {
+				buf.WriteString(filter.Word + "\n")
+			}
+		}
+
+	case WordFilterFormatCSV:
+		writer := csv.NewWriter(&buf)
+		writer.Write(wordFilterCSVColumns)
+		for _, filter := range filters {
+			writer.Write([]string{
+				filter.Word,
+				filter.GetMatchType(),
+				filter.GetAction(),
+				strconv.Itoa(filter.MuteDuration),
+				strings.Join(filter.Variants, "|"),
+				strconv.FormatBool(filter.IsAllow),
+			})
+		}
+		writer.Flush()
+		if err := writer.Error(); err != nil {
+			return nil, fmt.Errorf("failed to encode word filters: %w", err)
+		}
+
+	case WordFilterFormatJSON:
+		list := make([]AddWordFilterRequest, len(filters))
+		for i, filter := range filters {
+			list[i] = AddWordFilterRequest{
+				Word:         filter.Word,
+				MatchType:    filter.GetMatchType(),
+				Variants:     filter.Variants,
+				IsAllow:      filter.IsAllow,
+				Action:       filter.GetAction(),
+				MuteDuration: filter.MuteDuration,
+			}
+		}
+		encoder := json.NewEncoder(&buf)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(list); err != nil {
+			return nil, fmt.Errorf("failed to encode word filters: %w", err)
+		}
+
+	default:
+		return nil, fmt.Errorf("不支持的格式")
+	}
+
+	return buf.Bytes(), nil
+}
+
+// parseWordFilterList parses an import file into filter requests.
+// Entries that cannot be parsed are returned as invalid; a malformed file is an error.
+func parseWordFilterList(format string, data []byte) ([]wordFilterEntry, []WordFilterImportError, error) {
+	switch format {
+	case WordFilterFormatText:
+		var entries []wordFilterEntry
+		for i, line := range strings.Split(string(data), "\n") {
+			word := strings.TrimSpace(line)
+			if word == "" || strings.HasPrefix(word, "#") {
+				continue
+			}
+			entries = append(entries, wordFilterEntry{line: i + 1, req: &AddWordFilterRequest{Word: word}})
+		}
+		return entries, nil, nil
+
+	case WordFilterFormatCSV:
+		return parseWordFilterCSV(data)
+
+	case WordFilterFormatJSON:
+		var list []AddWordFilterRequest
+		if err := json.Unmarshal(data, &list); err != nil {
+			return nil, nil, fmt.Errorf("无效的 JSON 文件")
+		}
+		entries := make([]wordFilterEntry, len(list))
+		for i := range list {
+			entries[i] = wordFilterEntry{line: i + 1, req: &list[i]}
+		}
+		return entries, nil, nil
+
+	default:
+		return nil, nil, fmt.Errorf("不支持的格式")
+	}
+}
+
+// parseWordFilterCSV parses a CSV import. Columns are matched by header
+// name; "severity" is accepted as an alias for "action".
+func parseWordFilterCSV(data []byte) ([]wordFilterEntry, []WordFilterImportError, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("无效的 CSV 文件")
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if name == "severity" {
+			name = "action"
+		}
+		columns[name] = i
+	}
+	if _, ok := columns["word"]; !ok {
+		return nil, nil, fmt.Errorf("CSV 文件缺少 word 列")
+	}
+
+	var entries []wordFilterEntry
+	var invalid []WordFilterImportError
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("无效的 CSV 文件")
+		}
+
+		cell := func(name string) string {
+			if i, ok := columns[strings.ToLower(name)]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		req := &AddWordFilterRequest{
+			Word:      cell("word"),
+			MatchType: cell("matchType"),
+			Action:    strings.ToLower(cell("action")),
+		}
+		if req.Word == "" {
+			continue
+		}
+		if v := cell("muteDuration"); v != "" {
+			if req.MuteDuration, err = strconv.Atoi(v); err != nil {
+				invalid = append(invalid, WordFilterImportError{Line: line, Word: req.Word, Error: "无效的禁言时长"})
+				continue
+			}
+		}
+		if v := cell("isAllow"); v != "" {
+			if req.IsAllow, err = strconv.ParseBool(v); err != nil {
+				invalid = append(invalid, WordFilterImportError{Line: line, Word: req.Word, Error: "无效的 isAllow 值"})
+				continue
+			}
+		}
+		if v := cell("variants"); v != "" {
+			req.Variants = strings.Split(v, "|")
+		}
+
+		entries = append(entries, wordFilterEntry{line: line, req: req})
+	}
+
+	return entries, invalid, nil
+}
+
+// wordFilterKey identifies filters that match the same text
+func wordFilterKey(filter *models.WordFilter) string {
+	word := filter.Word
+	if filter.GetMatchType() != models.MatchTypeRegex {
+		word = string(utils.FoldPattern(word))
+	}
+	return fmt.Sprintf("%s|%t|%s", filter.GetMatchType(), filter.IsAllow, word)
+}
+
+// sameWordFilterSettings reports whether two filters for the same word behave alike
+func sameWordFilterSettings(a, b *models.WordFilter) bool {
+	return a.GetAction() == b.GetAction() && a.MuteDuration == b.MuteDuration
+}
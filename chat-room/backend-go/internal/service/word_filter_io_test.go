@@ -0,0 +1,148 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"chat-room-backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestParseWordFilterText(t *testing.T) {
+	data := "# comment\nspam\n\n  scam  \r\n#another\n"
+
+	entries, invalid, err := parseWordFilterList(WordFilterFormatText, []byte(data))
+	if err != nil || len(invalid) != 0 {
+		t.Fatalf("parseWordFilterList() invalid %v, err %v", invalid, err)
+	}
+
+	want := []struct {
+		line int
+		word string
+	}{{2, "spam"}, {4, "scam"}}
+	if len(entries) != len(want) {
+		t.Fatalf("got %d entries, want %d", len(entries), len(want))
+	}
+	for i, entry := range entries {
+		if entry.line != want[i].line || entry.req.Word != want[i].word {
+			t.Errorf("entry %d = line %d %q, want line %d %q", i, entry.line, entry.req.Word, want[i].line, want[i].word)
+		}
+	}
+}
+
+func TestParseWordFilterCSV(t *testing.T) {
+	data := "\ufeffWord,Severity,muteDuration,variants,isAllow\n" +
+		"spam,MUTE,15,sp4m|spamm,false\n" +
+		",block,,,\n" +
+		"scam,review,soon,,\n" +
+		"badminton,,,,yes\n" +
+		"short\n"
+
+	entries, invalid, err := parseWordFilterList(WordFilterFormatCSV, []byte(data))
+	if err != nil {
+		t.Fatalf("parseWordFilterList() err %v", err)
+	}
+
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2: %+v", len(entries), entries)
+	}
+	spam := entries[0].req
+	if entries[0].line != 2 || spam.Word != "spam" || spam.Action != models.FilterActionMute || spam.MuteDuration != 15 ||
+		!reflect.DeepEqual(spam.Variants, []string{"sp4m", "spamm"}) || spam.IsAllow {
+		t.Errorf("entry 0 = line %d %+v", entries[0].line, spam)
+	}
+	// Rows shorter than the header leave the missing columns empty
+	if entries[1].line != 6 || entries[1].req.Word != "short" {
+		t.Errorf("entry 1 = line %d %+v", entries[1].line, entries[1].req)
+	}
+
+	wantInvalid := []WordFilterImportError{
+		{Line: 4, Word: "scam", Error: "无效的禁言时长"},
+		{Line: 5, Word: "badminton", Error: "无效的 isAllow 值"},
+	}
+	if !reflect.DeepEqual(invalid, wantInvalid) {
+		t.Errorf("invalid = %+v, want %+v", invalid, wantInvalid)
+	}
+}
+
+func TestParseWordFilterListErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		data   string
+		want   string
+	}{
+		{"unknown format", "xml", "<words/>", "不支持的格式"},
+		{"bad JSON", WordFilterFormatJSON, `{"word":"spam"}`, "无效的 JSON 文件"},
+		{"CSV without word column", WordFilterFormatCSV, "action\nblock\n", "CSV 文件缺少 word 列"},
+		{"empty CSV", WordFilterFormatCSV, "", "无效的 CSV 文件"},
+	}
+
+	for _, tt := range tests {
+		if _, _, err := parseWordFilterList(tt.format, []byte(tt.data)); err == nil || err.Error() != tt.want {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestParseWordFilterJSON(t *testing.T) {
+	data := `[{"word":"spam","action":"mute","muteDuration":5},{"word":"scam","matchType":"word"}]`
+
+	entries, _, err := parseWordFilterList(WordFilterFormatJSON, []byte(data))
+	if err != nil {
+		t.Fatalf("parseWordFilterList() err %v", err)
+	}
+	if len(entries) != 2 || entries[0].line != 1 || entries[1].line != 2 {
+		t.Fatalf("entries = %+v", entries)
+	}
+	if req := entries[0].req; req.Word != "spam" || req.Action != models.FilterActionMute || req.MuteDuration != 5 {
+		t.Errorf("entry 0 = %+v", req)
+	}
+	if req := entries[1].req; req.Word != "scam" || req.MatchType != models.MatchTypeWord {
+		t.Errorf("entry 1 = %+v", req)
+	}
+}
+
+func TestWordFilterKey(t *testing.T) {
+	build := func(req AddWordFilterRequest) *models.WordFilter {
+		filter, err := buildWordFilter(&req, primitive.NewObjectID())
+		if err != nil {
+			t.Fatalf("buildWordFilter(%+v): %v", req, err)
+		}
+		return filter
+	}
+
+	spam := build(AddWordFilterRequest{Word: "spam"})
+	tests := []struct {
+		name string
+		req  AddWordFilterRequest
+		same bool
+	}{
+		{"case and width folded", AddWordFilterRequest{Word: "ＳＰＡＭ"}, true},
+		{"other match type", AddWordFilterRequest{Word: "spam", MatchType: models.MatchTypeWord}, false},
+		{"allowlist entry", AddWordFilterRequest{Word: "spam", IsAllow: true}, false},
+		{"regex", AddWordFilterRequest{Word: "SPAM", MatchType: models.MatchTypeRegex}, false},
+	}
+
+	for _, tt := range tests {
+		if got := wordFilterKey(build(tt.req)) == wordFilterKey(spam); got != tt.same {
+			t.Errorf("%s: same key = %v, want %v", tt.name, got, tt.same)
+		}
+	}
+
+	// Regexes are compared verbatim
+	regex := AddWordFilterRequest{Word: "sp[a4]m", MatchType: models.MatchTypeRegex}
+	upper := AddWordFilterRequest{Word: "SP[A4]M", MatchType: models.MatchTypeRegex}
+	if wordFilterKey(build(regex)) == wordFilterKey(build(upper)) {
+		t.Error("regexes differing in case share a key")
+	}
+
+	// Same word, different action: a conflict rather than a duplicate
+	mute := build(AddWordFilterRequest{Word: "spam", Action: models.FilterActionMute})
+	if sameWordFilterSettings(spam, mute) {
+		t.Error("block and mute filters reported as the same settings")
+	}
+	if !sameWordFilterSettings(spam, build(AddWordFilterRequest{Word: "Spam"})) {
+		t.Error("identical filters reported as different settings")
+	}
+}
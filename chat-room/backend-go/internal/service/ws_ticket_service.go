@@ -0,0 +1,92 @@
+package service
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"chat-room-backend/internal/utils"
+)
+
+// wsMaxTickets bounds the tickets issued but not yet used
+const wsMaxTickets = 10000
+
+// wsTicket is an issued, unused WebSocket ticket
+type wsTicket struct {
+	claims    *utils.JWTClaims // Claims of the access token the ticket was issued for
+	ip        string
+	expiresAt time.Time
+}
+
+// WSTicketService exchanges access tokens for WebSocket tickets. Browsers
+// cannot set headers on a WebSocket handshake, so the credential has to
+// go in the URL; a ticket that works once within seconds is harmless in
+// proxy logs, unlike the access token itself.
+type WSTicketService struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	tickets map[string]*wsTicket
+}
+
+// NewWSTicketService creates a new WSTicketService
+func NewWSTicketService(ttl time.Duration) *WSTicketService {
+	return &WSTicketService{
+		ttl:     ttl,
+		tickets: make(map[string]*wsTicket),
+	}
+}
+
+// WSTicketResponse is an issued ticket
+type WSTicketResponse struct {
+	Ticket    string `json:"ticket"`
+	ExpiresIn int    `json:"expiresIn"` // Seconds
+}
+
+// Issue creates a ticket for the session of an access token, usable once
+// from the same IP address
+func (s *WSTicketService) Issue(claims *utils.JWTClaims, ip string) (*WSTicketResponse, error) {
+	ticket, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate ticket: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.tickets) >= wsMaxTickets {
+		now := time.Now()
+		for key, t := range s.tickets {
+			if now.After(t.expiresAt) {
+				delete(s.tickets, key)
+			}
+		}
+		if len(s.tickets) >= wsMaxTickets {
+			return nil, fmt.Errorf("连接请求过多，请稍后再试")
+		}
+	}
+	s.tickets[ticket] = &wsTicket{
+		claims:    claims,
+		ip:        ip,
+		expiresAt: time.Now().Add(s.ttl),
+	}
+
+	return &WSTicketResponse{
+		Ticket:    ticket,
+		ExpiresIn: int(s.ttl.Seconds()),
+	}, nil
+}
+
+// Redeem uses up a ticket and returns the claims of the access token it
+// was issued for
+func (s *WSTicketService) Redeem(ticket, ip string) (*utils.JWTClaims, error) {
+	s.mu.Lock()
+	t, ok := s.tickets[ticket]
+	delete(s.tickets, ticket)
+	s.mu.Unlock()
+
+	if !ok || time.Now().After(t.expiresAt) || t.ip != ip {
+		return nil, fmt.Errorf("无效或已过期的连接票据")
+	}
+	return t.claims, nil
+}
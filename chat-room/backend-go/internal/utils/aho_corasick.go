@@ -0,0 +1,110 @@
+package utils
+
+// AhoCorasick is a compiled multi-pattern matcher.
+// Searching is linear in the text length regardless of the number of patterns.
+type AhoCorasick struct {
+	nodes    []acNode
+	patterns [][]rune
+}
+
+// acNode is a state of the automaton
+type acNode struct {
+	next map[rune]int
+	fail int
+	out  []int // Indices of patterns ending at this state
+}
+
+// ACMatch is a pattern occurrence, in rune indices of the searched text
+type ACMatch struct {
+	Pattern int // Index of the pattern passed to NewAhoCorasick
+	Start   int
+	End     int // Exclusive
+}
+
+// NewAhoCorasick builds an automaton for the given patterns.
+// Empty patterns are ignored.
+func NewAhoCorasick(patterns [][]rune) *AhoCorasick {
+	ac := &AhoCorasick{
+		nodes:    []acNode{{next: make(map[rune]int)}},
+		patterns: patterns,
+	}
+
+	// Build the trie
+	for i, pattern := range patterns {
+		if len(pattern) == 0 {
+			continue
+		}
+		state := 0
+		for _, r := range pattern {
+			nextState, ok := ac.nodes[state].next[r]
+			if !ok {
+				ac.nodes = append(ac.nodes, acNode{next: make(map[rune]int)})
+				nextState = len(ac.nodes) - 1
+				ac.nodes[state].next[r] = nextState
+			}
+			state = nextState
+		}
+		ac.nodes[state].out = append(ac.nodes[state].out, i)
+	}
+
+	// Compute failure links breadth-first
+	queue := make([]int, 0, len(ac.nodes))
+	for _, child := range ac.nodes[0].next {
+		queue = append(queue, child)
+	}
+	for len(queue) > 0 {
+		state := queue[0]
+		queue = queue[1:]
+
+		for r, child := range ac.nodes[state].next {
+			queue = append(queue, child)
+
+			fail := ac.nodes[state].fail
+			for fail != 0 {
+				if _, ok := ac.nodes[fail].next[r]; ok {
+					break
+				}
+				fail = ac.nodes[fail].fail
+			}
+			if target, ok := ac.nodes[fail].next[r]; ok && target != child {
+				ac.nodes[child].fail = target
+			}
+
+			// Patterns that end at the fallback state also end here
+			failOut := ac.nodes[ac.nodes[child].fail].out
+			ac.nodes[child].out = append(ac.nodes[child].out, failOut...)
+		}
+	}
+
+	return ac
+}
+
+// FindAll returns every (possibly overlapping) pattern occurrence in text
+func (ac *AhoCorasick) FindAll(text []rune) []ACMatch {
+	var matches []ACMatch
+
+	state := 0
+	for i, r := range text {
+		for {
+			if nextState, ok := ac.nodes[state].next[r]; ok {
+				state = nextState
+				break
+			}
+			if state == 0 {
+				break
+			}
+			state = ac.nodes[state].fail
+		}
+
+		for _, p := range ac.nodes[state].out {
+			length := len(ac.patterns[p])
+			matches = append(matches, ACMatch{
+				Pattern: p,
+				Start:   i + 1 - length,
+				End:     i + 1,
+			})
+		}
+	}
+
+	return matches
+}
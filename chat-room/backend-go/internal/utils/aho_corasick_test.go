@@ -0,0 +1,94 @@
+package utils
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestAhoCorasickFindAll(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		text     string
+		want     []ACMatch
+	}{
+		{
+			name:     "overlapping patterns",
+			patterns: []string{"he", "she", "his", "hers"},
+			text:     "ushers",
+			want: []ACMatch{
+				{Pattern: 1, Start: 1, End: 4},
+				{Pattern: 0, Start: 2, End: 4},
+				{Pattern: 3, Start: 2, End: 6},
+			},
+		},
+		{
+			name:     "pattern inside another",
+			patterns: []string{"bad", "badword", "word"},
+			text:     "badword",
+			want: []ACMatch{
+				{Pattern: 0, Start: 0, End: 3},
+				{Pattern: 1, Start: 0, End: 7},
+				{Pattern: 2, Start: 3, End: 7},
+			},
+		},
+		{
+			name:     "repeated occurrences",
+			patterns: []string{"aa"},
+			text:     "aaaa",
+			want: []ACMatch{
+				{Pattern: 0, Start: 0, End: 2},
+				{Pattern: 0, Start: 1, End: 3},
+				{Pattern: 0, Start: 2, End: 4},
+			},
+		},
+		{
+			name:     "multi-byte runes",
+			patterns: []string{"敏感", "感词"},
+			text:     "有敏感词",
+			want: []ACMatch{
+				{Pattern: 0, Start: 1, End: 3},
+				{Pattern: 1, Start: 2, End: 4},
+			},
+		},
+		{
+			name:     "empty pattern is ignored",
+			patterns: []string{"", "x"},
+			text:     "xx",
+			want: []ACMatch{
+				{Pattern: 1, Start: 0, End: 1},
+				{Pattern: 1, Start: 1, End: 2},
+			},
+		},
+		{
+			name:     "no match",
+			patterns: []string{"abc"},
+			text:     "abxabd",
+			want:     nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patterns := make([][]rune, len(tt.patterns))
+			for i, p := range tt.patterns {
+				patterns[i] = []rune(p)
+			}
+
+			got := NewAhoCorasick(patterns).FindAll([]rune(tt.text))
+			sort.Slice(got, func(i, j int) bool {
+				if got[i].Start != got[j].Start {
+					return got[i].Start < got[j].Start
+				}
+				return got[i].End < got[j].End
+			})
+			if len(got) == 0 && len(tt.want) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FindAll(%q) = %v, want %v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
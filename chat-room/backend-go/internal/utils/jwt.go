@@ -8,43 +8,81 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// PurposeTwoFactor marks a challenge token issued between the password
+// and the two-factor step of a login
+const PurposeTwoFactor = "2fa"
+
 // JWTClaims represents the JWT token claims
 type JWTClaims struct {
-	UserID   string `json:"userId"`
-	Username string `json:"username"`
+	UserID    string `json:"userId"`
+	Username  string `json:"username"`
+	SessionID string `json:"sid,omitempty"`     // Refresh token family the token was issued for
+	TwoFactor bool   `json:"tfa,omitempty"`     // The session was verified with a second factor
+	Purpose   string `json:"purpose,omitempty"` // Empty for access tokens
 	jwt.RegisteredClaims
 }
 
-// GenerateToken generates a new JWT token for a user
-func GenerateToken(userID primitive.ObjectID, username, secret string) (string, error) {
+// GenerateToken generates a short-lived access token for a user. Each token
+// gets a unique ID (jti) so it can be revoked on its own.
+func GenerateToken(userID primitive.ObjectID, username, sessionID string, twoFactor bool, keys *KeySet, ttl time.Duration) (string, error) {
+	now := time.Now()
 	claims := JWTClaims{
-		UserID:   userID.Hex(),
-		Username: username,
+		UserID:    userID.Hex(),
+		Username:  username,
+		SessionID: sessionID,
+		TwoFactor: twoFactor,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(7 * 24 * time.Hour)), // 7 days
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ID:        primitive.NewObjectID().Hex(),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(now),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(secret))
+	return keys.Sign(claims)
 }
 
 // ValidateToken validates a JWT token and returns the claims
-func ValidateToken(tokenString, secret string) (*JWTClaims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
-		// Verify signing method
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(secret), nil
-	})
+func ValidateToken(tokenString string, keys *KeySet) (*JWTClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, keys.keyFunc)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse token: %w", err)
+	}
+
+	// Challenge tokens are never accepted as access tokens
+	if claims, ok := token.Claims.(*JWTClaims); ok && token.Valid && claims.Purpose == "" {
+		return claims, nil
+	}
+
+	return nil, fmt.Errorf("invalid token")
+}
+
+// GenerateChallengeToken generates a token proving the password step of a
+// login succeeded, exchanged for an access token after the second factor
+func GenerateChallengeToken(userID primitive.ObjectID, username string, keys *KeySet, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims := JWTClaims{
+		UserID:   userID.Hex(),
+		Username: username,
+		Purpose:  PurposeTwoFactor,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        primitive.NewObjectID().Hex(),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+	}
+
+	return keys.Sign(claims)
+}
 
+// ValidateChallengeToken validates a two-factor challenge token
+func ValidateChallengeToken(tokenString string, keys *KeySet) (*JWTClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, keys.keyFunc)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse token: %w", err)
 	}
 
-	if claims, ok := token.Claims.(*JWTClaims); ok && token.Valid {
+	if claims, ok := token.Claims.(*JWTClaims); ok && token.Valid && claims.Purpose == PurposeTwoFactor {
 		return claims, nil
 	}
 
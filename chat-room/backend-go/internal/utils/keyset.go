@@ -0,0 +1,237 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+
+	"chat-room-backend/internal/config"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// signingKey is a key tokens can be verified with, and signed with if
+// private is set
+type signingKey struct {
+	kid     string
+	method  jwt.SigningMethod
+	private interface{} // []byte, ed25519.PrivateKey or *rsa.PrivateKey
+	public  interface{} // []byte, ed25519.PublicKey or *rsa.PublicKey
+}
+
+// KeySet holds the key new tokens are signed with and every key tokens
+// are still accepted with. Keys are selected by the kid header, so the
+// signing key can be rotated while tokens signed with the old key stay
+// valid until they expire.
+type KeySet struct {
+	signer    *signingKey
+	keys      map[string]*signingKey
+	legacyKID string // HMAC key for tokens issued before key IDs
+}
+
+// JWK is a public key in JSON Web Key format
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	Crv string `json:"crv,omitempty"` // OKP and EC
+	X   string `json:"x,omitempty"`   // OKP and EC
+	Y   string `json:"y,omitempty"`   // EC
+	N   string `json:"n,omitempty"`   // RSA
+	E   string `json:"e,omitempty"`   // RSA
+}
+
+// JWKS is a JSON Web Key Set
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// NewKeySet loads the signing key and the extra verification keys. The
+// shared secret is used for HS256 signing, and kept as a verification
+// key when set explicitly, so switching to an asymmetric algorithm does
+// not log everyone out.
+func NewKeySet(secret string, cfg config.JWTKeyConfig) (*KeySet, error) {
+	ks := &KeySet{keys: make(map[string]*signingKey)}
+
+	if secret != "" && (cfg.Algorithm == config.JWTAlgHS256 || secret != config.DefaultJWTSecret) {
+		key := &signingKey{
+			kid:     hmacKeyID(secret),
+			method:  jwt.SigningMethodHS256,
+			private: []byte(secret),
+			public:  []byte(secret),
+		}
+		ks.add(key)
+		ks.legacyKID = key.kid
+		if cfg.Algorithm == config.JWTAlgHS256 {
+			ks.signer = key
+		}
+	}
+
+	if cfg.Algorithm != config.JWTAlgHS256 {
+		if cfg.SigningKeyFile == "" {
+			return nil, fmt.Errorf("JWT_SIGNING_KEY_FILE is required for %s", cfg.Algorithm)
+		}
+		key, err := loadKeyFile(cfg.SigningKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		if key.private == nil {
+			return nil, fmt.Errorf("%s does not contain a private key", cfg.SigningKeyFile)
+		}
+		if key.method.Alg() != cfg.Algorithm {
+			return nil, fmt.Errorf("%s is not an %s key", cfg.SigningKeyFile, cfg.Algorithm)
+		}
+		ks.add(key)
+		ks.signer = key
+	}
+
+	if ks.signer == nil {
+		return nil, fmt.Errorf("unsupported JWT algorithm: %s", cfg.Algorithm)
+	}
+
+	for _, path := range cfg.VerifyKeyFiles {
+		key, err := loadKeyFile(path)
+		if err != nil {
+			return nil, err
+		}
+		// Only verify with retired keys, never sign
+		key.private = nil
+		ks.add(key)
+	}
+
+	return ks, nil
+}
+
+// add registers a verification key, keeping an existing signing key
+func (ks *KeySet) add(key *signingKey) {
+	if existing, ok := ks.keys[key.kid]; ok && existing.private != nil {
+		return
+	}
+	ks.keys[key.kid] = key
+}
+
+// Sign signs claims with the current signing key and sets the kid header
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(ks.signer.method, claims)
+	token.Header["kid"] = ks.signer.kid
+	return token.SignedString(ks.signer.private)
+}
+
+// keyFunc picks the verification key for a token by its kid header
+func (ks *KeySet) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		kid = ks.legacyKID
+	}
+
+	key, ok := ks.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key: %q", kid)
+	}
+	// The key decides the algorithm, never the token
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return key.public, nil
+}
+
+// JWKS returns the public verification keys. HMAC secrets are never published.
+func (ks *KeySet) JWKS() *JWKS {
+	jwks := &JWKS{Keys: []JWK{}}
+	for _, key := range ks.keys {
+		switch pub := key.public.(type) {
+		case ed25519.PublicKey:
+			jwks.Keys = append(jwks.Keys, JWK{
+				Kty: "OKP",
+				Kid: key.kid,
+				Alg: key.method.Alg(),
+				Use: "sig",
+				Crv: "Ed25519",
+				X:   base64.RawURLEncoding.EncodeToString(pub),
+			})
+		case *rsa.PublicKey:
+			jwks.Keys = append(jwks.Keys, JWK{
+				Kty: "RSA",
+				Kid: key.kid,
+				Alg: key.method.Alg(),
+				Use: "sig",
+				N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+			})
+		}
+	}
+
+	sort.Slice(jwks.Keys, func(i, j int) bool { return jwks.Keys[i].Kid < jwks.Keys[j].Kid })
+	return jwks
+}
+
+// loadKeyFile reads an Ed25519 or RSA key from a PEM file. Private keys
+// may be PKCS#8 or PKCS#1, public keys PKIX or PKCS#1.
+func loadKeyFile(path string) (*signingKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s is not a PEM file", path)
+	}
+
+	var private, public interface{}
+	switch block.Type {
+	case "PRIVATE KEY":
+		private, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		private, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		public, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		public, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%s: unsupported PEM block %q", path, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if signer, ok := private.(crypto.Signer); ok {
+		public = signer.Public()
+	}
+
+	key := &signingKey{private: private, public: public}
+	switch public.(type) {
+	case ed25519.PublicKey:
+		key.method = jwt.SigningMethodEdDSA
+	case *rsa.PublicKey:
+		key.method = jwt.SigningMethodRS256
+	default:
+		return nil, fmt.Errorf("%s: only Ed25519 and RSA keys are supported", path)
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s: %w", path, err)
+	}
+	key.kid = keyID(der)
+	return key, nil
+}
+
+// keyID derives a stable key ID from a public key
+func keyID(der []byte) string {
+	sum := sha256.Sum256(der)
+	return base64.RawURLEncoding.EncodeToString(sum[:12])
+}
+
+// hmacKeyID derives a key ID for a shared secret without revealing it
+func hmacKeyID(secret string) string {
+	sum := sha256.Sum256([]byte("kid:" + secret))
+	return "hs-" + hex.EncodeToString(sum[:6])
+}
@@ -0,0 +1,143 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"chat-room-backend/internal/config"
+	"github.com/golang-jwt/jwt/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const testSecret = "test-secret"
+
+// writePEM writes a PEM block to a file in dir and returns its path
+func writePEM(t *testing.T, dir, name, blockType string, der []byte) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatalf("failed to write %s: %v", name, err)
+	}
+	return path
+}
+
+func TestKeySetRejectsAlgorithmMismatch(t *testing.T) {
+	dir := t.TempDir()
+
+	edPublic, edPrivate, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edDER, _ := x509.MarshalPKCS8PrivateKey(edPrivate)
+	edPublicDER, _ := x509.MarshalPKIXPublicKey(edPublic)
+
+	rsaPrivate, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaPublicDER, _ := x509.MarshalPKIXPublicKey(&rsaPrivate.PublicKey)
+
+	// Sign with EdDSA, still accept a retired RSA key and the old secret
+	ks, err := NewKeySet(testSecret, config.JWTKeyConfig{
+		Algorithm:      config.JWTAlgEdDSA,
+		SigningKeyFile: writePEM(t, dir, "ed25519.pem", "PRIVATE KEY", edDER),
+		VerifyKeyFiles: []string{writePEM(t, dir, "rsa.pub", "PUBLIC KEY", rsaPublicDER)},
+	})
+	if err != nil {
+		t.Fatalf("NewKeySet: %v", err)
+	}
+	edKID := keyID(edPublicDER)
+	rsaKID := keyID(rsaPublicDER)
+	hsKID := hmacKeyID(testSecret)
+
+	claims := func() JWTClaims {
+		return JWTClaims{
+			UserID:   primitive.NewObjectID().Hex(),
+			Username: "alice",
+			RegisteredClaims: jwt.RegisteredClaims{
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+			},
+		}
+	}
+	sign := func(method jwt.SigningMethod, kid string, key interface{}) string {
+		token := jwt.NewWithClaims(method, claims())
+		if kid != "" {
+			token.Header["kid"] = kid
+		}
+		signed, err := token.SignedString(key)
+		if err != nil {
+			t.Fatalf("failed to sign %s token: %v", method.Alg(), err)
+		}
+		return signed
+	}
+	issued, err := ks.Sign(claims())
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		valid bool
+	}{
+		{"issued by the key set", issued, true},
+		{"EdDSA with its kid", sign(jwt.SigningMethodEdDSA, edKID, edPrivate), true},
+		{"RS256 with the retired kid", sign(jwt.SigningMethodRS256, rsaKID, rsaPrivate), true},
+		{"HS256 with the secret's kid", sign(jwt.SigningMethodHS256, hsKID, []byte(testSecret)), true},
+		{"HS256 without kid", sign(jwt.SigningMethodHS256, "", []byte(testSecret)), true},
+
+		// HMAC keyed with the public key, the classic algorithm confusion
+		{"HS256 against the EdDSA kid", sign(jwt.SigningMethodHS256, edKID, edPublicDER), false},
+		{"HS256 against the EdDSA kid with its PEM", sign(jwt.SigningMethodHS256, edKID, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: edPublicDER})), false},
+		{"HS256 against the RS256 kid", sign(jwt.SigningMethodHS256, rsaKID, rsaPublicDER), false},
+		{"HS256 against the RS256 kid with the secret", sign(jwt.SigningMethodHS256, rsaKID, []byte(testSecret)), false},
+		{"RS256 against the EdDSA kid", sign(jwt.SigningMethodRS256, edKID, rsaPrivate), false},
+		{"EdDSA against the RS256 kid", sign(jwt.SigningMethodEdDSA, rsaKID, edPrivate), false},
+		{"EdDSA against the HS256 kid", sign(jwt.SigningMethodEdDSA, hsKID, edPrivate), false},
+		{"EdDSA without kid", sign(jwt.SigningMethodEdDSA, "", edPrivate), false},
+		{"none against the EdDSA kid", sign(jwt.SigningMethodNone, edKID, jwt.UnsafeAllowNoneSignatureType), false},
+		{"unknown kid", sign(jwt.SigningMethodHS256, "unknown", []byte(testSecret)), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ValidateToken(tt.token, ks)
+			if valid := err == nil; valid != tt.valid {
+				t.Errorf("ValidateToken: err = %v, want valid=%v", err, tt.valid)
+			}
+		})
+	}
+}
+
+func TestKeySetDropsDefaultSecret(t *testing.T) {
+	dir := t.TempDir()
+
+	_, edPrivate, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edDER, _ := x509.MarshalPKCS8PrivateKey(edPrivate)
+
+	// The default secret is public; it must not survive a switch to EdDSA
+	ks, err := NewKeySet(config.DefaultJWTSecret, config.JWTKeyConfig{
+		Algorithm:      config.JWTAlgEdDSA,
+		SigningKeyFile: writePEM(t, dir, "ed25519.pem", "PRIVATE KEY", edDER),
+	})
+	if err != nil {
+		t.Fatalf("NewKeySet: %v", err)
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, JWTClaims{UserID: primitive.NewObjectID().Hex()})
+	signed, _ := token.SignedString([]byte(config.DefaultJWTSecret))
+	if _, err := ValidateToken(signed, ks); err == nil {
+		t.Error("token signed with the default secret was accepted")
+	}
+}
@@ -0,0 +1,506 @@
+package utils
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"chat-room-backend/internal/config"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// OIDC provider client settings
+const (
+	oidcHTTPTimeout      = 10 * time.Second
+	oidcMaxResponseBytes = 1 << 20
+	oidcDiscoveryTTL     = 24 * time.Hour
+	oidcKeyRefetchDelay  = time.Minute // Unknown kids refetch the provider's keys at most this often
+	oidcClockLeeway      = time.Minute
+)
+
+// ID token signing algorithms accepted from the provider. HMAC and "none"
+// are never accepted.
+var oidcSigningMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+
+// OIDCDiscovery is the part of an OpenID provider's metadata we use
+type OIDCDiscovery struct {
+	Issuer                   string   `json:"issuer"`
+	AuthorizationEndpoint    string   `json:"authorization_endpoint"`
+	TokenEndpoint            string   `json:"token_endpoint"`
+	UserinfoEndpoint         string   `json:"userinfo_endpoint"`
+	JWKSURI                  string   `json:"jwks_uri"`
+	TokenEndpointAuthMethods []string `json:"token_endpoint_auth_methods_supported"`
+}
+
+// OIDCTokens is the provider's response to an authorization code exchange
+type OIDCTokens struct {
+	IDToken     string `json:"id_token"`
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+}
+
+// OIDCIdentity is the verified identity from an ID token
+type OIDCIdentity struct {
+	Issuer            string
+	Subject           string
+	PreferredUsername string
+	Email             string
+	EmailVerified     bool
+	Name              string
+	Groups            []string
+	GroupsPresent     bool // False if the groups claim was missing, so userinfo may be asked
+	MFA               bool // The provider reports a multi-factor login (amr contains "mfa")
+}
+
+// OIDCProvider talks to an OpenID Connect provider: it builds authorization
+// URLs, exchanges codes and verifies ID tokens against the provider's
+// published keys. Metadata and keys are fetched on first use and cached.
+type OIDCProvider struct {
+	cfg    config.OIDCConfig
+	client *http.Client
+
+	mu            sync.Mutex
+	discovery     *OIDCDiscovery
+	discoveredAt  time.Time
+	keys          map[string]interface{} // kid → *rsa.PublicKey or *ecdsa.PublicKey
+	keysFetchedAt time.Time
+}
+
+// NewOIDCProvider creates a client for the configured provider. Nothing is
+// fetched until the first login, so the server starts while the provider
+// is unreachable.
+func NewOIDCProvider(cfg config.OIDCConfig) *OIDCProvider {
+	return &OIDCProvider{
+		cfg:    cfg,
+		client: &http.Client{Timeout: oidcHTTPTimeout},
+		keys:   make(map[string]interface{}),
+	}
+}
+
+// GeneratePKCE returns a PKCE code verifier and its S256 code challenge
+func GeneratePKCE() (verifier, challenge string, err error) {
+	verifier, err = GenerateOpaqueToken()
+	if err != nil {
+		return "", "", err
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	return verifier, base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// AuthCodeURL returns the provider URL the browser is sent to for login
+func (p *OIDCProvider) AuthCodeURL(ctx context.Context, state, nonce, codeChallenge string) (string, error) {
+	discovery, err := p.getDiscovery(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	params := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.cfg.ClientID},
+		"redirect_uri":          {p.cfg.RedirectURL},
+		"scope":                 {strings.Join(p.cfg.Scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {codeChallenge},
+		"code_challenge_method": {"S256"},
+	}
+
+	separator := "?"
+	if strings.Contains(discovery.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return discovery.AuthorizationEndpoint + separator + params.Encode(), nil
+}
+
+// Exchange trades an authorization code and its PKCE verifier for tokens
+func (p *OIDCProvider) Exchange(ctx context.Context, code, codeVerifier string) (*OIDCTokens, error) {
+	discovery, err := p.getDiscovery(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.cfg.RedirectURL},
+		"client_id":     {p.cfg.ClientID},
+		"code_verifier": {codeVerifier},
+	}
+	useBasicAuth := p.cfg.ClientSecret != "" && supportsBasicAuth(discovery.TokenEndpointAuthMethods)
+	if p.cfg.ClientSecret != "" && !useBasicAuth {
+		form.Set("client_secret", p.cfg.ClientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if useBasicAuth {
+		req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach token endpoint: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, oidcMaxResponseBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read token response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		var oauthErr struct {
+			Error       string `json:"error"`
+			Description string `json:"error_description"`
+		}
+		json.Unmarshal(body, &oauthErr)
+		return nil, fmt.Errorf("token endpoint returned status %d: %s", resp.StatusCode, strings.TrimSpace(oauthErr.Error+" "+oauthErr.Description))
+	}
+
+	var tokens OIDCTokens
+	if err := json.Unmarshal(body, &tokens); err != nil {
+		return nil, fmt.Errorf("failed to decode token response: %w", err)
+	}
+	if tokens.IDToken == "" {
+		return nil, fmt.Errorf("token response has no id_token")
+	}
+	return &tokens, nil
+}
+
+// VerifyIDToken checks an ID token's signature, issuer, audience, expiry
+// and nonce, and returns the identity it asserts
+func (p *OIDCProvider) VerifyIDToken(ctx context.Context, rawIDToken, nonce string) (*OIDCIdentity, error) {
+	discovery, err := p.getDiscovery(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	parser := jwt.NewParser(
+		jwt.WithValidMethods(oidcSigningMethods),
+		jwt.WithIssuer(discovery.Issuer),
+		jwt.WithAudience(p.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(oidcClockLeeway),
+	)
+	claims := jwt.MapClaims{}
+	_, err = parser.ParseWithClaims(rawIDToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.getKey(ctx, kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid ID token: %w", err)
+	}
+
+	if claimString(claims, "nonce") != nonce {
+		return nil, fmt.Errorf("invalid ID token: nonce mismatch")
+	}
+	// With several audiences the token must have been issued to us
+	if azp := claimString(claims, "azp"); azp != "" && azp != p.cfg.ClientID {
+		return nil, fmt.Errorf("invalid ID token: authorized party %q", azp)
+	}
+	if aud, _ := claims.GetAudience(); len(aud) > 1 && claimString(claims, "azp") == "" {
+		return nil, fmt.Errorf("invalid ID token: azp required with multiple audiences")
+	}
+
+	identity := &OIDCIdentity{
+		Issuer:  discovery.Issuer,
+		Subject: claimString(claims, "sub"),
+	}
+	if identity.Subject == "" {
+		return nil, fmt.Errorf("invalid ID token: missing subject")
+	}
+	p.fillIdentity(identity, claims)
+
+	for _, method := range claimStrings(claims["amr"]) {
+		if method == "mfa" {
+			identity.MFA = true
+		}
+	}
+	return identity, nil
+}
+
+// FetchGroups reads the groups claim from the userinfo endpoint, for
+// providers that leave it out of the ID token
+func (p *OIDCProvider) FetchGroups(ctx context.Context, accessToken string, identity *OIDCIdentity) error {
+	discovery, err := p.getDiscovery(ctx)
+	if err != nil {
+		return err
+	}
+	if discovery.UserinfoEndpoint == "" || accessToken == "" {
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, discovery.UserinfoEndpoint, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create userinfo request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Accept", "application/json")
+
+	var claims map[string]interface{}
+	if err := p.doJSON(req, &claims); err != nil {
+		return fmt.Errorf("failed to fetch userinfo: %w", err)
+	}
+	// Userinfo about someone else must not be mixed into this identity
+	if claimString(claims, "sub") != identity.Subject {
+		return fmt.Errorf("userinfo subject does not match ID token")
+	}
+
+	if _, ok := claims[p.cfg.GroupsClaim]; ok {
+		identity.Groups = claimStrings(claims[p.cfg.GroupsClaim])
+		identity.GroupsPresent = true
+	}
+	return nil
+}
+
+// fillIdentity copies the profile and group claims into an identity
+func (p *OIDCProvider) fillIdentity(identity *OIDCIdentity, claims map[string]interface{}) {
+	identity.PreferredUsername = claimString(claims, "preferred_username")
+	identity.Email = claimString(claims, "email")
+	identity.Name = claimString(claims, "name")
+
+	switch verified := claims["email_verified"].(type) {
+	case bool:
+		identity.EmailVerified = verified
+	case string:
+		identity.EmailVerified = verified == "true"
+	}
+
+	if _, ok := claims[p.cfg.GroupsClaim]; ok {
+		identity.Groups = claimStrings(claims[p.cfg.GroupsClaim])
+		identity.GroupsPresent = true
+	}
+}
+
+// getDiscovery returns the provider metadata, fetching it when missing or stale
+func (p *OIDCProvider) getDiscovery(ctx context.Context) (*OIDCDiscovery, error) {
+	p.mu.Lock()
+	discovery := p.discovery
+	fresh := time.Since(p.discoveredAt) < oidcDiscoveryTTL
+	p.mu.Unlock()
+	if discovery != nil && fresh {
+		return discovery, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.cfg.Issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create discovery request: %w", err)
+	}
+
+	var fetched OIDCDiscovery
+	if err := p.doJSON(req, &fetched); err != nil {
+		if discovery != nil {
+			// Keep using stale metadata while the provider is unreachable
+			return discovery, nil
+		}
+		return nil, fmt.Errorf("failed to fetch OIDC discovery document: %w", err)
+	}
+
+	// The document must describe the issuer we were configured with
+	if strings.TrimSuffix(fetched.Issuer, "/") != p.cfg.Issuer {
+		return nil, fmt.Errorf("OIDC discovery issuer %q does not match %q", fetched.Issuer, p.cfg.Issuer)
+	}
+	if fetched.AuthorizationEndpoint == "" || fetched.TokenEndpoint == "" || fetched.JWKSURI == "" {
+		return nil, fmt.Errorf("OIDC discovery document is missing endpoints")
+	}
+
+	p.mu.Lock()
+	p.discovery = &fetched
+	p.discoveredAt = time.Now()
+	p.mu.Unlock()
+	return &fetched, nil
+}
+
+// getKey returns the provider key with the given kid. An unknown kid means
+// the provider rotated its keys, so they are fetched again.
+func (p *OIDCProvider) getKey(ctx context.Context, kid string) (interface{}, error) {
+	p.mu.Lock()
+	key := p.lookupKey(kid)
+	canRefetch := time.Since(p.keysFetchedAt) >= oidcKeyRefetchDelay
+	p.mu.Unlock()
+	if key != nil {
+		return key, nil
+	}
+	if !canRefetch {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	if err := p.fetchKeys(ctx); err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if key = p.lookupKey(kid); key == nil {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	return key, nil
+}
+
+// lookupKey finds a cached key. Tokens without a kid are only accepted
+// while the provider publishes a single key. Callers hold p.mu.
+func (p *OIDCProvider) lookupKey(kid string) interface{} {
+	if kid != "" {
+		return p.keys[kid]
+	}
+	if len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key
+		}
+	}
+	return nil
+}
+
+// fetchKeys replaces the cached keys with the provider's JWKS
+func (p *OIDCProvider) fetchKeys(ctx context.Context) error {
+	discovery, err := p.getDiscovery(ctx)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, discovery.JWKSURI, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create JWKS request: %w", err)
+	}
+
+	var set JWKS
+	if err := p.doJSON(req, &set); err != nil {
+		p.mu.Lock()
+		p.keysFetchedAt = time.Now()
+		p.mu.Unlock()
+		return fmt.Errorf("failed to fetch OIDC signing keys: %w", err)
+	}
+
+	keys := make(map[string]interface{})
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := parseJWK(jwk)
+		if err != nil {
+			continue // Key types we cannot use are skipped
+		}
+		keys[jwk.Kid] = key
+	}
+
+	p.mu.Lock()
+	p.keys = keys
+	p.keysFetchedAt = time.Now()
+	p.mu.Unlock()
+	return nil
+}
+
+// doJSON sends a request and decodes a JSON response
+func (p *OIDCProvider) doJSON(req *http.Request, out interface{}) error {
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned status %d", req.URL.Redacted(), resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, oidcMaxResponseBytes)).Decode(out)
+}
+
+// parseJWK converts an RSA or EC JSON Web Key to a public key
+func parseJWK(jwk JWK) (interface{}, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if len(n) < 256 || !exponent.IsInt64() || exponent.Int64() < 3 {
+			return nil, fmt.Errorf("weak RSA key")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", jwk.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		key := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(key.X, key.Y) {
+			return nil, fmt.Errorf("EC point not on curve")
+		}
+		return key, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", jwk.Kty)
+}
+
+// supportsBasicAuth reports whether the token endpoint takes client
+// credentials via HTTP Basic auth, the default when not advertised
+func supportsBasicAuth(methods []string) bool {
+	if len(methods) == 0 {
+		return true
+	}
+	for _, method := range methods {
+		if method == "client_secret_basic" {
+			return true
+		}
+	}
+	return false
+}
+
+// claimString returns a string claim, or "" if missing or not a string
+func claimString(claims map[string]interface{}, name string) string {
+	value, _ := claims[name].(string)
+	return value
+}
+
+// claimStrings returns a claim holding a list of strings. A single string
+// is treated as a one-item list.
+func claimStrings(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				items = append(items, s)
+			}
+		}
+		return items
+	}
+	return nil
+}
@@ -0,0 +1,200 @@
+package utils
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"chat-room-backend/internal/config"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testOIDCClientID = "chat-room"
+
+// testOIDCIssuer is an OpenID provider serving discovery and a JWKS with
+// one ES256 key
+type testOIDCIssuer struct {
+	server *httptest.Server
+	key    *ecdsa.PrivateKey
+}
+
+func newTestOIDCIssuer(t *testing.T) *testOIDCIssuer {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer := &testOIDCIssuer{key: key}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(OIDCDiscovery{
+			Issuer:                issuer.server.URL,
+			AuthorizationEndpoint: issuer.server.URL + "/authorize",
+			TokenEndpoint:         issuer.server.URL + "/token",
+			JWKSURI:               issuer.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(JWKS{Keys: []JWK{{
+			Kty: "EC",
+			Kid: "key-1",
+			Alg: "ES256",
+			Use: "sig",
+			Crv: "P-256",
+			X:   base64.RawURLEncoding.EncodeToString(key.PublicKey.X.FillBytes(make([]byte, 32))),
+			Y:   base64.RawURLEncoding.EncodeToString(key.PublicKey.Y.FillBytes(make([]byte, 32))),
+		}}})
+	})
+	issuer.server = httptest.NewServer(mux)
+	t.Cleanup(issuer.server.Close)
+	return issuer
+}
+
+// provider returns a client for the issuer
+func (i *testOIDCIssuer) provider() *OIDCProvider {
+	return NewOIDCProvider(config.OIDCConfig{
+		Issuer:      i.server.URL,
+		ClientID:    testOIDCClientID,
+		RedirectURL: "https://chat.example.com/sso/callback",
+		Scopes:      []string{"openid", "profile"},
+		GroupsClaim: "groups",
+	})
+}
+
+// claims returns valid ID token claims for nonce
+func (i *testOIDCIssuer) claims(nonce string) jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"iss":                i.server.URL,
+		"aud":                testOIDCClientID,
+		"sub":                "user-1",
+		"nonce":              nonce,
+		"iat":                now.Unix(),
+		"exp":                now.Add(5 * time.Minute).Unix(),
+		"preferred_username": "alice",
+		"email":              "alice@example.com",
+		"email_verified":     "true",
+		"groups":             []string{"staff"},
+		"amr":                []string{"pwd", "mfa"},
+	}
+}
+
+// sign signs claims with the issuer's key
+func (i *testOIDCIssuer) sign(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+	token.Header["kid"] = "key-1"
+	signed, err := token.SignedString(i.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestGeneratePKCE(t *testing.T) {
+	verifier, challenge, err := GeneratePKCE()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	if challenge != base64.RawURLEncoding.EncodeToString(sum[:]) {
+		t.Errorf("challenge %q is not the S256 digest of the verifier", challenge)
+	}
+	if other, _, _ := GeneratePKCE(); other == verifier {
+		t.Error("verifier generated twice")
+	}
+}
+
+func TestOIDCAuthCodeURL(t *testing.T) {
+	issuer := newTestOIDCIssuer(t)
+
+	authURL, err := issuer.provider().AuthCodeURL(context.Background(), "the-state", "the-nonce", "the-challenge")
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	parsed, err := url.Parse(authURL)
+	if err != nil || !strings.HasPrefix(authURL, issuer.server.URL+"/authorize?") {
+		t.Fatalf("AuthCodeURL() = %q", authURL)
+	}
+
+	want := map[string]string{
+		"response_type":         "code",
+		"client_id":             testOIDCClientID,
+		"state":                 "the-state",
+		"nonce":                 "the-nonce",
+		"code_challenge":        "the-challenge",
+		"code_challenge_method": "S256",
+		"scope":                 "openid profile",
+	}
+	for name, value := range want {
+		if got := parsed.Query().Get(name); got != value {
+			t.Errorf("%s = %q, want %q", name, got, value)
+		}
+	}
+}
+
+func TestOIDCVerifyIDToken(t *testing.T) {
+	issuer := newTestOIDCIssuer(t)
+	provider := issuer.provider()
+	ctx := context.Background()
+
+	identity, err := provider.VerifyIDToken(ctx, issuer.sign(t, issuer.claims("nonce-1")), "nonce-1")
+	if err != nil {
+		t.Fatalf("VerifyIDToken: %v", err)
+	}
+	if identity.Issuer != issuer.server.URL || identity.Subject != "user-1" || identity.PreferredUsername != "alice" ||
+		!identity.EmailVerified || !identity.GroupsPresent || len(identity.Groups) != 1 || !identity.MFA {
+		t.Errorf("identity = %+v", identity)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(jwt.MapClaims)
+		nonce  string
+	}{
+		{"nonce mismatch", func(c jwt.MapClaims) {}, "nonce-2"},
+		{"nonce missing", func(c jwt.MapClaims) { delete(c, "nonce") }, "nonce-1"},
+		{"other audience", func(c jwt.MapClaims) { c["aud"] = "other-app" }, "nonce-1"},
+		{"other issuer", func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" }, "nonce-1"},
+		{"expired", func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-2 * oidcClockLeeway).Unix() }, "nonce-1"},
+		{"no expiry", func(c jwt.MapClaims) { delete(c, "exp") }, "nonce-1"},
+		{"missing subject", func(c jwt.MapClaims) { delete(c, "sub") }, "nonce-1"},
+		{"other authorized party", func(c jwt.MapClaims) { c["azp"] = "other-app" }, "nonce-1"},
+		{"several audiences without azp", func(c jwt.MapClaims) { c["aud"] = []string{testOIDCClientID, "other-app"} }, "nonce-1"},
+	}
+
+	for _, tt := range tests {
+		claims := issuer.claims("nonce-1")
+		tt.modify(claims)
+		if _, err := provider.VerifyIDToken(ctx, issuer.sign(t, claims), tt.nonce); err == nil {
+			t.Errorf("%s: token accepted", tt.name)
+		}
+	}
+
+	// HMAC tokens are never accepted, whatever key they claim
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, issuer.claims("nonce-1"))
+	token.Header["kid"] = "key-1"
+	signed, _ := token.SignedString([]byte("secret"))
+	if _, err := provider.VerifyIDToken(ctx, signed, "nonce-1"); err == nil {
+		t.Error("HS256 token accepted")
+	}
+
+	// A token signed by another key with the same kid is rejected
+	other := newTestOIDCIssuer(t)
+	if _, err := provider.VerifyIDToken(ctx, other.sign(t, issuer.claims("nonce-1")), "nonce-1"); err == nil {
+		t.Error("token signed with another key accepted")
+	}
+}
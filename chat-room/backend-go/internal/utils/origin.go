@@ -0,0 +1,45 @@
+package utils
+
+import (
+	"net/url"
+	"strings"
+)
+
+// OriginAllowed reports whether a browser Origin header matches one of the
+// allowed origins. Origins are compared by scheme, host and port; "*"
+// allows every origin.
+func OriginAllowed(origin string, allowed []string) bool {
+	want, ok := normalizeOrigin(origin)
+	if !ok {
+		return false
+	}
+
+	for _, candidate := range allowed {
+		if candidate == "*" {
+			return true
+		}
+		if got, ok := normalizeOrigin(candidate); ok && got == want {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeOrigin lowercases an origin and drops the default port
+func normalizeOrigin(origin string) (string, bool) {
+	u, err := url.Parse(strings.TrimSpace(origin))
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return "", false
+	}
+
+	scheme := strings.ToLower(u.Scheme)
+	host := strings.ToLower(u.Hostname())
+	port := u.Port()
+	if (scheme == "http" && port == "80") || (scheme == "https" && port == "443") {
+		port = ""
+	}
+	if port != "" {
+		host += ":" + port
+	}
+	return scheme + "://" + host, true
+}
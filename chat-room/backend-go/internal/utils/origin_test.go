@@ -0,0 +1,63 @@
+package utils
+
+import "testing"
+
+func TestNormalizeOrigin(t *testing.T) {
+	tests := []struct {
+		origin string
+		want   string
+		ok     bool
+	}{
+		{"https://example.com", "https://example.com", true},
+		{"HTTPS://Example.COM", "https://example.com", true},
+		{"https://example.com:443", "https://example.com", true},
+		{"http://example.com:80", "http://example.com", true},
+		{"http://example.com:443", "http://example.com:443", true},
+		{"https://example.com:80", "https://example.com:80", true},
+		{"http://localhost:5173", "http://localhost:5173", true},
+		{" https://example.com/ ", "https://example.com", true},
+		{"example.com", "", false},
+		{"null", "", false},
+		{"*", "", false},
+		{"", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := normalizeOrigin(tt.origin)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("normalizeOrigin(%q) = %q, %v, want %q, %v", tt.origin, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestOriginAllowed(t *testing.T) {
+	allowed := []string{"https://Chat.Example.com:443", "http://localhost:5173"}
+
+	tests := []struct {
+		name    string
+		origin  string
+		allowed []string
+		want    bool
+	}{
+		{"exact", "https://chat.example.com", allowed, true},
+		{"case and default port", "HTTPS://CHAT.EXAMPLE.COM:443", allowed, true},
+		{"second origin", "http://localhost:5173", allowed, true},
+		{"other port", "http://localhost:3000", allowed, false},
+		{"other scheme", "http://chat.example.com", allowed, false},
+		{"subdomain", "https://evil.chat.example.com", allowed, false},
+		{"suffix", "https://chat.example.com.evil.com", allowed, false},
+		{"null origin", "null", allowed, false},
+		{"wildcard", "https://anything.test", []string{"*"}, true},
+		{"wildcard among others", "https://anything.test", []string{"https://chat.example.com", "*"}, true},
+		{"wildcard needs a valid origin", "null", []string{"*"}, false},
+		{"nothing allowed", "https://chat.example.com", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := OriginAllowed(tt.origin, tt.allowed); got != tt.want {
+				t.Errorf("OriginAllowed(%q, %q) = %v, want %v", tt.origin, tt.allowed, got, tt.want)
+			}
+		})
+	}
+}
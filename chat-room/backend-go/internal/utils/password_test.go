@@ -0,0 +1,94 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+
+	"chat-room-backend/internal/config"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testArgon2 keeps hashing fast; parameters are clamped to at least 1 MiB
+var testArgon2 = config.Argon2Config{MemoryKiB: 1024, Iterations: 1, Parallelism: 1}
+
+func TestPasswordHasherVerify(t *testing.T) {
+	hasher := NewPasswordHasher(testArgon2)
+
+	current, err := hasher.Hash("correct horse")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	if !strings.HasPrefix(current, "$argon2id$v=19$m=1024,t=1,p=1$") {
+		t.Fatalf("Hash = %q, want an argon2id PHC string", current)
+	}
+
+	older, err := NewPasswordHasher(config.Argon2Config{MemoryKiB: 2048, Iterations: 1, Parallelism: 1}).Hash("correct horse")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+
+	legacy, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("bcrypt: %v", err)
+	}
+
+	parts := strings.Split(current, "$")
+	tests := []struct {
+		name        string
+		encoded     string
+		password    string
+		ok          bool
+		needsRehash bool
+	}{
+		{"argon2id", current, "correct horse", true, false},
+		{"argon2id wrong password", current, "wrong horse", false, false},
+		{"argon2id other parameters", older, "correct horse", true, true},
+		{"argon2id other parameters wrong password", older, "wrong horse", false, false},
+		{"bcrypt", string(legacy), "correct horse", true, true},
+		{"bcrypt wrong password", string(legacy), "wrong horse", false, false},
+		{"argon2i", strings.Replace(current, "argon2id", "argon2i", 1), "correct horse", false, false},
+		{"other version", strings.Replace(current, "v=19", "v=16", 1), "correct horse", false, false},
+		{"zero iterations", strings.Replace(current, "t=1", "t=0", 1), "correct horse", false, false},
+		{"truncated hash", strings.Join(parts[:5], "$"), "correct horse", false, false},
+		{"bad salt", strings.Replace(current, parts[4], "!!", 1), "correct horse", false, false},
+		{"empty", "", "", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, needsRehash := hasher.Verify(tt.encoded, tt.password)
+			if ok != tt.ok || needsRehash != tt.needsRehash {
+				t.Errorf("Verify = %v, %v, want %v, %v", ok, needsRehash, tt.ok, tt.needsRehash)
+			}
+		})
+	}
+}
+
+// TestPasswordHasherRehash follows a login with a legacy bcrypt hash: the
+// password matches, asks for a rehash, and the new hash is current
+func TestPasswordHasherRehash(t *testing.T) {
+	hasher := NewPasswordHasher(testArgon2)
+
+	legacy, err := bcrypt.GenerateFromPassword([]byte("hunter22"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("bcrypt: %v", err)
+	}
+
+	ok, needsRehash := hasher.Verify(string(legacy), "hunter22")
+	if !ok || !needsRehash {
+		t.Fatalf("Verify(bcrypt) = %v, %v, want true, true", ok, needsRehash)
+	}
+
+	rehashed, err := hasher.Hash("hunter22")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	if ok, needsRehash := hasher.Verify(rehashed, "hunter22"); !ok || needsRehash {
+		t.Errorf("Verify(rehashed) = %v, %v, want true, false", ok, needsRehash)
+	}
+
+	// Salts are random, so the same password never hashes the same twice
+	if again, _ := hasher.Hash("hunter22"); again == rehashed {
+		t.Error("Hash returned the same hash twice")
+	}
+}
@@ -0,0 +1,148 @@
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// homoglyphs folds look-alike characters and common substitutions to the
+// Latin letter they imitate. Applied after NFKC and lowercasing.
+var homoglyphs = map[rune]rune{
+	// Cyrillic
+	'а': 'a', 'в': 'b', 'е': 'e', 'ё': 'e', 'к': 'k', 'м': 'm', 'н': 'h',
+	'о': 'o', 'р': 'p', 'с': 'c', 'т': 't', 'у': 'y', 'х': 'x', 'і': 'i',
+	'ј': 'j', 'ѕ': 's',
+	// Greek
+	'α': 'a', 'β': 'b', 'ε': 'e', 'η': 'n', 'ι': 'i', 'κ': 'k', 'ν': 'v',
+	'ο': 'o', 'ρ': 'p', 'τ': 't', 'υ': 'u', 'χ': 'x',
+	// Leetspeak
+	'@': 'a', '$': 's', '0': 'o', '1': 'i', '3': 'e', '4': 'a', '5': 's',
+	'7': 't',
+}
+
+// NormalizedText is text folded for content matching.
+//
+// Runes holds only the significant characters (letters and digits) after
+// NFKC normalization, lowercasing and homoglyph folding, so separators
+// inserted between characters ("敏 感", "f.u.c.k") do not break a match.
+// Folded keeps every character, for regular expression matching.
+type NormalizedText struct {
+	Runes          []rune
+	Starts         []int  // Byte offset in the original text of each rune
+	Ends           []int  // Byte end offset in the original text of each rune
+	BoundaryBefore []bool // A word boundary precedes each rune
+
+	Folded        string
+	foldedToOrig  []int // Original byte offset for each byte of Folded (plus one for the end)
+	originalBytes int
+}
+
+// NormalizeForMatch folds text for content matching
+func NormalizeForMatch(text string) *NormalizedText {
+	nt := &NormalizedText{
+		Runes:          make([]rune, 0, len(text)),
+		Starts:         make([]int, 0, len(text)),
+		Ends:           make([]int, 0, len(text)),
+		BoundaryBefore: make([]bool, 0, len(text)),
+		foldedToOrig:   make([]int, 0, len(text)+1),
+		originalBytes:  len(text),
+	}
+
+	var folded strings.Builder
+	separated := true
+	for offset, r := range text {
+		end := offset + utf8.RuneLen(r)
+
+		for _, f := range FoldRunes(string(r)) {
+			n, _ := folded.WriteRune(f)
+			for i := 0; i < n; i++ {
+				nt.foldedToOrig = append(nt.foldedToOrig, offset)
+			}
+
+			if !unicode.IsLetter(f) && !unicode.IsNumber(f) {
+				separated = true
+				continue
+			}
+
+			// CJK characters are words on their own
+			boundary := separated || isCJK(f)
+			if last := len(nt.Runes) - 1; last >= 0 && isCJK(nt.Runes[last]) {
+				boundary = true
+			}
+
+			nt.Runes = append(nt.Runes, f)
+			nt.Starts = append(nt.Starts, offset)
+			nt.Ends = append(nt.Ends, end)
+			nt.BoundaryBefore = append(nt.BoundaryBefore, boundary)
+			separated = false
+		}
+	}
+	nt.foldedToOrig = append(nt.foldedToOrig, len(text))
+	nt.Folded = folded.String()
+
+	return nt
+}
+
+// IsWordAt reports whether Runes[start:end] is delimited by word boundaries
+func (nt *NormalizedText) IsWordAt(start, end int) bool {
+	if !nt.BoundaryBefore[start] {
+		return false
+	}
+	return end == len(nt.Runes) || nt.BoundaryBefore[end]
+}
+
+// OriginalRange converts a byte range of Folded to a byte range of the original text
+func (nt *NormalizedText) OriginalRange(foldedStart, foldedEnd int) (int, int) {
+	start := nt.foldedToOrig[foldedStart]
+	if foldedEnd >= len(nt.foldedToOrig)-1 {
+		return start, nt.originalBytes
+	}
+	// The end is the start of the first original rune not included
+	return start, nt.foldedToOrig[foldedEnd]
+}
+
+// FoldRunes applies NFKC, lowercasing and homoglyph folding to s
+func FoldRunes(s string) []rune {
+	runes := []rune(norm.NFKC.String(s))
+	for i, r := range runes {
+		r = unicode.ToLower(r)
+		if mapped, ok := homoglyphs[r]; ok {
+			r = mapped
+		}
+		runes[i] = r
+	}
+	return runes
+}
+
+// FoldPattern folds a filter word the same way as NormalizeForMatch,
+// keeping only significant characters
+func FoldPattern(word string) []rune {
+	folded := FoldRunes(word)
+	pattern := make([]rune, 0, len(folded))
+	for _, r := range folded {
+		if unicode.IsLetter(r) || unicode.IsNumber(r) {
+			pattern = append(pattern, r)
+		}
+	}
+	return pattern
+}
+
+// CompileFilterRegex compiles a regex word filter.
+// Regex filters are case-insensitive and run on the folded text.
+func CompileFilterRegex(expr string) (*regexp.Regexp, error) {
+	re, err := regexp.Compile("(?i)" + expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regular expression: %w", err)
+	}
+	return re, nil
+}
+
+// isCJK reports whether r is a Chinese, Japanese or Korean character
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
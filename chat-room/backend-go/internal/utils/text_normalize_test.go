@@ -0,0 +1,64 @@
+package utils
+
+import "testing"
+
+func TestNormalizeForMatch(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"lowercase", "BaD", "bad"},
+		{"full-width forms", "Ｂａｄ", "bad"},
+		{"Cyrillic homoglyphs", "bаd", "bad"},
+		{"Greek homoglyphs", "ΒΑd", "bad"},
+		{"leetspeak", "b4d", "bad"},
+		{"zero-width space", "b​ad", "bad"},
+		{"zero-width joiner", "b‍a‍d", "bad"},
+		{"soft hyphen", "ba­d", "bad"},
+		{"separators", "b.a-d", "bad"},
+		{"spaces between CJK", "敏 感", "敏感"},
+		{"ligature", "ﬁne", "fine"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(NormalizeForMatch(tt.text).Runes); got != tt.want {
+				t.Errorf("NormalizeForMatch(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeForMatchOffsets(t *testing.T) {
+	text := "x Ｂ​ad!"
+	nt := NormalizeForMatch(text)
+	if string(nt.Runes) != "xbad" {
+		t.Fatalf("Runes = %q", string(nt.Runes))
+	}
+
+	// The match "bad" maps back to the original bytes, separators included
+	if got := text[nt.Starts[1]:nt.Ends[3]]; got != "Ｂ​ad" {
+		t.Errorf("original range = %q", got)
+	}
+}
+
+func TestNormalizeForMatchWordBoundaries(t *testing.T) {
+	tests := []struct {
+		text       string
+		start, end int
+		want       bool
+	}{
+		{"bad day", 0, 3, true},
+		{"badday", 0, 3, false},
+		{"a bad", 1, 4, true},
+		{"abad", 1, 4, false},
+		{"说bad话", 1, 4, true}, // CJK characters are words on their own
+	}
+
+	for _, tt := range tests {
+		if got := NormalizeForMatch(tt.text).IsWordAt(tt.start, tt.end); got != tt.want {
+			t.Errorf("IsWordAt(%q, %d, %d) = %v, want %v", tt.text, tt.start, tt.end, got, tt.want)
+		}
+	}
+}
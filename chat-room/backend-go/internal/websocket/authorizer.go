@@ -0,0 +1,66 @@
+package websocket
+
+import (
+	"context"
+	"fmt"
+
+	"chat-room-backend/internal/middleware"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// eventPolicy describes what a client must satisfy to perform an inbound event
+type eventPolicy struct {
+	// requiresMembership means the event targets data.channelId and the
+	// client must have joined that channel
+	requiresMembership bool
+}
+
+// eventPolicies lists every Client -> Server event.
+// Events missing from this table are rejected.
+var eventPolicies = map[string]eventPolicy{
+	EventSwitchChannel: {requiresMembership: true},
+	EventSendMessage:   {requiresMembership: true},
+	EventTyping:        {requiresMembership: true},
+	EventStopTyping:    {requiresMembership: true},
+	// The report service checks membership of the reported message's channel
+	EventReportMessage: {requiresMembership: false},
+}
+
+// Authorizer is consulted for every inbound WebSocket event
+type Authorizer struct {
+	memberships *middleware.MembershipCache
+}
+
+// NewAuthorizer creates a new Authorizer
+func NewAuthorizer(memberships *middleware.MembershipCache) *Authorizer {
+	return &Authorizer{
+		memberships: memberships,
+	}
+}
+
+// Authorize checks whether a client may perform an event on a channel
+func (a *Authorizer) Authorize(ctx context.Context, c *Client, event, channelID string) error {
+	policy, ok := eventPolicies[event]
+	if !ok {
+		return fmt.Errorf("不支持的操作")
+	}
+
+	if !policy.requiresMembership {
+		return nil
+	}
+
+	channelObjID, err := primitive.ObjectIDFromHex(channelID)
+	if err != nil {
+		return fmt.Errorf("无效的频道ID")
+	}
+
+	isMember, err := a.memberships.IsMember(ctx, c.userID, channelObjID)
+	if err != nil {
+		return fmt.Errorf("Failed to verify channel membership")
+	}
+	if !isMember {
+		return fmt.Errorf("您不是该频道成员")
+	}
+
+	return nil
+}
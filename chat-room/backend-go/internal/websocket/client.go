@@ -3,12 +3,16 @@ package websocket
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log"
+	"math"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
 	"chat-room-backend/internal/middleware"
+	"chat-room-backend/internal/models"
 	"chat-room-backend/internal/service"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
@@ -34,16 +38,25 @@ type Client struct {
 	send           chan *WSMessage
 	userID         primitive.ObjectID
 	username       string
-	isAdmin        bool
+	twoFactor      bool // The session was verified with a second factor
+	ip             string
+	sessionID      string // sid of the access token used to connect
 	currentChannel string
 
+	// closing is set once the client is being disconnected; inbound
+	// frames are then discarded until the connection closes
+	closing atomic.Bool
+
 	// Services
 	chatService    *service.ChatService
 	channelService *service.ChannelService
+	moderation     *service.ModerationService
+	reports        *service.ReportService
 
 	// Middleware
-	wordFilter  *middleware.WordFilterCache
-	muteChecker *middleware.MuteChecker
+	floodGuard  *middleware.FloodGuard
+	permissions *middleware.PermissionChecker
+	authorizer  *Authorizer
 }
 
 // NewClient creates a new Client instance
@@ -52,11 +65,16 @@ func NewClient(
 	conn *websocket.Conn,
 	userID primitive.ObjectID,
 	username string,
-	isAdmin bool,
+	twoFactor bool,
+	ip string,
+	sessionID string,
 	chatService *service.ChatService,
 	channelService *service.ChannelService,
-	wordFilter *middleware.WordFilterCache,
-	muteChecker *middleware.MuteChecker,
+	moderation *service.ModerationService,
+	reports *service.ReportService,
+	floodGuard *middleware.FloodGuard,
+	permissions *middleware.PermissionChecker,
+	authorizer *Authorizer,
 ) *Client {
 	return &Client{
 		hub:            hub,
@@ -64,12 +82,62 @@ func NewClient(
 		send:           make(chan *WSMessage, 256),
 		userID:         userID,
 		username:       username,
-		isAdmin:        isAdmin,
+		twoFactor:      twoFactor,
+		ip:             ip,
+		sessionID:      sessionID,
 		chatService:    chatService,
 		channelService: channelService,
-		wordFilter:     wordFilter,
-		muteChecker:    muteChecker,
+		moderation:     moderation,
+		reports:        reports,
+		floodGuard:     floodGuard,
+		permissions:    permissions,
+		authorizer:     authorizer,
+	}
+}
+
+// UserID returns the connected user's ID
+func (c *Client) UserID() primitive.ObjectID {
+	return c.userID
+}
+
+// Username returns the connected user's username
+func (c *Client) Username() string {
+	return c.username
+}
+
+// Can reports whether the connected user holds a permission. Permissions
+// only apply to sessions verified with a second factor.
+func (c *Client) Can(permission string) bool {
+	return c.twoFactor && c.permissions.Has(c.userID, permission)
+}
+
+// Permissions returns the permissions the connected user can use
+func (c *Client) Permissions() []string {
+	if !c.twoFactor {
+		return []string{}
 	}
+	return c.permissions.Permissions(c.userID)
+}
+
+// Role returns the connected user's role
+func (c *Client) Role() string {
+	return c.permissions.RoleOf(c.userID)
+}
+
+// IP returns the remote address the client connected from
+func (c *Client) IP() string {
+	return c.ip
+}
+
+// Send queues a message for delivery to the client without blocking.
+// Messages to a disconnected client are dropped.
+func (c *Client) Send(message *WSMessage) {
+	c.hub.send(c, message)
+}
+
+// SendError sends an error message to the client
+func (c *Client) SendError(message string) {
+	c.sendError(message)
 }
 
 // readPump pumps messages from the WebSocket connection to the hub
@@ -93,11 +161,16 @@ func (c *Client) ReadPump() {
 			}
 			break
 		}
+		if c.closing.Load() {
+			continue
+		}
 
 		// Parse message
 		var wsMsg WSMessage
 		if err := json.Unmarshal(message, &wsMsg); err != nil {
 			log.Printf("Failed to parse WebSocket message: %v", err)
+			// Malformed frames still count towards the rate limit
+			c.checkFlood(context.Background(), "", c.floodGuard.Allow(c.userID, middleware.FloodClassEvent))
 			continue
 		}
 
@@ -142,6 +215,22 @@ func (c *Client) WritePump() {
 func (c *Client) handleMessage(msg *WSMessage) {
 	ctx := context.Background()
 
+	// Rate limit before anything touches the database
+	if !c.checkFlood(ctx, msg.Event, c.floodGuard.Allow(c.userID, floodClass(msg.Event))) {
+		return
+	}
+
+	// Every inbound event goes through the authorizer before it is handled
+	dataBytes, _ := json.Marshal(msg.Data)
+	var target channelTarget
+	json.Unmarshal(dataBytes, &target)
+
+	if err := c.authorizer.Authorize(ctx, c, msg.Event, target.ChannelID); err != nil {
+		log.Printf("🚫 %s denied %s on channel %s: %v", c.username, msg.Event, target.ChannelID, err)
+		c.sendError(err.Error())
+		return
+	}
+
 	switch msg.Event {
 	case EventSwitchChannel:
 		c.handleSwitchChannel(ctx, msg)
@@ -155,6 +244,9 @@ func (c *Client) handleMessage(msg *WSMessage) {
 	case EventStopTyping:
 		c.handleStopTyping(msg)
 
+	case EventReportMessage:
+		c.handleReportMessage(ctx, msg)
+
 	default:
 		log.Printf("Unknown event type: %s", msg.Event)
 	}
@@ -170,17 +262,6 @@ func (c *Client) handleSwitchChannel(ctx context.Context, msg *WSMessage) {
 		return
 	}
 
-	// Verify user is a member of the channel
-	isMember, err := c.channelService.IsMember(ctx, c.userID, data.ChannelID)
-	if err != nil {
-		c.sendError("Failed to verify channel membership")
-		return
-	}
-	if !isMember {
-		c.sendError("您不是该频道成员")
-		return
-	}
-
 	// Join channel room
 	c.hub.JoinChannel(c, data.ChannelID)
 
@@ -209,10 +290,10 @@ func (c *Client) handleSwitchChannel(ctx context.Context, msg *WSMessage) {
 		}
 	}
 
-	c.send <- &WSMessage{
+	c.Send(&WSMessage{
 		Event: EventChannelHistory,
 		Data:  messageData,
-	}
+	})
 
 	log.Printf("📺 %s switched to channel %s", c.username, data.ChannelID)
 }
@@ -233,48 +314,59 @@ func (c *Client) handleSendMessage(ctx context.Context, msg *WSMessage) {
 		return
 	}
 
-	// Check for AI command
-	if strings.HasPrefix(message, "/chat ") {
-		c.handleAICommand(ctx, data.ChannelID, message)
+	// Users exempt from moderation skip read-only channels, filters and slow mode
+	exempt := c.Can(models.PermModerationExempt)
+
+	// Copy-paste spam
+	if !c.checkFlood(ctx, msg.Event, c.floodGuard.CheckDuplicate(c.userID, message)) {
 		return
 	}
 
-	// Check mute status
-	muteResult, err := c.muteChecker.CheckMuteStatus(ctx, c.userID, c.username)
+	// Read-only channels, mutes, word filters and slow mode
+	checked, err := c.moderation.CheckMessage(ctx, c.userID, exempt, data.ChannelID, message)
 	if err != nil {
-		c.sendError("Failed to check mute status")
-		return
-	}
-	if muteResult.IsMuted {
-		c.send <- &WSMessage{
+		var blocked *service.MessageBlockedError
+		if !errors.As(err, &blocked) {
+			c.sendError("Failed to check mute status")
+			return
+		}
+		c.Send(&WSMessage{
 			Event: EventMessageBlocked,
 			Data: MessageBlockedData{
-				Reason:   muteResult.Reason,
-				IsGlobal: muteResult.IsGlobal,
+				Reason:     blocked.Reason,
+				IsGlobal:   blocked.IsGlobal,
+				RetryAfter: int(math.Ceil(blocked.RetryAfter.Seconds())),
 			},
+		})
+		if blocked.Screened != nil {
+			c.notifyEscalation(blocked.Screened)
 		}
 		return
 	}
+	message = checked.Text
 
-	// Check word filter
-	if c.wordFilter.ContainsBlockedWord(message) {
-		c.send <- &WSMessage{
-			Event: EventMessageBlocked,
-			Data: MessageBlockedData{
-				Reason:   "消息包含禁用词汇",
-				IsGlobal: false,
-			},
+	// Check for AI command. The slow mode slot is given back if the AI
+	// does not reply.
+	if strings.HasPrefix(message, "/chat ") {
+		if !c.handleAICommand(ctx, data.ChannelID, message) {
+			checked.Release()
 		}
 		return
 	}
 
 	// Save message
-	savedMsg, err := c.chatService.SendMessage(ctx, c.userID, c.username, message, data.ChannelID)
+	savedMsg, report, err := c.moderation.PostMessage(ctx, checked, func(text string) (*models.Message, error) {
+		return c.chatService.SendMessage(ctx, c.userID, c.username, text, data.ChannelID)
+	})
 	if err != nil {
 		c.sendError("Failed to send message")
 		return
 	}
 
+	if report != nil {
+		c.hub.NotifyNewReport(report)
+	}
+
 	// Broadcast to channel
 	userID := ""
 	if savedMsg.UserID != nil {
@@ -297,13 +389,104 @@ func (c *Client) handleSendMessage(ctx context.Context, msg *WSMessage) {
 	log.Printf("💬 [%s] %s: %s", data.ChannelID, c.username, message[:min(50, len(message))])
 }
 
-// handleAICommand handles AI chat command
-func (c *Client) handleAICommand(ctx context.Context, channelID, message string) {
+// floodClass returns the rate limit class of an inbound event
+func floodClass(event string) string {
+	switch event {
+	case EventSendMessage:
+		return middleware.FloodClassMessage
+	case EventTyping, EventStopTyping:
+		return middleware.FloodClassTyping
+	default:
+		return middleware.FloodClassEvent
+	}
+}
+
+// checkFlood applies a flood verdict: the event is dropped with a
+// rate-limited reply, the user is muted, or the connection is closed.
+// It reports whether the event may be handled.
+func (c *Client) checkFlood(ctx context.Context, event string, verdict *middleware.FloodVerdict) bool {
+	if verdict.Allowed {
+		return true
+	}
+
+	switch verdict.Action {
+	case middleware.FloodActionDisconnect:
+		log.Printf("🌊 Disconnecting %s for flooding (%d violation(s))", c.username, verdict.Violations)
+		c.hub.DisconnectClient(c, &WSMessage{
+			Event: EventError,
+			Data: ErrorData{
+				Message: "发送过于频繁，连接已断开",
+			},
+		})
+		return false
+
+	case middleware.FloodActionMute:
+		log.Printf("🌊 Muting %s for flooding (%d violation(s))", c.username, verdict.Violations)
+		c.notifyEscalation(c.moderation.MuteForFlooding(ctx, c.userID, c.Can(models.PermModerationExempt), verdict.MuteMinutes))
+	}
+
+	// Typing indicators are dropped silently to avoid replying to a flood with one
+	if floodClass(event) != middleware.FloodClassTyping {
+		c.Send(&WSMessage{
+			Event: EventRateLimited,
+			Data: RateLimitedData{
+				Event:        event,
+				Reason:       verdict.Reason,
+				RetryAfterMs: verdict.RetryAfter.Milliseconds(),
+			},
+		})
+	}
+	return false
+}
+
+// notifyEscalation tells all of the user's connections about an automatic
+// warning, mute or ban caused by a blocked message
+func (c *Client) notifyEscalation(screened *service.ModerationResult) {
+	switch {
+	case screened.Banned:
+		bannedUntil := ""
+		if screened.Escalation.Until != nil {
+			bannedUntil = screened.Escalation.Until.Format(time.RFC3339)
+		}
+		c.hub.DisconnectUser(c.userID, &WSMessage{
+			Event: EventBanned,
+			Data: BannedData{
+				Reason:      screened.Reason,
+				BannedUntil: bannedUntil,
+			},
+		})
+
+	case screened.Muted:
+		mutedUntil := ""
+		if screened.MutedUntil != nil {
+			mutedUntil = screened.MutedUntil.Format(time.RFC3339)
+		}
+		c.hub.SendToUser(c.userID, &WSMessage{
+			Event: EventYouWereMuted,
+			Data: YouWereMutedData{
+				Reason:     screened.Reason,
+				MutedUntil: mutedUntil,
+			},
+		})
+
+	case screened.Escalation != nil && screened.Escalation.Level == models.EscalationWarning:
+		c.hub.SendToUser(c.userID, &WSMessage{
+			Event: EventModerationWarning,
+			Data: ModerationWarningData{
+				Reason:      screened.Escalation.Reason,
+				StrikeCount: screened.Escalation.StrikeCount,
+			},
+		})
+	}
+}
+
+// handleAICommand handles AI chat command. It reports whether the AI replied.
+func (c *Client) handleAICommand(ctx context.Context, channelID, message string) bool {
 	// Extract AI message (remove "/chat " prefix)
 	aiMessage := strings.TrimSpace(strings.TrimPrefix(message, "/chat "))
 	if aiMessage == "" {
 		c.sendError("请在 /chat 后输入消息")
-		return
+		return false
 	}
 
 	// Send typing indicator
@@ -328,7 +511,7 @@ func (c *Client) handleAICommand(ctx context.Context, channelID, message string)
 		}, nil)
 
 		c.sendError("AI服务暂时不可用")
-		return
+		return false
 	}
 
 	// Stop typing indicator
@@ -354,6 +537,42 @@ func (c *Client) handleAICommand(ctx context.Context, channelID, message string)
 	}, nil)
 
 	log.Printf("🤖 [%s] DeepSeek AI responded to %s", channelID, c.username)
+	return true
+}
+
+// handleReportMessage handles a user reporting a message
+func (c *Client) handleReportMessage(ctx context.Context, msg *WSMessage) {
+	dataBytes, _ := json.Marshal(msg.Data)
+	var data ReportMessageData
+	if err := json.Unmarshal(dataBytes, &data); err != nil {
+		c.sendError("Invalid report data")
+		return
+	}
+
+	report, err := c.reports.ReportMessage(ctx, c.userID, c.username, &service.ReportMessageRequest{
+		MessageID: data.MessageID,
+		Reason:    data.Reason,
+	})
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "failed to") {
+			log.Printf("❌ Failed to report message %s: %v", data.MessageID, err)
+			c.sendError("Failed to report message")
+			return
+		}
+		c.sendError(err.Error())
+		return
+	}
+
+	c.Send(&WSMessage{
+		Event: EventReportReceived,
+		Data: ReportReceivedData{
+			ReportID:  report.ID.Hex(),
+			MessageID: report.MessageID.Hex(),
+		},
+	})
+	c.hub.NotifyNewReport(report)
+
+	log.Printf("🚩 %s reported message %s", c.username, data.MessageID)
 }
 
 // handleTyping handles typing indicator
@@ -392,12 +611,12 @@ func (c *Client) handleStopTyping(msg *WSMessage) {
 
 // sendError sends an error message to the client
 func (c *Client) sendError(message string) {
-	c.send <- &WSMessage{
+	c.Send(&WSMessage{
 		Event: EventError,
 		Data: ErrorData{
 			Message: message,
 		},
-	}
+	})
 }
 
 // Helper function
@@ -3,6 +3,12 @@ package websocket
 import (
 	"log"
 	"sync"
+	"time"
+
+	"chat-room-backend/internal/middleware"
+	"chat-room-backend/internal/models"
+	"chat-room-backend/internal/service"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // Hub maintains active WebSocket connections and broadcasts messages
@@ -16,9 +22,6 @@ type Hub struct {
 	// Broadcast messages to a specific channel
 	broadcast chan *BroadcastMessage
 
-	// Register requests from clients
-	register chan *Client
-
 	// Unregister requests from clients
 	unregister chan *Client
 
@@ -32,7 +35,6 @@ func NewHub() *Hub {
 		clients:    make(map[*Client]bool),
 		channels:   make(map[string]map[*Client]bool),
 		broadcast:  make(chan *BroadcastMessage, 256),
-		register:   make(chan *Client),
 		unregister: make(chan *Client),
 	}
 }
@@ -41,9 +43,6 @@ func NewHub() *Hub {
 func (h *Hub) Run() {
 	for {
 		select {
-		case client := <-h.register:
-			h.registerClient(client)
-
 		case client := <-h.unregister:
 			h.unregisterClient(client)
 
@@ -53,6 +52,12 @@ func (h *Hub) Run() {
 	}
 }
 
+// Register registers a client. It returns once the client is registered,
+// so messages sent to it right away are not dropped.
+func (h *Hub) Register(client *Client) {
+	h.registerClient(client)
+}
+
 // registerClient registers a new client
 func (h *Hub) registerClient(client *Client) {
 	h.mu.Lock()
@@ -67,28 +72,88 @@ func (h *Hub) unregisterClient(client *Client) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
-	if _, ok := h.clients[client]; ok {
-		// Remove from all channels
-		for channelID, clients := range h.channels {
-			delete(clients, client)
-			if len(clients) == 0 {
-				delete(h.channels, channelID)
-			}
+	h.removeClient(client)
+}
+
+// removeClient removes a registered client and closes its send channel.
+// Must be called with mu held for writing.
+func (h *Hub) removeClient(client *Client) {
+	if _, ok := h.clients[client]; !ok {
+		return
+	}
+
+	// Remove from all channels
+	for channelID, clients := range h.channels {
+		delete(clients, client)
+		if len(clients) == 0 {
+			delete(h.channels, channelID)
 		}
+	}
+
+	// Remove from clients map. Inbound frames still being read are
+	// ignored from now on.
+	delete(h.clients, client)
+	client.closing.Store(true)
+	close(client.send)
+
+	log.Printf("👋 Client unregistered: %s (total: %d)", client.username, len(h.clients))
+}
+
+// queue queues a message for a registered client without blocking. It
+// returns false if the client's send buffer is full. Must be called with
+// mu held, which keeps the send channel from being closed meanwhile.
+func (h *Hub) queue(client *Client, message *WSMessage) bool {
+	select {
+	case client.send <- message:
+		return true
+	default:
+		return false
+	}
+}
+
+// dropSlow closes the connections of clients whose send buffer was full.
+// Must be called without mu held.
+func (h *Hub) dropSlow(clients []*Client) {
+	if len(clients) == 0 {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, client := range clients {
+		log.Printf("🐢 Send buffer of %s is full, closing connection", client.username)
+		h.removeClient(client)
+	}
+}
 
-		// Remove from clients map
-		delete(h.clients, client)
-		close(client.send)
+// send queues a message for a single client. Messages to a client that
+// was unregistered are dropped; a client whose send buffer is full is
+// disconnected.
+func (h *Hub) send(client *Client, message *WSMessage) {
+	h.mu.RLock()
+	if _, ok := h.clients[client]; !ok {
+		h.mu.RUnlock()
+		return
+	}
+	queued := h.queue(client, message)
+	h.mu.RUnlock()
 
-		log.Printf("👋 Client unregistered: %s (total: %d)", client.username, len(h.clients))
+	if !queued {
+		h.dropSlow([]*Client{client})
 	}
 }
 
-// JoinChannel adds a client to a channel
+// JoinChannel adds a client to a channel. Clients that were already
+// unregistered are not added back.
 func (h *Hub) JoinChannel(client *Client, channelID string) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
+	if !h.clients[client] {
+		return
+	}
+
 	if h.channels[channelID] == nil {
 		h.channels[channelID] = make(map[*Client]bool)
 	}
@@ -114,29 +179,73 @@ func (h *Hub) LeaveChannel(client *Client, channelID string) {
 	log.Printf("📺 %s left channel %s", client.username, channelID)
 }
 
-// broadcastToChannel sends a message to all clients in a channel
-func (h *Hub) broadcastToChannel(msg *BroadcastMessage) {
-	h.mu.RLock()
-	clients := h.channels[msg.ChannelID]
-	h.mu.RUnlock()
+// RemoveUserFromChannel removes every connection of a user from a channel
+// and returns the affected clients
+func (h *Hub) RemoveUserFromChannel(userID primitive.ObjectID, channelID string) []*Client {
+	h.mu.Lock()
+	defer h.mu.Unlock()
 
-	if clients == nil {
-		return
+	removed := make([]*Client, 0)
+	clients, ok := h.channels[channelID]
+	if !ok {
+		return removed
 	}
 
 	for client := range clients {
+		if client.userID != userID {
+			continue
+		}
+		delete(clients, client)
+		if client.currentChannel == channelID {
+			client.currentChannel = ""
+		}
+		removed = append(removed, client)
+	}
+	if len(clients) == 0 {
+		delete(h.channels, channelID)
+	}
+
+	if len(removed) > 0 {
+		log.Printf("📺 Removed %d connection(s) of user %s from channel %s", len(removed), userID.Hex(), channelID)
+	}
+	return removed
+}
+
+// broadcastToChannel sends a message to all clients in a channel
+func (h *Hub) broadcastToChannel(msg *BroadcastMessage) {
+	h.mu.RLock()
+	var slow []*Client
+	for client := range h.channels[msg.ChannelID] {
 		// Skip excluded client if specified
 		if msg.Exclude != nil && client == msg.Exclude {
 			continue
 		}
+		// The send channel of an unregistered client is closed
+		if !h.clients[client] {
+			continue
+		}
+		if !h.queue(client, msg.Message) {
+			slow = append(slow, client)
+		}
+	}
+	h.mu.RUnlock()
 
-		select {
-		case client.send <- msg.Message:
-		default:
-			// Client's send buffer is full, close connection
-			h.unregister <- client
+	h.dropSlow(slow)
+}
+
+// deliver queues a message for every registered client a filter matches.
+// The sends happen under mu, so no client is unregistered meanwhile.
+func (h *Hub) deliver(message *WSMessage, match func(*Client) bool) {
+	h.mu.RLock()
+	var slow []*Client
+	for client := range h.clients {
+		if match(client) && !h.queue(client, message) {
+			slow = append(slow, client)
 		}
 	}
+	h.mu.RUnlock()
+
+	h.dropSlow(slow)
 }
 
 // BroadcastToChannel sends a message to a specific channel
@@ -150,17 +259,162 @@ func (h *Hub) BroadcastToChannel(channelID string, message *WSMessage, exclude *
 
 // BroadcastToAll sends a message to all connected clients
 func (h *Hub) BroadcastToAll(message *WSMessage) {
+	h.deliver(message, func(*Client) bool {
+		return true
+	})
+}
+
+// SendToUser sends a message to every connection of a user
+func (h *Hub) SendToUser(userID primitive.ObjectID, message *WSMessage) {
+	h.deliver(message, func(client *Client) bool {
+		return client.userID == userID
+	})
+}
+
+// SendToAdmins sends a message to every connection of an online user who
+// handles reports
+func (h *Hub) SendToAdmins(message *WSMessage) {
+	h.deliver(message, func(client *Client) bool {
+		return client.Can(models.PermReportsManage)
+	})
+}
+
+// NotifyNewReport tells online admins about a new or updated report
+func (h *Hub) NotifyNewReport(report *models.Report) {
+	h.SendToAdmins(&WSMessage{
+		Event: EventNewReport,
+		Data: NewReportData{
+			ReportID:       report.ID.Hex(),
+			MessageID:      report.MessageID.Hex(),
+			ChannelID:      report.ChannelID.Hex(),
+			AuthorUsername: report.AuthorUsername,
+			MessageText:    report.MessageText,
+			Source:         report.Source,
+			Reason:         report.Reason,
+			ReportCount:    report.ReportCount,
+		},
+	})
+}
+
+// GetUserClients returns all connections of a user
+func (h *Hub) GetUserClients(userID primitive.ObjectID) []*Client {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
 
+	result := make([]*Client, 0)
 	for client := range h.clients {
-		select {
-		case client.send <- message:
-		default:
-			// Client's send buffer is full, close connection
-			h.unregister <- client
+		if client.userID == userID {
+			result = append(result, client)
+		}
+	}
+
+	return result
+}
+
+// DisconnectUser sends a final message to every connection of a user and
+// then closes them. It returns the number of closed connections.
+func (h *Hub) DisconnectUser(userID primitive.ObjectID, message *WSMessage) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	closed := 0
+	for client := range h.clients {
+		if client.userID == userID {
+			h.disconnect(client, message)
+			closed++
+		}
+	}
+
+	if closed > 0 {
+		log.Printf("⛔ Disconnected %d connection(s) of user %s", closed, userID.Hex())
+	}
+	return closed
+}
+
+// disconnect queues a final message for a registered client and closes
+// it. The message is queued before the send channel is closed, so
+// WritePump still delivers it. Must be called with mu held for writing.
+func (h *Hub) disconnect(client *Client, message *WSMessage) {
+	h.queue(client, message)
+	h.removeClient(client)
+}
+
+// DisconnectSession closes every connection opened with a login session's
+// tokens. It returns the number of closed connections.
+func (h *Hub) DisconnectSession(sessionID string, message *WSMessage) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	closed := 0
+	for client := range h.clients {
+		if client.sessionID == sessionID {
+			h.disconnect(client, message)
+			closed++
 		}
 	}
+
+	if closed > 0 {
+		log.Printf("⛔ Disconnected %d connection(s) of session %s", closed, sessionID)
+	}
+	return closed
+}
+
+// DisconnectClient sends a final message to a single connection and then
+// closes it. Clients that are already gone are left alone.
+func (h *Hub) DisconnectClient(client *Client, message *WSMessage) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.clients[client]; ok {
+		h.disconnect(client, message)
+	}
+}
+
+// NotifyMuteExpiry pushes an unmuted event to a user's connections
+// whenever their timed mute expires
+func (h *Hub) NotifyMuteExpiry(muteChecker *middleware.MuteChecker) {
+	muteChecker.OnExpire(func(userID primitive.ObjectID) {
+		h.SendToUser(userID, &WSMessage{
+			Event: EventUnmuted,
+			Data: UnmutedData{
+				Reason: "禁言已到期",
+			},
+		})
+	})
+}
+
+// BroadcastGlobalMute tells every client about the current global mute state
+func (h *Hub) BroadcastGlobalMute(status *models.GlobalMuteStatus) {
+	data := GlobalMuteChangedData{
+		IsEnabled: status.IsEnabled,
+		Reason:    status.Reason,
+	}
+	if status.IsEnabled && status.ExpiresAt != nil {
+		data.ExpiresAt = status.ExpiresAt.Format(time.RFC3339)
+	}
+	h.BroadcastToAll(&WSMessage{
+		Event: EventGlobalMuteChanged,
+		Data:  data,
+	})
+}
+
+// NotifyGlobalMuteChanges broadcasts the global mute state whenever
+// the scheduler turns it on or off
+func (h *Hub) NotifyGlobalMuteChanges(scheduler *service.GlobalMuteScheduler) {
+	scheduler.OnChange(h.BroadcastGlobalMute)
+}
+
+// NotifySessionRevocations closes a session's connections whenever the
+// session is ended by logout, revocation or refresh token reuse
+func (h *Hub) NotifySessionRevocations(authService *service.AuthService) {
+	authService.OnSessionRevoked(func(sessionID string) {
+		h.DisconnectSession(sessionID, &WSMessage{
+			Event: EventSessionRevoked,
+			Data: SessionRevokedData{
+				Reason: "登录会话已失效，请重新登录",
+			},
+		})
+	})
 }
 
 // GetOnlineUsers returns a list of all online usernames
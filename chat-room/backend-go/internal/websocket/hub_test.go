@@ -0,0 +1,235 @@
+package websocket
+
+import (
+	"testing"
+	"time"
+
+	"chat-room-backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// newTestClient registers a client without a connection; messages queued
+// for it stay in its send buffer
+func newTestClient(h *Hub, userID primitive.ObjectID, sessionID string, buffer int) *Client {
+	client := &Client{
+		hub:       h,
+		send:      make(chan *WSMessage, buffer),
+		userID:    userID,
+		username:  userID.Hex(),
+		sessionID: sessionID,
+	}
+	h.Register(client)
+	return client
+}
+
+// drain returns the events queued for a client and whether its send
+// channel was closed
+func drain(client *Client) ([]string, bool) {
+	var events []string
+	for {
+		select {
+		case msg, ok := <-client.send:
+			if !ok {
+				return events, true
+			}
+			events = append(events, msg.Event)
+		default:
+			return events, false
+		}
+	}
+}
+
+func TestHubDisconnectUser(t *testing.T) {
+	h := NewHub()
+	userID := primitive.NewObjectID()
+	first := newTestClient(h, userID, "s1", 4)
+	second := newTestClient(h, userID, "s2", 4)
+	other := newTestClient(h, primitive.NewObjectID(), "s3", 4)
+	for _, client := range []*Client{first, second, other} {
+		h.JoinChannel(client, "general")
+	}
+
+	if closed := h.DisconnectUser(userID, &WSMessage{Event: EventBanned}); closed != 2 {
+		t.Fatalf("DisconnectUser closed %d connections, want 2", closed)
+	}
+
+	for _, client := range []*Client{first, second} {
+		events, closed := drain(client)
+		if !closed || len(events) != 1 || events[0] != EventBanned {
+			t.Errorf("disconnected client got %v, closed=%v", events, closed)
+		}
+		if !client.closing.Load() {
+			t.Error("disconnected client is not marked as closing")
+		}
+	}
+	if events, closed := drain(other); closed || len(events) != 0 {
+		t.Errorf("other client got %v, closed=%v", events, closed)
+	}
+	if clients := h.GetChannelClients("general"); len(clients) != 1 || clients[0] != other {
+		t.Errorf("channel members = %d, want only the other client", len(clients))
+	}
+}
+
+// TestHubJoinAfterDisconnect covers a switch-channel still in flight when
+// the client is disconnected: the closed client must not rejoin a channel
+// and be sent to
+func TestHubJoinAfterDisconnect(t *testing.T) {
+	h := NewHub()
+	client := newTestClient(h, primitive.NewObjectID(), "s1", 4)
+	other := newTestClient(h, primitive.NewObjectID(), "s2", 4)
+	h.JoinChannel(other, "general")
+
+	h.DisconnectUser(client.userID, &WSMessage{Event: EventBanned})
+	h.JoinChannel(client, "general")
+	h.broadcastToChannel(&BroadcastMessage{ChannelID: "general", Message: &WSMessage{Event: EventNewMessage}})
+	client.Send(&WSMessage{Event: EventError})
+
+	if clients := h.GetChannelClients("general"); len(clients) != 1 {
+		t.Errorf("channel has %d members, want 1", len(clients))
+	}
+	if events, _ := drain(other); len(events) != 1 || events[0] != EventNewMessage {
+		t.Errorf("other client got %v", events)
+	}
+}
+
+func TestHubBroadcastSkipsUnregisteredMembers(t *testing.T) {
+	h := NewHub()
+	client := newTestClient(h, primitive.NewObjectID(), "s1", 4)
+	h.JoinChannel(client, "general")
+
+	// A stale membership left behind by a racing join
+	h.mu.Lock()
+	h.removeClient(client)
+	h.channels["general"] = map[*Client]bool{client: true}
+	h.mu.Unlock()
+
+	h.broadcastToChannel(&BroadcastMessage{ChannelID: "general", Message: &WSMessage{Event: EventNewMessage}})
+	h.BroadcastToAll(&WSMessage{Event: EventGlobalMuteChanged})
+}
+
+func TestHubDisconnectSession(t *testing.T) {
+	h := NewHub()
+	userID := primitive.NewObjectID()
+	revoked := newTestClient(h, userID, "revoked", 4)
+	kept := newTestClient(h, userID, "kept", 4)
+
+	if closed := h.DisconnectSession("revoked", &WSMessage{Event: EventSessionRevoked}); closed != 1 {
+		t.Fatalf("DisconnectSession closed %d connections, want 1", closed)
+	}
+	if events, closed := drain(revoked); !closed || len(events) != 1 || events[0] != EventSessionRevoked {
+		t.Errorf("revoked session got %v, closed=%v", events, closed)
+	}
+	if _, closed := drain(kept); closed {
+		t.Error("other session was closed")
+	}
+
+	// Closing an already closed client must not close its channel twice
+	h.DisconnectClient(revoked, &WSMessage{Event: EventError})
+	h.unregisterClient(revoked)
+}
+
+func TestHubDropsSlowClients(t *testing.T) {
+	h := NewHub()
+	userID := primitive.NewObjectID()
+	slow := newTestClient(h, userID, "s1", 1)
+	h.JoinChannel(slow, "general")
+
+	h.SendToUser(userID, &WSMessage{Event: EventUnmuted})
+	if _, closed := drain(slow); closed {
+		t.Fatal("client was closed before its buffer was full")
+	}
+
+	h.SendToUser(userID, &WSMessage{Event: EventUnmuted})
+	h.SendToUser(userID, &WSMessage{Event: EventUnmuted})
+	if events, closed := drain(slow); !closed || len(events) != 1 {
+		t.Errorf("slow client got %v, closed=%v; want 1 message and closed", events, closed)
+	}
+	if clients := h.GetUserClients(userID); len(clients) != 0 {
+		t.Errorf("slow client still registered")
+	}
+	if clients := h.GetChannelClients("general"); len(clients) != 0 {
+		t.Errorf("slow client still in the channel")
+	}
+}
+
+func TestHubRemoveUserFromChannel(t *testing.T) {
+	h := NewHub()
+	userID := primitive.NewObjectID()
+	client := newTestClient(h, userID, "s1", 4)
+	other := newTestClient(h, primitive.NewObjectID(), "s2", 4)
+	h.JoinChannel(client, "general")
+	h.JoinChannel(other, "general")
+
+	if removed := h.RemoveUserFromChannel(userID, "general"); len(removed) != 1 || removed[0] != client {
+		t.Fatalf("RemoveUserFromChannel removed %d clients", len(removed))
+	}
+	if client.currentChannel != "" {
+		t.Errorf("currentChannel = %q, want it cleared", client.currentChannel)
+	}
+
+	h.broadcastToChannel(&BroadcastMessage{ChannelID: "general", Message: &WSMessage{Event: EventNewMessage}})
+	if events, _ := drain(client); len(events) != 0 {
+		t.Errorf("removed client got %v", events)
+	}
+	if events, _ := drain(other); len(events) != 1 {
+		t.Errorf("remaining member got %v", events)
+	}
+}
+
+func TestHubBroadcastGlobalMute(t *testing.T) {
+	h := NewHub()
+	client := newTestClient(h, primitive.NewObjectID(), "s1", 4)
+	expiresAt := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		status models.GlobalMuteStatus
+		want   GlobalMuteChangedData
+	}{
+		{
+			"enabled until a time",
+			models.GlobalMuteStatus{IsEnabled: true, Reason: "maintenance", ExpiresAt: &expiresAt},
+			GlobalMuteChangedData{IsEnabled: true, Reason: "maintenance", ExpiresAt: "2026-03-01T12:00:00Z"},
+		},
+		{
+			"enabled without end",
+			models.GlobalMuteStatus{IsEnabled: true, Reason: "maintenance"},
+			GlobalMuteChangedData{IsEnabled: true, Reason: "maintenance"},
+		},
+		{
+			"disabled keeps no stale end",
+			models.GlobalMuteStatus{IsEnabled: false, ExpiresAt: &expiresAt},
+			GlobalMuteChangedData{IsEnabled: false},
+		},
+	}
+
+	for _, tt := range tests {
+		h.BroadcastGlobalMute(&tt.status)
+		msg := <-client.send
+		if msg.Event != EventGlobalMuteChanged {
+			t.Fatalf("%s: event = %s", tt.name, msg.Event)
+		}
+		if got := msg.Data.(GlobalMuteChangedData); got != tt.want {
+			t.Errorf("%s: data = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestHubSendToUser(t *testing.T) {
+	h := NewHub()
+	userID := primitive.NewObjectID()
+	first := newTestClient(h, userID, "s1", 4)
+	second := newTestClient(h, userID, "s2", 4)
+	other := newTestClient(h, primitive.NewObjectID(), "s3", 4)
+
+	h.SendToUser(userID, &WSMessage{Event: EventYouWereMuted})
+
+	for _, client := range []*Client{first, second} {
+		if events, _ := drain(client); len(events) != 1 || events[0] != EventYouWereMuted {
+			t.Errorf("muted user's connection got %v", events)
+		}
+	}
+	if events, _ := drain(other); len(events) != 0 {
+		t.Errorf("other user got %v", events)
+	}
+}
@@ -19,22 +19,36 @@ type BroadcastMessage struct {
 
 const (
 	// Server -> Client events
-	EventInitialData       = "initial-data"
-	EventChannelHistory    = "channel-history"
-	EventNewMessage        = "new-message"
-	EventUserList          = "user-list"
-	EventUserJoinedChannel = "user-joined-channel"
-	EventUserLeft          = "user-left"
-	EventUserTyping        = "user-typing"
-	EventUserStopTyping    = "user-stop-typing"
-	EventMessageBlocked    = "message-blocked"
-	EventError             = "error"
+	EventInitialData          = "initial-data"
+	EventChannelHistory       = "channel-history"
+	EventNewMessage           = "new-message"
+	EventUserList             = "user-list"
+	EventUserJoinedChannel    = "user-joined-channel"
+	EventUserLeft             = "user-left"
+	EventUserTyping           = "user-typing"
+	EventUserStopTyping       = "user-stop-typing"
+	EventMessageBlocked       = "message-blocked"
+	EventRemovedFromChannel   = "removed-from-channel"
+	EventYouWereMuted         = "you-were-muted"
+	EventUnmuted              = "unmuted"
+	EventGlobalMuteChanged    = "global-mute-changed"
+	EventBanned               = "banned"
+	EventModerationWarning    = "moderation-warning"
+	EventReportReceived       = "report-received"
+	EventNewReport            = "new-report" // Sent to online admins
+	EventMessageDeleted       = "message-deleted"
+	EventRateLimited          = "rate-limited"
+	EventSlowModeChanged      = "slow-mode-changed"
+	EventPostingPolicyChanged = "posting-policy-changed"
+	EventSessionRevoked       = "session-revoked"
+	EventError                = "error"
 
 	// Client -> Server events (handled in client.go)
 	EventSwitchChannel = "switch-channel"
 	EventSendMessage   = "send-message"
 	EventTyping        = "typing"
 	EventStopTyping    = "stop-typing"
+	EventReportMessage = "report-message"
 )
 
 // ============================================================
@@ -43,20 +57,34 @@ const (
 
 // InitialData sent when client connects
 type InitialData struct {
-	Channels          []ChannelData `json:"channels"`
-	AvailableChannels []ChannelData `json:"availableChannels"`
-	IsAdmin           bool          `json:"isAdmin"`
-	Username          string        `json:"username"`
-	UserID            string        `json:"userId"`
+	Channels          []ChannelData  `json:"channels"`
+	AvailableChannels []ChannelData  `json:"availableChannels"`
+	IsAdmin           bool           `json:"isAdmin"` // Holds any permission
+	Role              string         `json:"role"`
+	Permissions       []string       `json:"permissions"`
+	Username          string         `json:"username"`
+	UserID            string         `json:"userId"`
+	MuteStatus        MuteStatusData `json:"muteStatus"`
+}
+
+// MuteStatusData represents the user's current mute state
+type MuteStatusData struct {
+	IsMuted    bool   `json:"isMuted"`
+	IsGlobal   bool   `json:"isGlobal"`
+	Reason     string `json:"reason,omitempty"`
+	MutedUntil string `json:"mutedUntil,omitempty"` // Empty for permanent mutes
 }
 
 // ChannelData represents channel information
 type ChannelData struct {
-	ID          string `json:"id"`
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	IsDefault   bool   `json:"isDefault"`
-	Icon        string `json:"icon"`
+	ID              string `json:"id"`
+	Name            string `json:"name"`
+	Description     string `json:"description"`
+	IsDefault       bool   `json:"isDefault"`
+	Icon            string `json:"icon"`
+	SlowModeSeconds int    `json:"slowModeSeconds"`
+	PostingPolicy   string `json:"postingPolicy"`
+	CanPost         bool   `json:"canPost"` // False when the composer should be disabled
 }
 
 // MessageData represents a chat message
@@ -84,8 +112,94 @@ type TypingData struct {
 
 // MessageBlockedData represents blocked message notification
 type MessageBlockedData struct {
-	Reason   string `json:"reason"`
-	IsGlobal bool   `json:"isGlobal"`
+	Reason     string `json:"reason"`
+	IsGlobal   bool   `json:"isGlobal"`
+	RetryAfter int    `json:"retryAfter,omitempty"` // Seconds until slow mode allows the next message
+}
+
+// RemovedFromChannelData tells a client it no longer belongs to a channel
+type RemovedFromChannelData struct {
+	ChannelID string `json:"channelId"`
+	Reason    string `json:"reason"`
+}
+
+// YouWereMutedData tells a user they have been muted
+type YouWereMutedData struct {
+	Reason     string `json:"reason"`
+	MutedUntil string `json:"mutedUntil,omitempty"` // Empty for permanent mutes
+}
+
+// UnmutedData tells a user their mute has been lifted
+type UnmutedData struct {
+	Reason string `json:"reason"`
+}
+
+// GlobalMuteChangedData represents a global mute toggle
+type GlobalMuteChangedData struct {
+	IsEnabled bool   `json:"isEnabled"`
+	Reason    string `json:"reason"`
+	ExpiresAt string `json:"expiresAt,omitempty"` // Set when it turns off by itself
+}
+
+// BannedData tells a user they have been banned before disconnecting them
+type BannedData struct {
+	Reason      string `json:"reason"`
+	BannedUntil string `json:"bannedUntil,omitempty"` // Empty for permanent bans
+}
+
+// SessionRevokedData tells a connection its login session was ended before disconnecting it
+type SessionRevokedData struct {
+	Reason string `json:"reason"`
+}
+
+// ModerationWarningData warns a user that further violations will be punished
+type ModerationWarningData struct {
+	Reason      string `json:"reason"`
+	StrikeCount int64  `json:"strikeCount"`
+}
+
+// ReportReceivedData confirms a report to the reporter
+type ReportReceivedData struct {
+	ReportID  string `json:"reportId"`
+	MessageID string `json:"messageId"`
+}
+
+// NewReportData tells admins a message was reported or flagged
+type NewReportData struct {
+	ReportID       string `json:"reportId"`
+	MessageID      string `json:"messageId"`
+	ChannelID      string `json:"channelId"`
+	AuthorUsername string `json:"authorUsername"`
+	MessageText    string `json:"messageText"`
+	Source         string `json:"source"`
+	Reason         string `json:"reason"`
+	ReportCount    int    `json:"reportCount"`
+}
+
+// MessageDeletedData tells a channel a message was removed by a moderator
+type MessageDeletedData struct {
+	MessageID string `json:"messageId"`
+	ChannelID string `json:"channelId"`
+}
+
+// RateLimitedData tells a client an event was dropped for exceeding the rate limit
+type RateLimitedData struct {
+	Event        string `json:"event"`
+	Reason       string `json:"reason"`
+	RetryAfterMs int64  `json:"retryAfterMs"`
+}
+
+// SlowModeChangedData tells a channel its slow mode interval changed
+type SlowModeChangedData struct {
+	ChannelID       string `json:"channelId"`
+	SlowModeSeconds int    `json:"slowModeSeconds"` // 0 when slow mode is off
+}
+
+// PostingPolicyChangedData tells a client whether it may post in a channel
+type PostingPolicyChangedData struct {
+	ChannelID     string `json:"channelId"`
+	PostingPolicy string `json:"postingPolicy"`
+	CanPost       bool   `json:"canPost"`
 }
 
 // ErrorData represents error notification
@@ -93,6 +207,11 @@ type ErrorData struct {
 	Message string `json:"message"`
 }
 
+// channelTarget extracts the channel an inbound event targets
+type channelTarget struct {
+	ChannelID string `json:"channelId"`
+}
+
 // SwitchChannelData from client
 type SwitchChannelData struct {
 	ChannelID string `json:"channelId"`
@@ -104,6 +223,12 @@ type SendMessageData struct {
 	ChannelID string `json:"channelId"`
 }
 
+// ReportMessageData from client
+type ReportMessageData struct {
+	MessageID string `json:"messageId"`
+	Reason    string `json:"reason"`
+}
+
 // TypingEventData from client
 type TypingEventData struct {
 	ChannelID string `json:"channelId"`